@@ -0,0 +1,33 @@
+package main
+
+import (
+	"flag"
+	"log"
+	"net/http"
+	"net/url"
+
+	reflectshape "github.com/podhmo/reflect-shape"
+)
+
+// the entry point of the doc server, serving the shapes extracted in this process.
+// replace the extracted types with yours.
+//
+//	$ go run ./_examples/docserver -addr :8080
+//	$ open http://localhost:8080/
+func main() {
+	addr := flag.String("addr", ":8080", "the address to listen")
+	title := flag.String("title", "Shapes", "the title of the page")
+	flag.Parse()
+
+	cfg := &reflectshape.Config{}
+	for _, ob := range []interface{}{http.Client{}, http.Request{}, http.Cookie{}, http.ListenAndServe, url.URL{}} {
+		cfg.Extract(ob)
+	}
+
+	server := reflectshape.NewDocServer(cfg.Registry())
+	server.Title = *title
+	log.Printf("listening on %s", *addr)
+	if err := http.ListenAndServe(*addr, server); err != nil {
+		log.Fatalf("!! %+v", err)
+	}
+}
@@ -0,0 +1,30 @@
+package reflectshape
+
+import "sync"
+
+// shapeSlab allocates the shapes in bulk, to reduce the GC pressure of the mass extraction. (see Config.BulkAllocation)
+//
+// A slab is released only after all shapes in it become unreachable, so it is not suitable for the long-running process.
+type shapeSlab struct {
+	mu   sync.Mutex
+	size int
+	buf  []Shape
+}
+
+// copyShape returns the new shape copied from s, allocated from the slab if a is not nil.
+func (a *shapeSlab) copyShape(s *Shape) *Shape {
+	if a == nil {
+		copied := *s
+		return &copied
+	}
+	a.mu.Lock()
+	if len(a.buf) == 0 {
+		a.buf = make([]Shape, a.size)
+	}
+	copied := &a.buf[0]
+	a.buf = a.buf[1:]
+	a.mu.Unlock()
+
+	*copied = *s
+	return copied
+}
@@ -0,0 +1,60 @@
+package reflectshape_test
+
+import (
+	"reflect"
+	"testing"
+
+	reflectshape "github.com/podhmo/reflect-shape"
+)
+
+func TestBulkAllocation(t *testing.T) {
+	cfg := &reflectshape.Config{SkipComments: true, BulkAllocation: 2}
+
+	obs := []interface{}{1, "", 1.0, true, []int{}, map[string]int{}, &Person{}}
+	shapes := make([]*reflectshape.Shape, len(obs))
+	for i, ob := range obs {
+		shapes[i] = cfg.Extract(ob)
+	}
+	for i, ob := range obs {
+		rt := reflect.TypeOf(ob)
+		if rt.Kind() == reflect.Pointer {
+			rt = rt.Elem()
+		}
+		if want, got := rt, shapes[i].Type; want != got {
+			t.Errorf("obs[%d]: want:%v != got:%v", i, want, got)
+		}
+		if want, got := i, shapes[i].Number; want != got {
+			t.Errorf("obs[%d] Number: want:%v != got:%v", i, want, got)
+		}
+	}
+	if want, got := 1, shapes[len(shapes)-1].Lv; want != got {
+		t.Errorf("Lv: want:%v != got:%v", want, got)
+	}
+
+	// the returned shapes are independent of each other, even in the same slab
+	shapes[0].Name = "modified"
+	if want, got := "int", cfg.Extract(1).Name; want != got {
+		t.Errorf("the cache is poisoned: want:%v != got:%v", want, got)
+	}
+}
+
+func BenchmarkExtract(b *testing.B) {
+	obs := []interface{}{1, "", 1.0, true, []int{}, map[string]int{}, Person{}, UserCreated{}, Address{}}
+	for _, bulk := range []int{0, 1024} {
+		name := "default"
+		if bulk > 0 {
+			name = "bulk"
+		}
+		b.Run(name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				cfg := &reflectshape.Config{SkipComments: true, BulkAllocation: bulk}
+				for j := 0; j < 100; j++ {
+					for _, ob := range obs {
+						cfg.Extract(ob)
+					}
+				}
+			}
+		})
+	}
+}
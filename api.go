@@ -2,49 +2,316 @@ package reflectshape
 
 import (
 	"go/token"
+	"log"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
 
 	"github.com/podhmo/reflect-shape/metadata"
 )
 
+// Config is the configuration of the extraction, and the entry point. (e.g. cfg.Extract(ob))
+//
+// Config is safe for concurrent use by multiple goroutines (e.g. shared across HTTP handlers),
+// but the settings (the fields, Override()) must not be changed while extracting.
 type Config struct {
 	SkipComments       bool // if true, skip extracting argNames and comments
-	FillArgNames       bool // func(context.Context, int) -> func(ctx context.Context, arg0 int)
-	FillReturnNames    bool // func() (int, error) -> func() (ret0, err)
-	IncludeGoTestFiles bool
+	FillArgNames       bool // func(context.Context, int) -> func(ctx context.Context, arg0 int) (the blank names are also filled)
+	FillReturnNames    bool // func() (int, error) -> func() (ret0, err) (the named returns are kept, the filled names don't conflict with the args)
+	IncludeGoTestFiles bool // if true, the test files (_test.go) are looked up too. In the test binary (go test), it is enabled automatically
+	ExcludeGoTestFiles bool // if true, the test files are not looked up even in the test binary (see IncludeGoTestFiles)
+	Offline            bool // if true, forbid the network access (module downloads) during loading packages
+	Trace              bool // if true, log how the names are parsed, for debugging
+	RecoverPanics      bool // if true, the panics in the metadata lookups, the hooks, and ExtractAll() are converted into *PanicError (see PanicError for the accessors)
+	FlattenEmbedded    bool // if true, Struct.Fields() returns the fields promoted from the embedded structs too (see Struct.PromotedFields())
+
+	// GOOS, GOARCH, and BuildTags override the build context for looking up the comments (e.g. selecting x_windows.go instead of x_linux.go)
+	GOOS      string
+	GOARCH    string
+	BuildTags []string
+
+	// MaxShapes and MaxPackages limit the cached shapes and the packages cached by the metadata lookup, if positive.
+	// The least recently used ones are evicted. (for the long-running servers, see CacheStats())
+	MaxShapes   int
+	MaxPackages int
+
+	// InternStrings deduplicates the strings held by the shapes (names and package paths of the funcs, and docs of the fields).
+	// It saves the memory of the large registries. (e.g. module-wide extraction, see CacheStats())
+	InternStrings bool
+
+	// BulkAllocation allocates the shapes in slabs of this size, and preallocates the caches, if positive.
+	// It reduces the GC pressure of the one-shot extraction of the many shapes (e.g. CLI), but the memory is released only per slab.
+	BulkAllocation int
+
+	// CacheDir enables the disk cache of the comments, if not empty (e.g. metadata.DefaultCacheDir()). It reduces the cost of loading packages at startup.
+	CacheDir string
+	// CacheEncoding is the encoding of the disk cache (e.g. metadata.EncodingGob, faster to load the large packages). If empty, JSON is used.
+	CacheEncoding metadata.Encoding
+
+	// MetadataSources is the ordered chain of the metadata sources (see metadata.Lookup.Sources). If nil, metadata.DefaultSources is used.
+	MetadataSources []metadata.Source
+
+	// CollectConcurrency is the number of the goroutines collecting the comments of the files in a package (see metadata.Lookup).
+	CollectConcurrency int
+
+	// PositionRoot is the directory the formatted positions are relative to (e.g. the repository root). (see FormatPosition)
+	PositionRoot string
 
 	DocTruncationSize int
+	WellKnownTypes    map[string]bool // full names of the types treated as scalar (e.g. "time.Time"), merged on the defaults (false disables the default one)
+
+	// Namespaces maps import path prefixes to exported namespaces (e.g. "github.com/foo/bar/internal" -> "bar"). the longest prefix is matched.
+	Namespaces map[string]string
+
+	// Patches are merged into the extracted shapes, keyed by path (<pkgpath>.<Type> or <pkgpath>.<Type>.<Field>). (see ReadPatches())
+	Patches map[string]*Patch
+
+	// CommentPackages are the patterns of the packages, whose comments are extracted (e.g. "github.com/foo/bar/..."). If empty, all packages are target.
+	// The shapes of the other packages are extracted structurally only.
+	CommentPackages []string
+	// ExcludeCommentPackages are the patterns of the packages excluded from CommentPackages. (e.g. "github.com/foo/bar/internal/...")
+	ExcludeCommentPackages []string
+
+	Hooks Hooks
+
+	// Logger receives the logs of the extraction and the metadata lookup (e.g. the load errors, the failures of the accessors), if not nil.
+	// If nil, the standard logger (log.Printf) is used.
+	Logger metadata.Logger
 
 	Fset      *token.FileSet
 	extractor *Extractor
 	lookup    *metadata.Lookup
+	positions *metadata.PositionFormatter
+
+	parent    *Config
+	overrides map[reflect.Type]reflect.Type
+
+	wellKnownTypes map[string]bool // WellKnownTypes merged on the defaults
+	initialized    uint32          // 1 if initialized (see init), accessed atomically
 }
 
 var (
 	DocTruncationSize = 10
+
+	// WellKnownTypes is the default value of Config.WellKnownTypes
+	WellKnownTypes = map[string]bool{
+		"time.Time":                   true,
+		"math/big.Int":                true,
+		"math/big.Float":              true,
+		"net/url.URL":                 true,
+		"github.com/google/uuid.UUID": true,
+	}
 )
 
+// Hooks are the extension points of the extraction.
+type Hooks struct {
+	// BeforeExtract is called before a new shape is extracted. If it returns false, the extraction is vetoed.
+	// (Extract() returns nil, and the vetoed fields, args, return values, and methods are skipped in Struct.Fields(), Func.Args(), Func.Returns(), and Interface.Methods())
+	BeforeExtract func(rt reflect.Type) bool
+
+	// AfterExtract is called after a new shape is extracted, before it is cached. It is called once per shape, the other goroutines extracting the same type wait for it.
+	AfterExtract func(s *Shape)
+
+	// LookupType overrides the metadata resolution of types. If ok is false, the default lookup is used.
+	LookupType func(rt reflect.Type) (m *metadata.Type, ok bool)
+
+	// LookupFunc overrides the metadata resolution of functions. If ok is false, the default lookup is used.
+	LookupFunc func(pc uintptr) (m *metadata.Func, ok bool)
+
+	// ArgName overrides the name of the unnamed i-th argument filled by Config.FillArgNames. If it returns "", the default name is used. (e.g. "ctx", "arg0")
+	// rt is the declared type (e.g. []string for ...string). The conflicting names are numbered. (e.g. "req1")
+	ArgName func(fn *Func, i int, rt reflect.Type) string
+
+	// ReturnName overrides the name of the unnamed i-th return value filled by Config.FillReturnNames, as ArgName. (e.g. "err", "ret0")
+	ReturnName func(fn *Func, i int, rt reflect.Type) string
+
+	// Redeclared chooses the declaration of the name declared in the multiple files of a package (e.g. the build-constrained duplicates).
+	// If it returns -1, the default rule is used. (see metadata.Redeclaration.Default)
+	Redeclared func(r *metadata.Redeclaration) int
+}
+
 func (c *Config) Extract(ob interface{}) *Shape {
+	c.init()
+	return c.extractor.Extract(ob)
+}
+
+// ExtractType is Extract for the callers holding the reflect.Type, without the instance of it (e.g. inside the encoders).
+// The shape is the same as Extract of the value of the type, except for the func types: they are the signatures, not the functions, so they have no names and no comments.
+func (c *Config) ExtractType(rt reflect.Type) *Shape {
+	c.init()
+	return c.extractor.ExtractType(rt)
+}
+
+// ExtractValue is Extract for the callers holding the reflect.Value. It returns nil for the zero Value.
+// rv.Type() is used as is, so the interface type of the value is extracted, unlike Extract. (e.g. the value of the field of any)
+func (c *Config) ExtractValue(rv reflect.Value) *Shape {
+	c.init()
+	return c.extractor.ExtractValue(rv)
+}
+
+// configInits holds the sync.Once of the configs being initialized, keyed by *Config. (Config is copied by NewChild, so it cannot have the Once)
+var configInits sync.Map
+
+// init initializes the config lazily, once per config. The initialized configs don't touch configInits.
+func (c *Config) init() {
+	if atomic.LoadUint32(&c.initialized) == 1 {
+		return
+	}
+	once, _ := configInits.LoadOrStore(c, new(sync.Once))
+	once.(*sync.Once).Do(c.initOnce)
+	configInits.Delete(c) // the Once stored after this is no-op, c.initialized is already set
+}
+
+func (c *Config) initOnce() {
+	if atomic.LoadUint32(&c.initialized) == 1 {
+		return
+	}
+	defer atomic.StoreUint32(&c.initialized, 1)
+
 	if c.DocTruncationSize == 0 {
 		c.DocTruncationSize = DocTruncationSize
 	}
+	if c.wellKnownTypes == nil {
+		c.wellKnownTypes = make(map[string]bool, len(WellKnownTypes)+len(c.WellKnownTypes))
+		for name, ok := range WellKnownTypes {
+			c.wellKnownTypes[name] = ok
+		}
+		for name, ok := range c.WellKnownTypes {
+			c.wellKnownTypes[name] = ok
+		}
+	}
 
 	if c.lookup == nil && !c.SkipComments {
 		if c.Fset == nil {
 			c.Fset = token.NewFileSet()
 		}
 		c.lookup = metadata.NewLookup(c.Fset)
-		c.lookup.IncludeGoTestFiles = c.IncludeGoTestFiles
+		c.lookup.IncludeGoTestFiles = c.IncludeGoTestFiles || (!c.ExcludeGoTestFiles && metadata.IsTestBinary())
 		c.lookup.IncludeUnexported = true
+		c.lookup.Offline = c.Offline
+		c.lookup.Trace = c.Trace
+		c.lookup.GOOS = c.GOOS
+		c.lookup.GOARCH = c.GOARCH
+		c.lookup.BuildTags = c.BuildTags
+		c.lookup.CacheDir = c.CacheDir
+		c.lookup.Encoding = c.CacheEncoding
+		c.lookup.MaxPackages = c.MaxPackages
+		c.lookup.CollectConcurrency = c.CollectConcurrency
+		c.lookup.Redeclared = c.Hooks.Redeclared
+		c.lookup.Sources = c.MetadataSources
+		c.lookup.Logger = c.Logger
+		c.positions = metadata.NewPositionFormatter(c.Fset, c.PositionRoot)
 	}
 	if c.extractor == nil {
 		c.extractor = &Extractor{
 			Config:   c,
 			Lookup:   c.lookup,
-			seen:     map[ID]*Shape{},
+			seen:     make(map[ID]*Shape, c.BulkAllocation),
 			packages: map[string]*Package{},
 		}
+		if c.BulkAllocation > 0 {
+			c.extractor.slab = &shapeSlab{size: c.BulkAllocation}
+		}
+		if c.InternStrings {
+			c.extractor.interner = &interner{strings: map[string]string{}}
+		}
 	}
-	return c.extractor.Extract(ob)
+}
+
+// NewChild returns the child config, that has the same settings and shares the metadata lookup with c.
+// The types overridden in the child are extracted as its replacements, and the other types fall back to the overrides of c.
+func (c *Config) NewChild() *Config {
+	c.init()
+	child := *c
+	child.initialized = 0
+	child.parent = c
+	child.overrides = nil
+	child.extractor = nil
+	return &child
+}
+
+// Override registers the replacement of the type of ob. (e.g. test doubles or redacted variants)
+// After that, when the type of ob is extracted, the shape of the type of replacement is returned instead.
+func (c *Config) Override(ob interface{}, replacement interface{}) {
+	rt := reflect.TypeOf(ob)
+	for rt.Kind() == reflect.Pointer {
+		rt = rt.Elem()
+	}
+	if c.overrides == nil {
+		c.overrides = map[reflect.Type]reflect.Type{}
+	}
+	c.overrides[rt] = reflect.TypeOf(replacement)
+}
+
+// FormatPosition returns the position as "<filename>:<line>:<column>", with the portable filename (see metadata.PositionFormatter).
+// The filename is relative to Config.PositionRoot, or prefixed with the module path, so the serialized dumps don't differ across the machines.
+func (c *Config) FormatPosition(pos token.Pos) string {
+	c.init()
+	if c.positions == nil { // SkipComments
+		return token.Position{}.String()
+	}
+	return c.positions.Format(pos)
+}
+
+func (c *Config) logf(format string, args ...interface{}) {
+	if c.Logger != nil {
+		c.Logger.Printf(format, args...)
+		return
+	}
+	log.Printf(format, args...)
+}
+
+func (c *Config) namespace(pkgpath string) string {
+	matched := ""
+	for prefix := range c.Namespaces {
+		if len(prefix) <= len(matched) {
+			continue
+		}
+		if pkgpath == prefix || strings.HasPrefix(pkgpath, strings.TrimSuffix(prefix, "/")+"/") {
+			matched = prefix
+		}
+	}
+	if matched == "" {
+		return pkgpath
+	}
+	return c.Namespaces[matched] + strings.TrimPrefix(pkgpath, matched)
+}
+
+func (c *Config) isCommentPackage(pkgpath string) bool {
+	pkgpath = strings.TrimSuffix(pkgpath, "_test")
+	for _, pattern := range c.ExcludeCommentPackages {
+		if matchPackage(pattern, pkgpath) {
+			return false
+		}
+	}
+	if len(c.CommentPackages) == 0 {
+		return true
+	}
+	for _, pattern := range c.CommentPackages {
+		if matchPackage(pattern, pkgpath) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchPackage reports whether pkgpath matches the pattern. (e.g. "github.com/foo/bar/..." matches github.com/foo/bar and its sub packages)
+func matchPackage(pattern, pkgpath string) bool {
+	if strings.HasSuffix(pattern, "/...") {
+		prefix := strings.TrimSuffix(pattern, "/...")
+		return pkgpath == prefix || strings.HasPrefix(pkgpath, prefix+"/")
+	}
+	return pkgpath == pattern
+}
+
+func (c *Config) override(rt reflect.Type) (reflect.Type, bool) {
+	for x := c; x != nil; x = x.parent {
+		if replacement, ok := x.overrides[rt]; ok {
+			return replacement, true
+		}
+	}
+	return rt, false
 }
 
 func (c *Config) Visited() map[ID]*Shape {
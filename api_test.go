@@ -2,12 +2,24 @@ package reflectshape_test
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path/filepath"
 	"reflect"
+	"sort"
+	"strings"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/google/go-cmp/cmp"
+	"github.com/podhmo/commentof/collect"
 	reflectshape "github.com/podhmo/reflect-shape"
+	"github.com/podhmo/reflect-shape/metadata"
 )
 
 type S0 struct{}
@@ -89,6 +101,323 @@ func TestPointerLevel(t *testing.T) {
 			if want, got := c.lv, s.Lv; want != got {
 				t.Errorf("Shape.Lv, must be want:%v == got:%v", want, got)
 			}
+			if want, got := c.lv > 0, s.IsPointer(); want != got {
+				t.Errorf("Shape.IsPointer(), must be want:%v == got:%v", want, got)
+			}
+
+			deref := s.Deref()
+			if want, got := 0, deref.Lv; want != got {
+				t.Errorf("Shape.Deref().Lv, must be want:%v == got:%v", want, got)
+			}
+			if !s.Equal(deref) {
+				t.Errorf("Shape.Equal(), must be %v == %v", s, deref)
+			}
+			if want, got := c.lv == 0, s.Identical(deref); want != got {
+				t.Errorf("Shape.Identical(), must be want:%v == got:%v", want, got)
+			}
+		})
+	}
+
+	t.Run("docs", func(t *testing.T) {
+		s := cfg.Extract(new(*Person))
+		if want, got := 2, s.Lv; want != got {
+			t.Errorf("Shape.Lv, must be want:%v == got:%v", want, got)
+		}
+		if want, got := s.Deref().Struct().Doc(), s.Struct().Doc(); want != got || got == "" {
+			t.Errorf("Shape.Struct().Doc(), must be resolved from the underlying type, want:%q == got:%q", want, got)
+		}
+	})
+}
+
+func TestExtractTypeAndValue(t *testing.T) {
+	cases := []struct {
+		msg   string
+		shape *reflectshape.Shape
+		want  *reflectshape.Shape
+	}{
+		{msg: "type", shape: cfg.ExtractType(reflect.TypeOf(Person{})), want: cfg.Extract(Person{})},
+		{msg: "type-pointer", shape: cfg.ExtractType(reflect.TypeOf((**Person)(nil))), want: cfg.Extract(new(*Person))},
+		{msg: "value", shape: cfg.ExtractValue(reflect.ValueOf(&Person{})), want: cfg.Extract(&Person{})},
+		{msg: "value-func", shape: cfg.ExtractValue(reflect.ValueOf(Foo)), want: cfg.Extract(Foo)},
+	}
+	for _, c := range cases {
+		c := c
+		t.Run(c.msg, func(t *testing.T) {
+			if want, got := c.want.ID, c.shape.ID; want != got {
+				t.Errorf("Shape.ID, want:%v != got:%v", want, got)
+			}
+			if want, got := c.want.Lv, c.shape.Lv; want != got {
+				t.Errorf("Shape.Lv, want:%v != got:%v", want, got)
+			}
+			if want, got := c.want.Name, c.shape.Name; want != got {
+				t.Errorf("Shape.Name, want:%q != got:%q", want, got)
+			}
+		})
+	}
+
+	t.Run("func-type", func(t *testing.T) {
+		s := cfg.ExtractType(reflect.TypeOf(Foo))
+		if want, got := reflect.Func, s.Kind; want != got {
+			t.Errorf("Shape.Kind, want:%v != got:%v", want, got)
+		}
+		if want, got := "", s.Name; want != got {
+			t.Errorf("Shape.Name, the signature has no name, want:%q != got:%q", want, got)
+		}
+	})
+	t.Run("interface-value", func(t *testing.T) {
+		rv := reflect.ValueOf(&struct{ Value any }{Value: 1}).Elem().Field(0)
+		if want, got := reflect.Interface, cfg.ExtractValue(rv).Kind; want != got {
+			t.Errorf("Shape.Kind, the static type is used, want:%v != got:%v", want, got)
+		}
+	})
+	t.Run("nil", func(t *testing.T) {
+		if s := cfg.ExtractType(nil); s != nil {
+			t.Errorf("ExtractType(nil), must be nil, but got %v", s)
+		}
+		if s := cfg.ExtractValue(reflect.Value{}); s != nil {
+			t.Errorf("ExtractValue(reflect.Value{}), must be nil, but got %v", s)
+		}
+	})
+}
+
+func TestReflectType(t *testing.T) {
+	cases := []struct {
+		msg  string
+		ob   any
+		want reflect.Type
+	}{
+		{msg: "struct", ob: Person{}, want: reflect.TypeOf(Person{})},
+		{msg: "pointer", ob: &Person{}, want: reflect.TypeOf(&Person{})},
+		{msg: "pointer-pointer", ob: new(*Person), want: reflect.TypeOf(new(*Person))},
+		{msg: "func", ob: Foo, want: reflect.TypeOf(Foo)},
+	}
+	for _, c := range cases {
+		c := c
+		t.Run(c.msg, func(t *testing.T) {
+			if want, got := c.want, cfg.Extract(c.ob).ReflectType(); want != got {
+				t.Errorf("Shape.ReflectType(), want:%v != got:%v", want, got)
+			}
+		})
+	}
+
+	t.Run("round-trip", func(t *testing.T) {
+		s := cfg.Extract(Person{})
+		rv := reflect.New(s.ReflectType()).Elem()
+		for _, f := range s.Struct().Fields() {
+			if f.Name == "Father" {
+				rv.FieldByIndex(f.Index).Set(reflect.New(f.Shape.ReflectType().Elem()))
+			}
+		}
+		if p, ok := rv.Interface().(Person); !ok || p.Father == nil {
+			t.Errorf("Shape.ReflectType(), must be able to set the field, but got %#v", rv.Interface())
+		}
+	})
+}
+
+func TestImmutable(t *testing.T) {
+	cfg := &reflectshape.Config{SkipComments: true}
+
+	shape := cfg.Extract(S0{})
+	shape.Name = "Modified"
+	shape.Kind = reflect.Invalid
+
+	got := cfg.Extract(S0{})
+	if want, got := "S0", got.Name; want != got {
+		t.Errorf("Shape.Name, must not be modified, want:%v != got:%v", want, got)
+	}
+	if want, got := reflect.Struct, got.Kind; want != got {
+		t.Errorf("Shape.Kind, must not be modified, want:%v != got:%v", want, got)
+	}
+	if !shape.Equal(got) {
+		t.Errorf("Shape.ID, must be %v == %v", shape, got)
+	}
+
+	t.Run("package", func(t *testing.T) {
+		shape := cfg.Extract(S0{})
+		want := *shape.Package
+		shape.Package.Name = "modified"
+		shape.Package.Path = "example.com/modified"
+
+		got := cfg.Extract(S0{})
+		if want, got := want.Name, got.Package.Name; want != got {
+			t.Errorf("Shape.Package.Name, must not be modified, want:%v != got:%v", want, got)
+		}
+		if want, got := want.Path, got.Package.Path; want != got {
+			t.Errorf("Shape.Package.Path, must not be modified, want:%v != got:%v", want, got)
+		}
+		if want, got := []string{"S0"}, got.Package.Scope().Names(); !reflect.DeepEqual(want, got) {
+			t.Errorf("Shape.Package.Scope().Names(), want:%v != got:%v", want, got)
+		}
+	})
+}
+
+func TestClone(t *testing.T) {
+	cfg := &reflectshape.Config{SkipComments: true, Namespaces: map[string]string{"github.com/podhmo/reflect-shape_test": "shape"}}
+	cfg.Extract(S1{})
+	shape := cfg.Extract(S0{})
+
+	t.Run("shallow", func(t *testing.T) {
+		copied := shape.Clone(false)
+		copied.Name = "Modified"
+		if want, got := "S0", shape.Name; want != got {
+			t.Errorf("Shape.Name, must not be modified, want:%v != got:%v", want, got)
+		}
+		if copied.Package != shape.Package {
+			t.Errorf("Shape.Package, must be shared")
+		}
+	})
+
+	t.Run("deep", func(t *testing.T) {
+		copied := shape.Clone(true)
+		copied.Package.Path = "modified"
+		if want, got := "github.com/podhmo/reflect-shape_test", shape.Package.Path; want != got {
+			t.Errorf("Shape.Package.Path, must not be modified, want:%v != got:%v", want, got)
+		}
+		if want, got := []string{"S0", "S1"}, copied.Package.Scope().Names(); !reflect.DeepEqual(want, got) {
+			t.Errorf("Package.Names(): %#+v != %#+v", want, got)
+		}
+		if want, got := "shape", copied.Package.Namespace; want != got {
+			t.Errorf("Shape.Package.Namespace, must be copied, want:%v != got:%v", want, got)
+		}
+	})
+}
+
+func TestElemAndKey(t *testing.T) {
+	type elem struct {
+		Kind reflect.Kind
+		Name string
+		Lv   int
+	}
+	walk := func(s *reflectshape.Shape) []elem {
+		var r []elem
+		for {
+			r = append(r, elem{Kind: s.Kind, Name: s.Name, Lv: s.Lv})
+			if s.Lv == 0 && s.Kind != reflect.Map && s.Kind != reflect.Slice && s.Kind != reflect.Array && s.Kind != reflect.Chan {
+				return r
+			}
+			s = s.Elem()
+		}
+	}
+
+	cases := []struct {
+		msg   string
+		input any
+		want  []elem
+	}{
+		{msg: "map", input: map[string][]*Person{}, want: []elem{{Kind: reflect.Map}, {Kind: reflect.Slice}, {Kind: reflect.Struct, Name: "Person", Lv: 1}, {Kind: reflect.Struct, Name: "Person"}}},
+		{msg: "pointer", input: new(*[2]chan int), want: []elem{{Kind: reflect.Array, Lv: 2}, {Kind: reflect.Array, Lv: 1}, {Kind: reflect.Array}, {Kind: reflect.Chan}, {Kind: reflect.Int, Name: "int"}}},
+	}
+	for _, c := range cases {
+		c := c
+		t.Run(c.msg, func(t *testing.T) {
+			if diff := cmp.Diff(c.want, walk(cfg.Extract(c.input))); diff != "" {
+				t.Errorf("Shape.Elem(): mismatch (-want, +got): \n%v", diff)
+			}
+		})
+	}
+
+	t.Run("key", func(t *testing.T) {
+		if want, got := "string", cfg.Extract(map[string][]*Person{}).Key().Name; want != got {
+			t.Errorf("Shape.Key().Name, want:%v != got:%v", want, got)
+		}
+	})
+}
+
+type Counter struct{ n int }
+
+func (c Counter) Count() int { return c.n }
+func (c *Counter) Incr()     { c.n++ }
+func (c *Counter) Reset()    { c.n = 0 }
+
+func TestMethodSet(t *testing.T) {
+	cases := []struct {
+		msg         string
+		input       any
+		value       []string
+		pointer     []string
+		pointerOnly []string
+	}{
+		{msg: "struct", input: Counter{}, value: []string{"Count"}, pointer: []string{"Count", "Incr", "Reset"}, pointerOnly: []string{"Incr", "Reset"}},
+		{msg: "struct-pointer", input: &Counter{}, value: []string{"Count"}, pointer: []string{"Count", "Incr", "Reset"}, pointerOnly: []string{"Incr", "Reset"}},
+		{msg: "no-methods", input: Person{}, value: nil, pointer: nil, pointerOnly: []string{}},
+	}
+	for _, c := range cases {
+		c := c
+		t.Run(c.msg, func(t *testing.T) {
+			ms := cfg.Extract(c.input).MethodSet()
+			if diff := cmp.Diff(c.value, ms.Value); diff != "" {
+				t.Errorf("Shape.MethodSet().Value: -want, +got: \n%v", diff)
+			}
+			if diff := cmp.Diff(c.pointer, ms.Pointer); diff != "" {
+				t.Errorf("Shape.MethodSet().Pointer: -want, +got: \n%v", diff)
+			}
+			if diff := cmp.Diff(c.pointerOnly, ms.PointerOnly()); diff != "" {
+				t.Errorf("Shape.MethodSet().PointerOnly(): -want, +got: \n%v", diff)
+			}
+		})
+	}
+}
+
+type Code int
+
+// String returns the name of code.
+func (c Code) String() string { return fmt.Sprintf("code-%d", int(c)) }
+
+type MyError struct{}
+
+// Error returns the message.
+func (e *MyError) Error() string { return "my error" }
+
+func TestConventions(t *testing.T) {
+	type convention struct {
+		Name              string
+		IsPointerReceiver bool
+		Doc               string
+	}
+	cases := []struct {
+		msg   string
+		input any
+		want  []convention
+	}{
+		{msg: "stringer", input: Code(0), want: []convention{{Name: "String", Doc: "String returns the name of code."}}},
+		{msg: "error", input: MyError{}, want: []convention{{Name: "Error", IsPointerReceiver: true, Doc: "Error returns the message."}}},
+		{msg: "nothing", input: Person{}, want: nil},
+	}
+	for _, c := range cases {
+		c := c
+		t.Run(c.msg, func(t *testing.T) {
+			var got []convention
+			for _, x := range cfg.Extract(c.input).Conventions() {
+				got = append(got, convention{Name: x.Name, IsPointerReceiver: x.IsPointerReceiver, Doc: x.Doc})
+			}
+			if diff := cmp.Diff(c.want, got); diff != "" {
+				t.Errorf("Shape.Conventions(): -want, +got: \n%v", diff)
+			}
+		})
+	}
+}
+
+func TestNilable(t *testing.T) {
+	cases := []struct {
+		msg     string
+		input   any
+		nilable bool
+	}{
+		{msg: "struct", input: S0{}, nilable: false},
+		{msg: "struct-pointer", input: &S0{}, nilable: true},
+		{msg: "int", input: 0, nilable: false},
+		{msg: "slice", input: []S0{}, nilable: true},
+		{msg: "map", input: map[string]int{}, nilable: true},
+		{msg: "chan", input: make(chan int), nilable: true},
+		{msg: "func", input: F0, nilable: true},
+	}
+
+	for _, c := range cases {
+		c := c
+		t.Run(c.msg, func(t *testing.T) {
+			if want, got := c.nilable, cfg.Extract(c.input).IsNilable(); want != got {
+				t.Errorf("Shape.IsNilable(), want:%v != got:%v", want, got)
+			}
 		})
 	}
 }
@@ -118,6 +447,35 @@ func TestPackagePath(t *testing.T) {
 	}
 }
 
+func TestPackageNamespace(t *testing.T) {
+	cfg := &reflectshape.Config{
+		SkipComments: true,
+		Namespaces: map[string]string{
+			"github.com/podhmo":                    "podhmo",
+			"github.com/podhmo/reflect-shape_test": "shape",
+			"net":                                  "network",
+		},
+	}
+
+	cases := []struct {
+		msg       string
+		input     any
+		namespace string
+	}{
+		{msg: "longest-prefix", input: S0{}, namespace: "shape"},
+		{msg: "not-matched", input: t.Run, namespace: "testing"},
+		{msg: "sub-package", input: http.Client{}, namespace: "network/http"},
+	}
+	for _, c := range cases {
+		c := c
+		t.Run(c.msg, func(t *testing.T) {
+			if want, got := c.namespace, cfg.Extract(c.input).Package.Namespace; want != got {
+				t.Errorf("Shape.Package.Namespace: %#+v != %#+v", want, got)
+			}
+		})
+	}
+}
+
 func TestPackageScopeNames(t *testing.T) {
 	t.Run("one", func(t *testing.T) {
 		want := []string{"F0"}
@@ -171,6 +529,16 @@ func FooWithVariadicArgs(ctx context.Context, name string, nickname *string, arg
 	return nil
 }
 
+// Foo's alternative that the names conflict with the filled names.
+func FooWithConflictNames(_ context.Context, err error) (int, error, error) {
+	return 0, nil, nil
+}
+
+// Foo's alternative that the return values are partially blank.
+func FooWithBlankRetNames() (_ int, ok bool, _ error) {
+	return 0, false, nil
+}
+
 func TestFunc(t *testing.T) {
 	cases := []struct {
 		fn           any
@@ -250,8 +618,83 @@ func TestFunc(t *testing.T) {
 			t.Errorf("Shape.Func().Doc(): -want, +got: \n%v", diff)
 		}
 	})
-	// PANIC (not supported)
-	// fmt.Println(cfg.Extract(func(fmt string, args ...any) {}).MustFunc())
+	t.Run("no-source", func(t *testing.T) {
+		fn := cfg.Extract(atomic.AddInt32).Func() // implemented in assembly
+		var noSource *metadata.NoSourceError
+		if !errors.As(fn.Err(), &noSource) {
+			t.Errorf("Shape.Func().Err(): must be *metadata.NoSourceError, but got %+v", fn.Err())
+		}
+		if want, got := 3, len(fn.Args())+len(fn.Returns()); want != got {
+			t.Errorf("Shape.Func(): len(args)+len(returns), want:%v != got:%v", want, got)
+		}
+	})
+	t.Run("closure", func(t *testing.T) {
+		// printf formats the message
+		fn := cfg.Extract(func(format string, args ...any) {}).Func()
+		if !fn.IsClosure() {
+			t.Errorf("Shape.Func().IsClosure(): must be true")
+		}
+		if want, got := "printf formats the message", fn.Doc(); want != got {
+			t.Errorf("Shape.Func().Doc(): want:%q != got:%q", want, got)
+		}
+		if diff := cmp.Diff([]string{"format", "args"}, varNames(fn.Args())); diff != "" {
+			t.Errorf("Shape.Func().Args(): mismatch (-want, +got): \n%v", diff)
+		}
+		if cfg.Extract(Foo).Func().IsClosure() {
+			t.Errorf("Shape.Func().IsClosure(): must be false")
+		}
+	})
+}
+
+func TestFillNames(t *testing.T) {
+	cases := []struct {
+		msg             string
+		fn              any
+		fillArgNames    bool
+		fillReturnNames bool
+		args            []string
+		returns         []string
+	}{
+		{msg: "unnamed", fn: FooWithoutArgNames, fillReturnNames: true, args: []string{"", "", ""}, returns: []string{"err"}},
+		{msg: "named", fn: FooWithRetNames, fillReturnNames: true, args: []string{"ctx", "name", "nickname"}, returns: []string{"err"}},
+		{msg: "conflict", fn: FooWithConflictNames, fillArgNames: true, fillReturnNames: true, args: []string{"ctx", "err"}, returns: []string{"ret0", "err1", "err2"}},
+		{msg: "conflict-args-only", fn: FooWithConflictNames, fillArgNames: true, args: []string{"ctx", "err"}, returns: []string{"", "", ""}},
+		{msg: "blank", fn: FooWithBlankRetNames, fillReturnNames: true, args: nil, returns: []string{"ret0", "ok", "err"}},
+		{msg: "blank-not-filled", fn: FooWithBlankRetNames, args: nil, returns: []string{"_", "ok", "_"}},
+	}
+	for _, c := range cases {
+		c := c
+		t.Run(c.msg, func(t *testing.T) {
+			cfg := &reflectshape.Config{IncludeGoTestFiles: true, FillArgNames: c.fillArgNames, FillReturnNames: c.fillReturnNames}
+			fn := cfg.Extract(c.fn).Func()
+			if diff := cmp.Diff(c.args, varNames(fn.Args())); diff != "" {
+				t.Errorf("Shape.Func().Args(): mismatch (-want, +got): \n%v", diff)
+			}
+			if diff := cmp.Diff(c.returns, varNames(fn.Returns())); diff != "" {
+				t.Errorf("Shape.Func().Returns(): mismatch (-want, +got): \n%v", diff)
+			}
+		})
+	}
+
+	t.Run("hooks", func(t *testing.T) {
+		cfg := &reflectshape.Config{IncludeGoTestFiles: true, FillArgNames: true, FillReturnNames: true}
+		cfg.Hooks.ArgName = func(fn *reflectshape.Func, i int, rt reflect.Type) string {
+			if strings.TrimPrefix(rt.String(), "*") == "string" {
+				return "s"
+			}
+			return "" // default
+		}
+		cfg.Hooks.ReturnName = func(fn *reflectshape.Func, i int, rt reflect.Type) string {
+			return fn.Name() + "Result"
+		}
+		fn := cfg.Extract(FooWithoutArgNames).Func()
+		if diff := cmp.Diff([]string{"ctx", "s", "s1"}, varNames(fn.Args())); diff != "" {
+			t.Errorf("Shape.Func().Args(): mismatch (-want, +got): \n%v", diff)
+		}
+		if diff := cmp.Diff([]string{"FooWithoutArgNamesResult"}, varNames(fn.Returns())); diff != "" {
+			t.Errorf("Shape.Func().Returns(): mismatch (-want, +got): \n%v", diff)
+		}
+	})
 }
 
 // Wrap type
@@ -362,6 +805,28 @@ func TestInterface(t *testing.T) {
 // Ordering is desc or asc
 type Ordering string
 
+const (
+	OrderingDesc Ordering = "desc" // descending order
+	OrderingAsc  Ordering = "asc"  // ascending order
+)
+
+type Weekday int
+
+const (
+	Sunday Weekday = iota
+	Monday
+	_
+	Wednesday
+)
+
+type Color string
+
+// the untyped constants, the type is inferred from the conversion
+const (
+	ColorRed  = Color("red") // red
+	ColorBlue = Color("blue")
+)
+
 func TestNamed(t *testing.T) {
 	cases := []struct {
 		input any
@@ -387,3 +852,770 @@ func TestNamed(t *testing.T) {
 		})
 	}
 }
+
+func TestHooks(t *testing.T) {
+	t.Run("veto", func(t *testing.T) {
+		cfg := &reflectshape.Config{IncludeGoTestFiles: true}
+		cfg.Hooks.BeforeExtract = func(rt reflect.Type) bool {
+			return rt.Kind() != reflect.Slice
+		}
+
+		if got := cfg.Extract([]int{}); got != nil {
+			t.Errorf("Extract(): vetoed shape must be nil, but got %v", got)
+		}
+
+		var got []string
+		for _, f := range cfg.Extract(Person{}).Struct().Fields() {
+			got = append(got, f.Name)
+		}
+		if want := []string{"Name", "Father"}; !reflect.DeepEqual(want, got) {
+			t.Errorf("Shape.Struct().Fields(): names, want:%#+v != got:%#+v", want, got)
+		}
+	})
+
+	t.Run("veto-args", func(t *testing.T) {
+		cfg := &reflectshape.Config{IncludeGoTestFiles: true}
+		cfg.Hooks.BeforeExtract = func(rt reflect.Type) bool {
+			return rt.Kind() != reflect.Interface // context.Context, error
+		}
+
+		fn := cfg.Extract((&Queue{}).Push).Func()
+		if want, got := []string{"item"}, varNames(fn.Args()); !reflect.DeepEqual(want, got) {
+			t.Errorf("Func.Args(): names, want:%#+v != got:%#+v", want, got)
+		}
+		if want, got := []string{"n"}, varNames(fn.Returns()); !reflect.DeepEqual(want, got) {
+			t.Errorf("Func.Returns(): names, want:%#+v != got:%#+v", want, got)
+		}
+		for _, v := range fn.Args() {
+			_ = v.String() // must not panic
+		}
+		_ = fn.String()
+	})
+
+	t.Run("after", func(t *testing.T) {
+		cfg := &reflectshape.Config{IncludeGoTestFiles: true}
+		cfg.Hooks.AfterExtract = func(s *reflectshape.Shape) {
+			if s.Kind == reflect.Struct {
+				s.Name = "X" + s.Name
+			}
+		}
+
+		shape := cfg.Extract(Person{})
+		if want, got := "XPerson", shape.Name; want != got {
+			t.Errorf("Shape.Name: want:%v != got:%v", want, got)
+		}
+		if want, got := []string{"XPerson"}, shape.Package.Scope().Names(); !reflect.DeepEqual(want, got) {
+			t.Errorf("Package.Names(): %#+v != %#+v", want, got)
+		}
+	})
+
+	t.Run("lookup-type", func(t *testing.T) {
+		cfg := &reflectshape.Config{SkipComments: true}
+		cfg.Hooks.LookupType = func(rt reflect.Type) (*metadata.Type, bool) {
+			if rt != reflect.TypeOf(Person{}) {
+				return nil, false
+			}
+			return &metadata.Type{Raw: &collect.Object{Name: "Person", Doc: "overridden"}}, true
+		}
+
+		if want, got := "overridden", cfg.Extract(Person{}).Struct().Doc(); want != got {
+			t.Errorf("Shape.Struct().Doc(): want:%v != got:%v", want, got)
+		}
+	})
+}
+
+type Secret struct {
+	Value string
+}
+
+type RedactedSecret struct{}
+
+type Credential struct {
+	User   string
+	Secret Secret
+}
+
+func TestOverride(t *testing.T) {
+	parent := &reflectshape.Config{SkipComments: true}
+	parent.Override(Secret{}, RedactedSecret{})
+
+	child := parent.NewChild()
+	child.Override(S0{}, S1{})
+
+	cases := []struct {
+		msg   string
+		cfg   *reflectshape.Config
+		input any
+		name  string
+	}{
+		{msg: "parent-overridden", cfg: parent, input: Secret{}, name: "RedactedSecret"},
+		{msg: "parent-not-overridden", cfg: parent, input: S0{}, name: "S0"},
+		{msg: "child-overridden", cfg: child, input: S0{}, name: "S1"},
+		{msg: "child-fallback", cfg: child, input: &Secret{}, name: "RedactedSecret"},
+	}
+	for _, c := range cases {
+		c := c
+		t.Run(c.msg, func(t *testing.T) {
+			if want, got := c.name, c.cfg.Extract(c.input).Name; want != got {
+				t.Errorf("Shape.Name: want:%v != got:%v", want, got)
+			}
+		})
+	}
+
+	t.Run("field", func(t *testing.T) {
+		fields := child.Extract(Credential{}).Struct().Fields()
+		if want, got := "RedactedSecret", fields[1].Shape.Name; want != got {
+			t.Errorf("Shape.Struct().Fields()[1].Shape.Name: want:%v != got:%v", want, got)
+		}
+	})
+}
+
+func TestProvenance(t *testing.T) {
+	cfg := &reflectshape.Config{IncludeGoTestFiles: true}
+	cfg.Override(Secret{}, RedactedSecret{})
+
+	if want, got := reflectshape.ProvenanceReflect, cfg.Extract(S0{}).Provenance; want != got {
+		t.Errorf("Shape.Provenance: want:%v != got:%v", want, got)
+	}
+	if want, got := reflectshape.ProvenanceOverride, cfg.Extract(Secret{}).Provenance; want != got {
+		t.Errorf("Shape.Provenance: want:%v != got:%v", want, got)
+	}
+
+	s := cfg.Extract(Person{}).Struct()
+	if want, got := reflectshape.ProvenanceSource, s.DocProvenance(); want != got {
+		t.Errorf("Shape.Struct().DocProvenance(): want:%v != got:%v", want, got)
+	}
+	fields := s.Fields()
+	if want, got := reflectshape.ProvenanceSource, fields[0].DocProvenance; want != got {
+		t.Errorf("Shape.Struct().Fields()[0].DocProvenance: want:%v != got:%v", want, got)
+	}
+	if want, got := reflectshape.ProvenanceNone, fields[1].DocProvenance; want != got {
+		t.Errorf("Shape.Struct().Fields()[1].DocProvenance: want:%v != got:%v", want, got)
+	}
+
+	flatten := cfg.Extract(Account{}).Struct().FlattenFields()
+	if want, got := reflectshape.ProvenanceInherited, flatten[0].Provenance; want != got {
+		t.Errorf("Shape.Struct().FlattenFields()[0].Provenance: want:%v != got:%v", want, got)
+	}
+	if want, got := reflectshape.ProvenanceReflect, flatten[len(flatten)-1].Provenance; want != got {
+		t.Errorf("Shape.Struct().FlattenFields()[-1].Provenance: want:%v != got:%v", want, got)
+	}
+}
+
+func TestCommentPackages(t *testing.T) {
+	cases := []struct {
+		msg      string
+		patterns []string
+		doc      string
+	}{
+		{msg: "empty", patterns: nil, doc: "Person object"},
+		{msg: "matched", patterns: []string{"github.com/podhmo/reflect-shape"}, doc: "Person object"},
+		{msg: "matched-recursive", patterns: []string{"github.com/podhmo/..."}, doc: "Person object"},
+		{msg: "not-matched", patterns: []string{"github.com/podhmo/reflect-shape/metadata/..."}, doc: ""},
+	}
+	for _, c := range cases {
+		c := c
+		t.Run(c.msg, func(t *testing.T) {
+			cfg := &reflectshape.Config{IncludeGoTestFiles: true, CommentPackages: c.patterns}
+			if want, got := c.doc, cfg.Extract(Person{}).Struct().Doc(); want != got {
+				t.Errorf("Shape.Struct().Doc(): want:%q != got:%q", want, got)
+			}
+		})
+	}
+}
+
+func TestWellKnownTypes(t *testing.T) {
+	cases := []struct {
+		msg         string
+		input       any
+		isWellKnown bool
+	}{
+		{msg: "time", input: time.Now(), isWellKnown: true},
+		{msg: "time-pointer", input: &time.Time{}, isWellKnown: true},
+		{msg: "duration", input: time.Second, isWellKnown: false},
+		{msg: "struct", input: Person{}, isWellKnown: false},
+	}
+
+	cfg := &reflectshape.Config{SkipComments: true}
+	for _, c := range cases {
+		c := c
+		t.Run(c.msg, func(t *testing.T) {
+			if want, got := c.isWellKnown, cfg.Extract(c.input).IsWellKnown; want != got {
+				t.Errorf("Shape.IsWellKnown: want:%v != got:%v", want, got)
+			}
+		})
+	}
+
+	t.Run("merged", func(t *testing.T) {
+		cfg := &reflectshape.Config{SkipComments: true, WellKnownTypes: map[string]bool{
+			"time.Duration": true,
+			"net/url.URL":   false,
+		}}
+		if want, got := true, cfg.Extract(time.Now()).IsWellKnown; want != got {
+			t.Errorf("Shape.IsWellKnown of the default: want:%v != got:%v", want, got)
+		}
+		if want, got := true, cfg.Extract(time.Second).IsWellKnown; want != got {
+			t.Errorf("Shape.IsWellKnown of the added: want:%v != got:%v", want, got)
+		}
+		if want, got := false, cfg.Extract(url.URL{}).IsWellKnown; want != got {
+			t.Errorf("Shape.IsWellKnown of the disabled: want:%v != got:%v", want, got)
+		}
+		if want, got := true, reflectshape.WellKnownTypes["net/url.URL"]; want != got {
+			t.Errorf("WellKnownTypes, must not be modified: want:%v != got:%v", want, got)
+		}
+	})
+}
+
+func TestEnum(t *testing.T) {
+	type member struct {
+		Name  string
+		Value any
+		Doc   string
+	}
+	cases := []struct {
+		input   any
+		members []member
+	}{
+		{input: OrderingAsc, members: []member{{Name: "OrderingDesc", Value: OrderingDesc, Doc: "descending order"}, {Name: "OrderingAsc", Value: OrderingAsc, Doc: "ascending order"}}},
+		{input: Sunday, members: []member{{Name: "Sunday", Value: Sunday}, {Name: "Monday", Value: Monday}, {Name: "Wednesday", Value: Wednesday}}},
+		{input: ColorRed, members: []member{{Name: "ColorRed", Value: ColorRed, Doc: "red"}, {Name: "ColorBlue", Value: ColorBlue}}},
+	}
+
+	for i, c := range cases {
+		c := c
+		t.Run(fmt.Sprintf("case%d", i), func(t *testing.T) {
+			enum := cfg.Extract(c.input).Enum()
+			t.Logf("%s", enum)
+
+			var got []member
+			for _, m := range enum.Members() {
+				got = append(got, member{Name: m.Name, Value: m.Value.Interface(), Doc: m.Doc})
+			}
+			if diff := cmp.Diff(c.members, got); diff != "" {
+				t.Errorf("Shape.Enum().Members(): -want, +got: \n%v", diff)
+			}
+		})
+	}
+}
+
+type Base struct {
+	ID   int
+	Name string
+}
+
+type Timestamp struct {
+	CreatedAt string
+	Name      string
+}
+
+type Account struct {
+	*Base
+	Timestamp
+	Meta  Base `json:"meta"`
+	Email string
+}
+
+func TestStructFlattenFields(t *testing.T) {
+	s := cfg.Extract(Account{}).Struct()
+
+	{
+		var got []bool
+		for _, f := range s.Fields() {
+			got = append(got, f.Anonymous)
+		}
+		if want := []bool{true, true, false, false}; !reflect.DeepEqual(want, got) {
+			t.Errorf("Shape.Struct().Fields(): anonymous, want:%#+v != got:%#+v", want, got)
+		}
+	}
+
+	type field struct {
+		Name  string
+		Index []int
+	}
+	want := []field{
+		{Name: "ID", Index: []int{0, 0}},
+		{Name: "CreatedAt", Index: []int{1, 0}},
+		{Name: "Meta", Index: []int{2}},
+		{Name: "Email", Index: []int{3}},
+	} // Name is conflicted (Base.Name and Timestamp.Name), so dropped
+	var got []field
+	for _, f := range s.FlattenFields() {
+		got = append(got, field{Name: f.Name, Index: f.Index})
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Shape.Struct().FlattenFields(): -want, +got: \n%v", diff)
+	}
+
+	// the same as the keys of encoding/json
+	for _, ob := range []any{FlatByJSONName{}, FlatTwice{}, FlatUnexported{}} {
+		var keys map[string]any
+		b, err := json.Marshal(ob)
+		if err != nil {
+			t.Fatalf("unexpected error: %+v", err)
+		}
+		if err := json.Unmarshal(b, &keys); err != nil {
+			t.Fatalf("unexpected error: %+v", err)
+		}
+		var want []string
+		for k := range keys {
+			want = append(want, k)
+		}
+		sort.Strings(want)
+		var got []string
+		for _, f := range cfg.Extract(ob).Struct().FlattenFields() {
+			name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+			if name == "" {
+				name = f.Name
+			}
+			got = append(got, name)
+		}
+		sort.Strings(got) // the fields are ordered by the index
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Errorf("Shape.Struct().FlattenFields(): %T, -want, +got: \n%v", ob, diff)
+		}
+	}
+}
+
+type FlatA struct {
+	X string `json:"Y"`
+}
+type FlatB struct {
+	Y string
+}
+
+// FlatByJSONName has the fields conflicted by the json names. (FlatA.X and FlatB.Y, the tagged FlatA.X wins)
+type FlatByJSONName struct {
+	FlatA
+	FlatB
+	Z string
+}
+
+// FlatUnexported has the unexported field, not serialized by encoding/json.
+type FlatUnexported struct {
+	FlatB
+	hidden string
+	Shown  string
+}
+
+type FlatInner struct{ V string }
+type FlatL struct{ FlatInner }
+type FlatR struct{ FlatInner }
+
+// FlatTwice has the same type embedded twice at the same depth. (FlatL.FlatInner and FlatR.FlatInner)
+type FlatTwice struct {
+	FlatL
+	FlatR
+	W string
+}
+
+type Audit struct {
+	// CreatedBy is the creator.
+	CreatedBy string
+	// Note is the note of the audit.
+	Note string
+}
+
+type Entry struct {
+	Audit
+	Timestamp
+	// Note is the note of the entry.
+	Note string
+}
+
+func TestStructPromotedFields(t *testing.T) {
+	type field struct {
+		Name      string
+		Index     []int
+		Doc       string
+		Anonymous bool
+	}
+
+	cases := []struct {
+		msg   string
+		input any
+		want  []field
+	}{
+		{msg: "shadowing", input: Entry{}, want: []field{
+			{Name: "Audit", Index: []int{0}, Anonymous: true},
+			{Name: "CreatedBy", Index: []int{0, 0}, Doc: "CreatedBy is the creator."},
+			{Name: "Timestamp", Index: []int{1}, Anonymous: true},
+			{Name: "CreatedAt", Index: []int{1, 0}},
+			{Name: "Name", Index: []int{1, 1}},
+			{Name: "Note", Index: []int{2}, Doc: "Note is the note of the entry."}, // Audit.Note is shadowed
+		}},
+		{msg: "ambiguous", input: Account{}, want: []field{
+			{Name: "Base", Index: []int{0}, Anonymous: true},
+			{Name: "ID", Index: []int{0, 0}},
+			{Name: "Timestamp", Index: []int{1}, Anonymous: true},
+			{Name: "CreatedAt", Index: []int{1, 0}},
+			{Name: "Meta", Index: []int{2}},
+			{Name: "Email", Index: []int{3}},
+		}}, // Name is ambiguous (Base.Name and Timestamp.Name), so dropped
+	}
+
+	for _, c := range cases {
+		c := c
+		t.Run(c.msg, func(t *testing.T) {
+			var got []field
+			for _, f := range cfg.Extract(c.input).Struct().PromotedFields() {
+				got = append(got, field{Name: f.Name, Index: f.Index, Doc: f.Doc, Anonymous: f.Anonymous})
+			}
+			if diff := cmp.Diff(c.want, got); diff != "" {
+				t.Errorf("Shape.Struct().PromotedFields(): mismatch (-want, +got): \n%v", diff)
+			}
+		})
+	}
+
+	t.Run("config", func(t *testing.T) {
+		cfg := &reflectshape.Config{IncludeGoTestFiles: true, FlattenEmbedded: true}
+		var got []string
+		for _, f := range cfg.Extract(Entry{}).Struct().Fields() {
+			got = append(got, f.Name)
+		}
+		if want := []string{"Audit", "CreatedBy", "Timestamp", "CreatedAt", "Name", "Note"}; !reflect.DeepEqual(want, got) {
+			t.Errorf("Shape.Struct().Fields() with FlattenEmbedded, want:%v != got:%v", want, got)
+		}
+	})
+}
+
+type ServerSettings struct {
+	// Server is the settings of the server.
+	Server struct {
+		// Host is the host name.
+		Host string
+		// TLS is the settings of TLS.
+		TLS struct {
+			// CertFile is the path of the certificate.
+			CertFile string
+		}
+	}
+}
+
+func TestAnonymousStruct(t *testing.T) {
+	t.Run("toplevel", func(t *testing.T) {
+		shape := cfg.Extract(struct{ Name string }{})
+		if want, got := "struct { Name string }", shape.FullName(); want != got {
+			t.Errorf("Shape.FullName(), want:%q != got:%q", want, got)
+		}
+		s := shape.Struct()
+		if !s.IsAnonymous() {
+			t.Errorf("Struct.IsAnonymous(), must be true")
+		}
+		if want, got := "Name", s.Fields()[0].Name; want != got {
+			t.Errorf("Struct.Fields()[0].Name, want:%q != got:%q", want, got)
+		}
+	})
+
+	t.Run("nested", func(t *testing.T) {
+		type row struct {
+			Name string
+			Doc  string
+		}
+
+		server := cfg.Extract(ServerSettings{}).Struct().Fields()[0].Struct()
+		if want, got := "ServerSettings.Server", server.Name(); want != got {
+			t.Errorf("Field.Struct().Name(), want:%q != got:%q", want, got)
+		}
+		if want, got := "Server is the settings of the server.", server.Doc(); want != got {
+			t.Errorf("Field.Struct().Doc(), want:%q != got:%q", want, got)
+		}
+
+		var got []row
+		fields := server.Fields()
+		for _, f := range fields {
+			got = append(got, row{Name: f.Name, Doc: f.Doc})
+		}
+		want := []row{{Name: "Host", Doc: "Host is the host name."}, {Name: "TLS", Doc: "TLS is the settings of TLS."}}
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Errorf("Field.Struct().Fields(): mismatch (-want, +got): \n%v", diff)
+		}
+
+		tls := fields[1].Struct()
+		if want, got := "ServerSettings.Server.TLS", tls.Name(); want != got {
+			t.Errorf("Field.Struct().Name(), want:%q != got:%q", want, got)
+		}
+		if want, got := "CertFile is the path of the certificate.", tls.Fields()[0].Doc; want != got {
+			t.Errorf("Field.Struct().Fields()[0].Doc, want:%q != got:%q", want, got)
+		}
+
+		// the same type is not cached with the comments of the parent
+		if want, got := "", cfg.Extract(ServerSettings{}.Server).Struct().Fields()[0].Doc; want != got {
+			t.Errorf("Shape.Struct().Fields()[0].Doc, want:%q != got:%q", want, got)
+		}
+	})
+}
+
+type Greeter interface{ Greet() string }
+
+type englishGreeter struct{}
+
+func (g *englishGreeter) Greet() string { return "hello" }
+
+type japaneseGreeter struct{}
+
+func (g *japaneseGreeter) Greet() string { return "konnichiwa" }
+
+// NewGreeter creates the greeter for the lang.
+func NewGreeter(lang string) (Greeter, error) {
+	if lang == "ja" {
+		return &japaneseGreeter{}, nil
+	}
+	return new(englishGreeter), nil
+}
+
+// NewEnglishGreeter creates the english greeter.
+func NewEnglishGreeter() *englishGreeter {
+	return &englishGreeter{}
+}
+
+func TestFuncConcreteReturns(t *testing.T) {
+	cases := []struct {
+		msg     string
+		input   any
+		extract []any // extracted before
+		want    []string
+	}{
+		{msg: "interface", input: NewGreeter, extract: []any{englishGreeter{}, japaneseGreeter{}}, want: []string{"japaneseGreeter", "englishGreeter"}},
+		{msg: "interface-not-extracted", input: NewGreeter, want: nil},
+		{msg: "concrete", input: NewEnglishGreeter, want: []string{"englishGreeter"}},
+	}
+	for _, c := range cases {
+		c := c
+		t.Run(c.msg, func(t *testing.T) {
+			cfg := &reflectshape.Config{IncludeGoTestFiles: true}
+			for _, ob := range c.extract {
+				cfg.Extract(ob)
+			}
+
+			var got []string
+			for _, s := range cfg.Extract(c.input).Func().ConcreteReturns() {
+				got = append(got, s.Name)
+			}
+			if diff := cmp.Diff(c.want, got); diff != "" {
+				t.Errorf("Func.ConcreteReturns(): -want, +got: \n%v", diff)
+			}
+		})
+	}
+
+	t.Run("vetoed", func(t *testing.T) {
+		cfg := &reflectshape.Config{IncludeGoTestFiles: true}
+		cfg.Hooks.BeforeExtract = func(rt reflect.Type) bool { return rt.Name() != "englishGreeter" }
+		if got := cfg.Extract(NewEnglishGreeter).Func().ConcreteReturns(); len(got) != 0 {
+			t.Errorf("Func.ConcreteReturns(): the vetoed types must be skipped, but got %v", got)
+		}
+	})
+}
+
+type ServerConfig struct {
+	Host string
+	Port int
+
+	// --- Authentication ---
+
+	// User is the name of the user.
+	User     string
+	Password string
+
+	// --- Logging ---
+	LogLevel string
+	Debug    bool `group:"Development"`
+}
+
+func TestStructGroups(t *testing.T) {
+	type group struct {
+		Name   string
+		Fields []string
+		Docs   []string
+	}
+	want := []group{
+		{Name: "", Fields: []string{"Host", "Port"}, Docs: []string{"", ""}},
+		{Name: "Authentication", Fields: []string{"User", "Password"}, Docs: []string{"User is the name of the user.", ""}},
+		{Name: "Logging", Fields: []string{"LogLevel"}, Docs: []string{""}},
+		{Name: "Development", Fields: []string{"Debug"}, Docs: []string{""}},
+	}
+
+	var got []group
+	for _, g := range cfg.Extract(ServerConfig{}).Struct().Groups() {
+		x := group{Name: g.Name}
+		for _, f := range g.Fields {
+			x.Fields = append(x.Fields, f.Name)
+			x.Docs = append(x.Docs, f.Doc)
+		}
+		got = append(got, x)
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Struct.Groups(): -want, +got: \n%v", diff)
+	}
+}
+
+// _Ctype_struct_point imitates the type generated by cgo for C.struct_point. (the name is the same, but not opaque actually)
+type _Ctype_struct_point struct {
+	x _Ctype_int
+	y _Ctype_int
+}
+
+type _Ctype_int int32
+
+type Shape2D struct {
+	Origin _Ctype_struct_point
+}
+
+func TestOpaque(t *testing.T) {
+	cfg := &reflectshape.Config{IncludeGoTestFiles: true}
+
+	s := cfg.Extract(_Ctype_struct_point{})
+	if want, got := true, s.IsOpaque; want != got {
+		t.Errorf("Shape.IsOpaque: want:%v != got:%v", want, got)
+	}
+	if want, got := reflectshape.ProvenanceNone, s.Struct().DocProvenance(); want != got {
+		t.Errorf("Struct.DocProvenance(): want:%q != got:%q", want, got)
+	}
+	if want, got := 2, len(s.Struct().Fields()); want != got {
+		t.Errorf("len(Struct.Fields()): want:%v != got:%v", want, got)
+	}
+
+	f := cfg.Extract(Shape2D{}).Struct().Fields()[0]
+	if want, got := true, f.Shape.IsOpaque; want != got {
+		t.Errorf("Field.Shape.IsOpaque: want:%v != got:%v", want, got)
+	}
+	if want, got := false, cfg.Extract(Shape2D{}).IsOpaque; want != got {
+		t.Errorf("Shape.IsOpaque: want:%v != got:%v", want, got)
+	}
+}
+
+type WireHeader struct {
+	Flag uint8
+	Size uintptr
+}
+
+func TestStructStaticLayout(t *testing.T) {
+	cases := []struct {
+		goarch string
+		size   int64
+	}{
+		{goarch: "amd64", size: 16},
+		{goarch: "386", size: 8},
+	}
+	for _, c := range cases {
+		c := c
+		t.Run(c.goarch, func(t *testing.T) {
+			cfg := &reflectshape.Config{IncludeGoTestFiles: true, GOARCH: c.goarch}
+			layout, err := cfg.Extract(WireHeader{}).Struct().StaticLayout()
+			if err != nil {
+				t.Fatalf("unexpected error: %+v", err)
+			}
+			if want, got := c.size, layout.Size; want != got {
+				t.Errorf("StaticLayout().Size: want:%v != got:%v", want, got)
+			}
+		})
+	}
+
+	t.Run("reflect", func(t *testing.T) {
+		layout := cfg.Extract(WireHeader{}).Struct().Layout()
+		if want, got := int64(reflect.TypeOf(WireHeader{}).Size()), layout.Size; want != got {
+			t.Errorf("Layout().Size: want:%v != got:%v", want, got)
+		}
+	})
+}
+
+func TestConfigLogger(t *testing.T) {
+	var logs []string
+	cfg := &reflectshape.Config{IncludeGoTestFiles: true, Trace: true}
+	cfg.Logger = metadata.LoggerFunc(func(format string, args ...interface{}) {
+		logs = append(logs, fmt.Sprintf(format, args...))
+	})
+
+	cfg.Extract(Hello).Func().Doc()
+	if len(logs) == 0 {
+		t.Fatalf("no logs")
+	}
+	if want, got := "trace: extract func", logs[0]; !strings.HasPrefix(got, want) {
+		t.Errorf("log: want prefix:%q != got:%q", want, got)
+	}
+}
+
+// Storage is the storage of the objects.
+type Storage interface {
+	io.Closer
+	StorageReader
+
+	// Put puts the object.
+	Put(key string, value []byte) error
+}
+
+// StorageReader reads the objects.
+type StorageReader interface {
+	// Get gets the object.
+	Get(key string) ([]byte, error)
+}
+
+func TestInterfaceEmbedded(t *testing.T) {
+	cfg := &reflectshape.Config{IncludeGoTestFiles: true}
+	iface := cfg.Extract((*Storage)(nil)).Interface()
+
+	got := map[string]string{}
+	for _, m := range iface.Methods() {
+		got[m.Name] = m.Doc
+	}
+	want := map[string]string{
+		"Close": "", // io.Closer has no doc on the method
+		"Get":   "Get gets the object.",
+		"Put":   "Put puts the object.",
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Shape.Interface().Methods(): docs mismatch (-want, +got): \n%v", diff)
+	}
+}
+
+func TestConfigMetadataSources(t *testing.T) {
+	cfg := &reflectshape.Config{IncludeGoTestFiles: true, MetadataSources: []metadata.Source{}}
+	cfg.Logger = metadata.LoggerFunc(func(format string, args ...interface{}) {}) // silence the lookup errors
+
+	if want, got := "", cfg.Extract(Person{}).Struct().Doc(); want != got {
+		t.Errorf("Shape.Struct().Doc(): no sources, want:%q != got:%q", want, got)
+	}
+}
+
+func TestFormatPosition(t *testing.T) {
+	root, err := filepath.Abs(".")
+	if err != nil {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+
+	cases := []struct {
+		msg    string
+		cfg    *reflectshape.Config
+		prefix string
+	}{
+		{msg: "module", cfg: &reflectshape.Config{IncludeGoTestFiles: true}, prefix: "github.com/podhmo/reflect-shape/api_test.go:"},
+		{msg: "root", cfg: &reflectshape.Config{IncludeGoTestFiles: true, PositionRoot: root}, prefix: "api_test.go:"},
+	}
+	for _, c := range cases {
+		c := c
+		t.Run(c.msg, func(t *testing.T) {
+			pos := c.cfg.Extract(Person{}).Struct().Pos()
+			if got := c.cfg.FormatPosition(pos); !strings.HasPrefix(got, c.prefix) {
+				t.Errorf("Config.FormatPosition(), must have the prefix %q, but got %q", c.prefix, got)
+			}
+		})
+	}
+}
+
+func TestGoTestFilesInTestBinary(t *testing.T) {
+	t.Run("auto", func(t *testing.T) {
+		cfg := &reflectshape.Config{} // IncludeGoTestFiles is not set, but enabled in the test binary
+		if want, got := "Invoice is the invoice.", cfg.Extract(Invoice{}).Struct().Doc(); want != got {
+			t.Errorf("Struct.Doc(), want:%q != got:%q", want, got)
+		}
+		if fn := cfg.Extract(ExampleConfig).Func(); fn.Err() != nil {
+			t.Errorf("Func.Err(), the example must be found, but %+v", fn.Err())
+		}
+	})
+	t.Run("exclude", func(t *testing.T) {
+		cfg := &reflectshape.Config{ExcludeGoTestFiles: true}
+		cfg.Logger = metadata.LoggerFunc(func(format string, args ...interface{}) {}) // silence the lookup errors
+		if want, got := "", cfg.Extract(Invoice{}).Struct().Doc(); want != got {
+			t.Errorf("Struct.Doc(), want:%q != got:%q", want, got)
+		}
+	})
+}
@@ -0,0 +1,129 @@
+package reflectshape
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/podhmo/reflect-shape/metadata"
+)
+
+// ErrVetoed is the error of the object vetoed by Hooks.BeforeExtract.
+var ErrVetoed = errors.New("vetoed")
+
+// ExtractError is the error of the object in the batch extraction.
+type ExtractError struct {
+	Index  int
+	Object interface{}
+	Err    error
+}
+
+func (e *ExtractError) Error() string {
+	return fmt.Sprintf("extract obs[%d] (%T): %v", e.Index, e.Object, e.Err)
+}
+
+func (e *ExtractError) Unwrap() error {
+	return e.Err
+}
+
+// ExtractErrors is the errors of the batch extraction, for each failed object.
+type ExtractErrors []*ExtractError
+
+func (errs ExtractErrors) Error() string {
+	parts := make([]string, len(errs))
+	for i, err := range errs {
+		parts[i] = err.Error()
+	}
+	return fmt.Sprintf("%d errors occurred: %s", len(errs), strings.Join(parts, "; "))
+}
+
+// ExtractAll extracts the shapes of obs, without failing the whole batch on the bad object.
+//
+// The result has the same length as obs (nil for the failed object), and the error is ExtractErrors if some of the objects are failed.
+// The object is failed if it is nil, vetoed by Hooks.BeforeExtract, or its metadata cannot be looked up.
+// If Config.RecoverPanics is true, the panic in the extraction of the object is also reported as the failure (*PanicError).
+// (the source-less functions, e.g. implemented in assembly, are not failed)
+func (c *Config) ExtractAll(obs ...interface{}) ([]*Shape, error) {
+	c.init()
+
+	var errs ExtractErrors
+	r := make([]*Shape, len(obs))
+	for i, ob := range obs {
+		if ob == nil {
+			errs = append(errs, &ExtractError{Index: i, Object: ob, Err: fmt.Errorf("nil object")})
+			continue
+		}
+		s, err := c.extractor.tryExtract(ob)
+		if err != nil {
+			errs = append(errs, &ExtractError{Index: i, Object: ob, Err: err})
+			continue
+		}
+		if s == nil {
+			errs = append(errs, &ExtractError{Index: i, Object: ob, Err: ErrVetoed})
+			continue
+		}
+		if err := c.extractor.resolve(context.Background(), s); err != nil {
+			errs = append(errs, &ExtractError{Index: i, Object: ob, Err: err})
+			continue
+		}
+		r[i] = s
+	}
+	if len(errs) > 0 {
+		return r, errs
+	}
+	return r, nil
+}
+
+// ExtractContext extracts the shape of ob, and looks up its metadata (comments) eagerly with ctx.
+// The loading of the packages is cancelled if ctx is done, and the lookup error is returned. (like ExtractAll)
+// After that, the accessors of the shape (e.g. Struct().Doc()) use the cached metadata.
+func (c *Config) ExtractContext(ctx context.Context, ob interface{}) (*Shape, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	c.init()
+	if ob == nil {
+		return nil, fmt.Errorf("nil object")
+	}
+	s, err := c.extractor.tryExtract(ob)
+	if err != nil {
+		return nil, err
+	}
+	if s == nil {
+		return nil, ErrVetoed
+	}
+	if err := c.extractor.resolve(ctx, s); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (e *Extractor) tryExtract(ob interface{}) (s *Shape, err error) {
+	defer e.recoverPanic(reflect.TypeOf(ob), &err)
+	return e.Extract(ob), nil
+}
+
+// resolve looks up the metadata of the shape, to report the error eagerly.
+func (e *Extractor) resolve(ctx context.Context, s *Shape) error {
+	switch s.Kind {
+	case reflect.Func:
+		if s.Name == "" || (s.Package.Path == "" && anonymousFuncNameRegex.MatchString(s.Name)) {
+			return nil
+		}
+		_, _, err := e.lookupFuncContext(ctx, s.ID.pc, s.Package.Path)
+		if err != nil && errors.Is(err, metadata.ErrNotSupported) {
+			return nil
+		}
+		return err
+	case reflect.Struct, reflect.Interface:
+		if s.Name == "" {
+			return nil
+		}
+		_, _, err := e.lookupTypeContext(ctx, s.Type)
+		return err
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,120 @@
+package reflectshape_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	reflectshape "github.com/podhmo/reflect-shape"
+	"github.com/podhmo/reflect-shape/metadata"
+)
+
+func TestExtractAll(t *testing.T) {
+	type local struct{} // not found, declared in the function
+
+	cfg := &reflectshape.Config{IncludeGoTestFiles: true}
+	cfg.Hooks.BeforeExtract = func(rt reflect.Type) bool {
+		return rt.Kind() != reflect.Slice
+	}
+
+	shapes, err := cfg.ExtractAll(Person{}, nil, []int{}, local{}, Hello)
+	if err == nil {
+		t.Fatalf("ExtractAll(): error is expected")
+	}
+
+	var names []string
+	for _, s := range shapes {
+		if s == nil {
+			names = append(names, "")
+			continue
+		}
+		names = append(names, s.Name)
+	}
+	if diff := cmp.Diff([]string{"Person", "", "", "", "Hello"}, names); diff != "" {
+		t.Errorf("ExtractAll() shapes: -want, +got: \n%v", diff)
+	}
+
+	var errs reflectshape.ExtractErrors
+	if !errors.As(err, &errs) {
+		t.Fatalf("ExtractAll() error: want:%T != got:%T", errs, err)
+	}
+	var indices []int
+	for _, err := range errs {
+		indices = append(indices, err.Index)
+	}
+	if diff := cmp.Diff([]int{1, 2, 3}, indices); diff != "" {
+		t.Errorf("ExtractErrors indices: -want, +got: \n%v", diff)
+	}
+	if !errors.Is(errs[1], reflectshape.ErrVetoed) {
+		t.Errorf("ExtractErrors[1]: want:%v != got:%v", reflectshape.ErrVetoed, errs[1])
+	}
+	if !errors.Is(errs[2], metadata.ErrNotFound) {
+		t.Errorf("ExtractErrors[2]: want:%v != got:%v", metadata.ErrNotFound, errs[2])
+	}
+}
+
+func TestExtractAllRecoverPanics(t *testing.T) {
+	cfg := &reflectshape.Config{IncludeGoTestFiles: true, RecoverPanics: true}
+	cfg.Hooks.LookupType = func(rt reflect.Type) (*metadata.Type, bool) {
+		if rt == reflect.TypeOf(Person{}) {
+			panic("weird type")
+		}
+		return nil, false
+	}
+
+	shapes, err := cfg.ExtractAll(Person{}, Hello)
+	if shapes[0] != nil || shapes[1] == nil {
+		t.Errorf("ExtractAll() shapes: want:[nil, Hello] != got:%v", shapes)
+	}
+
+	var errs reflectshape.ExtractErrors
+	if !errors.As(err, &errs) {
+		t.Fatalf("ExtractAll() error: want:%T != got:%T", errs, err)
+	}
+	var perr *reflectshape.PanicError
+	if !errors.As(errs[0], &perr) {
+		t.Fatalf("ExtractErrors[0]: want:%T != got:%+v", perr, errs[0])
+	}
+	if want, got := reflect.TypeOf(Person{}), perr.Type; want != got {
+		t.Errorf("PanicError.Type: want:%v != got:%v", want, got)
+	}
+	if want, got := "weird type", perr.Value; want != got {
+		t.Errorf("PanicError.Value: want:%v != got:%v", want, got)
+	}
+	if len(perr.Stack) == 0 {
+		t.Errorf("PanicError.Stack is empty")
+	}
+}
+
+func TestRecoverPanicsInAccessors(t *testing.T) {
+	cfg := &reflectshape.Config{IncludeGoTestFiles: true, RecoverPanics: true}
+	var logs []string
+	cfg.Logger = metadata.LoggerFunc(func(format string, args ...interface{}) {
+		logs = append(logs, fmt.Sprintf(format, args...))
+	})
+	ctxType := reflect.TypeOf((*context.Context)(nil)).Elem()
+	cfg.Hooks.BeforeExtract = func(rt reflect.Type) bool {
+		if rt == ctxType {
+			panic("weird type")
+		}
+		return true
+	}
+
+	fn := cfg.Extract((*Queue).Push).Func()
+	if got := fn.Args(); got != nil {
+		t.Errorf("Func.Args(): want:nil != got:%v", got)
+	}
+	if len(logs) != 1 || !strings.Contains(logs[0], "weird type") {
+		t.Errorf("the panic must be logged, but %q", logs)
+	}
+
+	// the result of the panicked call is not cached
+	cfg.Hooks.BeforeExtract = nil
+	if want, got := 3, len(fn.Args()); want != got {
+		t.Errorf("len(Func.Args()): want:%d != got:%d", want, got)
+	}
+}
@@ -0,0 +1,261 @@
+package reflectshape
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"io"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// BinderGenerator generates the binding code of the request structs. (query/path/header/body -> struct)
+//
+// The sources of the fields are declared by the tags.
+//
+//	type GetUserRequest struct {
+//		ID     int64  `path:"id"`
+//		Pretty bool   `query:"pretty"`
+//		Token  string `header:"X-Token" validate:"required"`
+//		Name   string `json:"name"` // body
+//	}
+//
+// For each struct, Bind<Type>(r *http.Request, pathValue func(string) string) (*<Type>, error) is generated,
+// with the stub of Validate<Type>(*<Type>) error, which checks the `validate:"required"` fields and has TODO comments for the others.
+// The generated code is the scaffolding, so it can be edited freely.
+type BinderGenerator struct {
+	Package string // the package name of the generated code (default is guessed from PkgPath)
+	PkgPath string // the package path of the generated code (default is the package of the first shape, unless Package is set)
+}
+
+// Generate writes the gofmt-ed binding code of the structs to w.
+func (g *BinderGenerator) Generate(w io.Writer, shapes ...*Shape) error {
+	if len(shapes) == 0 {
+		return fmt.Errorf("no shapes")
+	}
+	pkgpath := g.PkgPath
+	if pkgpath == "" && g.Package == "" {
+		pkgpath = shapes[0].Package.Path
+	}
+	pkgname := g.Package
+	if pkgname == "" {
+		pkgname = guessPackageName(pkgpath)
+	}
+
+	imports := map[string]bool{"net/http": true} // the others are added where they are used
+	var body bytes.Buffer
+	for _, s := range shapes {
+		if s.Kind != reflect.Struct || s.Name == "" {
+			return fmt.Errorf("%v is not named struct", s)
+		}
+		bg := &binderGen{pkgpath: pkgpath, imports: imports, w: &body}
+		bg.generate(s.Struct())
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintln(&buf, "// Code generated by reflect-shape, as the scaffolding (edit as you like).")
+	fmt.Fprintln(&buf)
+	fmt.Fprintf(&buf, "package %s\n\n", pkgname)
+	paths := make([]string, 0, len(imports))
+	for path := range imports {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	fmt.Fprintln(&buf, "import (")
+	for _, path := range paths {
+		fmt.Fprintf(&buf, "\t%q\n", path)
+	}
+	fmt.Fprintln(&buf, ")")
+	buf.Write(body.Bytes())
+
+	code, err := format.Source(buf.Bytes())
+	if err != nil {
+		return fmt.Errorf("format generated code: %w", err)
+	}
+	_, err = w.Write(code)
+	return err
+}
+
+type binderGen struct {
+	pkgpath string // the types in it are not qualified
+	imports map[string]bool
+	w       io.Writer
+}
+
+func (g *binderGen) printf(format string, args ...interface{}) {
+	fmt.Fprintf(g.w, format, args...)
+}
+
+// typeExpr returns the type expression in the generated code.
+func (g *binderGen) typeExpr(rt reflect.Type) string {
+	if rt.Name() == "" {
+		switch rt.Kind() {
+		case reflect.Pointer:
+			return "*" + g.typeExpr(rt.Elem())
+		case reflect.Slice:
+			return "[]" + g.typeExpr(rt.Elem())
+		}
+		return rt.String()
+	}
+	if rt.PkgPath() == "" {
+		return rt.Name()
+	}
+	if rt.PkgPath() == g.pkgpath {
+		return rt.Name()
+	}
+	g.imports[rt.PkgPath()] = true
+	return strings.SplitN(rt.String(), ".", 2)[0] + "." + rt.Name()
+}
+
+func (g *binderGen) generate(s *Struct) {
+	name := s.Shape.Name
+	typ := g.typeExpr(s.Shape.Type)
+
+	type param struct {
+		field *Field
+		in    string // path, query or header
+		key   string
+	}
+	var params []param
+	hasBody := false
+	for _, f := range s.Fields() {
+		if !f.IsExported() {
+			continue
+		}
+		found := false
+		for _, in := range []string{"path", "query", "header"} {
+			if key := tagName(f.Tag, in); key != "" {
+				params = append(params, param{field: f, in: in, key: key})
+				found = true
+				break
+			}
+		}
+		if !found && tagName(f.Tag, "json") != "" {
+			hasBody = true
+		}
+	}
+
+	g.printf("\n// Bind%s binds the http request to %s.\n", name, name)
+	if doc := s.Doc(); doc != "" {
+		g.printf("//\n")
+		for _, line := range strings.Split(doc, "\n") {
+			g.printf("// %s\n", line)
+		}
+	}
+	g.printf("func Bind%s(r *http.Request, pathValue func(name string) string) (*%s, error) {\n", name, typ)
+	g.printf("var v %s\n", typ)
+	if hasBody {
+		g.imports["encoding/json"] = true
+		g.imports["fmt"] = true
+		g.printf("if r.Body != nil && r.ContentLength != 0 {\n")
+		g.printf("if err := json.NewDecoder(r.Body).Decode(&v); err != nil {\n")
+		g.printf("return nil, fmt.Errorf(\"body: %%w\", err)\n")
+		g.printf("}\n}\n")
+	}
+	for _, p := range params {
+		var getter string
+		switch p.in {
+		case "path":
+			getter = fmt.Sprintf("pathValue(%q)", p.key)
+		case "query":
+			getter = fmt.Sprintf("r.URL.Query().Get(%q)", p.key)
+		case "header":
+			getter = fmt.Sprintf("r.Header.Get(%q)", p.key)
+		}
+		if p.field.Doc != "" {
+			g.printf("// %s: %s\n", p.field.Name, strings.ReplaceAll(p.field.Doc, "\n", " "))
+		}
+		g.printf("if s := %s; s != \"\" {\n", getter)
+		g.assign(p.field, p.in, p.key)
+		g.printf("}\n")
+	}
+	g.printf("if err := Validate%s(&v); err != nil {\n", name)
+	g.printf("return nil, err\n")
+	g.printf("}\n")
+	g.printf("return &v, nil\n")
+	g.printf("}\n")
+
+	g.printf("\n// Validate%s validates %s. (stub)\n", name, name)
+	g.printf("func Validate%s(v *%s) error {\n", name, typ)
+	for _, f := range s.Fields() {
+		if !f.IsExported() {
+			continue
+		}
+		if tagName(f.Tag, "validate") != "required" {
+			if f.Tag.Get("validate") != "" {
+				g.printf("// TODO: validate %s (%s)\n", f.Name, f.Tag.Get("validate"))
+			}
+			continue
+		}
+		zero := zeroExpr(f.Type, g.typeExpr(f.Type))
+		if zero == "" {
+			g.printf("// TODO: validate %s (required)\n", f.Name)
+			continue
+		}
+		g.imports["fmt"] = true
+		g.printf("if v.%s == %s {\n", f.Name, zero)
+		g.printf("return fmt.Errorf(\"%%q is required\", %q)\n", f.Name)
+		g.printf("}\n")
+	}
+	g.printf("return nil\n")
+	g.printf("}\n")
+}
+
+// assign writes the conversion from the string s to the field.
+func (g *binderGen) assign(f *Field, in, key string) {
+	rt := f.Type
+	expr := g.typeExpr(rt)
+	onError := func() string {
+		g.imports["fmt"] = true
+		return fmt.Sprintf("return nil, fmt.Errorf(\"%s %%q: %%w\", %q, err)", in, key)
+	}
+
+	switch rt.Kind() {
+	case reflect.String:
+		g.printf("v.%s = %s(s)\n", f.Name, expr)
+	case reflect.Bool:
+		g.imports["strconv"] = true
+		g.printf("x, err := strconv.ParseBool(s)\nif err != nil {\n%s\n}\n", onError())
+		g.printf("v.%s = %s(x)\n", f.Name, expr)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		g.imports["strconv"] = true
+		g.printf("x, err := strconv.ParseInt(s, 10, %d)\nif err != nil {\n%s\n}\n", rt.Bits(), onError())
+		g.printf("v.%s = %s(x)\n", f.Name, expr)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		g.imports["strconv"] = true
+		g.printf("x, err := strconv.ParseUint(s, 10, %d)\nif err != nil {\n%s\n}\n", rt.Bits(), onError())
+		g.printf("v.%s = %s(x)\n", f.Name, expr)
+	case reflect.Float32, reflect.Float64:
+		g.imports["strconv"] = true
+		g.printf("x, err := strconv.ParseFloat(s, %d)\nif err != nil {\n%s\n}\n", rt.Bits(), onError())
+		g.printf("v.%s = %s(x)\n", f.Name, expr)
+	case reflect.Slice:
+		if rt.Elem().Kind() == reflect.String && in == "query" {
+			g.printf("v.%s = %s(r.URL.Query()[%q])\n", f.Name, expr, key)
+			return
+		}
+		g.printf("// TODO: bind %s (%s)\n", f.Name, expr)
+	default:
+		g.printf("// TODO: bind %s (%s)\n", f.Name, expr)
+	}
+}
+
+// zeroExpr returns the expression of the zero value comparable with ==, or "" if not found.
+func zeroExpr(rt reflect.Type, expr string) string {
+	switch rt.Kind() {
+	case reflect.String:
+		return `""`
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return "0"
+	case reflect.Pointer, reflect.Slice, reflect.Map, reflect.Interface:
+		return "nil"
+	case reflect.Struct:
+		if rt.Comparable() {
+			return "(" + expr + "{})"
+		}
+	}
+	return ""
+}
@@ -0,0 +1,120 @@
+package reflectshape_test
+
+import (
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"strings"
+	"testing"
+
+	reflectshape "github.com/podhmo/reflect-shape"
+)
+
+// UpdateUserRequest is the request for updating the user.
+type UpdateUserRequest struct {
+	ID     int64    `path:"id"`
+	Pretty bool     `query:"pretty"`
+	Fields []string `query:"fields"`
+	Token  string   `header:"X-Token" validate:"required"` // token for authentication
+
+	Name  string `json:"name" validate:"required"`
+	Email string `json:"email" validate:"email"`
+}
+
+// GetItemRequest is the request having only the string params, the generated code doesn't use fmt.
+type GetItemRequest struct {
+	ID   string `path:"id"`
+	Lang string `header:"Accept-Language"`
+}
+
+func TestBinderGenerator(t *testing.T) {
+	g := &reflectshape.BinderGenerator{Package: "handlers"}
+
+	var buf strings.Builder
+	if err := g.Generate(&buf, cfg.Extract(UpdateUserRequest{})); err != nil {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+	code := buf.String()
+
+	for _, want := range []string{
+		"package handlers\n",
+		"\t\"github.com/podhmo/reflect-shape_test\"\n", // qualified, different package
+		"// BindUpdateUserRequest binds the http request to UpdateUserRequest.\n//\n// UpdateUserRequest is the request for updating the user.\n",
+		"func BindUpdateUserRequest(r *http.Request, pathValue func(name string) string) (*reflectshape_test.UpdateUserRequest, error) {",
+		"json.NewDecoder(r.Body).Decode(&v)",
+		"if s := pathValue(\"id\"); s != \"\" {\n\t\tx, err := strconv.ParseInt(s, 10, 64)",
+		"v.Pretty = bool(x)",
+		"v.Fields = []string(r.URL.Query()[\"fields\"])",
+		"// Token: token for authentication\n\tif s := r.Header.Get(\"X-Token\"); s != \"\" {\n\t\tv.Token = string(s)",
+		"func ValidateUpdateUserRequest(v *reflectshape_test.UpdateUserRequest) error {",
+		"if v.Token == \"\" {\n\t\treturn fmt.Errorf(\"%q is required\", \"Token\")",
+		"if v.Name == \"\" {",
+		"// TODO: validate Email (email)",
+	} {
+		if !strings.Contains(code, want) {
+			t.Errorf("Generate(): %q is not found in\n%s", want, code)
+		}
+	}
+
+	t.Run("pkgpath", func(t *testing.T) {
+		cases := []struct {
+			msg  string
+			gen  *reflectshape.BinderGenerator
+			want string
+		}{
+			{msg: "default", gen: &reflectshape.BinderGenerator{}, want: "func BindUpdateUserRequest(r *http.Request, pathValue func(name string) string) (*UpdateUserRequest, error) {"},
+			{msg: "same-name", gen: &reflectshape.BinderGenerator{PkgPath: "github.com/foo/reflect-shape_test"}, want: "(*reflectshape_test.UpdateUserRequest, error) {"},
+		}
+		for _, c := range cases {
+			c := c
+			t.Run(c.msg, func(t *testing.T) {
+				var buf strings.Builder
+				if err := c.gen.Generate(&buf, cfg.Extract(UpdateUserRequest{})); err != nil {
+					t.Fatalf("unexpected error: %+v", err)
+				}
+				if code := buf.String(); !strings.Contains(code, c.want) {
+					t.Errorf("Generate(): %q is not found in\n%s", c.want, code)
+				}
+			})
+		}
+	})
+
+	t.Run("type-check", func(t *testing.T) {
+		cases := []struct {
+			msg   string
+			input interface{}
+			decl  string // the declaration of the struct, in the generated package
+		}{
+			{msg: "all", input: UpdateUserRequest{}, decl: "type UpdateUserRequest struct { ID int64; Pretty bool; Fields []string; Token, Name, Email string }"},
+			{msg: "string-only", input: GetItemRequest{}, decl: "type GetItemRequest struct { ID, Lang string }"},
+		}
+		for _, c := range cases {
+			c := c
+			t.Run(c.msg, func(t *testing.T) {
+				var buf strings.Builder
+				if err := (&reflectshape.BinderGenerator{}).Generate(&buf, cfg.Extract(c.input)); err != nil {
+					t.Fatalf("unexpected error: %+v", err)
+				}
+				code := buf.String() + "\n" + c.decl + "\n"
+
+				fset := token.NewFileSet()
+				f, err := parser.ParseFile(fset, "binder.go", code, 0)
+				if err != nil {
+					t.Fatalf("unexpected error: %+v", err)
+				}
+				conf := types.Config{Importer: importer.Default()}
+				if _, err := conf.Check("handlers", fset, []*ast.File{f}, nil); err != nil {
+					t.Errorf("the generated code is not compiled: %+v\n%s", err, code)
+				}
+			})
+		}
+	})
+
+	t.Run("not-struct", func(t *testing.T) {
+		if err := g.Generate(&buf, cfg.Extract(0)); err == nil {
+			t.Errorf("Generate(): error is expected")
+		}
+	})
+}
@@ -0,0 +1,87 @@
+package reflectshape
+
+import "container/list"
+
+// CacheStats is the statistics of the caches of the config. (see Config.MaxShapes and Config.MaxPackages)
+type CacheStats struct {
+	Shapes        int // the number of the cached shapes
+	EvictedShapes int
+	ShapePackages int // the number of the packages of the cached shapes (the packages are dropped with their last shapes)
+	Views         int // the number of the shapes having the cached views (e.g. Struct.Fields(), dropped with the shapes)
+
+	Packages        int // the number of the packages cached by the metadata lookup
+	EvictedPackages int
+
+	InternedStrings  int // the number of the interned strings (see Config.InternStrings)
+	InternedBytes    int // the total size of the interned strings
+	InternSavedBytes int // the total size of the duplicated strings, replaced by the interned ones (the shapes are interned once, their views are cached)
+}
+
+// CacheStats returns the current statistics of the caches.
+func (c *Config) CacheStats() CacheStats {
+	c.init()
+	c.extractor.mu.Lock()
+	defer c.extractor.mu.Unlock()
+	r := CacheStats{Shapes: len(c.extractor.seen), EvictedShapes: c.extractor.evicted, ShapePackages: len(c.extractor.packages), Views: len(c.extractor.views)}
+	if in := c.extractor.interner; in != nil {
+		r.InternedStrings = len(in.strings)
+		r.InternedBytes = in.bytes
+		r.InternSavedBytes = in.saved
+	}
+	if c.lookup != nil {
+		stats := c.lookup.CacheStats()
+		r.Packages = stats.Packages
+		r.EvictedPackages = stats.Evicted
+	}
+	return r
+}
+
+// snapshot returns the cached shapes. (the shapes must not be modified)
+func (e *Extractor) snapshot() []*Shape {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	shapes := make([]*Shape, 0, len(e.seen))
+	for _, s := range e.seen {
+		shapes = append(shapes, s)
+	}
+	return shapes
+}
+
+// touch marks the shape as used, for LRU eviction. (e.mu must be held)
+func (e *Extractor) touch(id ID) {
+	if e.Config.MaxShapes <= 0 {
+		return
+	}
+	if e.lru == nil {
+		e.lru = list.New()
+		e.elems = map[ID]*list.Element{}
+	}
+	if el, ok := e.elems[id]; ok {
+		e.lru.MoveToFront(el)
+		return
+	}
+	e.elems[id] = e.lru.PushFront(id)
+}
+
+// evict drops the least recently used shapes, until the number of the cached shapes is within Config.MaxShapes.
+// The evicted shapes are extracted again if needed (with the new Number), and the package is dropped with its last shape,
+// so that the memory is bounded even if the process extracts the types of the many packages (e.g. plugins). (e.mu must be held)
+func (e *Extractor) evict() {
+	for e.Config.MaxShapes > 0 && e.lru.Len() > e.Config.MaxShapes {
+		el := e.lru.Back()
+		id := e.lru.Remove(el).(ID)
+		delete(e.elems, id)
+
+		delete(e.views, id)
+		if shape, ok := e.seen[id]; ok {
+			delete(e.seen, id)
+			if scope := shape.Package.scope; scope.shapes[shape.Name] == shape {
+				delete(scope.shapes, shape.Name)
+				if len(scope.shapes) == 0 && e.packages[shape.Package.Path] == shape.Package {
+					delete(e.packages, shape.Package.Path)
+				}
+			}
+			e.evicted++
+		}
+	}
+}
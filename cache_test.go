@@ -0,0 +1,87 @@
+package reflectshape_test
+
+import (
+	"bytes"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	reflectshape "github.com/podhmo/reflect-shape"
+)
+
+func TestMaxShapes(t *testing.T) {
+	cfg := &reflectshape.Config{SkipComments: true, MaxShapes: 2}
+
+	type A struct{}
+	type B struct{}
+	type C struct{}
+
+	a := cfg.Extract(A{})
+	cfg.Extract(B{})
+	cfg.Extract(A{}) // A is used recently, so B is evicted
+	cfg.Extract(C{})
+
+	stats := cfg.CacheStats()
+	if want, got := 2, stats.Shapes; want != got {
+		t.Errorf("CacheStats().Shapes: want:%d != got:%d", want, got)
+	}
+	if want, got := 1, stats.EvictedShapes; want != got {
+		t.Errorf("CacheStats().EvictedShapes: want:%d != got:%d", want, got)
+	}
+
+	if want, got := a.Number, cfg.Extract(A{}).Number; want != got {
+		t.Errorf("A is cached, Number: want:%d != got:%d", want, got)
+	}
+	if want, got := 3, cfg.Extract(B{}).Number; want != got {
+		t.Errorf("B is extracted again, Number: want:%d != got:%d", want, got)
+	}
+	if want, got := 2, cfg.CacheStats().EvictedShapes; want != got {
+		t.Errorf("CacheStats().EvictedShapes: want:%d != got:%d", want, got)
+	}
+}
+
+func TestMaxShapesDropViews(t *testing.T) {
+	cfg := &reflectshape.Config{SkipComments: true, MaxShapes: 2}
+
+	type A struct{ Name string }
+	type B struct{ Name string }
+	type C struct{ Name string }
+
+	var held []*reflectshape.Shape // held past the eviction
+	for _, ob := range []interface{}{A{}, B{}, C{}, A{}, B{}, C{}} {
+		held = append(held, cfg.Extract(ob))
+	}
+	for _, s := range held {
+		s.Struct().Fields()
+		s.Fingerprint()
+	}
+
+	if got := cfg.CacheStats().Views; got > 2 {
+		t.Errorf("CacheStats().Views: want:<=%d != got:%d", 2, got)
+	}
+}
+
+func TestMaxShapesDropPackages(t *testing.T) {
+	cfg := &reflectshape.Config{SkipComments: true, MaxShapes: 2}
+
+	obs := []interface{}{bytes.Buffer{}, strings.Builder{}, http.Client{}, url.URL{}, Person{}}
+	for i, ob := range obs {
+		cfg.Extract(ob)
+		stats := cfg.CacheStats()
+		want := 2
+		if i == 0 {
+			want = 1
+		}
+		if got := stats.ShapePackages; want != got {
+			t.Errorf("%T: CacheStats().ShapePackages: want:%d != got:%d", ob, want, got)
+		}
+	}
+
+	s := cfg.Extract(Person{})
+	if diff := cmp.Diff([]string{"Person"}, s.Package.Scope().Names()); diff != "" {
+		t.Errorf("Scope().Names() mismatch (-want, +got): \n%v", diff)
+	}
+}
@@ -0,0 +1,89 @@
+package reflectshape
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+)
+
+// Catalog is the catalog of the events, the payload structs registered with the topic names.
+// The catalog is written as the AsyncAPI-style document, with the schemas and the docs of the payloads.
+type Catalog struct {
+	Title   string
+	Version string
+
+	config *Config
+	events []*catalogEvent
+}
+
+func (c *Config) Catalog(title, version string) *Catalog {
+	return &Catalog{Title: title, Version: version, config: c}
+}
+
+type catalogEvent struct {
+	topic   string
+	payload *Shape
+}
+
+// Register registers the payload struct of the topic. (e.g. Register("user.created", UserCreated{}))
+func (c *Catalog) Register(topic string, payload interface{}) error {
+	for _, ev := range c.events {
+		if ev.topic == topic {
+			return fmt.Errorf("topic %q is already registered", topic)
+		}
+	}
+	s := c.config.Extract(payload)
+	if s == nil {
+		return fmt.Errorf("topic %q: %w", topic, ErrVetoed)
+	}
+	if s.Kind != reflect.Struct || s.Name == "" {
+		return fmt.Errorf("topic %q: payload must be named struct, but %v", topic, s)
+	}
+	c.events = append(c.events, &catalogEvent{topic: topic, payload: s})
+	return nil
+}
+
+// Document returns the catalog as the AsyncAPI-style document. (channels, messages, and schemas)
+func (c *Catalog) Document() map[string]interface{} {
+	b := newSchemaBuilder("#/components/schemas/")
+	channels := map[string]interface{}{}
+	messages := map[string]interface{}{}
+	for _, ev := range c.events {
+		payload := b.schema(ev.payload)
+		name := b.defName(ev.payload) // the same as the key of the schema, qualified if the name is taken
+		msg := map[string]interface{}{
+			"name":    name,
+			"payload": payload,
+		}
+		if doc := ev.payload.Struct().Doc(); doc != "" {
+			msg["description"] = doc
+		}
+		messages[name] = msg
+		channels[ev.topic] = map[string]interface{}{
+			"subscribe": map[string]interface{}{
+				"message": map[string]interface{}{"$ref": "#/components/messages/" + name},
+			},
+		}
+	}
+
+	return map[string]interface{}{
+		"asyncapi": "2.6.0",
+		"info":     map[string]interface{}{"title": c.Title, "version": c.Version},
+		"channels": channels,
+		"components": map[string]interface{}{
+			"messages": messages,
+			"schemas":  b.defs,
+		},
+	}
+}
+
+// WriteTo writes the catalog document as JSON.
+func (c *Catalog) WriteTo(w io.Writer) (int64, error) {
+	b, err := json.MarshalIndent(c.Document(), "", "  ")
+	if err != nil {
+		return 0, fmt.Errorf("marshal catalog: %w", err)
+	}
+	n, err := w.Write(append(b, '\n'))
+	return int64(n), err
+}
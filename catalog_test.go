@@ -0,0 +1,239 @@
+package reflectshape_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/podhmo/commentof/collect"
+	reflectshape "github.com/podhmo/reflect-shape"
+)
+
+// UserCreated is published when the user is created.
+type UserCreated struct {
+	ID      string   `json:"id"`
+	Tags    []string `json:"tags,omitempty"`
+	Address *Address `json:"address"` // the address of the user
+}
+
+// Address is the address.
+type Address struct {
+	City string `json:"city"`
+}
+
+// UserDeleted is published when the user is deleted.
+type UserDeleted struct {
+	ID string `json:"id"`
+}
+
+// Object is the same name as collect.Object.
+type Object struct {
+	Name string `json:"name"`
+}
+
+// ObjectChanged is published when the object is changed.
+type ObjectChanged struct {
+	Object   Object          `json:"object"`
+	Original *collect.Object `json:"original"`
+}
+
+func TestCatalog(t *testing.T) {
+	cfg := &reflectshape.Config{IncludeGoTestFiles: true}
+	catalog := cfg.Catalog("users", "1.0.0")
+	if err := catalog.Register("user.created", UserCreated{}); err != nil {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+	if err := catalog.Register("user.deleted", UserDeleted{}); err != nil {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+
+	var buf strings.Builder
+	if _, err := catalog.WriteTo(&buf); err != nil {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+
+	want := `{
+  "asyncapi": "2.6.0",
+  "channels": {
+    "user.created": {
+      "subscribe": {
+        "message": {
+          "$ref": "#/components/messages/UserCreated"
+        }
+      }
+    },
+    "user.deleted": {
+      "subscribe": {
+        "message": {
+          "$ref": "#/components/messages/UserDeleted"
+        }
+      }
+    }
+  },
+  "components": {
+    "messages": {
+      "UserCreated": {
+        "description": "UserCreated is published when the user is created.",
+        "name": "UserCreated",
+        "payload": {
+          "$ref": "#/components/schemas/UserCreated"
+        }
+      },
+      "UserDeleted": {
+        "description": "UserDeleted is published when the user is deleted.",
+        "name": "UserDeleted",
+        "payload": {
+          "$ref": "#/components/schemas/UserDeleted"
+        }
+      }
+    },
+    "schemas": {
+      "Address": {
+        "description": "Address is the address.",
+        "properties": {
+          "city": {
+            "type": "string"
+          }
+        },
+        "required": [
+          "city"
+        ],
+        "type": "object"
+      },
+      "UserCreated": {
+        "description": "UserCreated is published when the user is created.",
+        "properties": {
+          "address": {
+            "$ref": "#/components/schemas/Address",
+            "description": "the address of the user"
+          },
+          "id": {
+            "type": "string"
+          },
+          "tags": {
+            "items": {
+              "type": "string"
+            },
+            "type": "array"
+          }
+        },
+        "required": [
+          "id"
+        ],
+        "type": "object"
+      },
+      "UserDeleted": {
+        "description": "UserDeleted is published when the user is deleted.",
+        "properties": {
+          "id": {
+            "type": "string"
+          }
+        },
+        "required": [
+          "id"
+        ],
+        "type": "object"
+      }
+    }
+  },
+  "info": {
+    "title": "users",
+    "version": "1.0.0"
+  }
+}
+`
+	if diff := cmp.Diff(want, buf.String()); diff != "" {
+		t.Errorf("Catalog.WriteTo(): -want, +got: \n%v", diff)
+	}
+
+	t.Run("duplicated", func(t *testing.T) {
+		if err := catalog.Register("user.created", UserCreated{}); err == nil {
+			t.Errorf("Catalog.Register(): error is expected")
+		}
+	})
+	t.Run("not-struct", func(t *testing.T) {
+		if err := catalog.Register("user.count", 0); err == nil {
+			t.Errorf("Catalog.Register(): error is expected")
+		}
+	})
+
+	t.Run("same-name", func(t *testing.T) {
+		catalog := cfg.Catalog("objects", "1.0.0")
+		if err := catalog.Register("object.changed", ObjectChanged{}); err != nil {
+			t.Fatalf("unexpected error: %+v", err)
+		}
+		schemas := catalog.Document()["components"].(map[string]interface{})["schemas"].(map[string]interface{})
+		props := schemas["ObjectChanged"].(map[string]interface{})["properties"].(map[string]interface{})
+
+		want := map[string]string{"object": "Object", "original": "github.com.podhmo.commentof.collect.Object"}
+		for prop, name := range want {
+			if want, got := "#/components/schemas/"+name, props[prop].(map[string]interface{})["$ref"]; want != got {
+				t.Errorf("$ref of %s: want:%v != got:%v", prop, want, got)
+			}
+			if _, ok := schemas[name]; !ok {
+				t.Errorf("schema %q is not found in %v", name, schemas)
+			}
+		}
+	})
+
+	t.Run("rename", func(t *testing.T) {
+		cfg := &reflectshape.Config{IncludeGoTestFiles: true, Patches: map[string]*reflectshape.Patch{
+			"github.com/podhmo/reflect-shape_test.UserDeleted.ID": {Rename: "userId"},
+		}}
+		catalog := cfg.Catalog("users", "1.0.0")
+		if err := catalog.Register("user.deleted", UserDeleted{}); err != nil {
+			t.Fatalf("unexpected error: %+v", err)
+		}
+		schema := catalog.Document()["components"].(map[string]interface{})["schemas"].(map[string]interface{})["UserDeleted"].(map[string]interface{})
+		props := schema["properties"].(map[string]interface{})
+		if _, ok := props["userId"]; !ok {
+			t.Errorf("the renamed property is not found in %v", props)
+		}
+		if _, ok := props["id"]; ok {
+			t.Errorf("the property of the json tag must be renamed, but %v", props)
+		}
+		if diff := cmp.Diff([]string{"userId"}, schema["required"]); diff != "" {
+			t.Errorf("required: mismatch (-want, +got): \n%v", diff)
+		}
+	})
+
+	t.Run("same-name-namespace", func(t *testing.T) {
+		cfg := &reflectshape.Config{IncludeGoTestFiles: true, Namespaces: map[string]string{"github.com/podhmo/commentof": "commentof"}}
+		catalog := cfg.Catalog("objects", "1.0.0")
+		if err := catalog.Register("object.changed", ObjectChanged{}); err != nil {
+			t.Fatalf("unexpected error: %+v", err)
+		}
+		schemas := catalog.Document()["components"].(map[string]interface{})["schemas"].(map[string]interface{})
+		if _, ok := schemas["commentof.collect.Object"]; !ok {
+			t.Errorf("schema %q is not found in %v", "commentof.collect.Object", schemas)
+		}
+	})
+
+	t.Run("same-name-payloads", func(t *testing.T) {
+		catalog := cfg.Catalog("objects", "1.0.0")
+		if err := catalog.Register("object.created", Object{}); err != nil {
+			t.Fatalf("unexpected error: %+v", err)
+		}
+		if err := catalog.Register("collect.object.created", collect.Object{}); err != nil {
+			t.Fatalf("unexpected error: %+v", err)
+		}
+		doc := catalog.Document()
+		channels := doc["channels"].(map[string]interface{})
+		messages := doc["components"].(map[string]interface{})["messages"].(map[string]interface{})
+
+		want := map[string]string{"object.created": "Object", "collect.object.created": "github.com.podhmo.commentof.collect.Object"}
+		for topic, name := range want {
+			ref := channels[topic].(map[string]interface{})["subscribe"].(map[string]interface{})["message"].(map[string]interface{})["$ref"]
+			if want, got := "#/components/messages/"+name, ref; want != got {
+				t.Errorf("$ref of %s: want:%v != got:%v", topic, want, got)
+			}
+			msg, ok := messages[name].(map[string]interface{})
+			if !ok {
+				t.Fatalf("message %q is not found in %v", name, messages)
+			}
+			if want, got := "#/components/schemas/"+name, msg["payload"].(map[string]interface{})["$ref"]; want != got {
+				t.Errorf("payload of %s: want:%v != got:%v", name, want, got)
+			}
+		}
+	})
+}
@@ -0,0 +1,97 @@
+package reflectshape
+
+import (
+	"reflect"
+	"sort"
+)
+
+// Census is the aggregate statistics of the shapes in the registry. (see Registry.Census)
+type Census struct {
+	Shapes int            `json:"shapes"`
+	Kinds  map[string]int `json:"kinds"` // the number of the shapes keyed by the kind (e.g. "struct")
+	Coverage
+
+	Packages []*PackageCensus `json:"packages"` // ordered by the path. (the builtin types, e.g. int, are not included)
+}
+
+// PackageCensus is the statistics of the shapes in the package.
+type PackageCensus struct {
+	Path   string         `json:"path"`
+	Shapes int            `json:"shapes"`
+	Kinds  map[string]int `json:"kinds"`
+	Coverage
+}
+
+// Coverage is the doc coverage of the shapes.
+type Coverage struct {
+	Named            int     `json:"named"`            // the number of the named types and functions (the target of the doc coverage)
+	Documented       int     `json:"documented"`       // the number of the named ones having docs
+	DocCoverage      float64 `json:"docCoverage"`      // Documented / Named, in percentage (100 if Named is 0)
+	Fields           int     `json:"fields"`           // the number of the fields of the named structs
+	DocumentedFields int     `json:"documentedFields"` // the number of the fields having docs
+	FieldCoverage    float64 `json:"fieldCoverage"`    // DocumentedFields / Fields, in percentage (100 if Fields is 0)
+}
+
+func (c *Coverage) add(s *Shape) {
+	if s.Name == "" || s.Package.Path == "" || (s.Kind == reflect.Func && anonymousFuncNameRegex.MatchString(s.Name)) {
+		return
+	}
+	c.Named++
+	if s.doc() != "" {
+		c.Documented++
+	}
+	if s.Kind == reflect.Struct && s.ID.pc == 0 {
+		for _, f := range s.Struct().Fields() {
+			c.Fields++
+			if f.Doc != "" {
+				c.DocumentedFields++
+			}
+		}
+	}
+}
+
+func (c *Coverage) compute() {
+	c.DocCoverage = percentage(c.Documented, c.Named)
+	c.FieldCoverage = percentage(c.DocumentedFields, c.Fields)
+}
+
+func percentage(n, total int) float64 {
+	if total == 0 {
+		return 100
+	}
+	return float64(n) * 100 / float64(total)
+}
+
+// Census returns the aggregate statistics of the shapes: the number of the shapes per package and per kind, and the doc coverage.
+// The docs are looked up for all the named shapes, so it loads the packages not loaded yet.
+// The shapes of the fields are extracted for the field coverage, but only the shapes extracted before the call are counted.
+func (r *Registry) Census() *Census {
+	census := &Census{Kinds: map[string]int{}}
+	packages := map[string]*PackageCensus{}
+	for _, s := range r.Shapes() {
+		kind := s.Kind.String()
+		census.Shapes++
+		census.Kinds[kind]++
+		census.add(s)
+
+		if s.Package.Path == "" {
+			continue
+		}
+		p, ok := packages[s.Package.Path]
+		if !ok {
+			p = &PackageCensus{Path: s.Package.Path, Kinds: map[string]int{}}
+			packages[s.Package.Path] = p
+			census.Packages = append(census.Packages, p)
+		}
+		p.Shapes++
+		p.Kinds[kind]++
+		p.add(s)
+	}
+
+	census.compute()
+	for _, p := range census.Packages {
+		p.compute()
+	}
+	sort.Slice(census.Packages, func(i, j int) bool { return census.Packages[i].Path < census.Packages[j].Path })
+	return census
+}
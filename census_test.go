@@ -0,0 +1,44 @@
+package reflectshape_test
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	reflectshape "github.com/podhmo/reflect-shape"
+)
+
+func TestRegistryCensus(t *testing.T) {
+	cfg := &reflectshape.Config{IncludeGoTestFiles: true}
+	cfg.Extract(Person{})
+	cfg.Extract(Counter{})
+	cfg.Extract(ListUsers)
+
+	n := len(cfg.Registry().Shapes())
+	census := cfg.Registry().Census() // the shapes of the fields are extracted, but not counted
+	if want, got := n, census.Shapes; want != got {
+		t.Errorf("Census.Shapes, want:%v != got:%v", want, got)
+	}
+
+	var p *reflectshape.PackageCensus
+	for _, x := range census.Packages {
+		if x.Path == "github.com/podhmo/reflect-shape_test" {
+			p = x
+		}
+	}
+	if p == nil {
+		t.Fatalf("Census.Packages, the package is not found: %+v", census.Packages)
+	}
+
+	want := &reflectshape.PackageCensus{
+		Path:   "github.com/podhmo/reflect-shape_test",
+		Shapes: 3,
+		Kinds:  map[string]int{"struct": 2, "func": 1},
+		Coverage: reflectshape.Coverage{
+			Named: 3, Documented: 2, DocCoverage: 200.0 / 3, // Counter has no doc
+			Fields: 4, DocumentedFields: 1, FieldCoverage: 25, // only Person.Name has doc
+		},
+	}
+	if diff := cmp.Diff(want, p); diff != "" {
+		t.Errorf("Census.Packages[]: mismatch (-want, +got): \n%v", diff)
+	}
+}
@@ -0,0 +1,111 @@
+package reflectshape
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Collection is the named subset of the shapes in the registry (e.g. "Public API v2"), curated for the exporters and the doc server.
+// The collections are stored in the extractor, so Registry.Collection returns the same collection for the same name.
+type Collection struct {
+	Name string
+
+	e      *Extractor
+	shapes []*Shape // in the order of addition, unique by ID (e.mu guards)
+}
+
+// Collection returns the collection of the name, creating if not found.
+func (r *Registry) Collection(name string) *Collection {
+	r.e.mu.Lock()
+	defer r.e.mu.Unlock()
+	if r.e.collections == nil {
+		r.e.collections = map[string]*Collection{}
+	}
+	c, ok := r.e.collections[name]
+	if !ok {
+		c = &Collection{Name: name, e: r.e}
+		r.e.collections[name] = c
+	}
+	return c
+}
+
+// Collections returns the collections, ordered by the names.
+func (r *Registry) Collections() []*Collection {
+	r.e.mu.Lock()
+	defer r.e.mu.Unlock()
+	collections := make([]*Collection, 0, len(r.e.collections))
+	for _, c := range r.e.collections {
+		collections = append(collections, c)
+	}
+	sort.Slice(collections, func(i, j int) bool { return collections[i].Name < collections[j].Name })
+	return collections
+}
+
+// Add adds the shapes to the collection. The shapes already added (Shape.Equal) and nil (vetoed) are ignored.
+func (c *Collection) Add(shapes ...*Shape) *Collection {
+	c.e.mu.Lock()
+	defer c.e.mu.Unlock()
+	for _, s := range shapes {
+		if s == nil || c.index(s) >= 0 {
+			continue
+		}
+		copied := *s
+		c.shapes = append(c.shapes, &copied)
+	}
+	return c
+}
+
+// AddQuery adds the shapes matched with the query. (see Registry.Search)
+func (c *Collection) AddQuery(q Query) error {
+	shapes, err := (&Registry{e: c.e}).Search(q)
+	if err != nil {
+		return fmt.Errorf("collection %q: %w", c.Name, err)
+	}
+	c.Add(shapes...)
+	return nil
+}
+
+// Remove removes the shape from the collection.
+func (c *Collection) Remove(s *Shape) {
+	c.e.mu.Lock()
+	defer c.e.mu.Unlock()
+	if i := c.index(s); i >= 0 {
+		c.shapes = append(c.shapes[:i], c.shapes[i+1:]...)
+	}
+}
+
+// Contains reports whether the shape is in the collection, ignoring the pointer level.
+func (c *Collection) Contains(s *Shape) bool {
+	c.e.mu.Lock()
+	defer c.e.mu.Unlock()
+	return c.index(s) >= 0
+}
+
+// index returns the index of the shape, or -1. (c.e.mu must be held)
+func (c *Collection) index(s *Shape) int {
+	for i, x := range c.shapes {
+		if x.Equal(s) {
+			return i
+		}
+	}
+	return -1
+}
+
+// Shapes returns the shapes of the collection, ordered by Shape.Number.
+func (c *Collection) Shapes() []*Shape {
+	c.e.mu.Lock()
+	shapes := make([]*Shape, len(c.shapes))
+	for i, s := range c.shapes {
+		copied := *s
+		shapes[i] = &copied
+	}
+	c.e.mu.Unlock()
+	sort.Slice(shapes, func(i, j int) bool { return shapes[i].Number < shapes[j].Number })
+	return shapes
+}
+
+// Stream passes the shapes of the collection to the exporter, as Config.Stream does.
+// The dependencies of the shapes are passed too, even if not in the collection, for rendering them completely.
+func (c *Collection) Stream(exporter StreamExporter) error {
+	return c.e.stream(exporter, c.Shapes())
+}
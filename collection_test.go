@@ -0,0 +1,94 @@
+package reflectshape_test
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	reflectshape "github.com/podhmo/reflect-shape"
+)
+
+func TestCollection(t *testing.T) {
+	cfg := &reflectshape.Config{IncludeGoTestFiles: true}
+	person := cfg.Extract(&Person{})
+	cfg.Extract(CreateUserRequest{})
+	cfg.Extract(DeleteUserRequest{})
+	cfg.Extract(UserResponse{})
+	r := cfg.Registry()
+
+	names := func(shapes []*reflectshape.Shape) []string {
+		var r []string
+		for _, s := range shapes {
+			r = append(r, s.Name)
+		}
+		return r
+	}
+
+	c := r.Collection("Public API v2")
+	if err := c.AddQuery(reflectshape.Query{Name: "*Request"}); err != nil {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+	c.Add(person, cfg.Extract(Person{}), nil) // Person is added once
+	c.Remove(cfg.Extract(DeleteUserRequest{}))
+
+	t.Run("shapes", func(t *testing.T) {
+		want := []string{"Person", "CreateUserRequest"}
+		if diff := cmp.Diff(want, names(c.Shapes())); diff != "" {
+			t.Errorf("Collection.Shapes(): mismatch (-want, +got): \n%v", diff)
+		}
+		if !c.Contains(cfg.Extract(Person{})) {
+			t.Errorf("Collection.Contains(): must contain Person")
+		}
+		if c.Contains(cfg.Extract(UserResponse{})) {
+			t.Errorf("Collection.Contains(): must not contain UserResponse")
+		}
+	})
+
+	t.Run("stored in registry", func(t *testing.T) {
+		if cfg.Registry().Collection("Public API v2") != c {
+			t.Errorf("Registry.Collection(): must return the same collection for the same name")
+		}
+		r.Collection("Internal")
+		var got []string
+		for _, c := range cfg.Registry().Collections() {
+			got = append(got, c.Name)
+		}
+		if diff := cmp.Diff([]string{"Internal", "Public API v2"}, got); diff != "" {
+			t.Errorf("Registry.Collections(): mismatch (-want, +got): \n%v", diff)
+		}
+	})
+
+	t.Run("stream", func(t *testing.T) {
+		var got []string
+		err := c.Stream(reflectshape.StreamExporterFunc(func(s *reflectshape.Shape) error {
+			got = append(got, s.Name)
+			return nil
+		}))
+		if err != nil {
+			t.Fatalf("unexpected error: %+v", err)
+		}
+		want := []string{"string", "" /* []*Person */, "Person", "CreateUserRequest"} // with the dependencies
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Errorf("Collection.Stream(): mismatch (-want, +got): \n%v", diff)
+		}
+	})
+
+	t.Run("doc server", func(t *testing.T) {
+		d := reflectshape.NewDocServer(r)
+		d.Collection = c
+		if diff := cmp.Diff([]string{"CreateUserRequest", "Person"}, func() []string {
+			var r []string
+			for _, s := range d.Shapes() {
+				r = append(r, s.Name)
+			}
+			return r
+		}()); diff != "" {
+			t.Errorf("DocServer.Shapes(): mismatch (-want, +got): \n%v", diff)
+		}
+	})
+
+	t.Run("invalid query", func(t *testing.T) {
+		if err := c.AddQuery(reflectshape.Query{Name: "["}); err == nil {
+			t.Errorf("Collection.AddQuery(): must be error")
+		}
+	})
+}
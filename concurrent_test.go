@@ -0,0 +1,69 @@
+package reflectshape_test
+
+import (
+	"reflect"
+	"sync"
+	"testing"
+
+	reflectshape "github.com/podhmo/reflect-shape"
+)
+
+func TestConcurrentExtract(t *testing.T) {
+	cfg := &reflectshape.Config{IncludeGoTestFiles: true, MaxShapes: 10, InternStrings: true}
+
+	obs := []interface{}{Person{}, UserCreated{}, UserDeleted{}, Address{}, Hello}
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		for _, ob := range obs {
+			ob := ob
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				s := cfg.Extract(ob)
+				if s.Kind.String() == "struct" {
+					for _, f := range s.Struct().Fields() {
+						_ = f.Doc
+					}
+				}
+				_ = s.Package.Scope().Names()
+				_ = cfg.Registry().Shapes()
+				_ = cfg.CacheStats()
+			}()
+		}
+	}
+	wg.Wait()
+
+	want := map[string]bool{}
+	for _, ob := range obs {
+		want[cfg.Extract(ob).Name] = true
+	}
+	if len(want) != len(obs) {
+		t.Errorf("the shapes are broken: %v", want)
+	}
+}
+
+func TestConcurrentAfterExtract(t *testing.T) {
+	cfg := &reflectshape.Config{SkipComments: true}
+	var mu sync.Mutex
+	called := map[string]int{}
+	cfg.Hooks.AfterExtract = func(s *reflectshape.Shape) {
+		mu.Lock()
+		defer mu.Unlock()
+		called[s.Name]++
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			cfg.Extract(Person{})
+			cfg.Extract(Address{})
+		}()
+	}
+	wg.Wait()
+
+	if want, got := map[string]int{"Person": 1, "Address": 1}, called; !reflect.DeepEqual(want, got) {
+		t.Errorf("Hooks.AfterExtract must be called once per shape: want:%v != got:%v", want, got)
+	}
+}
@@ -0,0 +1,144 @@
+package reflectshape
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ConfigFile is the shared configuration of the CLI and the embedded use. (reflect-shape.json)
+//
+//	{
+//	  "include": ["github.com/foo/bar/..."],
+//	  "exclude": ["github.com/foo/bar/internal/..."],
+//	  "tag": "json",
+//	  "naming": "camelCase",
+//	  "overrides": "./patches.json",
+//	  "exporters": ["jsonschema", "exec:reflect-shape-ts --strict"]
+//	}
+//
+// YAML can be used, after converting to JSON. The struct can also be embedded in the Go code directly.
+type ConfigFile struct {
+	Include []string `json:"include,omitempty"` // see Config.CommentPackages
+	Exclude []string `json:"exclude,omitempty"` // see Config.ExcludeCommentPackages
+
+	Tag       string   `json:"tag,omitempty"`       // the tag name for the field names (default is "json")
+	Naming    string   `json:"naming,omitempty"`    // "go", "camelCase", "snake_case" or "PascalCase" (default is "go")
+	Overrides string   `json:"overrides,omitempty"` // the file of the patches (see ReadPatches), relative to the config file
+	Exporters []string `json:"exporters,omitempty"` // the names of the registered exporters, or "exec:<command> <args>..." for the plugins
+
+	IncludeGoTestFiles bool              `json:"includeGoTestFiles,omitempty"`
+	Offline            bool              `json:"offline,omitempty"`
+	GOOS               string            `json:"goos,omitempty"`
+	GOARCH             string            `json:"goarch,omitempty"`
+	BuildTags          []string          `json:"buildTags,omitempty"`
+	Namespaces         map[string]string `json:"namespaces,omitempty"`
+
+	dir string // the directory of the config file
+}
+
+// ReadConfigFile reads the config file from JSON. (the relative paths are resolved from the current directory)
+func ReadConfigFile(r io.Reader) (*ConfigFile, error) {
+	var f ConfigFile
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&f); err != nil {
+		return nil, fmt.Errorf("read config file: %w", err)
+	}
+	return &f, nil
+}
+
+// LoadConfigFile loads the config file. (the relative paths are resolved from the directory of the file)
+func LoadConfigFile(filename string) (*ConfigFile, error) {
+	r, err := os.Open(filename)
+	if err != nil {
+		return nil, fmt.Errorf("load config file: %w", err)
+	}
+	defer r.Close()
+
+	f, err := ReadConfigFile(r)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", filename, err)
+	}
+	f.dir = filepath.Dir(filename)
+	return f, nil
+}
+
+// Config returns the new Config configured by the file. (the overrides file is read)
+func (f *ConfigFile) Config() (*Config, error) {
+	c := &Config{
+		CommentPackages:        f.Include,
+		ExcludeCommentPackages: f.Exclude,
+		IncludeGoTestFiles:     f.IncludeGoTestFiles,
+		Offline:                f.Offline,
+		GOOS:                   f.GOOS,
+		GOARCH:                 f.GOARCH,
+		BuildTags:              f.BuildTags,
+		Namespaces:             f.Namespaces,
+	}
+	if f.Overrides != "" {
+		filename := f.Overrides
+		if !filepath.IsAbs(filename) && f.dir != "" {
+			filename = filepath.Join(f.dir, filename)
+		}
+		r, err := os.Open(filename)
+		if err != nil {
+			return nil, fmt.Errorf("overrides: %w", err)
+		}
+		defer r.Close()
+		patches, err := ReadPatches(r)
+		if err != nil {
+			return nil, fmt.Errorf("overrides %s: %w", filename, err)
+		}
+		c.Patches = patches
+	}
+	return c, nil
+}
+
+// TagName returns the tag name for the field names.
+func (f *ConfigFile) TagName() string {
+	if f.Tag == "" {
+		return "json"
+	}
+	return f.Tag
+}
+
+// NamingRule returns the naming of the file.
+func (f *ConfigFile) NamingRule() (Naming, error) {
+	switch f.Naming {
+	case "", "go":
+		return GoNaming, nil
+	case "camelCase":
+		return CamelCaseNaming, nil
+	case "snake_case":
+		return SnakeCaseNaming, nil
+	case "PascalCase":
+		return PascalCaseNaming, nil
+	default:
+		return Naming{}, fmt.Errorf("unknown naming %q", f.Naming)
+	}
+}
+
+// LookupExporters returns the exporters of the file, in order. (the registered exporters, see RegisterExporter)
+func (f *ConfigFile) LookupExporters() ([]Exporter, error) {
+	r := make([]Exporter, len(f.Exporters))
+	for i, name := range f.Exporters {
+		if strings.HasPrefix(name, "exec:") {
+			args := strings.Fields(strings.TrimPrefix(name, "exec:"))
+			if len(args) == 0 {
+				return nil, fmt.Errorf("exporters[%d]: command is empty", i)
+			}
+			r[i] = &ExecExporter{Command: args[0], Args: args[1:], Dir: f.dir}
+			continue
+		}
+		exporter, ok := LookupExporter(name)
+		if !ok {
+			return nil, fmt.Errorf("exporters[%d]: %q is not registered", i, name)
+		}
+		r[i] = exporter
+	}
+	return r, nil
+}
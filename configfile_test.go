@@ -0,0 +1,81 @@
+package reflectshape_test
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	reflectshape "github.com/podhmo/reflect-shape"
+)
+
+func TestLoadConfigFile(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		filename := filepath.Join(dir, name)
+		if err := os.WriteFile(filename, []byte(content), 0o644); err != nil {
+			t.Fatalf("unexpected error: %+v", err)
+		}
+		return filename
+	}
+
+	reflectshape.RegisterExporter("test-configfile", reflectshape.ExporterFunc(func(w io.Writer, shapes []*reflectshape.Shape) error { return nil }))
+	t.Cleanup(func() { reflectshape.UnregisterExporter("test-configfile") })
+	write("patches.json", `{"github.com/podhmo/reflect-shape_test.Person": {"doc": "Person is the human"}}`)
+	filename := write("reflect-shape.json", `{
+  "exclude": ["github.com/podhmo/reflect-shape"],
+  "naming": "snake_case",
+  "overrides": "patches.json",
+  "exporters": ["test-configfile", "exec:cat -"],
+  "includeGoTestFiles": true
+}`)
+
+	f, err := reflectshape.LoadConfigFile(filename)
+	if err != nil {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+
+	cfg, err := f.Config()
+	if err != nil {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+	s := cfg.Extract(Person{}).Struct()
+	if want, got := "Person is the human", s.Doc(); want != got {
+		t.Errorf("Struct.Doc(): want:%q != got:%q", want, got)
+	}
+	if want, got := "", s.Fields()[0].Doc; want != got { // excluded, the comments are not extracted
+		t.Errorf("Struct.Fields()[0].Doc: want:%q != got:%q", want, got)
+	}
+
+	naming, err := f.NamingRule()
+	if err != nil {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+	if want, got := "person", naming.Type(s.Shape); want != got {
+		t.Errorf("Naming.Type(): want:%q != got:%q", want, got)
+	}
+	if want, got := "json", f.TagName(); want != got {
+		t.Errorf("TagName(): want:%q != got:%q", want, got)
+	}
+
+	exporters, err := f.LookupExporters()
+	if err != nil {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+	if exec, ok := exporters[1].(*reflectshape.ExecExporter); !ok || exec.Command != "cat" || exec.Dir != dir {
+		t.Errorf("LookupExporters()[1]: want:exec exporter (cat) != got:%#+v", exporters[1])
+	}
+
+	t.Run("unknown-field", func(t *testing.T) {
+		if _, err := reflectshape.ReadConfigFile(strings.NewReader(`{"includes": []}`)); err == nil {
+			t.Errorf("ReadConfigFile(): error is expected")
+		}
+	})
+	t.Run("unknown-exporter", func(t *testing.T) {
+		f := &reflectshape.ConfigFile{Exporters: []string{"unknown"}}
+		if _, err := f.LookupExporters(); err == nil {
+			t.Errorf("LookupExporters(): error is expected")
+		}
+	})
+}
@@ -0,0 +1,43 @@
+package reflectshape_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	reflectshape "github.com/podhmo/reflect-shape"
+)
+
+func TestExtractContext(t *testing.T) {
+	t.Run("ok", func(t *testing.T) {
+		cfg := &reflectshape.Config{IncludeGoTestFiles: true}
+		s, err := cfg.ExtractContext(context.Background(), Person{})
+		if err != nil {
+			t.Fatalf("unexpected error: %+v", err)
+		}
+		if want, got := "Person", s.Name; want != got {
+			t.Errorf("Name: want:%v != got:%v", want, got)
+		}
+		if got := s.Struct().Doc(); got == "" {
+			t.Errorf("Doc: want non-empty, but empty")
+		}
+	})
+
+	t.Run("cancelled", func(t *testing.T) {
+		cfg := &reflectshape.Config{IncludeGoTestFiles: true}
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		if _, err := cfg.ExtractContext(ctx, Person{}); !errors.Is(err, context.Canceled) {
+			t.Errorf("ExtractContext(): want:%v != got:%+v", context.Canceled, err)
+		}
+	})
+
+	t.Run("vetoed", func(t *testing.T) {
+		cfg := &reflectshape.Config{SkipComments: true}
+		cfg.Hooks.BeforeExtract = func(rt reflect.Type) bool { return false }
+		if _, err := cfg.ExtractContext(context.Background(), Person{}); !errors.Is(err, reflectshape.ErrVetoed) {
+			t.Errorf("ExtractContext(): want:%v != got:%+v", reflectshape.ErrVetoed, err)
+		}
+	})
+}
@@ -0,0 +1,150 @@
+package reflectshape
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+func init() {
+	RegisterExporter("crd", ExporterFunc(ExportCRDSchemas))
+}
+
+// CRDSchema returns the OpenAPI v3 structural schema of the struct, as openAPIV3Schema of the CustomResourceDefinition.
+//
+// The properties are named by the json tags, and the named structs are inlined (the structural schema has no $ref).
+// The kubebuilder-style markers in the comments are honored, and removed from the descriptions.
+//
+//	type FooSpec struct {
+//		// Replicas is the number of the replicas.
+//		// +kubebuilder:validation:Minimum=1
+//		// +optional
+//		Replicas *int32 `json:"replicas,omitempty"`
+//	}
+//
+// The supported markers are +optional, +required, +nullable, +kubebuilder:default and
+// +kubebuilder:validation:{Optional,Required,Nullable,Minimum,Maximum,ExclusiveMinimum,ExclusiveMaximum,MultipleOf,
+// MinLength,MaxLength,MinItems,MaxItems,MinProperties,MaxProperties,Pattern,Format,Enum,XPreserveUnknownFields}.
+// The markers on the named types (e.g. type Phase string) are applied to the fields of the types.
+func CRDSchema(s *Shape) (map[string]interface{}, error) {
+	if s.Kind != reflect.Struct || s.Name == "" {
+		return nil, fmt.Errorf("%v is not named struct", s)
+	}
+
+	b := newSchemaBuilder("")
+	b.inline = true
+	b.onObject = func(s *Struct, r map[string]interface{}) {
+		markers, doc := ParseMarkers(s.Doc())
+		setDescription(r, doc)
+		applyCRDMarkers(r, markers, false)
+	}
+	b.onField = func(f *Field, prop map[string]interface{}, required bool) bool {
+		if f.Shape.Package.Path == "k8s.io/apimachinery/pkg/apis/meta/v1" && f.Shape.Name == "ObjectMeta" {
+			for k := range prop {
+				delete(prop, k) // metadata is validated by the apiserver
+			}
+			prop["type"] = "object"
+			return required
+		}
+		if _, ok := prop["type"]; !ok { // interface{}, json.RawMessage, ...
+			prop["x-kubernetes-preserve-unknown-fields"] = true
+		}
+
+		if f.Shape.Name != "" && f.Shape.Package.Path != "" && f.Shape.Kind != reflect.Struct && !f.Shape.IsWellKnown {
+			required = applyCRDMarkers(prop, f.Shape.Markers(), required)
+		}
+		markers, doc := ParseMarkers(f.Doc)
+		setDescription(prop, doc)
+		return applyCRDMarkers(prop, markers, required)
+	}
+	return b.schema(s), nil
+}
+
+// ExportCRDSchemas writes the CRD schemas of the structs as JSON. ({"<Name>": {"openAPIV3Schema": ...}})
+// The name is qualified by the namespace of the package, if it is taken by the struct of the other package. (e.g. "github.com.foo.bar.Spec")
+// This is registered as the "crd" exporter.
+func ExportCRDSchemas(w io.Writer, shapes []*Shape) error {
+	r := make(map[string]interface{}, len(shapes))
+	names := make(map[string]string, len(shapes)) // the full name of the struct -> the key of r
+	for _, s := range shapes {
+		schema, err := CRDSchema(s)
+		if err != nil {
+			return fmt.Errorf("crd schema: %w", err)
+		}
+		name, ok := names[s.FullName()]
+		if !ok {
+			name = s.Name
+			if _, taken := r[name]; taken {
+				name = qualifiedDefName(s)
+			}
+			names[s.FullName()] = name
+		}
+		r[name] = map[string]interface{}{"openAPIV3Schema": schema}
+	}
+	b, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshal crd schemas: %w", err)
+	}
+	_, err = w.Write(append(b, '\n'))
+	return err
+}
+
+// applyCRDMarkers applies the markers to the schema, and returns whether the field is required.
+func applyCRDMarkers(r map[string]interface{}, markers Markers, required bool) bool {
+	for _, fullname := range markers.Names() {
+		value := markers[fullname][len(markers[fullname])-1] // the last one wins
+		switch name := strings.TrimPrefix(fullname, "kubebuilder:validation:"); name {
+		case "optional", "Optional":
+			required = false
+		case "required", "Required":
+			required = true
+		case "nullable", "Nullable":
+			r["nullable"] = true
+		case "kubebuilder:default":
+			r["default"] = markerValue(value)
+		case "XPreserveUnknownFields", "kubebuilder:pruning:PreserveUnknownFields":
+			r["x-kubernetes-preserve-unknown-fields"] = true
+		case "Minimum", "Maximum", "ExclusiveMinimum", "ExclusiveMaximum", "MultipleOf",
+			"MinLength", "MaxLength", "MinItems", "MaxItems", "MinProperties", "MaxProperties":
+			key := strings.ToLower(name[:1]) + name[1:]
+			if name == "ExclusiveMinimum" || name == "ExclusiveMaximum" {
+				if v, err := strconv.ParseBool(value); err == nil { // e.g. +kubebuilder:validation:ExclusiveMinimum=true
+					r[key] = v
+					continue
+				}
+			}
+			r[key] = markerValue(value)
+		case "Pattern", "Format":
+			r[strings.ToLower(name)] = strings.Trim(value, "`\"")
+		case "Enum":
+			values := strings.Split(strings.Trim(value, "{}"), ";")
+			enum := make([]interface{}, 0, len(values))
+			for _, v := range values {
+				enum = append(enum, markerValue(v))
+			}
+			r["enum"] = enum
+		}
+	}
+	return required
+}
+
+// markerValue returns the value of the marker as JSON if possible, otherwise as string.
+func markerValue(s string) interface{} {
+	s = strings.TrimSpace(s)
+	var v interface{}
+	if err := json.Unmarshal([]byte(s), &v); err == nil {
+		return v
+	}
+	return s
+}
+
+func setDescription(r map[string]interface{}, doc string) {
+	if doc == "" {
+		delete(r, "description")
+		return
+	}
+	r["description"] = doc
+}
@@ -0,0 +1,154 @@
+package reflectshape_test
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/podhmo/commentof/collect"
+	reflectshape "github.com/podhmo/reflect-shape"
+)
+
+// Guestbook is the guestbook resource.
+// +kubebuilder:object:root=true
+type Guestbook struct {
+	Spec   GuestbookSpec   `json:"spec"`
+	Status GuestbookStatus `json:"status,omitempty"`
+}
+
+type GuestbookSpec struct {
+	// Replicas is the number of the replicas.
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:default=3
+	// +optional
+	Replicas *int32 `json:"replicas,omitempty"`
+
+	// +kubebuilder:validation:Pattern=`^[a-z]+$`
+	Name string `json:"name"`
+
+	Extra interface{} `json:"extra,omitempty"`
+}
+
+type GuestbookStatus struct {
+	Phase GuestbookPhase `json:"phase"`
+}
+
+// GuestbookPhase is the phase of the guestbook.
+// +kubebuilder:validation:Enum=Pending;Running
+type GuestbookPhase string
+
+func TestCRDSchema(t *testing.T) {
+	cfg := &reflectshape.Config{IncludeGoTestFiles: true}
+	got, err := reflectshape.CRDSchema(cfg.Extract(Guestbook{}))
+	if err != nil {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+
+	want := `{
+  "description": "Guestbook is the guestbook resource.",
+  "properties": {
+    "spec": {
+      "properties": {
+        "extra": {
+          "x-kubernetes-preserve-unknown-fields": true
+        },
+        "name": {
+          "pattern": "^[a-z]+$",
+          "type": "string"
+        },
+        "replicas": {
+          "default": 3,
+          "description": "Replicas is the number of the replicas.",
+          "minimum": 1,
+          "type": "integer"
+        }
+      },
+      "required": [
+        "name"
+      ],
+      "type": "object"
+    },
+    "status": {
+      "properties": {
+        "phase": {
+          "enum": [
+            "Pending",
+            "Running"
+          ],
+          "type": "string"
+        }
+      },
+      "required": [
+        "phase"
+      ],
+      "type": "object"
+    }
+  },
+  "required": [
+    "spec"
+  ],
+  "type": "object"
+}`
+	b, err := json.MarshalIndent(got, "", "  ")
+	if err != nil {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+	if diff := cmp.Diff(want, string(b)); diff != "" {
+		t.Errorf("CRDSchema() mismatch (-want, +got): \n%v", diff)
+	}
+
+	t.Run("exporter", func(t *testing.T) {
+		exporter, ok := reflectshape.LookupExporter("crd")
+		if !ok {
+			t.Fatalf("crd exporter is not registered")
+		}
+		var buf strings.Builder
+		if err := exporter.Export(&buf, []*reflectshape.Shape{cfg.Extract(Guestbook{})}); err != nil {
+			t.Fatalf("unexpected error: %+v", err)
+		}
+		if want, got := `"openAPIV3Schema"`, buf.String(); !strings.Contains(got, want) {
+			t.Errorf("want:%v is not contained in got:%v", want, got)
+		}
+	})
+
+	t.Run("same-name", func(t *testing.T) {
+		cases := []struct {
+			msg        string
+			namespaces map[string]string
+			want       []string
+		}{
+			{msg: "qualified", want: []string{"Object", "github.com.podhmo.commentof.collect.Object"}},
+			{msg: "namespace", namespaces: map[string]string{"github.com/podhmo/commentof": "commentof"}, want: []string{"Object", "commentof.collect.Object"}},
+		}
+		for _, c := range cases {
+			c := c
+			t.Run(c.msg, func(t *testing.T) {
+				cfg := &reflectshape.Config{IncludeGoTestFiles: true, Namespaces: c.namespaces}
+				var buf strings.Builder
+				if err := reflectshape.ExportCRDSchemas(&buf, []*reflectshape.Shape{cfg.Extract(Object{}), cfg.Extract(collect.Object{})}); err != nil {
+					t.Fatalf("unexpected error: %+v", err)
+				}
+				var got map[string]interface{}
+				if err := json.Unmarshal([]byte(buf.String()), &got); err != nil {
+					t.Fatalf("unexpected error: %+v", err)
+				}
+				names := make([]string, 0, len(got))
+				for name := range got {
+					names = append(names, name)
+				}
+				sort.Strings(names)
+				if diff := cmp.Diff(c.want, names); diff != "" {
+					t.Errorf("ExportCRDSchemas() names (-want, +got): \n%v", diff)
+				}
+			})
+		}
+	})
+
+	t.Run("not-struct", func(t *testing.T) {
+		if _, err := reflectshape.CRDSchema(cfg.Extract(GuestbookPhase(""))); err == nil {
+			t.Errorf("want error, but nil")
+		}
+	})
+}
@@ -0,0 +1,152 @@
+package reflectshape
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+)
+
+// ChangeKind is the kind of the change between the struct shapes.
+type ChangeKind string
+
+const (
+	ChangeFieldAdded   ChangeKind = "field-added"
+	ChangeFieldRemoved ChangeKind = "field-removed"
+	ChangeFieldRenamed ChangeKind = "field-renamed" // the field name or the tag name is changed, but the other is kept
+	ChangeTypeChanged  ChangeKind = "type-changed"
+	ChangeTypeNarrowed ChangeKind = "type-narrowed" // e.g. int64 -> int32, the values may be truncated
+)
+
+// Change is the change of the field between the struct shapes.
+type Change struct {
+	Kind ChangeKind
+	Old  *Field // nil if added
+	New  *Field // nil if removed
+
+	Struct *Struct // the struct after the change
+}
+
+func (c *Change) String() string {
+	switch c.Kind {
+	case ChangeFieldAdded:
+		return fmt.Sprintf("%s: %s %v", c.Kind, c.New.Name, c.New.Type)
+	case ChangeFieldRemoved:
+		return fmt.Sprintf("%s: %s %v", c.Kind, c.Old.Name, c.Old.Type)
+	default:
+		return fmt.Sprintf("%s: %s %v -> %s %v", c.Kind, c.Old.Name, c.Old.Type, c.New.Name, c.New.Type)
+	}
+}
+
+// ChangeHandler handles the change, e.g. emitting the migration artifacts (SQL ALTER, data-migration stubs) to w.
+type ChangeHandler func(w io.Writer, c *Change) error
+
+// Differ compares the struct shapes, and dispatches the changes to the handlers registered per kind.
+//
+// The shapes of both sides are needed in one process (e.g. the old struct kept as the copy until the migration is written).
+// There is no command-line diff, the shapes are extracted from the runtime values, so the standalone binary cannot see the user types.
+// The tools comparing the dumps of the other processes read GraphJSON.
+type Differ struct {
+	TagKey string // the tag used for detecting the renamed fields (default is "json")
+
+	handlers map[ChangeKind][]ChangeHandler
+}
+
+// Handle registers the handler for the kind of the changes. The handlers are called in order of registration.
+func (d *Differ) Handle(kind ChangeKind, h ChangeHandler) {
+	if d.handlers == nil {
+		d.handlers = map[ChangeKind][]ChangeHandler{}
+	}
+	d.handlers[kind] = append(d.handlers[kind], h)
+}
+
+// Diff returns the changes of the fields from `from` to `to`.
+//
+// The fields are matched by name, and the rest are matched by tag name (renamed). the others are added or removed.
+func (d *Differ) Diff(from, to *Struct) []*Change {
+	key := d.TagKey
+	if key == "" {
+		key = "json"
+	}
+
+	oldFields := from.Fields()
+	newFields := to.Fields()
+	matched := make(map[*Field]*Field, len(newFields)) // new -> old
+	used := make(map[*Field]bool, len(oldFields))
+	for _, nf := range newFields {
+		for _, of := range oldFields {
+			if !used[of] && of.Name == nf.Name {
+				matched[nf], used[of] = of, true
+				break
+			}
+		}
+	}
+	for _, nf := range newFields {
+		name := tagName(nf.Tag, key)
+		if _, ok := matched[nf]; ok || name == "" {
+			continue
+		}
+		for _, of := range oldFields {
+			if !used[of] && tagName(of.Tag, key) == name {
+				matched[nf], used[of] = of, true
+				break
+			}
+		}
+	}
+
+	var r []*Change
+	for _, of := range oldFields {
+		if !used[of] {
+			r = append(r, &Change{Kind: ChangeFieldRemoved, Old: of, Struct: to})
+		}
+	}
+	for _, nf := range newFields {
+		of, ok := matched[nf]
+		if !ok {
+			r = append(r, &Change{Kind: ChangeFieldAdded, New: nf, Struct: to})
+			continue
+		}
+		if of.Name != nf.Name || tagName(of.Tag, key) != tagName(nf.Tag, key) {
+			r = append(r, &Change{Kind: ChangeFieldRenamed, Old: of, New: nf, Struct: to})
+		}
+		if canonicalTypeString(of.Type) != canonicalTypeString(nf.Type) {
+			kind := ChangeTypeChanged
+			if isNarrowed(of.Type, nf.Type) {
+				kind = ChangeTypeNarrowed
+			}
+			r = append(r, &Change{Kind: kind, Old: of, New: nf, Struct: to})
+		}
+	}
+	return r
+}
+
+// Apply calls the handlers of the changes, in order of the changes. The changes without handlers are skipped.
+func (d *Differ) Apply(w io.Writer, changes []*Change) error {
+	for _, c := range changes {
+		for _, h := range d.handlers[c.Kind] {
+			if err := h(w, c); err != nil {
+				return fmt.Errorf("handle %s: %w", c, err)
+			}
+		}
+	}
+	return nil
+}
+
+// isNarrowed returns true if the numeric type is changed to the smaller one of the same class. (e.g. int64 -> int32, float64 -> float32)
+func isNarrowed(from, to reflect.Type) bool {
+	class := func(k reflect.Kind) int {
+		switch k {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			return 1
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+			return 2
+		case reflect.Float32, reflect.Float64:
+			return 3
+		case reflect.Complex64, reflect.Complex128:
+			return 4
+		default:
+			return 0
+		}
+	}
+	c := class(from.Kind())
+	return c != 0 && c == class(to.Kind()) && to.Size() < from.Size()
+}
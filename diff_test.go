@@ -0,0 +1,91 @@
+package reflectshape_test
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	reflectshape "github.com/podhmo/reflect-shape"
+)
+
+type UserV1 struct {
+	ID       int64  `json:"id"`
+	Name     string `json:"name"`
+	Mail     string `json:"email"`
+	Age      int64  `json:"age"`
+	Nickname string `json:"nickname"`
+}
+
+type UserV2 struct {
+	ID     int64   `json:"id"`
+	Name   string  `json:"fullName"` // renamed via tag
+	Email  string  `json:"email"`    // renamed, but same tag
+	Age    int32   `json:"age"`
+	Score  float64 `json:"score"`
+	Active bool    `json:"active"`
+}
+
+func TestDiffer(t *testing.T) {
+	cfg := &reflectshape.Config{SkipComments: true}
+	old := cfg.Extract(UserV1{}).Struct()
+	updated := cfg.Extract(UserV2{}).Struct()
+
+	t.Run("diff", func(t *testing.T) {
+		d := &reflectshape.Differ{}
+		var got []string
+		for _, c := range d.Diff(old, updated) {
+			got = append(got, c.String())
+		}
+		want := []string{
+			"field-removed: Nickname string",
+			"field-renamed: Name string -> Name string",
+			"field-renamed: Mail string -> Email string",
+			"type-narrowed: Age int64 -> Age int32",
+			"field-added: Score float64",
+			"field-added: Active bool",
+		}
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Errorf("Differ.Diff(): -want, +got: \n%v", diff)
+		}
+	})
+
+	t.Run("handlers", func(t *testing.T) {
+		column := func(f *reflectshape.Field) string {
+			name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+			return name
+		}
+
+		d := &reflectshape.Differ{}
+		d.Handle(reflectshape.ChangeFieldAdded, func(w io.Writer, c *reflectshape.Change) error {
+			_, err := fmt.Fprintf(w, "ALTER TABLE users ADD COLUMN %s;\n", column(c.New))
+			return err
+		})
+		d.Handle(reflectshape.ChangeFieldRemoved, func(w io.Writer, c *reflectshape.Change) error {
+			_, err := fmt.Fprintf(w, "ALTER TABLE users DROP COLUMN %s;\n", column(c.Old))
+			return err
+		})
+		d.Handle(reflectshape.ChangeFieldRenamed, func(w io.Writer, c *reflectshape.Change) error {
+			if column(c.Old) == column(c.New) {
+				return nil
+			}
+			_, err := fmt.Fprintf(w, "ALTER TABLE users RENAME COLUMN %s TO %s;\n", column(c.Old), column(c.New))
+			return err
+		})
+
+		var buf strings.Builder
+		if err := d.Apply(&buf, d.Diff(old, updated)); err != nil {
+			t.Fatalf("unexpected error: %+v", err)
+		}
+		want := strings.Join([]string{
+			"ALTER TABLE users DROP COLUMN nickname;",
+			"ALTER TABLE users RENAME COLUMN name TO fullName;",
+			"ALTER TABLE users ADD COLUMN score;",
+			"ALTER TABLE users ADD COLUMN active;",
+		}, "\n") + "\n"
+		if diff := cmp.Diff(want, buf.String()); diff != "" {
+			t.Errorf("Differ.Apply(): -want, +got: \n%v", diff)
+		}
+	})
+}
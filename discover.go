@@ -0,0 +1,90 @@
+package reflectshape
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/podhmo/reflect-shape/metadata"
+	"github.com/podhmo/reflect-shape/metadata/runtimeinfo"
+)
+
+// DiscoveredFunc is the function found in the binary by DiscoverFuncs.
+type DiscoveredFunc struct {
+	Name    string  // the name of the function in the binary (e.g. "github.com/foo/bar/handlers.(*Users).List")
+	PkgPath string  // e.g. "github.com/foo/bar/handlers"
+	PC      uintptr // the entry pc
+
+	// Func is the func shape, or nil if the signature is unknown.
+	// The binary has no reflect types of the functions, so it is available only for the functions extracted before (e.g. by Extract).
+	Func *Func
+
+	Metadata *metadata.Func // the comments and the names of the arguments, or nil if not found
+	Err      error          // the error of the metadata lookup
+}
+
+// Doc returns the doc of the function.
+func (f *DiscoveredFunc) Doc() string {
+	if f.Func != nil {
+		return f.Func.Doc()
+	}
+	if f.Metadata == nil {
+		return ""
+	}
+	return f.Metadata.Doc()
+}
+
+// DiscoverFuncs returns the functions (and the methods) linked into the binary, in the packages matched by the patterns (e.g. "github.com/foo/bar/handlers/...").
+// The closures, the initializers, the instantiations of the generic functions and the autogenerated wrappers are not included.
+//
+// It depends on the runtime internals (see runtimeinfo.Probe), and it returns the error wrapping metadata.ErrNotSupported if not supported.
+// Only the functions reachable from main are linked, so the unused functions are not found.
+func (c *Config) DiscoverFuncs(patterns ...string) ([]*DiscoveredFunc, error) {
+	c.init()
+	accessor := runtimeinfo.New()
+	if !runtimeinfo.Probe().Modules {
+		return nil, fmt.Errorf("discover funcs: %w", metadata.ErrNotSupported)
+	}
+
+	var r []*DiscoveredFunc
+	seen := map[uintptr]bool{}
+	for _, pattern := range patterns {
+		for _, fn := range accessor.Funcs(strings.TrimSuffix(pattern, "/...")) {
+			if seen[fn.PC] || !matchPackage(pattern, fn.PkgPath) {
+				continue
+			}
+			seen[fn.PC] = true
+			if name, err := runtimeinfo.ParseFuncName(fn.Name); err != nil || name.IsClosure() || name.Name == "init" || name.TypeArgs != "" {
+				continue // e.g. "pkg.F.func1", "pkg.init.0", "pkg.Map[...]"
+			}
+			if rfunc := accessor.FuncForPC(fn.PC); rfunc != nil {
+				if filename, _ := rfunc.FileLine(fn.PC); !strings.HasSuffix(filename, ".go") { // e.g. <autogenerated>
+					continue
+				}
+			}
+
+			d := &DiscoveredFunc{Name: fn.Name, PkgPath: fn.PkgPath, PC: fn.PC}
+			if shape := c.extractor.shapeOfPC(fn.PC); shape != nil {
+				d.Func = shape.Func()
+				d.Err = d.Func.Err()
+			} else {
+				d.Metadata, _, d.Err = c.extractor.lookupFunc(fn.PC, fn.PkgPath)
+			}
+			r = append(r, d)
+		}
+	}
+	sort.SliceStable(r, func(i, j int) bool { return r[i].Name < r[j].Name })
+	return r, nil
+}
+
+// shapeOfPC returns the extracted func shape of pc, or nil if not extracted.
+func (e *Extractor) shapeOfPC(pc uintptr) *Shape {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	for id, shape := range e.seen {
+		if id.pc == pc {
+			return shape
+		}
+	}
+	return nil
+}
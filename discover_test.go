@@ -0,0 +1,73 @@
+package reflectshape_test
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	reflectshape "github.com/podhmo/reflect-shape"
+	"github.com/podhmo/reflect-shape/metadata/runtimeinfo"
+)
+
+// ListUsers lists the users.
+func ListUsers(ctx context.Context, limit int) ([]string, error) {
+	return nil, nil
+}
+
+func TestDiscoverFuncs(t *testing.T) {
+	if !runtimeinfo.Probe().Modules {
+		t.Skip("the runtime internals are not supported")
+	}
+
+	cfg := &reflectshape.Config{IncludeGoTestFiles: true}
+	discover := func() map[string]*reflectshape.DiscoveredFunc {
+		funcs, err := cfg.DiscoverFuncs("github.com/podhmo/reflect-shape_test")
+		if err != nil {
+			t.Fatalf("unexpected error: %+v", err)
+		}
+		r := map[string]*reflectshape.DiscoveredFunc{}
+		for _, fn := range funcs {
+			if strings.Contains(fn.Name, ".func") {
+				t.Errorf("DiscoverFuncs(), the closure %s must not be included", fn.Name)
+			}
+			r[strings.TrimPrefix(fn.Name, "github.com/podhmo/reflect-shape_test.")] = fn
+		}
+		return r
+	}
+
+	found := discover()
+	fn, ok := found["ListUsers"]
+	if !ok {
+		t.Fatalf("DiscoverFuncs(), ListUsers is not found")
+	}
+	if want, got := "ListUsers lists the users.", fn.Doc(); want != got {
+		t.Errorf("DiscoveredFunc.Doc(), want:%q != got:%q", want, got)
+	}
+	if fn.Func != nil {
+		t.Errorf("DiscoveredFunc.Func, must be nil before extracted")
+	}
+	var args []string
+	for _, v := range fn.Metadata.Args() {
+		args = append(args, v.Name)
+	}
+	if want, got := []string{"ctx", "limit"}, args; !reflect.DeepEqual(want, got) {
+		t.Errorf("DiscoveredFunc.Metadata.Args(), want:%v != got:%v", want, got)
+	}
+	if _, ok := found["(*Queue).Push"]; !ok {
+		t.Errorf("DiscoverFuncs(), the method (*Queue).Push is not found")
+	}
+
+	cfg.Extract(ListUsers)
+	fn = discover()["ListUsers"]
+	if fn.Func == nil {
+		t.Fatalf("DiscoveredFunc.Func, must not be nil after extracted")
+	}
+	if want, got := "limit", fn.Func.Args()[1].Name; want != got {
+		t.Errorf("DiscoveredFunc.Func.Args()[1].Name, want:%q != got:%q", want, got)
+	}
+
+	if funcs, err := cfg.DiscoverFuncs("github.com/podhmo/reflect-shape_tes/..."); err != nil || len(funcs) != 0 {
+		t.Errorf("DiscoverFuncs(), must not match the other packages, but got %d funcs (err=%v)", len(funcs), err)
+	}
+}
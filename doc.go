@@ -0,0 +1,31 @@
+package reflectshape
+
+import (
+	"go/doc/comment"
+	"reflect"
+)
+
+// DocParsed returns the doc of the shape, parsed as the doc comment. (paragraphs, lists, code blocks, links, ...)
+func (s *Shape) DocParsed() *comment.Doc {
+	return ParseDoc(s.doc())
+}
+
+// doc returns the doc of the shape, by the accessor of its kind.
+func (s *Shape) doc() string {
+	switch {
+	case s.Kind == reflect.Func || s.ID.pc != 0:
+		return s.Func().Doc()
+	case s.Kind == reflect.Struct:
+		return s.Struct().Doc()
+	case s.Kind == reflect.Interface:
+		return s.Interface().Doc()
+	default:
+		return s.Named().Doc()
+	}
+}
+
+// ParseDoc parses the doc (e.g. Field.Doc) as the doc comment.
+func ParseDoc(doc string) *comment.Doc {
+	var p comment.Parser
+	return p.Parse(doc)
+}
@@ -0,0 +1,50 @@
+package reflectshape_test
+
+import (
+	"fmt"
+	"go/doc/comment"
+	"testing"
+
+	reflectshape "github.com/podhmo/reflect-shape"
+)
+
+// Document is the object for testing DocParsed().
+//
+// Features:
+//   - paragraphs
+//   - lists
+//
+// Example:
+//
+//	doc := cfg.Extract(Document{}).DocParsed()
+type Document struct{}
+
+func TestDocParsed(t *testing.T) {
+	doc := cfg.Extract(Document{}).DocParsed()
+
+	var kinds []string
+	for _, b := range doc.Content {
+		switch b.(type) {
+		case *comment.Paragraph:
+			kinds = append(kinds, "paragraph")
+		case *comment.List:
+			kinds = append(kinds, "list")
+		case *comment.Code:
+			kinds = append(kinds, "code")
+		default:
+			kinds = append(kinds, "other")
+		}
+	}
+
+	want := "[paragraph paragraph list paragraph code]"
+	if got := fmt.Sprintf("%v", kinds); want != got {
+		t.Errorf("Shape.DocParsed(): want:%v != got:%v", want, got)
+	}
+
+	t.Run("field", func(t *testing.T) {
+		doc := reflectshape.ParseDoc(cfg.Extract(Person{}).Struct().Fields()[0].Doc)
+		if want, got := 1, len(doc.Content); want != got {
+			t.Errorf("ParseDoc(): len(Content), want:%v != got:%v", want, got)
+		}
+	})
+}
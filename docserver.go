@@ -0,0 +1,268 @@
+package reflectshape
+
+import (
+	"bytes"
+	"encoding/json"
+	"html/template"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// DocServer serves the shapes of the registry as the browsable data dictionary. (HTML and JSON)
+//
+//	GET /                   the HTML page of the shapes, grouped by the packages
+//	GET /api/shapes         the JSON of the shapes
+//	GET /api/shapes/{num}   the JSON of the shape, by Shape.Number
+//
+// The shapes are extracted in the process (the reflection needs the compiled types), so mount it into the binary importing the packages.
+// The shapes extracted after the start are served too.
+//
+//	cfg := &reflectshape.Config{}
+//	cfg.Extract(model.User{})
+//	http.Handle("/shapes/", http.StripPrefix("/shapes", reflectshape.NewDocServer(cfg.Registry())))
+//
+// There is no command serving the shapes of the module (the binary can't see the types not compiled into it).
+// For the runnable entry point, see _examples/docserver, and copy it into your module with your types.
+//
+// For serving the curated subset, set Collection. (e.g. the "Public API v2" collection of the registry)
+// For exposing it on the internal network, set the access policy. (Authenticate, Authorize, and Packages)
+// The fields marked as sensitive (see SensitiveMarker) are redacted, unless ShowSensitive is true.
+type DocServer struct {
+	Registry   *Registry
+	Collection *Collection // if not nil, only the shapes of the collection are served
+	Title      string      // the title of the HTML page, if empty, "Shapes"
+
+	// Authenticate is called before serving the request. If it returns the error, the request is rejected with 401 Unauthorized.
+	// (e.g. checking the bearer token, or the client certificate)
+	Authenticate func(req *http.Request) error
+
+	// Authorize reports whether the shapes of the package are visible to the request. If nil, all packages are visible.
+	// The invisible shapes are not listed, and served as 404 Not Found, so their existence is not revealed.
+	Authorize func(req *http.Request, pkgpath string) bool
+
+	// Packages are the patterns of the served packages (e.g. "github.com/foo/bar/..."). If empty, all packages are served.
+	Packages []string
+
+	ShowSensitive bool // if true, the sensitive fields are not redacted
+}
+
+// NewDocServer returns the doc server of the registry.
+func NewDocServer(r *Registry) *DocServer {
+	return &DocServer{Registry: r}
+}
+
+// DocShape is the shape served by DocServer.
+type DocShape struct {
+	Number  int        `json:"number"`
+	Name    string     `json:"name"`
+	Package string     `json:"package"` // mapped by Config.Namespaces (Packages and Authorize match the package path)
+	Kind    string     `json:"kind"`
+	Doc     string     `json:"doc,omitempty"`
+	Fields  []DocField `json:"fields,omitempty"`  // for the structs
+	Args    []DocField `json:"args,omitempty"`    // for the funcs
+	Returns []DocField `json:"returns,omitempty"` // for the funcs
+}
+
+// DocField is the field of the struct, or the argument and the return value of the func, served by DocServer.
+type DocField struct {
+	Name     string `json:"name,omitempty"`
+	Type     string `json:"type,omitempty"` // the type expression, qualified from the package of the shape (e.g. "[]*model.User")
+	Tag      string `json:"tag,omitempty"`
+	Doc      string `json:"doc,omitempty"`
+	Redacted bool   `json:"redacted,omitempty"` // if true, the field is sensitive, and only the name is served
+}
+
+func (d *DocServer) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet && req.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+	if d.Authenticate != nil {
+		if err := d.Authenticate(req); err != nil {
+			http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+			return
+		}
+	}
+	switch path := req.URL.Path; {
+	case path == "/" || path == "":
+		d.serveHTML(w, d.shapes(req))
+	case path == "/api/shapes":
+		writeDocJSON(w, d.shapes(req))
+	case strings.HasPrefix(path, "/api/shapes/"):
+		num, err := strconv.Atoi(strings.TrimPrefix(path, "/api/shapes/"))
+		if err != nil {
+			http.NotFound(w, req)
+			return
+		}
+		for _, s := range d.served(req) {
+			if s.Number == num {
+				writeDocJSON(w, d.newDocShape(s))
+				return
+			}
+		}
+		http.NotFound(w, req)
+	default:
+		http.NotFound(w, req)
+	}
+}
+
+// Shapes returns the served shapes, the named types and the funcs ordered by the packages and the names.
+// (the builtin types and the pointer shapes are not included, the pointer is served as its element)
+// Packages and the redaction are applied, but Authorize is not. (it is for each request)
+func (d *DocServer) Shapes() []*DocShape {
+	return d.shapes(nil)
+}
+
+// shapes returns the shapes visible to the request. If req is nil, Authorize is not applied.
+func (d *DocServer) shapes(req *http.Request) []*DocShape {
+	served := d.served(req)
+	r := make([]*DocShape, len(served))
+	for i, s := range served {
+		r[i] = d.newDocShape(s)
+	}
+	sort.SliceStable(r, func(i, j int) bool {
+		if r[i].Package != r[j].Package {
+			return r[i].Package < r[j].Package
+		}
+		return r[i].Name < r[j].Name
+	})
+	return r
+}
+
+// served returns the shapes visible to the request, before building the DocShapes. (the first one of the same name is served)
+func (d *DocServer) served(req *http.Request) []*Shape {
+	var shapes []*Shape
+	if d.Collection != nil {
+		shapes = d.Collection.Shapes()
+	} else {
+		shapes = d.Registry.Shapes()
+	}
+	var r []*Shape
+	seen := map[string]bool{}
+	for _, s := range shapes {
+		if s.Name == "" || s.Package.Path == "" || !d.visible(req, s.Package.Path) {
+			continue
+		}
+		key := s.Package.Path + "." + s.Name
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		r = append(r, s)
+	}
+	return r
+}
+
+func (d *DocServer) visible(req *http.Request, pkgpath string) bool {
+	if len(d.Packages) > 0 {
+		matched := false
+		for _, pattern := range d.Packages {
+			if matchPackage(pattern, pkgpath) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	return req == nil || d.Authorize == nil || d.Authorize(req, pkgpath)
+}
+
+func (d *DocServer) newDocShape(s *Shape) *DocShape {
+	imports := NewImports(s.Package.Path)
+	ds := &DocShape{Number: s.Number, Name: s.Name, Package: s.Package.Namespace, Kind: s.Kind.String(), Doc: s.doc()}
+	switch {
+	case s.Kind == reflect.Func || s.ID.pc != 0:
+		fn := s.Func()
+		for _, v := range fn.Args() {
+			ds.Args = append(ds.Args, DocField{Name: v.Name, Type: imports.VarExpr(v), Doc: v.Doc})
+		}
+		for _, v := range fn.Returns() {
+			ds.Returns = append(ds.Returns, DocField{Name: v.Name, Type: imports.VarExpr(v), Doc: v.Doc})
+		}
+	case s.Kind == reflect.Struct:
+		for _, f := range s.Struct().Fields() {
+			if !d.ShowSensitive && f.IsSensitive() {
+				ds.Fields = append(ds.Fields, DocField{Name: f.Name, Redacted: true})
+				continue
+			}
+			ds.Fields = append(ds.Fields, DocField{Name: f.Name, Type: imports.TypeExpr(f.Shape), Tag: string(f.Tag), Doc: f.Doc})
+		}
+	}
+	return ds
+}
+
+// writeDocJSON writes v as JSON. It is encoded into the buffer first, so the error is reported before the body is written.
+func writeDocJSON(w http.ResponseWriter, v interface{}) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.Write(buf.Bytes())
+}
+
+type docPackage struct {
+	Path   string
+	Shapes []*DocShape
+}
+
+func (d *DocServer) serveHTML(w http.ResponseWriter, shapes []*DocShape) {
+	var packages []*docPackage
+	for _, s := range shapes {
+		if len(packages) == 0 || packages[len(packages)-1].Path != s.Package {
+			packages = append(packages, &docPackage{Path: s.Package})
+		}
+		p := packages[len(packages)-1]
+		p.Shapes = append(p.Shapes, s)
+	}
+	title := d.Title
+	if title == "" {
+		title = "Shapes"
+	}
+
+	var buf bytes.Buffer // as writeDocJSON
+	if err := docServerTemplate.Execute(&buf, map[string]interface{}{"Title": title, "Packages": packages}); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write(buf.Bytes())
+}
+
+var docServerTemplate = template.Must(template.New("docserver").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+pre { white-space: pre-wrap; }
+table { border-collapse: collapse; }
+td, th { border: 1px solid #ccc; padding: 0.2em 0.5em; text-align: left; vertical-align: top; }
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+<ul>{{range .Packages}}<li><a href="#{{.Path}}">{{.Path}}</a></li>{{end}}</ul>
+{{range .Packages}}
+<h2 id="{{.Path}}">{{.Path}}</h2>
+{{range .Shapes}}
+<h3 id="{{.Package}}.{{.Name}}">{{.Name}} <small>({{.Kind}}, <a href="api/shapes/{{.Number}}">json</a>)</small></h3>
+{{with .Doc}}<pre>{{.}}</pre>{{end}}
+{{with .Fields}}<table><tr><th>field</th><th>type</th><th>tag</th><th>doc</th></tr>{{range .}}<tr><td>{{.Name}}</td>{{if .Redacted}}<td colspan="3"><em>redacted</em></td>{{else}}<td><code>{{.Type}}</code></td><td><code>{{.Tag}}</code></td><td>{{.Doc}}</td>{{end}}</tr>{{end}}</table>{{end}}
+{{with .Args}}<table><tr><th>arg</th><th>type</th><th>doc</th></tr>{{range .}}<tr><td>{{.Name}}</td><td><code>{{.Type}}</code></td><td>{{.Doc}}</td></tr>{{end}}</table>{{end}}
+{{with .Returns}}<table><tr><th>return</th><th>type</th><th>doc</th></tr>{{range .}}<tr><td>{{.Name}}</td><td><code>{{.Type}}</code></td><td>{{.Doc}}</td></tr>{{end}}</table>{{end}}
+{{end}}
+{{end}}
+</body>
+</html>
+`))
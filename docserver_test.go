@@ -0,0 +1,208 @@
+package reflectshape_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	reflectshape "github.com/podhmo/reflect-shape"
+)
+
+func TestDocServer(t *testing.T) {
+	cfg := &reflectshape.Config{IncludeGoTestFiles: true}
+	invoice := cfg.Extract(&Invoice{})
+	cfg.Extract(IssueInvoice)
+	ts := httptest.NewServer(reflectshape.NewDocServer(cfg.Registry()))
+	defer ts.Close()
+
+	get := func(t *testing.T, path string, wantStatus int) string {
+		t.Helper()
+		res, err := http.Get(ts.URL + path)
+		if err != nil {
+			t.Fatalf("unexpected error: %+v", err)
+		}
+		defer res.Body.Close()
+		if want, got := wantStatus, res.StatusCode; want != got {
+			t.Fatalf("GET %s, status code: want:%d != got:%d", path, want, got)
+		}
+		b, err := io.ReadAll(res.Body)
+		if err != nil {
+			t.Fatalf("unexpected error: %+v", err)
+		}
+		return string(b)
+	}
+
+	t.Run("json", func(t *testing.T) {
+		var got []*reflectshape.DocShape
+		if err := json.Unmarshal([]byte(get(t, "/api/shapes", 200)), &got); err != nil {
+			t.Fatalf("unexpected error: %+v", err)
+		}
+		want := []*reflectshape.DocShape{
+			{Number: invoice.Number, Name: "Invoice", Package: "github.com/podhmo/reflect-shape_test", Kind: "struct", Doc: "Invoice is the invoice.",
+				Fields: []reflectshape.DocField{{Name: "Amount", Type: "int", Doc: "Amount is the amount."}, {Name: "Memo", Type: "string"}}},
+			{Name: "IssueInvoice", Package: "github.com/podhmo/reflect-shape_test", Kind: "func", Doc: "IssueInvoice issues the invoice.",
+				Args: []reflectshape.DocField{{Name: "amount", Type: "int"}}, Returns: []reflectshape.DocField{{Type: "*Invoice"}}},
+		}
+		for _, s := range got {
+			if s.Name == "IssueInvoice" {
+				s.Number = 0 // the order of the extraction is not stable
+			}
+		}
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Errorf("GET /api/shapes: mismatch (-want, +got): \n%v", diff)
+		}
+	})
+
+	t.Run("json-one", func(t *testing.T) {
+		var got reflectshape.DocShape
+		if err := json.Unmarshal([]byte(get(t, "/api/shapes/"+strconv.Itoa(invoice.Number), 200)), &got); err != nil {
+			t.Fatalf("unexpected error: %+v", err)
+		}
+		if want, got := "Invoice", got.Name; want != got {
+			t.Errorf("GET /api/shapes/{num}, name: want:%q != got:%q", want, got)
+		}
+		get(t, "/api/shapes/100000", 404)
+		get(t, "/api/shapes/xxx", 404)
+	})
+
+	t.Run("html", func(t *testing.T) {
+		body := get(t, "/", 200)
+		for _, want := range []string{"<h2 id=\"github.com/podhmo/reflect-shape_test\">", "Invoice is the invoice.", "<code>*Invoice</code>"} {
+			if !strings.Contains(body, want) {
+				t.Errorf("GET /, must contain %q, but:\n%s", want, body)
+			}
+		}
+	})
+}
+
+func TestDocServerNamespace(t *testing.T) {
+	cfg := &reflectshape.Config{IncludeGoTestFiles: true, Namespaces: map[string]string{"github.com/podhmo/reflect-shape_test": "shape"}}
+	cfg.Extract(&Invoice{})
+
+	shapes := reflectshape.NewDocServer(cfg.Registry()).Shapes()
+	if want, got := 1, len(shapes); want != got {
+		t.Fatalf("DocServer.Shapes(), len: want:%d != got:%d", want, got)
+	}
+	if want, got := "shape", shapes[0].Package; want != got {
+		t.Errorf("DocShape.Package: want:%q != got:%q", want, got)
+	}
+}
+
+// LoginCredential is the credential of the user.
+type LoginCredential struct {
+	User     string
+	Password string // +sensitive
+}
+
+func TestDocServerAccessPolicy(t *testing.T) {
+	cfg := &reflectshape.Config{IncludeGoTestFiles: true}
+	credential := cfg.Extract(LoginCredential{})
+	cfg.Extract(url.URL{}).Struct().Fields() // with url.Userinfo
+
+	d := reflectshape.NewDocServer(cfg.Registry())
+	d.Authenticate = func(req *http.Request) error {
+		if req.Header.Get("Authorization") != "Bearer xxx" {
+			return fmt.Errorf("invalid token")
+		}
+		return nil
+	}
+	d.Authorize = func(req *http.Request, pkgpath string) bool {
+		return pkgpath != "net/url" || req.Header.Get("X-Role") == "admin"
+	}
+	ts := httptest.NewServer(d)
+	defer ts.Close()
+
+	get := func(t *testing.T, path string, header http.Header, wantStatus int) []byte {
+		t.Helper()
+		req, err := http.NewRequest("GET", ts.URL+path, nil)
+		if err != nil {
+			t.Fatalf("unexpected error: %+v", err)
+		}
+		req.Header = header
+		res, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("unexpected error: %+v", err)
+		}
+		defer res.Body.Close()
+		if want, got := wantStatus, res.StatusCode; want != got {
+			t.Fatalf("GET %s, status code: want:%d != got:%d", path, want, got)
+		}
+		b, err := io.ReadAll(res.Body)
+		if err != nil {
+			t.Fatalf("unexpected error: %+v", err)
+		}
+		return b
+	}
+	names := func(t *testing.T, b []byte) []string {
+		t.Helper()
+		var shapes []*reflectshape.DocShape
+		if err := json.Unmarshal(b, &shapes); err != nil {
+			t.Fatalf("unexpected error: %+v", err)
+		}
+		var r []string
+		for _, s := range shapes {
+			r = append(r, s.Name)
+		}
+		return r
+	}
+
+	t.Run("authenticate", func(t *testing.T) {
+		get(t, "/api/shapes", http.Header{}, 401)
+		get(t, "/", http.Header{"Authorization": {"Bearer yyy"}}, 401)
+	})
+
+	t.Run("authorize", func(t *testing.T) {
+		user := http.Header{"Authorization": {"Bearer xxx"}}
+		if diff := cmp.Diff([]string{"LoginCredential"}, names(t, get(t, "/api/shapes", user, 200))); diff != "" {
+			t.Errorf("GET /api/shapes: mismatch (-want, +got): \n%v", diff)
+		}
+		admin := http.Header{"Authorization": {"Bearer xxx"}, "X-Role": {"admin"}}
+		if diff := cmp.Diff([]string{"LoginCredential", "URL", "Userinfo"}, names(t, get(t, "/api/shapes", admin, 200))); diff != "" {
+			t.Errorf("GET /api/shapes: mismatch (-want, +got): \n%v", diff)
+		}
+
+		num := strconv.Itoa(cfg.Extract(url.URL{}).Number)
+		get(t, "/api/shapes/"+num, user, 404)
+		get(t, "/api/shapes/"+num, admin, 200)
+	})
+
+	t.Run("packages", func(t *testing.T) {
+		d := reflectshape.NewDocServer(cfg.Registry())
+		d.Packages = []string{"net/..."}
+		var got []string
+		for _, s := range d.Shapes() {
+			got = append(got, s.Name)
+		}
+		if diff := cmp.Diff([]string{"URL", "Userinfo"}, got); diff != "" {
+			t.Errorf("DocServer.Shapes(): mismatch (-want, +got): \n%v", diff)
+		}
+	})
+
+	t.Run("redacted", func(t *testing.T) {
+		var got reflectshape.DocShape
+		if err := json.Unmarshal(get(t, "/api/shapes/"+strconv.Itoa(credential.Number), http.Header{"Authorization": {"Bearer xxx"}}, 200), &got); err != nil {
+			t.Fatalf("unexpected error: %+v", err)
+		}
+		want := []reflectshape.DocField{{Name: "User", Type: "string"}, {Name: "Password", Redacted: true}}
+		if diff := cmp.Diff(want, got.Fields); diff != "" {
+			t.Errorf("GET /api/shapes/{num}, fields: mismatch (-want, +got): \n%v", diff)
+		}
+
+		d := reflectshape.NewDocServer(cfg.Registry())
+		d.ShowSensitive = true
+		for _, s := range d.Shapes() {
+			if s.Name == "LoginCredential" {
+				if want, got := "string", s.Fields[1].Type; want != got {
+					t.Errorf("DocServer.Shapes(), with ShowSensitive: want:%q != got:%q", want, got)
+				}
+			}
+		}
+	})
+}
@@ -0,0 +1,103 @@
+package reflectshape
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// StreamExporter receives the shapes one at a time, and writes its output incrementally.
+type StreamExporter interface {
+	ExportShape(s *Shape) error
+}
+
+// StreamExporterFunc is the adapter to use the ordinary function as StreamExporter.
+type StreamExporterFunc func(s *Shape) error
+
+func (f StreamExporterFunc) ExportShape(s *Shape) error {
+	return f(s)
+}
+
+// Stream passes the shapes reachable from obs to the exporter, one at a time, in topological order. (the dependencies first)
+// Each shape is passed only once, even if the types are recursive.
+func (c *Config) Stream(exporter StreamExporter, obs ...interface{}) error {
+	c.init()
+	shapes := make([]*Shape, 0, len(obs))
+	for _, ob := range obs {
+		if s := c.extractor.Extract(ob); s != nil { // nil if vetoed
+			shapes = append(shapes, s)
+		}
+	}
+	return c.extractor.stream(exporter, shapes)
+}
+
+func (e *Extractor) stream(exporter StreamExporter, shapes []*Shape) error {
+	const (
+		visiting = 1
+		done     = 2
+	)
+	state := map[ID]int{}
+
+	var walk func(s *Shape) error
+	walk = func(s *Shape) error {
+		if state[s.ID] != 0 {
+			return nil // done, or cyclic reference
+		}
+		state[s.ID] = visiting
+		for _, dep := range e.dependencies(s) {
+			if err := walk(dep); err != nil {
+				return err
+			}
+		}
+		state[s.ID] = done
+		if err := exporter.ExportShape(s); err != nil {
+			return fmt.Errorf("export %s: %w", s.Name, err)
+		}
+		return nil
+	}
+
+	for _, s := range shapes {
+		if err := walk(s); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// dependencies returns the shapes directly referenced by s. (fields, elements, arguments, ...)
+func (e *Extractor) dependencies(s *Shape) []*Shape {
+	if s.IsWellKnown || s.IsOpaque {
+		return nil
+	}
+
+	var types []reflect.Type
+	rt := s.Type
+	switch rt.Kind() {
+	case reflect.Struct:
+		for i := 0; i < rt.NumField(); i++ {
+			types = append(types, rt.Field(i).Type)
+		}
+	case reflect.Map:
+		types = append(types, rt.Key(), rt.Elem())
+	case reflect.Slice, reflect.Array, reflect.Chan:
+		types = append(types, rt.Elem())
+	case reflect.Func:
+		for i := 0; i < rt.NumIn(); i++ {
+			types = append(types, rt.In(i))
+		}
+		for i := 0; i < rt.NumOut(); i++ {
+			types = append(types, rt.Out(i))
+		}
+	case reflect.Interface:
+		for i := 0; i < rt.NumMethod(); i++ {
+			types = append(types, rt.Method(i).Type)
+		}
+	}
+
+	r := make([]*Shape, 0, len(types))
+	for _, rt := range types {
+		if dep := e.extract(rt, rzero(rt)); dep != nil {
+			r = append(r, dep)
+		}
+	}
+	return r
+}
@@ -0,0 +1,48 @@
+package reflectshape_test
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	reflectshape "github.com/podhmo/reflect-shape"
+)
+
+type Node struct {
+	Name     string
+	Parent   *Node
+	Children []*Node
+	Meta     Meta
+}
+
+type Meta struct {
+	Tags []string
+}
+
+func TestStream(t *testing.T) {
+	cfg := &reflectshape.Config{SkipComments: true}
+
+	var got []string
+	err := cfg.Stream(reflectshape.StreamExporterFunc(func(s *reflectshape.Shape) error {
+		got = append(got, s.Type.String())
+		return nil
+	}), Node{}, Meta{})
+	if err != nil {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+
+	want := []string{"string", "[]*reflectshape_test.Node", "[]string", "reflectshape_test.Meta", "reflectshape_test.Node"}
+	if !reflect.DeepEqual(want, got) {
+		t.Errorf("Config.Stream(): want:%#+v != got:%#+v", want, got)
+	}
+
+	t.Run("error", func(t *testing.T) {
+		stop := errors.New("stop")
+		err := cfg.Stream(reflectshape.StreamExporterFunc(func(s *reflectshape.Shape) error {
+			return stop
+		}), Node{})
+		if !errors.Is(err, stop) {
+			t.Errorf("Config.Stream(): must be stop, but got %+v", err)
+		}
+	})
+}
@@ -1,25 +1,54 @@
 package reflectshape
 
 import (
+	"container/list"
+	"context"
 	"fmt"
 	"reflect"
 	"runtime"
 	"sort"
 	"strings"
+	"sync"
 
 	"github.com/podhmo/reflect-shape/metadata"
+	"github.com/podhmo/reflect-shape/metadata/runtimeinfo"
 )
 
 type Extractor struct {
 	Config *Config
 	Lookup *metadata.Lookup
 
+	// mu guards the caches below, and the scopes of the packages.
+	// It is one lock, not sharded, because the eviction updates the seen map, the LRU, and the packages together,
+	// and it is not held during the lookups of the metadata (the slow part), so the critical sections are short.
+	mu       sync.Mutex
 	seen     map[ID]*Shape
 	packages map[string]*Package
+	inflight map[ID]chan struct{} // the shapes being extracted with Hooks.AfterExtract, closed when cached
+
+	count   int                  // the number of the extracted shapes, used as Shape.Number
+	lru     *list.List           // the IDs of the shapes, the front is the most recently used (only if Config.MaxShapes > 0)
+	elems   map[ID]*list.Element // ID -> the element of lru
+	evicted int
+
+	views    map[ID]*views // the materialized views of the shapes (see views.go)
+	interner *interner     // nil if Config.InternStrings is false
+	slab     *shapeSlab    // nil if Config.BulkAllocation is 0
+
+	findings    map[string][]*Finding  // keyed by the path, see Config.AttachFindings
+	collections map[string]*Collection // see Registry.Collection
 }
 
+// Visited returns the copies of the extracted shapes.
 func (e *Extractor) Visited() map[ID]*Shape {
-	return e.seen
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	r := make(map[ID]*Shape, len(e.seen))
+	for id, shape := range e.seen {
+		copied := *shape
+		r[id] = &copied
+	}
+	return r
 }
 
 func (e *Extractor) Extract(ob interface{}) *Shape {
@@ -29,27 +58,93 @@ func (e *Extractor) Extract(ob interface{}) *Shape {
 	return e.extract(rt, rv)
 }
 
+// ExtractType returns the shape of the type, without the instance. The func types have no pc, so they are the signatures, not the functions.
+func (e *Extractor) ExtractType(rt reflect.Type) *Shape {
+	if rt == nil {
+		return nil
+	}
+	return e.extract(rt, rzero(rt))
+}
+
+// ExtractValue returns the shape of the type of rv. It returns nil for the zero Value.
+func (e *Extractor) ExtractValue(rv reflect.Value) *Shape {
+	if !rv.IsValid() {
+		return nil
+	}
+	return e.extract(rv.Type(), rv)
+}
+
 func (e *Extractor) extract(rt reflect.Type, rv reflect.Value) *Shape {
 	lv := 0
 	for rt.Kind() == reflect.Pointer {
 		rt = rt.Elem()
 		rv = rv.Elem()
+		if !rv.IsValid() { // nil pointer
+			rv = rzero(rt)
+		}
 		lv++
 	}
+	provenance := ProvenanceReflect
+	if replacement, ok := e.Config.override(rt); ok {
+		provenance = ProvenanceOverride
+		rt = replacement
+		rv = rzero(rt)
+		for rt.Kind() == reflect.Pointer {
+			rt = rt.Elem()
+			rv = rzero(rt)
+			lv++
+		}
+	}
 
 	id := ID{rt: rt}
 	if rt.Kind() == reflect.Func {
 		id.pc = rv.Pointer() // distinguish same signature function
 	}
+	return e.extractByID(id, rv, lv, provenance, false)
+}
+
+// extractMethod extracts the method of the type as the func shape. rt is the signature without the receiver, pc is of the method expression.
+func (e *Extractor) extractMethod(rt reflect.Type, pc uintptr) *Shape {
+	return e.extractByID(ID{rt: rt, pc: pc}, rzero(rt), 0, ProvenanceReflect, true)
+}
 
-	shape, ok := e.seen[id]
-	if ok {
-		if lv == 0 {
-			return shape
+// extractByID returns the shape of the id, extracting it if not cached. If method is true, the pc is of the method expression (e.g. pkg.(*T).M).
+func (e *Extractor) extractByID(id ID, rv reflect.Value, lv int, provenance Provenance, method bool) *Shape {
+	rt := id.rt
+	after := e.Config.Hooks.AfterExtract
+	e.mu.Lock()
+	for {
+		if shape, ok := e.seen[id]; ok {
+			e.touch(id)
+			e.mu.Unlock()
+			return e.copyCached(shape, lv, provenance)
 		}
-		copied := *shape
-		copied.Lv = lv
-		return &copied
+		ch, ok := e.inflight[id]
+		if !ok {
+			break
+		}
+		// wait for the extraction by the other goroutine, so that AfterExtract is called only once per shape
+		e.mu.Unlock()
+		<-ch
+		e.mu.Lock()
+	}
+	if after != nil {
+		if e.inflight == nil {
+			e.inflight = map[ID]chan struct{}{}
+		}
+		ch := make(chan struct{})
+		e.inflight[id] = ch
+		defer func() {
+			e.mu.Lock()
+			delete(e.inflight, id)
+			e.mu.Unlock()
+			close(ch)
+		}()
+	}
+	e.mu.Unlock()
+
+	if h := e.Config.Hooks.BeforeExtract; h != nil && !h(rt) {
+		return nil
 	}
 
 	name := rt.Name()
@@ -57,60 +152,156 @@ func (e *Extractor) extract(rt reflect.Type, rv reflect.Value) *Shape {
 	isMethod := false
 
 	if id.pc != 0 { // is function?
+		// @@ github.com/podhmo/reflect-shape/neo_test.F1
+		// @@ github.com/podhmo/reflect-shape/neo_test.S0.M-fm
+		// @@ github.com/podhmo/reflect-shape/neo_test.(*S1).M-fm
+		// @@ github.com/podhmo/reflect-shape/neo_test.(*S1).M (method expression, see extractMethod)
+		// @@ github.com/podhmo/reflect-shape/neo_test.F1.func1 (closure, named "F1.func1")
 		fullname := runtime.FuncForPC(id.pc).Name()
-		parts := strings.Split(fullname, ".")
-
-		if strings.HasSuffix(fullname, "-fm") {
+		fn, err := runtimeinfo.ParseFuncName(fullname) // generics are mapped to the declaration, e.g. pkg.Map[...] -> Map
+		if err != nil {
+			e.Config.logf("extract func: %+v", err)
+		}
+		pkgPath = fn.PkgPath
+		switch {
+		case fn.IsMethod() && (method || fn.IsMethodValue) && !fn.IsClosure():
 			isMethod = true
-			// @@ github.com/podhmo/reflect-shape/neo_test.S0.M-fm
-			// @@ github.com/podhmo/reflect-shape/neo_test.(*S1).M-fm
-			pkgPath = strings.Join(parts[:len(parts)-2], ".")
-			name = fmt.Sprintf("%s.%s", strings.Trim(parts[len(parts)-2], "(*)"), strings.TrimSuffix(parts[len(parts)-1], "-fm"))
-		} else {
-			// @@ github.com/podhmo/reflect-shape/neo_test.F1
-			// @@ github.com/podhmo/reflect-shape/neo_test.S0
-			pkgPath = strings.Join(parts[:len(parts)-1], ".")
-			name = parts[len(parts)-1]
+			name = fmt.Sprintf("%s.%s", fn.Recv, fn.Name)
+		case fn.IsMethod():
+			name = fmt.Sprintf("%s.%s", fn.Recv, fn.Name)
+		default:
+			name = fn.Name
+		}
+		if fn.IsClosure() {
+			name = name + "." + fn.Closure
+		}
+		if e.Config.Trace {
+			e.Config.logf("trace: extract func %q -> pkgpath=%q, name=%q, isMethod=%v", fullname, pkgPath, name, isMethod)
 		}
 	}
 
+	e.mu.Lock()
+	if id.pc != 0 { // the names of the types (rt.Name() and rt.PkgPath()) are in the binary, so interning them saves nothing
+		name = e.interner.intern(name)
+		pkgPath = e.interner.intern(pkgPath)
+	}
 	pkg, ok := e.packages[pkgPath]
 	if !ok {
 		parts := strings.Split(pkgPath, "/") // todo fix
 		pkgName := parts[len(parts)-1]
 		pkg = &Package{
-			Name:  pkgName,
-			Path:  pkgPath,
-			scope: &Scope{shapes: map[string]*Shape{}},
+			Name:      pkgName,
+			Path:      pkgPath,
+			Namespace: e.Config.namespace(pkgPath),
+			scope:     &Scope{shapes: map[string]*Shape{}, mu: &e.mu},
 		}
 		e.packages[pkgPath] = pkg
 	}
+	number := e.count
+	e.count++
+	e.mu.Unlock()
 
-	shape = &Shape{
+	shape := e.slab.copyShape(&Shape{
 		Name:         name,
 		Kind:         rt.Kind(),
 		ID:           id,
 		Type:         rt,
 		DefaultValue: rv,
-		Number:       len(e.seen),
+		Number:       number,
+		Provenance:   ProvenanceReflect,
 		IsMethod:     isMethod,
+		IsWellKnown:  id.pc == 0 && name != "" && e.Config.wellKnownTypes[pkgPath+"."+name],
+		IsOpaque:     isCgoName(name),
 		Package:      pkg,
 		e:            e,
+	})
+	if after != nil {
+		after(shape) // not locked, so the hook can extract the other shapes
 	}
-	e.seen[id] = shape
-	pkg.scope.shapes[name] = shape
 
-	if lv == 0 {
-		return shape
+	e.mu.Lock()
+	if existing, ok := e.seen[id]; ok { // extracted by the other goroutine, meanwhile (without AfterExtract)
+		shape = existing
+	} else {
+		if current, ok := e.packages[pkgPath]; !ok { // dropped by the eviction, meanwhile
+			e.packages[pkgPath] = pkg
+		} else if current != pkg {
+			pkg = current
+			shape.Package = pkg
+		}
+		e.seen[id] = shape
+		pkg.scope.shapes[shape.Name] = shape
 	}
-	copied := *shape
+	e.touch(id)
+	e.evict()
+	e.mu.Unlock()
+
+	return e.copyCached(shape, lv, provenance)
+}
+
+// copyCached returns the copy of the cached shape. The cached shape is never returned, so that consumers cannot poison the cache.
+// The package is also copied, but its scope is shared. (it is read only via the accessors)
+func (e *Extractor) copyCached(shape *Shape, lv int, provenance Provenance) *Shape {
+	copied := e.slab.copyShape(shape)
 	copied.Lv = lv
-	return &copied
+	copied.Provenance = provenance
+	if shape.Package != nil {
+		pkg := *shape.Package
+		copied.Package = &pkg
+	}
+	return copied
+}
+
+func (e *Extractor) lookupType(rt reflect.Type) (m *metadata.Type, provenance Provenance, err error) {
+	return e.lookupTypeContext(context.Background(), rt)
+}
+
+func (e *Extractor) lookupTypeContext(ctx context.Context, rt reflect.Type) (m *metadata.Type, provenance Provenance, err error) {
+	defer e.recoverPanic(rt, &err)
+	if h := e.Config.Hooks.LookupType; h != nil {
+		if m, ok := h(rt); ok {
+			return m, ProvenanceHook, nil
+		}
+	}
+	if e.Lookup == nil || !e.Config.isCommentPackage(rt.PkgPath()) || isCgoName(rt.Name()) {
+		return nil, ProvenanceNone, nil
+	}
+	m, err = e.Lookup.LookupFromTypeForReflectTypeContext(ctx, rt)
+	return m, ProvenanceSource, err
+}
+
+func (e *Extractor) lookupFunc(pc uintptr, pkgpath string) (m *metadata.Func, provenance Provenance, err error) {
+	return e.lookupFuncContext(context.Background(), pc, pkgpath)
+}
+
+func (e *Extractor) lookupFuncContext(ctx context.Context, pc uintptr, pkgpath string) (m *metadata.Func, provenance Provenance, err error) {
+	defer e.recoverPanic(nil, &err)
+	if h := e.Config.Hooks.LookupFunc; h != nil {
+		if m, ok := h(pc); ok {
+			return m, ProvenanceHook, nil
+		}
+	}
+	if e.Lookup == nil || !e.Config.isCommentPackage(pkgpath) {
+		return nil, ProvenanceNone, nil
+	}
+	if rfunc := runtime.FuncForPC(pc); rfunc != nil {
+		if fn, err := runtimeinfo.ParseFuncName(rfunc.Name()); err == nil && isCgoName(fn.Name) {
+			return nil, ProvenanceNone, nil
+		}
+	}
+	m, err = e.Lookup.LookupFromFuncForPCContext(ctx, pc)
+	return m, ProvenanceSource, err
+}
+
+// isCgoName returns true if the name is generated by cgo. (e.g. C.struct_x is _Ctype_struct_x, C.puts is _Cfunc_puts)
+func isCgoName(name string) bool {
+	return strings.HasPrefix(name, "_Ctype_") || strings.HasPrefix(name, "_Cfunc_") || strings.HasPrefix(name, "_Cmacro_")
 }
 
 type Package struct {
-	Name string
-	Path string
+	Name      string
+	Path      string
+	Namespace string // the name used in exported output instead of Path (see Config.Namespaces)
 
 	scope *Scope
 }
@@ -121,6 +312,22 @@ func (p *Package) Scope() *Scope {
 
 type Scope struct {
 	shapes map[string]*Shape
+	mu     *sync.Mutex // the lock of the extractor (nil for the cloned scope)
+}
+
+func (s *Scope) lock() func() {
+	if s.mu == nil {
+		return func() {}
+	}
+	s.mu.Lock()
+	return s.mu.Unlock
+}
+
+// lookup returns the shape of the name in the scope.
+func (s *Scope) lookup(name string) (*Shape, bool) {
+	defer s.lock()()
+	shape, ok := s.shapes[name]
+	return shape, ok
 }
 
 func (s *Scope) Names() []string {
@@ -132,6 +339,7 @@ func (s *Scope) NamesWithMethod() []string {
 }
 
 func (s *Scope) names(withMethod bool) []string {
+	defer s.lock()()
 	// anonymous function is not supported yet
 	r := make([]string, 0, len(s.shapes))
 	for name, s := range s.shapes {
@@ -0,0 +1,181 @@
+package reflectshape
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"go/token"
+	"io"
+	"path/filepath"
+	"reflect"
+	"strings"
+
+	"github.com/podhmo/reflect-shape/metadata"
+)
+
+// Finding is the issue reported by the external analysis. (e.g. golangci-lint, staticcheck)
+type Finding struct {
+	Filename string `json:"filename"` // absolute, or relative to the module root
+	Line     int    `json:"line"`
+	Column   int    `json:"column,omitempty"`
+	Linter   string `json:"linter,omitempty"`   // e.g. "staticcheck"
+	Code     string `json:"code,omitempty"`     // e.g. "SA1019"
+	Severity string `json:"severity,omitempty"` // e.g. "error", "warning"
+	Message  string `json:"message"`
+}
+
+func (f *Finding) String() string {
+	code := f.Linter
+	if f.Code != "" {
+		code = f.Code
+	}
+	return fmt.Sprintf("%s:%d:%d: %s (%s)", f.Filename, f.Line, f.Column, f.Message, code)
+}
+
+// ReadGolangciFindings reads the findings from the JSON output of golangci-lint. (golangci-lint run --out-format=json)
+func ReadGolangciFindings(r io.Reader) ([]*Finding, error) {
+	var out struct {
+		Issues []struct {
+			FromLinter string
+			Text       string
+			Severity   string
+			Pos        struct {
+				Filename string
+				Line     int
+				Column   int
+			}
+		}
+	}
+	if err := json.NewDecoder(r).Decode(&out); err != nil {
+		return nil, fmt.Errorf("read golangci-lint findings: %w", err)
+	}
+	findings := make([]*Finding, len(out.Issues))
+	for i, x := range out.Issues {
+		findings[i] = &Finding{Filename: x.Pos.Filename, Line: x.Pos.Line, Column: x.Pos.Column, Linter: x.FromLinter, Severity: x.Severity, Message: x.Text}
+	}
+	return findings, nil
+}
+
+// ReadStaticcheckFindings reads the findings from the JSON output of staticcheck, one object per line. (staticcheck -f json)
+func ReadStaticcheckFindings(r io.Reader) ([]*Finding, error) {
+	var findings []*Finding
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		var x struct {
+			Code     string `json:"code"`
+			Severity string `json:"severity"`
+			Message  string `json:"message"`
+			Location struct {
+				File   string `json:"file"`
+				Line   int    `json:"line"`
+				Column int    `json:"column"`
+			} `json:"location"`
+		}
+		if err := json.Unmarshal([]byte(line), &x); err != nil {
+			return nil, fmt.Errorf("read staticcheck findings: %w", err)
+		}
+		findings = append(findings, &Finding{Filename: x.Location.File, Line: x.Location.Line, Column: x.Location.Column, Linter: "staticcheck", Code: x.Code, Severity: x.Severity, Message: x.Message})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("read staticcheck findings: %w", err)
+	}
+	return findings, nil
+}
+
+// AttachFindings attaches the findings to the extracted shapes and their fields (see Shape.Findings and Field.Findings), and returns the unattached ones.
+//
+// The finding is attached if it is reported at the line of the declaration: the type, the field (or the method of the interface), or the function.
+// The relative filename of the finding is matched with the suffix of the source path. (the findings in the bodies of the functions are not attached)
+// The shapes extracted after the call are not the targets, so call it after extracting.
+func (c *Config) AttachFindings(findings []*Finding) (unattached []*Finding) {
+	c.init()
+	e := c.extractor
+	if e.Lookup == nil {
+		return findings
+	}
+
+	type decl struct {
+		filename string
+		path     string
+	}
+	decls := map[int][]decl{} // keyed by the line
+	add := func(pos token.Pos, path string) {
+		if !pos.IsValid() {
+			return
+		}
+		position := e.Lookup.Fset.Position(pos)
+		decls[position.Line] = append(decls[position.Line], decl{filename: filepath.ToSlash(position.Filename), path: path})
+	}
+	for _, s := range e.snapshot() {
+		if s.Name == "" || s.Package.Path == "" {
+			continue
+		}
+		path := s.FullName()
+		var m *metadata.Type
+		switch {
+		case s.Kind == reflect.Func || s.ID.pc != 0:
+			if f := s.Func(); f.metadata != nil {
+				add(f.metadata.Raw.Pos, path)
+			}
+			continue
+		case s.Kind == reflect.Struct:
+			m = s.Struct().metadata
+		case s.Kind == reflect.Interface:
+			m = s.Interface().metadata
+		default:
+			m = s.Named().metadata
+		}
+		if m == nil {
+			continue
+		}
+		add(m.Raw.Pos, path)
+		for _, name := range m.Raw.FieldNames {
+			add(m.Raw.Fields[name].Pos, path+"."+name)
+		}
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	for _, f := range findings {
+		filename := "/" + strings.TrimPrefix(filepath.ToSlash(filepath.Clean(f.Filename)), "/")
+		attached := false
+		for _, d := range decls[f.Line] {
+			if d.filename == f.Filename || strings.HasSuffix(d.filename, filename) {
+				if e.findings == nil {
+					e.findings = map[string][]*Finding{}
+				}
+				e.findings[d.path] = append(e.findings[d.path], f)
+				attached = true
+				break
+			}
+		}
+		if !attached {
+			unattached = append(unattached, f)
+		}
+	}
+	return unattached
+}
+
+// Findings returns the findings attached to the shape. (see Config.AttachFindings)
+func (s *Shape) Findings() []*Finding {
+	return s.e.findingsOf(s.FullName())
+}
+
+// Findings returns the findings attached to the field. (see Config.AttachFindings)
+func (f *Field) Findings() []*Finding {
+	if f.parent == nil {
+		return nil
+	}
+	return f.parent.Shape.e.findingsOf(f.parent.fullName() + "." + f.Name)
+}
+
+func (e *Extractor) findingsOf(path string) []*Finding {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return append([]*Finding(nil), e.findings[path]...)
+}
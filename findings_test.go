@@ -0,0 +1,95 @@
+package reflectshape_test
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	reflectshape "github.com/podhmo/reflect-shape"
+)
+
+// Invoice is the invoice.
+type Invoice struct {
+	// Amount is the amount.
+	Amount int
+	Memo   string
+}
+
+// IssueInvoice issues the invoice.
+func IssueInvoice(amount int) *Invoice {
+	return &Invoice{Amount: amount}
+}
+
+func TestAttachFindings(t *testing.T) {
+	b, err := os.ReadFile("findings_test.go")
+	if err != nil {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+	lineOf := func(prefix string) int {
+		for i, line := range strings.Split(string(b), "\n") {
+			if strings.HasPrefix(strings.TrimSpace(line), prefix) {
+				return i + 1
+			}
+		}
+		t.Fatalf("%q is not found", prefix)
+		return 0
+	}
+	abspath, err := filepath.Abs("findings_test.go")
+	if err != nil {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+
+	golangci, err := reflectshape.ReadGolangciFindings(strings.NewReader(fmt.Sprintf(`{"Issues": [
+		{"FromLinter": "revive", "Text": "exported type should have comment", "Pos": {"Filename": "findings_test.go", "Line": %d, "Column": 6}},
+		{"FromLinter": "unused", "Text": "field Amount is unused", "Pos": {"Filename": "./findings_test.go", "Line": %d, "Column": 2}},
+		{"FromLinter": "unused", "Text": "other file", "Pos": {"Filename": "other.go", "Line": %d, "Column": 2}}
+	]}`, lineOf("type Invoice struct"), lineOf("Amount int"), lineOf("Amount int"))))
+	if err != nil {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+	staticcheck, err := reflectshape.ReadStaticcheckFindings(strings.NewReader(fmt.Sprintf(`
+{"code":"SA4006","severity":"error","location":{"file":%q,"line":%d,"column":2},"message":"this value is never used"}
+{"code":"SA1019","severity":"error","location":{"file":%q,"line":%d,"column":6},"message":"IssueInvoice is deprecated"}
+`, abspath, lineOf("return &Invoice"), abspath, lineOf("func IssueInvoice"))))
+	if err != nil {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+
+	cfg := &reflectshape.Config{IncludeGoTestFiles: true}
+	invoice := cfg.Extract(Invoice{})
+	fn := cfg.Extract(IssueInvoice)
+
+	unattached := cfg.AttachFindings(append(golangci, staticcheck...))
+	var messages []string
+	for _, f := range unattached {
+		messages = append(messages, f.Message)
+	}
+	if want, got := "other file,this value is never used", strings.Join(messages, ","); want != got {
+		t.Errorf("Config.AttachFindings(), unattached, want:%q != got:%q", want, got)
+	}
+
+	cases := []struct {
+		msg      string
+		findings []*reflectshape.Finding
+		want     string
+	}{
+		{msg: "type", findings: invoice.Findings(), want: "revive"},
+		{msg: "field", findings: invoice.Struct().Fields()[0].Findings(), want: "unused"},
+		{msg: "field-none", findings: invoice.Struct().Fields()[1].Findings(), want: ""},
+		{msg: "func", findings: fn.Findings(), want: "staticcheck"},
+	}
+	for _, c := range cases {
+		c := c
+		t.Run(c.msg, func(t *testing.T) {
+			var linters []string
+			for _, f := range c.findings {
+				linters = append(linters, f.Linter)
+			}
+			if want, got := c.want, strings.Join(linters, ","); want != got {
+				t.Errorf("Findings(), want:%q != got:%q", want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,105 @@
+package reflectshape
+
+import (
+	"bytes"
+	"hash"
+	"io"
+)
+
+// Fingerprint returns the stable digest (FNV-1a, 64bit) of the structure of the shape, the same form as hashed by Registry.Hash.
+// The canonical form is built once per shape and cached by the extractor, so the calls after the first one don't allocate.
+// (useful for the per-request compatibility checks, e.g. comparing with the fingerprint sent by the client)
+func (s *Shape) Fingerprint() uint64 {
+	return fnv64a(s.canonical())
+}
+
+// WriteHash writes the canonical form of the shape into h. It doesn't allocate, once the form is cached (see Fingerprint).
+func (s *Shape) WriteHash(h hash.Hash) {
+	h.Write(s.canonical())
+}
+
+// Fingerprint returns the digest of all contained shapes, combined from Shape.Fingerprint in the order-independent way.
+// Unlike Hash, it doesn't allocate once the fingerprints of all shapes are cached.
+func (r *Registry) Fingerprint() uint64 {
+	for {
+		sum, missing := r.fingerprint()
+		if !missing {
+			return sum
+		}
+		for _, s := range r.e.snapshot() { // build the canonical forms, and retry
+			s.canonical()
+		}
+	}
+}
+
+func (r *Registry) fingerprint() (sum uint64, missing bool) {
+	e := r.e
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	for id := range e.seen {
+		v := e.views[id]
+		if v == nil || v.canonical == nil {
+			return 0, true
+		}
+		sum += mix64(fnv64a(v.canonical))
+	}
+	return sum, false
+}
+
+// canonical returns the canonical form of the shape: "<key>\n" and the lines written by writeCanonicalShape.
+// The form is cached in the views, because building it needs the allocations in reflect (e.g. reflect.Type.Field()).
+func (s *Shape) canonical() []byte {
+	e := s.e
+	if e == nil { // not extracted by the extractor
+		return buildCanonical(s)
+	}
+	e.mu.Lock()
+	v := e.views[s.ID]
+	e.mu.Unlock()
+	if v != nil && v.canonical != nil {
+		return v.canonical
+	}
+
+	b := buildCanonical(s)
+	e.mu.Lock()
+	if v := e.view(s.ID); v != nil {
+		v.canonical = b
+	}
+	e.mu.Unlock()
+	return b
+}
+
+func buildCanonical(s *Shape) []byte {
+	var buf bytes.Buffer
+	key := canonicalTypeString(s.Type)
+	if s.ID.pc != 0 {
+		key = s.Package.Path + "." + s.Name + " " + key
+	}
+	io.WriteString(&buf, key)
+	io.WriteString(&buf, "\n")
+	writeCanonicalShape(&buf, s)
+	return buf.Bytes()
+}
+
+func fnv64a(b []byte) uint64 {
+	const (
+		offset64 = 14695981039346656037
+		prime64  = 1099511628211
+	)
+	h := uint64(offset64)
+	for _, c := range b {
+		h ^= uint64(c)
+		h *= prime64
+	}
+	return h
+}
+
+// mix64 is the finalizer of splitmix64, so that the sum of the fingerprints is not cancelled out easily.
+func mix64(x uint64) uint64 {
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+	return x
+}
@@ -0,0 +1,70 @@
+package reflectshape_test
+
+import (
+	"crypto/sha256"
+	"testing"
+
+	reflectshape "github.com/podhmo/reflect-shape"
+)
+
+func TestFingerprint(t *testing.T) {
+	cfg := &reflectshape.Config{SkipComments: true}
+	person := cfg.Extract(Person{})
+
+	if want, got := person.Fingerprint(), (&reflectshape.Config{SkipComments: true}).Extract(&Person{}).Fingerprint(); want != got {
+		t.Errorf("Shape.Fingerprint(): must be stable, want:%v != got:%v", want, got)
+	}
+	if person.Fingerprint() == cfg.Extract(Node{}).Fingerprint() {
+		t.Errorf("Shape.Fingerprint(): must be changed, if shapes are changed")
+	}
+	if person.Fingerprint() == cfg.Extract(Foo).Fingerprint() {
+		t.Errorf("Shape.Fingerprint(): must be changed, if shapes are changed")
+	}
+
+	other := &reflectshape.Config{SkipComments: true}
+	other.Extract(Foo)
+	other.Extract(Node{})
+	other.Extract(&Person{})
+	if want, got := cfg.Registry().Fingerprint(), other.Registry().Fingerprint(); want != got {
+		t.Errorf("Registry.Fingerprint(): must not depend on the order of extraction, want:%v != got:%v", want, got)
+	}
+	other.Extract(CreateUserRequest{})
+	if cfg.Registry().Fingerprint() == other.Registry().Fingerprint() {
+		t.Errorf("Registry.Fingerprint(): must be changed, if shapes are changed")
+	}
+}
+
+func TestFingerprintAllocs(t *testing.T) {
+	cfg := &reflectshape.Config{SkipComments: true}
+	person := cfg.Extract(Person{})
+	cfg.Extract(Foo)
+	r := cfg.Registry()
+	h := sha256.New()
+
+	cases := []struct {
+		msg string
+		fn  func()
+	}{
+		{msg: "Shape.Fingerprint", fn: func() { person.Fingerprint() }},
+		{msg: "Shape.WriteHash", fn: func() { person.WriteHash(h) }},
+		{msg: "Registry.Fingerprint", fn: func() { r.Fingerprint() }},
+	}
+	for _, c := range cases {
+		c := c
+		t.Run(c.msg, func(t *testing.T) {
+			c.fn() // build the canonical forms
+			if got := testing.AllocsPerRun(100, c.fn); got != 0 {
+				t.Errorf("allocs per run: want:0 != got:%v", got)
+			}
+		})
+	}
+}
+
+func BenchmarkFingerprint(b *testing.B) {
+	cfg := &reflectshape.Config{SkipComments: true}
+	person := cfg.Extract(Person{})
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		person.Fingerprint()
+	}
+}
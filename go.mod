@@ -1,14 +1,12 @@
 module github.com/podhmo/reflect-shape
 
-go 1.18
+go 1.19
 
 require (
 	github.com/google/go-cmp v0.5.9
 	github.com/podhmo/commentof v0.1.4
+	golang.org/x/mod v0.8.0
 	golang.org/x/tools v0.6.0
 )
 
-require (
-	golang.org/x/mod v0.8.0 // indirect
-	golang.org/x/sys v0.5.0 // indirect
-)
+require golang.org/x/sys v0.5.0 // indirect
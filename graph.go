@@ -0,0 +1,85 @@
+package reflectshape
+
+// Graph is the shapes reachable from the roots, resolved eagerly. (see Config.Graph)
+//
+// Each type has exactly one shape shared by all the references, so the recursive types become the cyclic references between the shapes
+// instead of the infinite recursion. The shared shapes have no pointer levels (Lv is 0).
+type Graph struct {
+	Roots []*Shape
+	Nodes []*Shape // in the order of the discovery (breadth first)
+
+	index map[ID]*Shape
+	edges map[ID][]*Shape
+}
+
+// Graph extracts the shapes of obs and the shapes reachable from them (fields, elements, arguments, ...), with the cycle detection.
+func (c *Config) Graph(obs ...interface{}) *Graph {
+	c.init()
+	e := c.extractor
+	g := &Graph{index: map[ID]*Shape{}, edges: map[ID][]*Shape{}}
+
+	var queue []*Shape
+	node := func(s *Shape) *Shape {
+		if n, ok := g.index[s.ID]; ok {
+			return n
+		}
+		n := s.Deref()
+		g.index[s.ID] = n
+		g.Nodes = append(g.Nodes, n)
+		queue = append(queue, n)
+		return n
+	}
+
+	for _, ob := range obs {
+		s := e.Extract(ob)
+		if s == nil { // vetoed
+			continue
+		}
+		g.Roots = append(g.Roots, node(s))
+	}
+	for len(queue) > 0 {
+		s := queue[0]
+		queue = queue[1:]
+
+		var deps []*Shape
+		seen := map[ID]bool{}
+		for _, dep := range e.dependencies(s) {
+			if seen[dep.ID] {
+				continue
+			}
+			seen[dep.ID] = true
+			deps = append(deps, node(dep))
+		}
+		g.edges[s.ID] = deps
+	}
+	return g
+}
+
+// Node returns the shared shape of the same type as s, or nil if not reachable.
+func (g *Graph) Node(s *Shape) *Shape {
+	return g.index[s.ID]
+}
+
+// Dependencies returns the shared shapes directly referenced by s. (without duplicates)
+func (g *Graph) Dependencies(s *Shape) []*Shape {
+	return g.edges[s.ID]
+}
+
+// IsRecursive reports whether s references itself, directly or indirectly. (e.g. type Node struct { Next *Node })
+func (g *Graph) IsRecursive(s *Shape) bool {
+	visited := map[ID]bool{}
+	stack := append([]*Shape(nil), g.edges[s.ID]...)
+	for len(stack) > 0 {
+		x := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if x.ID == s.ID {
+			return true
+		}
+		if visited[x.ID] {
+			continue
+		}
+		visited[x.ID] = true
+		stack = append(stack, g.edges[x.ID]...)
+	}
+	return false
+}
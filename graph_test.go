@@ -0,0 +1,100 @@
+package reflectshape_test
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	reflectshape "github.com/podhmo/reflect-shape"
+	"github.com/podhmo/reflect-shape/testdata/recursive"
+)
+
+// Page references the recursive type of the other package, and itself.
+type Page struct {
+	Root *recursive.Tree
+	Refs []*Page
+}
+
+func TestGraph(t *testing.T) {
+	names := func(shapes []*reflectshape.Shape) []string {
+		r := make([]string, len(shapes))
+		for i, s := range shapes {
+			r[i] = s.FullName()
+		}
+		return r
+	}
+
+	cases := []struct {
+		msg       string
+		input     any
+		nodes     []string
+		recursive map[string]bool
+	}{
+		{
+			msg:   "self",
+			input: recursive.Node{},
+			nodes: []string{"github.com/podhmo/reflect-shape/testdata/recursive.Node", ".int"},
+			recursive: map[string]bool{
+				"github.com/podhmo/reflect-shape/testdata/recursive.Node": true,
+			},
+		},
+		{
+			msg:   "mutual",
+			input: &recursive.Expr{},
+			nodes: []string{"github.com/podhmo/reflect-shape/testdata/recursive.Expr", ".string", "[]*recursive.Term", "github.com/podhmo/reflect-shape/testdata/recursive.Term"},
+			recursive: map[string]bool{
+				"github.com/podhmo/reflect-shape/testdata/recursive.Expr": true,
+				"github.com/podhmo/reflect-shape/testdata/recursive.Term": true,
+				"[]*recursive.Term": true,
+			},
+		},
+		{
+			msg:   "across-packages",
+			input: Page{},
+			nodes: []string{
+				"github.com/podhmo/reflect-shape_test.Page", "github.com/podhmo/reflect-shape/testdata/recursive.Tree", "[]*reflectshape_test.Page",
+				"map[string]*recursive.Tree", ".string",
+			},
+			recursive: map[string]bool{
+				"github.com/podhmo/reflect-shape_test.Page":               true,
+				"github.com/podhmo/reflect-shape/testdata/recursive.Tree": true,
+				"[]*reflectshape_test.Page":                               true,
+				"map[string]*recursive.Tree":                              true,
+			},
+		},
+	}
+
+	for _, c := range cases {
+		c := c
+		t.Run(c.msg, func(t *testing.T) {
+			cfg := &reflectshape.Config{SkipComments: true}
+			g := cfg.Graph(c.input)
+			if diff := cmp.Diff(c.nodes, names(g.Nodes)); diff != "" {
+				t.Errorf("Graph.Nodes: mismatch (-want, +got): \n%v", diff)
+			}
+			for _, s := range g.Nodes {
+				if want, got := c.recursive[s.FullName()], g.IsRecursive(s); want != got {
+					t.Errorf("Graph.IsRecursive(%s), want:%v != got:%v", s.FullName(), want, got)
+				}
+			}
+		})
+	}
+
+	t.Run("shared", func(t *testing.T) {
+		cfg := &reflectshape.Config{SkipComments: true}
+		g := cfg.Graph(recursive.Node{}, &recursive.Node{})
+		if g.Roots[0] != g.Roots[1] {
+			t.Errorf("Graph.Roots, the same type must be the same shape")
+		}
+		root := g.Roots[0]
+		next := g.Dependencies(root)[1] // Value, Next
+		if next != root {
+			t.Errorf("Graph.Dependencies(), the recursive reference must be the shared shape, got %v", next)
+		}
+		if want, got := 0, next.Lv; want != got {
+			t.Errorf("Graph.Dependencies()[1].Lv, want:%v != got:%v", want, got)
+		}
+		if g.Node(cfg.Extract(&recursive.Node{})) != root {
+			t.Errorf("Graph.Node(), must return the shared shape")
+		}
+	})
+}
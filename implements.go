@@ -0,0 +1,48 @@
+package reflectshape
+
+import (
+	"reflect"
+	"sort"
+
+	"github.com/podhmo/reflect-shape/metadata/runtimeinfo"
+)
+
+// Implementations returns the shapes of the concrete types implementing the interface, in the order of the full names.
+// If only *T implements it, the shape of *T (Lv=1) is returned.
+//
+// The candidates are the named types extracted before and the ones linked into the binary (see runtimeinfo.Accessor.Types).
+// If the runtime internals are not supported, only the extracted ones are the candidates.
+func (iface *Interface) Implementations() []*Shape {
+	rt := iface.Shape.Type
+	e := iface.Shape.e
+
+	candidates := runtimeinfo.New().Types()
+	e.mu.Lock()
+	for id := range e.seen {
+		if id.pc == 0 {
+			candidates = append(candidates, id.rt)
+		}
+	}
+	e.mu.Unlock()
+
+	var r []*Shape
+	seen := map[reflect.Type]bool{}
+	for _, t := range candidates {
+		if seen[t] || t.Name() == "" || t.Kind() == reflect.Interface {
+			continue
+		}
+		seen[t] = true
+		switch {
+		case t.Implements(rt):
+		case reflect.PointerTo(t).Implements(rt):
+			t = reflect.PointerTo(t)
+		default:
+			continue
+		}
+		if s := e.extract(t, rzero(t)); s != nil {
+			r = append(r, s)
+		}
+	}
+	sort.SliceStable(r, func(i, j int) bool { return r[i].FullName() < r[j].FullName() })
+	return r
+}
@@ -0,0 +1,77 @@
+package reflectshape_test
+
+import (
+	"reflect"
+	"testing"
+
+	reflectshape "github.com/podhmo/reflect-shape"
+	"github.com/podhmo/reflect-shape/metadata/runtimeinfo"
+)
+
+type Notifier interface {
+	Notify(msg string) error
+}
+
+type mailNotifier struct{}
+
+func (mailNotifier) Notify(msg string) error { return nil }
+
+type slackNotifier struct{}
+
+func (*slackNotifier) Notify(msg string) error { return nil }
+
+type Duration interface {
+	Hours() float64
+}
+
+func TestImplementations(t *testing.T) {
+	type impl struct {
+		Name string
+		Lv   int
+	}
+	implementations := func(cfg *reflectshape.Config, iface any) []impl {
+		var r []impl
+		for _, s := range cfg.Extract(iface).Interface().Implementations() {
+			if s.Package.Path != "github.com/podhmo/reflect-shape_test" {
+				continue
+			}
+			r = append(r, impl{Name: s.Name, Lv: s.Lv})
+		}
+		return r
+	}
+
+	t.Run("binary", func(t *testing.T) {
+		if !runtimeinfo.Probe().Modules {
+			t.Skip("the runtime internals are not supported")
+		}
+		cfg := &reflectshape.Config{SkipComments: true}
+		want := []impl{{Name: "mailNotifier"}, {Name: "slackNotifier", Lv: 1}} // not extracted, but linked (see below)
+		if got := implementations(cfg, new(Notifier)); !reflect.DeepEqual(want, got) {
+			t.Errorf("Interface.Implementations(), want:%+v != got:%+v", want, got)
+		}
+	})
+
+	t.Run("extracted", func(t *testing.T) {
+		cfg := &reflectshape.Config{SkipComments: true}
+		for _, n := range []Notifier{mailNotifier{}, &slackNotifier{}} {
+			cfg.Extract(n)
+		}
+		want := []impl{{Name: "mailNotifier"}, {Name: "slackNotifier", Lv: 1}}
+		if got := implementations(cfg, new(Notifier)); !reflect.DeepEqual(want, got) {
+			t.Errorf("Interface.Implementations(), want:%+v != got:%+v", want, got)
+		}
+	})
+
+	t.Run("other-packages", func(t *testing.T) {
+		cfg := &reflectshape.Config{SkipComments: true}
+		var found bool
+		for _, s := range cfg.Extract(new(Duration)).Interface().Implementations() {
+			if s.FullName() == "time.Duration" {
+				found = true
+			}
+		}
+		if !found {
+			t.Errorf("Interface.Implementations(), time.Duration is not found")
+		}
+	})
+}
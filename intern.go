@@ -0,0 +1,32 @@
+package reflectshape
+
+// interner deduplicates the strings held by the shapes. (see Config.InternStrings)
+type interner struct {
+	strings map[string]string
+	bytes   int // the total size of the interned strings
+	saved   int // the total size of the duplicated strings, replaced by the interned ones (counted per replacement)
+}
+
+// intern returns the canonical string equal to s. If in is nil, s is returned as is.
+func (in *interner) intern(s string) string {
+	if in == nil || s == "" {
+		return s
+	}
+	if canonical, ok := in.strings[s]; ok {
+		in.saved += len(s)
+		return canonical
+	}
+	in.strings[s] = s
+	in.bytes += len(s)
+	return s
+}
+
+// intern returns the canonical string equal to s, if Config.InternStrings is true.
+func (e *Extractor) intern(s string) string {
+	if e.interner == nil {
+		return s
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.interner.intern(s)
+}
@@ -0,0 +1,53 @@
+package reflectshape_test
+
+import (
+	"testing"
+
+	reflectshape "github.com/podhmo/reflect-shape"
+)
+
+type InternedUser struct {
+	// the name of the user
+	// ---- profile ----
+	Name string
+}
+
+type InternedAdmin struct {
+	// the name of the user
+	Name string
+}
+
+func TestInternStrings(t *testing.T) {
+	cfg := &reflectshape.Config{IncludeGoTestFiles: true, InternStrings: true}
+	docs := []string{
+		cfg.Extract(InternedUser{}).Struct().Fields()[0].Doc,
+		cfg.Extract(InternedAdmin{}).Struct().Fields()[0].Doc,
+	}
+	if want, got := "the name of the user", docs[0]; want != got {
+		t.Errorf("Field.Doc: want:%q != got:%q", want, got)
+	}
+
+	stats := cfg.CacheStats()
+	if stats.InternedStrings == 0 || stats.InternedBytes == 0 {
+		t.Errorf("CacheStats(): the strings are not interned: %+v", stats)
+	}
+	if want, got := len(docs[1]), stats.InternSavedBytes; want != got {
+		t.Errorf("CacheStats().InternSavedBytes: want:%d != got:%d", want, got)
+	}
+
+	t.Run("repeated", func(t *testing.T) {
+		cfg.Extract(InternedUser{}).Struct().Fields()
+		cfg.Extract(InternedAdmin{}).Struct().Fields()
+		if want, got := stats.InternSavedBytes, cfg.CacheStats().InternSavedBytes; want != got {
+			t.Errorf("CacheStats().InternSavedBytes: the cached fields are interned again: want:%d != got:%d", want, got)
+		}
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		cfg := &reflectshape.Config{IncludeGoTestFiles: true}
+		cfg.Extract(InternedUser{}).Struct().Fields()
+		if want, got := 0, cfg.CacheStats().InternedStrings; want != got {
+			t.Errorf("CacheStats().InternedStrings: want:%d != got:%d", want, got)
+		}
+	})
+}
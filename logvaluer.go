@@ -0,0 +1,172 @@
+package reflectshape
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"io"
+	"reflect"
+)
+
+// LogValuerGenerator generates the logging helpers of the structs, building slog.GroupValue with the typed attributes.
+//
+//	type User struct {
+//		Name     string `json:"name"`
+//		Age      int    `json:"age"`
+//		Password string `json:"password"` // +sensitive
+//	}
+//
+// For each struct, the LogValue() slog.Value method (slog.LogValuer) is generated, if the generated code is in the package of the struct.
+// Otherwise, the LogValue<Type>(v <Type>) slog.Value function is generated. The key of the attribute is the name of the json tag, or the field name.
+// The fields marked as sensitive (see SensitiveMarker) are logged as Redacted, and the unexported fields and the fields of json:"-" are not logged.
+//
+// The nested structs are logged by their LogValue, if they are generated together. Otherwise, they are logged by slog.Any as is,
+// so pass all the structs having the sensitive fields.
+type LogValuerGenerator struct {
+	PkgPath  string // the package path of the generated code (default is the package of the first shape)
+	Redacted string // the value of the sensitive fields (default is "[REDACTED]")
+}
+
+// Generate writes the gofmt-ed logging helpers of the structs to w.
+func (g *LogValuerGenerator) Generate(w io.Writer, shapes ...*Shape) error {
+	if len(shapes) == 0 {
+		return fmt.Errorf("no shapes")
+	}
+	pkgpath := g.PkgPath
+	if pkgpath == "" {
+		pkgpath = shapes[0].Package.Path
+	}
+	redacted := g.Redacted
+	if redacted == "" {
+		redacted = "[REDACTED]"
+	}
+
+	imports := NewImports(pkgpath)
+	imports.Import("log/slog", "slog")
+	lg := &logValuerGen{imports: imports, redacted: redacted, generated: map[reflect.Type]bool{}}
+	for _, s := range shapes {
+		if s.Kind != reflect.Struct || s.Name == "" {
+			return fmt.Errorf("%v is not named struct", s)
+		}
+		lg.generated[s.Type] = true
+	}
+	for _, s := range shapes {
+		lg.generate(s.Deref().Struct())
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintln(&buf, "// Code generated by reflect-shape. DO NOT EDIT.")
+	fmt.Fprintln(&buf)
+	fmt.Fprintf(&buf, "package %s\n\n", guessPackageName(pkgpath))
+	fmt.Fprintln(&buf, imports.String())
+	buf.Write(lg.body.Bytes())
+
+	code, err := format.Source(buf.Bytes())
+	if err != nil {
+		return fmt.Errorf("format generated code: %w", err)
+	}
+	_, err = w.Write(code)
+	return err
+}
+
+type logValuerGen struct {
+	imports   *Imports
+	redacted  string
+	generated map[reflect.Type]bool // the structs generated together
+	body      bytes.Buffer
+}
+
+func (g *logValuerGen) printf(format string, args ...interface{}) {
+	fmt.Fprintf(&g.body, format, args...)
+}
+
+// funcName returns the name of the generated helper of the struct, "" if it is the LogValue method.
+func (g *logValuerGen) funcName(rt reflect.Type) string {
+	if rt.PkgPath() == g.imports.Here {
+		return ""
+	}
+	return "LogValue" + rt.Name()
+}
+
+func (g *logValuerGen) generate(s *Struct) {
+	name := s.Shape.Name
+	typ := g.imports.TypeExpr(s.Shape)
+	if fn := g.funcName(s.Shape.Type); fn == "" {
+		g.printf("\n// LogValue implements slog.LogValuer, %s is logged as the group.\n", name)
+		g.printf("func (v %s) LogValue() slog.Value {\n", typ)
+	} else {
+		g.printf("\n// %s returns the value of %s for logging, as the group.\n", fn, name)
+		g.printf("func %s(v %s) slog.Value {\n", fn, typ)
+	}
+	var attrs []string
+	for _, f := range s.Fields() {
+		if !f.IsExported() || f.Tag.Get("json") == "-" {
+			continue
+		}
+		key := tagName(f.Tag, "json")
+		if key == "" {
+			key = f.Name
+		}
+		switch {
+		case f.IsSensitive():
+			attrs = append(attrs, fmt.Sprintf("slog.String(%q, %q)", key, g.redacted))
+		case f.Shape.Lv == 1 && g.generated[f.Shape.Type]:
+			// the LogValue of the value receiver panics with the nil pointer, so dereferenced
+			g.printf("%s := slog.Any(%q, nil)\n", "attr"+f.Name, key)
+			g.printf("if v.%s != nil {\n", f.Name)
+			g.printf("%s = %s\n", "attr"+f.Name, g.attr(key, "*v."+f.Name, f.Shape.Deref()))
+			g.printf("}\n")
+			attrs = append(attrs, "attr"+f.Name)
+		default:
+			attrs = append(attrs, g.attr(key, "v."+f.Name, f.Shape))
+		}
+	}
+	g.printf("return slog.GroupValue(\n")
+	for _, attr := range attrs {
+		g.printf("%s,\n", attr)
+	}
+	g.printf(")\n")
+	g.printf("}\n")
+}
+
+// attr returns the expression of the typed attribute of the field.
+func (g *logValuerGen) attr(key, expr string, s *Shape) string {
+	rt := s.Type
+	if s.Lv > 0 {
+		return fmt.Sprintf("slog.Any(%q, %s)", key, expr)
+	}
+	switch {
+	case rt.PkgPath() == "time" && rt.Name() == "Time":
+		return fmt.Sprintf("slog.Time(%q, %s)", key, expr)
+	case rt.PkgPath() == "time" && rt.Name() == "Duration":
+		return fmt.Sprintf("slog.Duration(%q, %s)", key, expr)
+	case g.generated[rt]:
+		if fn := g.funcName(rt); fn != "" {
+			return fmt.Sprintf("slog.Attr{Key: %q, Value: %s(%s)}", key, fn, expr)
+		}
+		return fmt.Sprintf("slog.Any(%q, %s)", key, expr) // resolved by LogValue
+	}
+
+	switch rt.Kind() {
+	case reflect.String:
+		return fmt.Sprintf("slog.String(%q, %s)", key, convExpr("string", expr, rt))
+	case reflect.Bool:
+		return fmt.Sprintf("slog.Bool(%q, %s)", key, convExpr("bool", expr, rt))
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return fmt.Sprintf("slog.Int64(%q, %s)", key, convExpr("int64", expr, rt))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return fmt.Sprintf("slog.Uint64(%q, %s)", key, convExpr("uint64", expr, rt))
+	case reflect.Float32, reflect.Float64:
+		return fmt.Sprintf("slog.Float64(%q, %s)", key, convExpr("float64", expr, rt))
+	default:
+		return fmt.Sprintf("slog.Any(%q, %s)", key, expr)
+	}
+}
+
+// convExpr returns the conversion of expr to the builtin type, omitted if expr is already of it.
+func convExpr(builtin, expr string, rt reflect.Type) string {
+	if rt.PkgPath() == "" && rt.Name() == builtin {
+		return expr
+	}
+	return builtin + "(" + expr + ")"
+}
@@ -0,0 +1,96 @@
+package reflectshape_test
+
+import (
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	reflectshape "github.com/podhmo/reflect-shape"
+)
+
+type Level int
+
+// Member is the member of the team.
+type Member struct {
+	Name      string    `json:"name"`
+	Level     Level     `json:"level"`
+	Token     string    `json:"token"` // +sensitive
+	Team      Team      `json:"team"`
+	Leader    *Member   `json:"leader"`
+	JoinedAt  time.Time `json:"joinedAt"`
+	Tags      []string
+	Password  string `json:"-"`
+	lastLogin time.Time
+}
+
+// Team is the team.
+type Team struct {
+	Name string
+}
+
+func TestLogValuerGenerator(t *testing.T) {
+	cfg := &reflectshape.Config{IncludeGoTestFiles: true}
+	shapes := []*reflectshape.Shape{cfg.Extract(Member{}), cfg.Extract(Team{})}
+
+	t.Run("method", func(t *testing.T) {
+		g := &reflectshape.LogValuerGenerator{}
+		var buf strings.Builder
+		if err := g.Generate(&buf, shapes...); err != nil {
+			t.Fatalf("unexpected error: %+v", err)
+		}
+		code := buf.String()
+		for _, want := range []string{
+			"package reflectshape_test\n",
+			"\t\"log/slog\"\n",
+			"// LogValue implements slog.LogValuer, Member is logged as the group.\nfunc (v Member) LogValue() slog.Value {\n",
+			"\tattrLeader := slog.Any(\"leader\", nil)\n\tif v.Leader != nil {\n\t\tattrLeader = slog.Any(\"leader\", *v.Leader)\n\t}\n", // not panicked with nil
+			"\t\tslog.String(\"name\", v.Name),\n",
+			"\t\tslog.Int64(\"level\", int64(v.Level)),\n",
+			"\t\tslog.String(\"token\", \"[REDACTED]\"),\n",
+			"\t\tslog.Any(\"team\", v.Team),\n",
+			"\t\tattrLeader,\n",
+			"\t\tslog.Time(\"joinedAt\", v.JoinedAt),\n",
+			"\t\tslog.Any(\"Tags\", v.Tags),\n\t)\n",
+			"func (v Team) LogValue() slog.Value {",
+		} {
+			if !strings.Contains(code, want) {
+				t.Errorf("Generate(): %q is not found in\n%s", want, code)
+			}
+		}
+		if strings.Contains(code, "lastLogin") {
+			t.Errorf("Generate(): the unexported field must not be logged, but\n%s", code)
+		}
+		if strings.Contains(code, "Password") {
+			t.Errorf("Generate(): the field of json:\"-\" must not be logged, but\n%s", code)
+		}
+	})
+
+	t.Run("func", func(t *testing.T) {
+		g := &reflectshape.LogValuerGenerator{PkgPath: "github.com/foo/logging", Redacted: "***"}
+		var buf strings.Builder
+		if err := g.Generate(&buf, shapes...); err != nil {
+			t.Fatalf("unexpected error: %+v", err)
+		}
+		code := buf.String()
+		for _, want := range []string{
+			"package logging\n",
+			"\t\"github.com/podhmo/reflect-shape_test\"\n",
+			"// LogValueMember returns the value of Member for logging, as the group.\nfunc LogValueMember(v reflectshape_test.Member) slog.Value {",
+			"\t\tslog.String(\"token\", \"***\"),\n",
+			"\t\tslog.Attr{Key: \"team\", Value: LogValueTeam(v.Team)},\n",
+			"\t\tattrLeader = slog.Attr{Key: \"leader\", Value: LogValueMember(*v.Leader)}\n",
+		} {
+			if !strings.Contains(code, want) {
+				t.Errorf("Generate(): %q is not found in\n%s", want, code)
+			}
+		}
+	})
+
+	t.Run("not-struct", func(t *testing.T) {
+		g := &reflectshape.LogValuerGenerator{}
+		if err := g.Generate(io.Discard, cfg.Extract(0)); err == nil {
+			t.Errorf("Generate(): error is expected")
+		}
+	})
+}
@@ -0,0 +1,284 @@
+package reflectshape
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Markers are the "+key=value" and "+key" lines in the doc comments, keyed by the names. (the flag marker has "" as the value)
+//
+//	// Person is the person.
+//	// +kubebuilder:object:root=true
+//	// +kubebuilder:printcolumn:name="Age",type="integer"
+//	// +kubebuilder:printcolumn:name="Name",type="string"
+//
+// The marker can be repeated, so the values are kept in order.
+type Markers map[string][]string
+
+// Get returns the first value of the marker.
+func (m Markers) Get(name string) (string, bool) {
+	values, ok := m[name]
+	if !ok || len(values) == 0 {
+		return "", false
+	}
+	return values[0], true
+}
+
+// Has reports whether the marker is found.
+func (m Markers) Has(name string) bool {
+	_, ok := m[name]
+	return ok
+}
+
+// Names returns the sorted names of the markers.
+func (m Markers) Names() []string {
+	names := make([]string, 0, len(m))
+	for name := range m {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+var markerNameRx = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_.:/-]*$`)
+
+// ParseMarkers returns the markers in the doc, and the rest of the doc. (the markers are removed)
+// The lines starting with "+" but not having the marker name (e.g. "+1 for this") are kept as the text.
+func ParseMarkers(doc string) (Markers, string) {
+	if !strings.Contains(doc, "+") {
+		return nil, doc
+	}
+	var markers Markers
+	var lines []string
+	for _, line := range strings.Split(doc, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if !strings.HasPrefix(trimmed, "+") {
+			lines = append(lines, line)
+			continue
+		}
+		name, value, _ := strings.Cut(trimmed[1:], "=")
+		if !markerNameRx.MatchString(name) {
+			lines = append(lines, line)
+			continue
+		}
+		if markers == nil {
+			markers = Markers{}
+		}
+		markers[name] = append(markers[name], value)
+	}
+	return markers, strings.TrimSpace(strings.Join(lines, "\n"))
+}
+
+// Markers returns the markers in the doc of the shape.
+func (s *Shape) Markers() Markers {
+	m, _ := ParseMarkers(s.doc())
+	return m
+}
+
+// Markers returns the markers in the doc of the field.
+func (f *Field) Markers() Markers {
+	m, _ := ParseMarkers(f.Doc)
+	return m
+}
+
+// SensitiveMarker is the marker of the sensitive field (e.g. the password), redacted by DocServer and LogValuerGenerator.
+//
+//	type User struct {
+//		Name     string
+//		Password string // +sensitive
+//	}
+const SensitiveMarker = "sensitive"
+
+// IsSensitive reports whether the field is marked with SensitiveMarker.
+func (f *Field) IsSensitive() bool {
+	return f.Markers().Has(SensitiveMarker)
+}
+
+// MarkerType is the type of the marker value.
+type MarkerType int
+
+const (
+	MarkerFlag   MarkerType = iota // +name (no value)
+	MarkerString                   // +name=value (the quotes are optional)
+	MarkerInt                      // +name=1
+	MarkerNumber                   // +name=1.5
+	MarkerBool                     // +name=true (+name is also true)
+	MarkerList                     // +name=a;b;c
+)
+
+// MarkerDefinition is the schema of the marker, used for the validation.
+type MarkerDefinition struct {
+	Name       string // e.g. "kubebuilder:validation:Minimum"
+	Type       MarkerType
+	Repeatable bool   // if true, the marker can be written more than once
+	Help       string // the description of the marker
+}
+
+// Parse parses the value of the marker by its type. (string, int64, float64, bool, []string, or nil for the flag)
+func (d *MarkerDefinition) Parse(value string) (interface{}, error) {
+	switch d.Type {
+	case MarkerFlag:
+		if value != "" {
+			return nil, fmt.Errorf("marker %q is flag, but has the value %q", d.Name, value)
+		}
+		return nil, nil
+	case MarkerString:
+		if unquoted, err := strconv.Unquote(value); err == nil {
+			return unquoted, nil
+		}
+		return value, nil
+	case MarkerInt:
+		v, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("marker %q: %w", d.Name, err)
+		}
+		return v, nil
+	case MarkerNumber:
+		v, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return nil, fmt.Errorf("marker %q: %w", d.Name, err)
+		}
+		return v, nil
+	case MarkerBool:
+		if value == "" {
+			return true, nil
+		}
+		v, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, fmt.Errorf("marker %q: %w", d.Name, err)
+		}
+		return v, nil
+	case MarkerList:
+		return strings.Split(strings.Trim(value, "{}"), ";"), nil
+	default:
+		return nil, fmt.Errorf("marker %q: unknown type %d", d.Name, d.Type)
+	}
+}
+
+// MarkerError is the error of the invalid marker.
+type MarkerError struct {
+	Target string // e.g. "github.com/foo/bar.Person.Name"
+	Name   string
+	Err    error
+}
+
+func (e *MarkerError) Error() string {
+	return fmt.Sprintf("%s: +%s: %v", e.Target, e.Name, e.Err)
+}
+
+func (e *MarkerError) Unwrap() error {
+	return e.Err
+}
+
+// MarkerErrors is the errors of the validation, for each invalid marker.
+type MarkerErrors []*MarkerError
+
+func (errs MarkerErrors) Error() string {
+	parts := make([]string, len(errs))
+	for i, err := range errs {
+		parts[i] = err.Error()
+	}
+	return fmt.Sprintf("%d errors occurred: %s", len(errs), strings.Join(parts, "; "))
+}
+
+// MarkerRegistry is the set of the marker definitions, for validating the markers. (like controller-gen)
+//
+// The markers not registered are ignored, unless their namespace (the part before the first ":", e.g. "kubebuilder")
+// is the namespace of some definition. So the typo like +kubebuilder:validaton:Minimum is reported.
+type MarkerRegistry struct {
+	mu   sync.RWMutex
+	defs map[string]*MarkerDefinition
+}
+
+func NewMarkerRegistry() *MarkerRegistry {
+	return &MarkerRegistry{defs: map[string]*MarkerDefinition{}}
+}
+
+// Register registers the definitions. It is failed if the name is registered twice.
+func (r *MarkerRegistry) Register(defs ...*MarkerDefinition) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, d := range defs {
+		if !markerNameRx.MatchString(d.Name) {
+			return fmt.Errorf("invalid marker name %q", d.Name)
+		}
+		if _, dup := r.defs[d.Name]; dup {
+			return fmt.Errorf("marker %q is already registered", d.Name)
+		}
+		r.defs[d.Name] = d
+	}
+	return nil
+}
+
+// Lookup returns the definition of the marker.
+func (r *MarkerRegistry) Lookup(name string) (*MarkerDefinition, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	d, ok := r.defs[name]
+	return d, ok
+}
+
+// Validate validates the markers of the target. The error is MarkerErrors.
+func (r *MarkerRegistry) Validate(target string, m Markers) error {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var errs MarkerErrors
+	for _, name := range m.Names() {
+		values := m[name]
+		d, ok := r.defs[name]
+		if !ok {
+			if r.isKnownNamespace(name) {
+				errs = append(errs, &MarkerError{Target: target, Name: name, Err: fmt.Errorf("unknown marker")})
+			}
+			continue
+		}
+		if len(values) > 1 && !d.Repeatable {
+			errs = append(errs, &MarkerError{Target: target, Name: name, Err: fmt.Errorf("cannot be repeated (%d times)", len(values))})
+		}
+		for _, v := range values {
+			if _, err := d.Parse(v); err != nil {
+				errs = append(errs, &MarkerError{Target: target, Name: name, Err: err})
+			}
+		}
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// ValidateStruct validates the markers of the struct and its fields. The error is MarkerErrors.
+func (r *MarkerRegistry) ValidateStruct(s *Struct) error {
+	var errs MarkerErrors
+	collect := func(err error) {
+		if err != nil {
+			errs = append(errs, err.(MarkerErrors)...)
+		}
+	}
+	collect(r.Validate(s.Shape.FullName(), s.Shape.Markers()))
+	for _, f := range s.Fields() {
+		collect(r.Validate(s.Shape.FullName()+"."+f.Name, f.Markers()))
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+func (r *MarkerRegistry) isKnownNamespace(name string) bool {
+	ns, _, ok := strings.Cut(name, ":")
+	if !ok {
+		return false
+	}
+	for defname := range r.defs {
+		if strings.HasPrefix(defname, ns+":") {
+			return true
+		}
+	}
+	return false
+}
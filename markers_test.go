@@ -0,0 +1,125 @@
+package reflectshape_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	reflectshape "github.com/podhmo/reflect-shape"
+)
+
+func TestParseMarkers(t *testing.T) {
+	cases := []struct {
+		msg         string
+		doc         string
+		wantMarkers reflectshape.Markers
+		wantDoc     string
+	}{
+		{msg: "no-markers", doc: "Person is the person.", wantDoc: "Person is the person."},
+		{msg: "flag", doc: "Person is the person.\n+optional", wantMarkers: reflectshape.Markers{"optional": {""}}, wantDoc: "Person is the person."},
+		{msg: "key-value", doc: "+kubebuilder:validation:Minimum=1\nthe age.", wantMarkers: reflectshape.Markers{"kubebuilder:validation:Minimum": {"1"}}, wantDoc: "the age."},
+		{msg: "repeated", doc: "+printcolumn:name=\"Age\"\n+printcolumn:name=\"Name\"", wantMarkers: reflectshape.Markers{"printcolumn:name": {`"Age"`, `"Name"`}}, wantDoc: ""},
+		{msg: "not-marker", doc: "+1 for this\n+ a list item", wantDoc: "+1 for this\n+ a list item"},
+	}
+	for _, c := range cases {
+		c := c
+		t.Run(c.msg, func(t *testing.T) {
+			markers, doc := reflectshape.ParseMarkers(c.doc)
+			if diff := cmp.Diff(c.wantMarkers, markers); diff != "" {
+				t.Errorf("ParseMarkers() markers mismatch (-want, +got): \n%v", diff)
+			}
+			if want, got := c.wantDoc, doc; want != got {
+				t.Errorf("ParseMarkers() doc: want:%q != got:%q", want, got)
+			}
+		})
+	}
+}
+
+// Cluster is the cluster.
+// +example:root
+type Cluster struct {
+	// Size is the number of the nodes.
+	// +example:min=1
+	// +example:min=2
+	Size int
+
+	// +example:mni=1
+	// +example:name=main
+	Name string
+}
+
+func TestMarkerRegistry(t *testing.T) {
+	cfg := &reflectshape.Config{IncludeGoTestFiles: true}
+	s := cfg.Extract(Cluster{}).Struct()
+
+	if want, got := true, s.Shape.Markers().Has("example:root"); want != got {
+		t.Errorf("Shape.Markers().Has(): want:%v != got:%v", want, got)
+	}
+	if v, _ := s.Fields()[1].Markers().Get("example:name"); v != "main" {
+		t.Errorf("Field.Markers().Get(): want:%v != got:%v", "main", v)
+	}
+
+	r := reflectshape.NewMarkerRegistry()
+	if err := r.Register(
+		&reflectshape.MarkerDefinition{Name: "example:root", Type: reflectshape.MarkerFlag},
+		&reflectshape.MarkerDefinition{Name: "example:min", Type: reflectshape.MarkerInt},
+		&reflectshape.MarkerDefinition{Name: "example:name", Type: reflectshape.MarkerString},
+	); err != nil {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+	if err := r.Register(&reflectshape.MarkerDefinition{Name: "example:root"}); err == nil {
+		t.Errorf("want error (duplicated), but nil")
+	}
+
+	err := r.ValidateStruct(s)
+	var errs reflectshape.MarkerErrors
+	if !errors.As(err, &errs) {
+		t.Fatalf("want MarkerErrors, but got %T: %+v", err, err)
+	}
+	got := make([]string, len(errs))
+	for i, err := range errs {
+		got[i] = err.Name
+	}
+	want := []string{"example:min", "example:mni"} // repeated, unknown (typo)
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("ValidateStruct() mismatch (-want, +got): \n%v", diff)
+	}
+}
+
+func TestMarkerDefinitionParse(t *testing.T) {
+	cases := []struct {
+		msg     string
+		typ     reflectshape.MarkerType
+		value   string
+		want    interface{}
+		wantErr bool
+	}{
+		{msg: "flag", typ: reflectshape.MarkerFlag, value: "", want: nil},
+		{msg: "flag-with-value", typ: reflectshape.MarkerFlag, value: "x", wantErr: true},
+		{msg: "string-quoted", typ: reflectshape.MarkerString, value: `"foo bar"`, want: "foo bar"},
+		{msg: "int", typ: reflectshape.MarkerInt, value: "10", want: int64(10)},
+		{msg: "int-invalid", typ: reflectshape.MarkerInt, value: "ten", wantErr: true},
+		{msg: "number", typ: reflectshape.MarkerNumber, value: "1.5", want: 1.5},
+		{msg: "bool-implicit", typ: reflectshape.MarkerBool, value: "", want: true},
+		{msg: "list", typ: reflectshape.MarkerList, value: "a;b", want: []string{"a", "b"}},
+	}
+	for _, c := range cases {
+		c := c
+		t.Run(c.msg, func(t *testing.T) {
+			d := &reflectshape.MarkerDefinition{Name: "x", Type: c.typ}
+			got, err := d.Parse(c.value)
+			if c.wantErr {
+				if err == nil {
+					t.Errorf("want error, but nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %+v", err)
+			}
+			if diff := cmp.Diff(c.want, got); diff != "" {
+				t.Errorf("Parse() mismatch (-want, +got): \n%v", diff)
+			}
+		})
+	}
+}
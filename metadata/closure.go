@@ -0,0 +1,115 @@
+package metadata
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"runtime"
+	"strings"
+
+	"github.com/podhmo/commentof/collect"
+	"github.com/podhmo/reflect-shape/metadata/runtimeinfo"
+)
+
+// lookupClosure returns the metadata of the closure (the func literal, e.g. "pkg.F.func1"), found in the source by the position of the entry.
+// The literal is parsed as the function named by the runtime name (e.g. "F.func1"), and the comment immediately preceding it is its doc.
+// If the literal is not found, the metadata has no args and no returns (Args() returns nil), but the enclosing function is resolved if possible.
+//
+// The enclosing function is resolved from the source, not from the name. (the closures of the inlined functions are named after the callers)
+func (l *Lookup) lookupClosure(pc uintptr, rfunc *runtime.Func, fn runtimeinfo.FuncName) (*Func, error) {
+	if m, ok := l.closures[fn.PkgPath][pc]; ok {
+		return m, nil
+	}
+	name := fn.Name + "." + fn.Closure
+	if fn.Recv != "" {
+		name = fn.Recv + "." + name
+	}
+
+	if len(l.loadSources()) == 0 {
+		return nil, l.unavailable(name, fn.PkgPath)
+	}
+
+	filename, line := rfunc.FileLine(rfunc.Entry())
+	f, err := parser.ParseFile(l.Fset, filename, nil, parser.ParseComments)
+	if f == nil {
+		return nil, &SourceUnavailableError{Symbol: name, PkgPath: fn.PkgPath, Filename: filename, Err: err}
+	}
+	f = normalizeGenericReceivers(f)
+
+	// the innermost literal including the entry line (the entry is the line of the first statement, not of the "func" keyword)
+	var enclosing *ast.FuncDecl
+	var lit *ast.FuncLit
+	ast.Inspect(f, func(node ast.Node) bool {
+		if node == nil {
+			return false
+		}
+		if l.Fset.Position(node.Pos()).Line > line || l.Fset.Position(node.End()).Line < line {
+			return false
+		}
+		switch node := node.(type) {
+		case *ast.FuncDecl:
+			enclosing = node
+		case *ast.FuncLit:
+			lit = node
+		}
+		return true
+	})
+
+	c := &collect.Collector{Fset: l.Fset, Dot: ".", Sharp: "#"}
+	collectFunc := func(decl *ast.FuncDecl) (*collect.Func, error) {
+		cf := collect.NewFile()
+		if err := c.CollectFromFuncDecl(cf, f, decl); err != nil {
+			return nil, fmt.Errorf("collect closure: %s: %w", filename, err)
+		}
+		return cf.Functions[cf.Names[len(cf.Names)-1]], nil
+	}
+
+	m := &Func{pc: pc, Raw: &collect.Func{Name: name}, Recv: fn.Recv, IsClosure: true}
+	if enclosing != nil {
+		raw, err := collectFunc(enclosing)
+		if err != nil {
+			return nil, err
+		}
+		m.Enclosing = &Func{Raw: raw, Recv: strings.TrimPrefix(raw.Recv, "*")}
+		m.Raw.Pos = enclosing.Pos()
+	}
+	if lit != nil {
+		decl := &ast.FuncDecl{Doc: precedingComment(l.Fset, f, lit, enclosing), Name: ast.NewIdent(name), Type: lit.Type, Body: lit.Body}
+		raw, err := collectFunc(decl)
+		if err != nil {
+			return nil, err
+		}
+		m.Raw = raw
+	} else if l.Trace {
+		l.logf("trace: closure %q -> the func literal is not found at %s:%d", rfunc.Name(), filename, line)
+	}
+
+	if l.closures == nil {
+		l.closures = map[string]map[uintptr]*Func{}
+	}
+	if l.closures[fn.PkgPath] == nil {
+		l.closures[fn.PkgPath] = map[uintptr]*Func{}
+	}
+	l.closures[fn.PkgPath][pc] = m
+	return m, nil
+}
+
+// precedingComment returns the comment group ending on the line before the literal, except the doc of the enclosing declaration.
+// (e.g. the comment of the statement or the variable, having the literal)
+func precedingComment(fset *token.FileSet, f *ast.File, lit *ast.FuncLit, enclosing *ast.FuncDecl) *ast.CommentGroup {
+	line := fset.Position(lit.Pos()).Line
+	for _, cg := range f.Comments {
+		if cg.Pos() > lit.Pos() {
+			break
+		}
+		if fset.Position(cg.End()).Line != line-1 {
+			continue
+		}
+		if enclosing != nil && cg == enclosing.Doc {
+			return nil
+		}
+		return cg
+	}
+	return nil
+}
@@ -0,0 +1,128 @@
+package metadata
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"runtime"
+	"sort"
+	"sync"
+
+	"github.com/podhmo/commentof/collect"
+)
+
+// collectPackage collects the comments of the package like commentof.Package, but the files are processed concurrently.
+// (see CollectConcurrency) The files are merged in the order of the filenames, so the result is the same as the sequential one.
+// The names declared in the multiple files are resolved deterministically. (see Redeclaration)
+func (l *Lookup) collectPackage(fset *token.FileSet, pkgpath string, tree *ast.Package) (*collect.Package, error) {
+	filenames := make([]string, 0, len(tree.Files))
+	for filename := range tree.Files {
+		filenames = append(filenames, filename)
+	}
+	sort.Strings(filenames)
+
+	c := &collect.Collector{Fset: fset, Dot: ".", Sharp: "#"}
+	files := make([]*collect.File, len(filenames))
+	errs := make([]error, len(filenames))
+	collectFile := func(i int) {
+		f := collect.NewFile()
+		if err := c.CollectFromFile(f, normalizeGenericReceivers(tree.Files[filenames[i]])); err != nil {
+			errs[i] = fmt.Errorf("collect file: %s: %w", filenames[i], err)
+			return
+		}
+		files[i] = f
+	}
+
+	workers := l.CollectConcurrency
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	if workers > len(filenames) {
+		workers = len(filenames)
+	}
+	if workers <= 1 {
+		for i := range filenames {
+			collectFile(i)
+		}
+	} else {
+		indices := make(chan int)
+		var wg sync.WaitGroup
+		for w := 0; w < workers; w++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for i := range indices {
+					collectFile(i)
+				}
+			}()
+		}
+		for i := range filenames {
+			indices <- i
+		}
+		close(indices)
+		wg.Wait()
+	}
+
+	b := &collect.PackageBuilder{Package: collect.NewPackage(), EnableMergeMethod: true, IgnoreExported: !l.IncludeUnexported}
+	for i := range filenames {
+		if errs[i] != nil {
+			for j := 0; j < i; j++ {
+				b.AddFile(files[j], filenames[j])
+			}
+			return b.Package, errs[i] // the first error in the order of the filenames, as the sequential one
+		}
+	}
+
+	trees := make([]*ast.File, len(filenames))
+	for i, filename := range filenames {
+		trees[i] = tree.Files[filename]
+	}
+	l.resolveRedeclarations(pkgpath, filenames, trees, files)
+	for i, filename := range filenames {
+		b.AddFile(files[i], filename)
+	}
+	return b.Build(), nil
+}
+
+// normalizeGenericReceivers returns the file, whose methods of the generic types have the plain receivers. (e.g. *List[T] -> *List)
+// commentof cannot collect them as the methods, because the receivers are not the names. The file is shallow-copied if needed.
+func normalizeGenericReceivers(f *ast.File) *ast.File {
+	var decls []ast.Decl
+	for i, decl := range f.Decls {
+		decl, ok := decl.(*ast.FuncDecl)
+		if !ok || decl.Recv == nil || len(decl.Recv.List) == 0 {
+			continue
+		}
+		recv := decl.Recv.List[0]
+		typ, isPointer := recv.Type, false
+		if star, ok := typ.(*ast.StarExpr); ok {
+			typ, isPointer = star.X, true
+		}
+		var ident ast.Expr
+		switch x := typ.(type) {
+		case *ast.IndexExpr:
+			ident = x.X
+		case *ast.IndexListExpr:
+			ident = x.X
+		default:
+			continue
+		}
+		if isPointer {
+			ident = &ast.StarExpr{Star: recv.Type.Pos(), X: ident}
+		}
+
+		if decls == nil {
+			decls = make([]ast.Decl, len(f.Decls))
+			copy(decls, f.Decls)
+		}
+		copied := *decl
+		copied.Recv = &ast.FieldList{Opening: decl.Recv.Opening, List: []*ast.Field{{Doc: recv.Doc, Names: recv.Names, Type: ident, Comment: recv.Comment}}, Closing: decl.Recv.Closing}
+		decls[i] = &copied
+	}
+	if decls == nil {
+		return f
+	}
+	copied := *f
+	copied.Decls = decls
+	return &copied
+}
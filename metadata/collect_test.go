@@ -0,0 +1,122 @@
+package metadata
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/podhmo/commentof"
+	"golang.org/x/tools/go/packages"
+)
+
+func TestCollectPackageConcurrency(t *testing.T) {
+	fset := token.NewFileSet()
+	pkgs, err := packages.Load(&packages.Config{Fset: fset, Mode: packages.NeedName | packages.NeedFiles | packages.NeedSyntax}, "net/http")
+	if err != nil {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+	tree := &ast.Package{Name: pkgs[0].Name, Files: map[string]*ast.File{}}
+	for _, f := range pkgs[0].Syntax {
+		tree.Files[fset.File(f.Pos()).Name()] = f
+	}
+
+	want, err := commentof.Package(fset, tree)
+	if err != nil {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+	l := NewLookup(fset)
+	for _, n := range []int{1, 0, 4} {
+		l.CollectConcurrency = n
+		got, err := l.collectPackage(fset, "net/http", tree)
+		if err != nil {
+			t.Fatalf("unexpected error: %+v", err)
+		}
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Errorf("collectPackage() with CollectConcurrency=%d mismatch (-commentof.Package, +got): \n%v", n, diff)
+		}
+	}
+}
+
+func BenchmarkCollectPackage(b *testing.B) {
+	fset := token.NewFileSet()
+	pkgs, err := packages.Load(&packages.Config{Fset: fset, Mode: packages.NeedName | packages.NeedFiles | packages.NeedSyntax}, "net/http")
+	if err != nil {
+		b.Fatalf("unexpected error: %+v", err)
+	}
+	tree := &ast.Package{Name: pkgs[0].Name, Files: map[string]*ast.File{}}
+	for _, f := range pkgs[0].Syntax {
+		tree.Files[fset.File(f.Pos()).Name()] = f
+	}
+
+	cases := []struct {
+		msg         string
+		concurrency int
+	}{
+		{msg: "sequential", concurrency: 1},
+		{msg: "concurrent", concurrency: 0},
+	}
+	for _, c := range cases {
+		l := NewLookup(fset)
+		l.CollectConcurrency = c.concurrency
+		b.Run(c.msg, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				if _, err := l.collectPackage(fset, "net/http", tree); err != nil {
+					b.Fatalf("unexpected error: %+v", err)
+				}
+			}
+		})
+	}
+}
+
+func TestCollectPackageRedeclared(t *testing.T) {
+	fset := token.NewFileSet()
+	pkgs, err := parser.ParseDir(fset, "testdata/redeclared", nil, parser.ParseComments) // the build constraints are ignored
+	if err != nil {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+	tree := pkgs["redeclared"]
+
+	cases := []struct {
+		msg        string
+		goos       string
+		redeclared func(r *Redeclaration) int
+		value      string
+		open       string
+	}{
+		{msg: "linux", goos: "linux", value: "Value is the hand-written declaration.", open: "Open is the declaration for linux."},
+		{msg: "windows", goos: "windows", value: "Value is the hand-written declaration.", open: "Open is the declaration for windows."},
+		{msg: "hook", goos: "windows", value: "Value is the generated declaration.", open: "Open is the declaration for windows.",
+			redeclared: func(r *Redeclaration) int {
+				for i, d := range r.Candidates {
+					if d.Generated {
+						return i
+					}
+				}
+				return -1
+			}},
+	}
+	for _, c := range cases {
+		c := c
+		t.Run(c.msg, func(t *testing.T) {
+			l := NewLookup(fset)
+			l.GOOS = c.goos
+			l.Redeclared = c.redeclared
+			p, err := l.collectPackage(fset, "example.com/redeclared", tree)
+			if err != nil {
+				t.Fatalf("unexpected error: %+v", err)
+			}
+			if want, got := c.value, strings.TrimSpace(p.Types["Value"].Doc); want != got {
+				t.Errorf("Types[Value].Doc: want:%q != got:%q", want, got)
+			}
+			if want, got := c.open, strings.TrimSpace(p.Functions["Open"].Doc); want != got {
+				t.Errorf("Functions[Open].Doc: want:%q != got:%q", want, got)
+			}
+			if diff := cmp.Diff([]string{"Open", "Value"}, p.Names); diff != "" {
+				t.Errorf("Names: mismatch (-want, +got): \n%v", diff)
+			}
+		})
+	}
+}
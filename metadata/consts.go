@@ -0,0 +1,151 @@
+package metadata
+
+import (
+	"go/ast"
+	"go/constant"
+	"go/token"
+	"go/types"
+	"strings"
+)
+
+// Const is the metadata of the constant, used as a member of enum.
+type Const struct {
+	Name  string
+	Value constant.Value // nil if the value cannot be evaluated
+	Doc   string
+}
+
+// collectConsts collects typed constants, grouped by type name.
+// The type of the untyped spec is inferred from the conversion. (e.g. Color = Name("color"))
+//
+//	type Ordering string
+//	const (
+//		OrderingDesc Ordering = "desc"
+//		OrderingAsc  Ordering = "asc"
+//	)
+func collectConsts(files []*ast.File) map[string][]Const {
+	r := map[string][]Const{}
+	for _, f := range files {
+		for _, decl := range f.Decls {
+			decl, ok := decl.(*ast.GenDecl)
+			if !ok || decl.Tok != token.CONST {
+				continue
+			}
+
+			env := map[string]constant.Value{}
+			var typename string
+			var values []ast.Expr
+			for iota, spec := range decl.Specs {
+				spec := spec.(*ast.ValueSpec)
+				if spec.Type != nil || len(spec.Values) > 0 { // not implicit repetition
+					typename = ""
+					if ident, ok := spec.Type.(*ast.Ident); ok {
+						typename = ident.Name
+					}
+					values = spec.Values
+				}
+
+				for i, name := range spec.Names {
+					var val constant.Value
+					typename := typename
+					if i < len(values) {
+						val = evalConstSafe(values[i], iota, env)
+						env[name.Name] = val
+						if typename == "" {
+							typename = conversionType(values[i])
+						}
+					}
+					if typename == "" || name.Name == "_" {
+						continue
+					}
+
+					doc := ""
+					if spec.Doc != nil {
+						doc = spec.Doc.Text()
+					}
+					if doc == "" && spec.Comment != nil {
+						doc = spec.Comment.Text()
+					}
+					r[typename] = append(r[typename], Const{Name: name.Name, Value: val, Doc: strings.TrimSpace(doc)})
+				}
+			}
+		}
+	}
+	return r
+}
+
+// conversionType returns the name of the type converted to, if expr is the conversion to the declared type. (e.g. "Ordering" of Ordering("desc"))
+func conversionType(expr ast.Expr) string {
+	call, ok := expr.(*ast.CallExpr)
+	if !ok || len(call.Args) != 1 {
+		return ""
+	}
+	ident, ok := call.Fun.(*ast.Ident)
+	if !ok || types.Universe.Lookup(ident.Name) != nil { // e.g. string("x"), len("x")
+		return ""
+	}
+	return ident.Name
+}
+
+func evalConstSafe(expr ast.Expr, iota int, env map[string]constant.Value) (val constant.Value) {
+	defer func() {
+		if r := recover(); r != nil { // e.g. division by zero, mismatched operands
+			val = nil
+		}
+	}()
+	return evalConst(expr, iota, env)
+}
+
+// evalConst evaluates the simple constant expression. (iota, literal, unary and binary operation, and reference of other constants)
+func evalConst(expr ast.Expr, iota int, env map[string]constant.Value) constant.Value {
+	switch expr := expr.(type) {
+	case *ast.BasicLit:
+		return constant.MakeFromLiteral(expr.Value, expr.Kind, 0)
+	case *ast.Ident:
+		switch expr.Name {
+		case "iota":
+			return constant.MakeInt64(int64(iota))
+		case "true":
+			return constant.MakeBool(true)
+		case "false":
+			return constant.MakeBool(false)
+		}
+		return env[expr.Name]
+	case *ast.ParenExpr:
+		return evalConst(expr.X, iota, env)
+	case *ast.CallExpr: // type conversion, e.g. Ordering("desc")
+		if len(expr.Args) != 1 {
+			return nil
+		}
+		return evalConst(expr.Args[0], iota, env)
+	case *ast.UnaryExpr:
+		x := evalConst(expr.X, iota, env)
+		if x == nil {
+			return nil
+		}
+		return constant.UnaryOp(expr.Op, x, 0)
+	case *ast.BinaryExpr:
+		x := evalConst(expr.X, iota, env)
+		y := evalConst(expr.Y, iota, env)
+		if x == nil || y == nil {
+			return nil
+		}
+		switch expr.Op {
+		case token.SHL, token.SHR:
+			s, ok := constant.Uint64Val(y)
+			if !ok {
+				return nil
+			}
+			return constant.Shift(x, expr.Op, uint(s))
+		case token.EQL, token.NEQ, token.LSS, token.LEQ, token.GTR, token.GEQ:
+			return constant.MakeBool(constant.Compare(x, expr.Op, y))
+		case token.QUO:
+			if x.Kind() == constant.Int && y.Kind() == constant.Int {
+				return constant.BinaryOp(x, token.QUO_ASSIGN, y) // integer division
+			}
+		}
+		return constant.BinaryOp(x, expr.Op, y)
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,345 @@
+package metadata
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"go/constant"
+	"go/token"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/podhmo/commentof/collect"
+	"golang.org/x/tools/go/packages"
+)
+
+// diskCacheVersion is the version of the format of the disk cache. (incremented if the format is changed)
+const diskCacheVersion = 5
+
+// DefaultCacheDir returns the default directory of the disk cache. (<os.UserCacheDir()>/reflect-shape)
+func DefaultCacheDir() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("default cache dir: %w", err)
+	}
+	return filepath.Join(dir, "reflect-shape"), nil
+}
+
+// diskEntry is the metadata of the package stored in the disk cache.
+//
+// The entry is valid only while the contents of Files are not changed, and the .go files in the directories are not added or removed.
+// The positions (token.Pos) are stored as the offsets in the files, and restored without parsing.
+type diskEntry struct {
+	Version    int                          `json:"version"`
+	PkgPath    string                       `json:"pkgpath"`
+	Files      []diskFile                   `json:"files"`
+	Dirs       map[string][]string          `json:"dirs"` // dir -> the names of the .go files
+	Package    *collect.Package             `json:"package"`
+	Pos        map[string]diskPos           `json:"pos"` // e.g. "type:Person", "type:Person.Name", "func:Hello(name)"
+	Consts     map[string][]diskConst       `json:"consts,omitempty"`
+	Locals     map[string][]string          `json:"locals,omitempty"`     // see packageRef.locals
+	Imports    map[string]map[string]string `json:"imports,omitempty"`    // see packageRef.imports
+	Embeds     map[string][]string          `json:"embeds,omitempty"`     // see packageRef.embeds
+	TypeParams map[string][]TypeParam       `json:"typeparams,omitempty"` // see packageRef.typeParams
+}
+
+type diskFile struct {
+	Name string `json:"name"`
+	Hash string `json:"hash,omitempty"` // sha256 of the content
+
+	// Size and Lines are the size and the offsets of the lines of the file, for restoring the positions without the source (see precompiled.go)
+	Size  int   `json:"size,omitempty"`
+	Lines []int `json:"lines,omitempty"`
+}
+
+type diskPos struct {
+	File   int `json:"file"` // the index of Files
+	Offset int `json:"offset"`
+}
+
+type diskConst struct {
+	Name  string `json:"name"`
+	Kind  int    `json:"kind,omitempty"` // constant.Kind (0 is unknown, the value cannot be evaluated)
+	Value string `json:"value,omitempty"`
+	Doc   string `json:"doc,omitempty"`
+}
+
+// diskCachePath returns the path of the cache file of the package, keyed by the options affecting the collected metadata.
+func (l *Lookup) diskCachePath(pkgpath string) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "v%d\x00%s\x00%s\x00%s\x00%s\x00%v\x00%v", diskCacheVersion, pkgpath,
+		l.GOOS, l.GOARCH, strings.Join(l.BuildTags, ","), l.IncludeGoTestFiles, l.IncludeUnexported)
+	return filepath.Join(l.CacheDir, hex.EncodeToString(h.Sum(nil))+"."+string(l.encoding()))
+}
+
+// readDiskCache returns the package stored in the disk cache, if the entry is found and valid.
+// The files of the package are added to l.Fset, so the positions of the metadata are available.
+func (l *Lookup) readDiskCache(pkgpath string) (*packageRef, bool) {
+	b, err := os.ReadFile(l.diskCachePath(pkgpath))
+	if err != nil {
+		return nil, false
+	}
+	var entry diskEntry
+	if err := decodeEntry(l.encoding(), b, &entry); err != nil || entry.Version != diskCacheVersion || entry.PkgPath != pkgpath || entry.Package == nil {
+		return nil, false
+	}
+
+	for dir, names := range entry.Dirs {
+		if got, err := goFileNames(dir); err != nil || strings.Join(got, "\x00") != strings.Join(names, "\x00") {
+			return nil, false
+		}
+	}
+	tfiles := make([]*token.File, len(entry.Files))
+	srcs := make([][]byte, len(entry.Files))
+	for i, f := range entry.Files {
+		src, err := os.ReadFile(f.Name)
+		if err != nil || contentHash(src) != f.Hash {
+			return nil, false
+		}
+		srcs[i] = src
+	}
+	for i, f := range entry.Files {
+		src := srcs[i]
+		tfiles[i] = l.addFile(f.Name, f.Hash, len(src), func(tf *token.File) bool {
+			tf.SetLinesForContent(src)
+			return true
+		})
+	}
+	if DEBUG {
+		l.logf("OK disk cache %v", pkgpath)
+	}
+	return restoreEntry(&entry, tfiles), true
+}
+
+// addFile returns the file added to l.Fset, for restoring the positions of the cached metadata. (l.mu must be held)
+// The file added by the previous read is reused if the content is the same (by the digest), so l.Fset doesn't grow by the repeated reads. (e.g. after the eviction)
+func (l *Lookup) addFile(name, digest string, size int, setLines func(tf *token.File) bool) *token.File {
+	key := name + "\x00" + digest
+	if tf, ok := l.files[key]; ok && tf.Size() == size {
+		return tf
+	}
+	tf := l.Fset.AddFile(name, -1, size)
+	if !setLines(tf) {
+		return tf // not reused
+	}
+	if l.files == nil {
+		l.files = map[string]*token.File{}
+	}
+	l.files[key] = tf
+	return tf
+}
+
+// restoreEntry returns the package of the entry, the positions are restored in the files.
+func restoreEntry(entry *diskEntry, tfiles []*token.File) *packageRef {
+	p := entry.Package
+	if p.Files == nil {
+		p.Files = map[string]*collect.File{}
+	}
+	walkPositions(p, func(key string, pos *token.Pos) {
+		if dp, ok := entry.Pos[key]; ok && 0 <= dp.File && dp.File < len(tfiles) && dp.Offset <= tfiles[dp.File].Size() {
+			*pos = tfiles[dp.File].Pos(dp.Offset)
+		}
+	})
+
+	consts := make(map[string][]Const, len(entry.Consts))
+	for typename, values := range entry.Consts {
+		for _, c := range values {
+			consts[typename] = append(consts[typename], Const{Name: c.Name, Value: decodeConstant(constant.Kind(c.Kind), c.Value), Doc: c.Doc})
+		}
+	}
+	return &packageRef{Package: p, fullset: true, consts: consts, locals: entry.Locals, imports: entry.Imports, embeds: entry.Embeds, typeParams: entry.TypeParams}
+}
+
+// writeDiskCache stores the collected package into the disk cache. (the failure is only logged)
+func (l *Lookup) writeDiskCache(pkg *packages.Package, ref *packageRef) {
+	pkgpath := pkg.PkgPath
+	entry, err := l.newDiskEntry(pkg, ref, false)
+	if err != nil {
+		return // e.g. the files generated by cgo
+	}
+
+	b, err := encodeEntry(l.encoding(), entry)
+	if err != nil {
+		l.logf("write disk cache (%s) %+v", pkgpath, err)
+		return
+	}
+	if err := os.MkdirAll(l.CacheDir, 0o755); err != nil {
+		l.logf("write disk cache (%s) %+v", pkgpath, err)
+		return
+	}
+	// write and rename, not to be read the partial content by the other processes
+	filename := l.diskCachePath(pkgpath)
+	tmp, err := os.CreateTemp(l.CacheDir, filepath.Base(filename)+".*")
+	if err != nil {
+		l.logf("write disk cache (%s) %+v", pkgpath, err)
+		return
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(b); err != nil {
+		tmp.Close()
+		l.logf("write disk cache (%s) %+v", pkgpath, err)
+		return
+	}
+	if err := tmp.Close(); err != nil {
+		l.logf("write disk cache (%s) %+v", pkgpath, err)
+		return
+	}
+	if err := os.Rename(tmp.Name(), filename); err != nil {
+		l.logf("write disk cache (%s) %+v", pkgpath, err)
+	}
+}
+
+// newDiskEntry returns the entry of the collected package.
+// If precompiled is true, the entry has the lines of the files instead of the hashes, so it can be restored without the source.
+func (l *Lookup) newDiskEntry(pkg *packages.Package, ref *packageRef, precompiled bool) (*diskEntry, error) {
+	fset := l.Fset
+	if pkg.Fset != nil {
+		fset = pkg.Fset
+	}
+	filenames := make([]string, 0, len(pkg.Syntax))
+	tfiles := make(map[string]*token.File, len(pkg.Syntax))
+	for _, f := range pkg.Syntax {
+		tf := fset.File(f.Pos())
+		filenames = append(filenames, tf.Name())
+		tfiles[tf.Name()] = tf
+	}
+	sort.Strings(filenames)
+
+	entry := &diskEntry{
+		Version:    diskCacheVersion,
+		PkgPath:    pkg.PkgPath,
+		Dirs:       map[string][]string{},
+		Package:    ref.Package,
+		Pos:        map[string]diskPos{},
+		Consts:     map[string][]diskConst{},
+		Locals:     ref.locals,
+		Imports:    ref.imports,
+		Embeds:     ref.embeds,
+		TypeParams: ref.typeParams,
+	}
+
+	indices := map[string]int{}
+	for _, name := range filenames {
+		indices[name] = len(entry.Files)
+		if precompiled {
+			tf := tfiles[name]
+			lines := make([]int, tf.LineCount())
+			for i := range lines {
+				lines[i] = tf.Offset(tf.LineStart(i + 1))
+			}
+			entry.Files = append(entry.Files, diskFile{Name: name, Size: tf.Size(), Lines: lines})
+			continue
+		}
+
+		src, err := os.ReadFile(name)
+		if err != nil {
+			return nil, err
+		}
+		entry.Files = append(entry.Files, diskFile{Name: name, Hash: contentHash(src)})
+
+		dir := filepath.Dir(name)
+		if _, ok := entry.Dirs[dir]; !ok {
+			names, err := goFileNames(dir)
+			if err != nil {
+				return nil, err
+			}
+			entry.Dirs[dir] = names
+		}
+	}
+	walkPositions(ref.Package, func(key string, pos *token.Pos) {
+		if !pos.IsValid() {
+			return
+		}
+		position := fset.Position(*pos)
+		if i, ok := indices[position.Filename]; ok {
+			entry.Pos[key] = diskPos{File: i, Offset: position.Offset}
+		}
+	})
+	for typename, values := range ref.consts {
+		for _, c := range values {
+			dc := diskConst{Name: c.Name, Doc: c.Doc}
+			if c.Value != nil {
+				dc.Kind = int(c.Value.Kind())
+				dc.Value = c.Value.ExactString()
+			}
+			entry.Consts[typename] = append(entry.Consts[typename], dc)
+		}
+	}
+	return entry, nil
+}
+
+// walkPositions calls fn with the positions in the package, keyed by the paths of the declarations.
+func walkPositions(p *collect.Package, fn func(key string, pos *token.Pos)) {
+	var walkObject func(key string, ob *collect.Object)
+	var walkFunc func(key string, ob *collect.Func)
+	walkObject = func(key string, ob *collect.Object) {
+		fn(key, &ob.Pos)
+		for name, f := range ob.Fields {
+			fn(key+"."+name, &f.Pos)
+			if f.Anonymous != nil {
+				walkObject(key+"."+name+"{}", f.Anonymous)
+			}
+		}
+		for name, m := range ob.Methods {
+			walkFunc(key+"#"+name, m)
+		}
+	}
+	walkFunc = func(key string, ob *collect.Func) {
+		fn(key, &ob.Pos)
+		for id, f := range ob.Params {
+			fn(key+"("+id+")", &f.Pos)
+		}
+		for id, f := range ob.Returns {
+			fn(key+"->("+id+")", &f.Pos)
+		}
+	}
+
+	for name, ob := range p.Types {
+		walkObject("type:"+name, ob)
+	}
+	for name, ob := range p.Interfaces {
+		walkObject("interface:"+name, ob)
+	}
+	for name, ob := range p.Functions {
+		walkFunc("func:"+name, ob)
+	}
+}
+
+func decodeConstant(kind constant.Kind, s string) constant.Value {
+	switch kind {
+	case constant.Bool:
+		return constant.MakeBool(s == "true")
+	case constant.String:
+		return constant.MakeFromLiteral(s, token.STRING, 0)
+	case constant.Int:
+		return constant.MakeFromLiteral(s, token.INT, 0)
+	case constant.Float:
+		if r, ok := new(big.Rat).SetString(s); ok { // ExactString() of float is the fraction (e.g. "1/3")
+			return constant.Make(r)
+		}
+	}
+	return nil
+}
+
+func goFileNames(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, e := range entries {
+		if !e.IsDir() && strings.HasSuffix(e.Name(), ".go") {
+			names = append(names, e.Name())
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+func contentHash(src []byte) string {
+	h := sha256.Sum256(src)
+	return hex.EncodeToString(h[:])
+}
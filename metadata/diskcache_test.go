@@ -0,0 +1,133 @@
+package metadata
+
+import (
+	"encoding/json"
+	"go/token"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/podhmo/reflect-shape/metadata/testdata/multi"
+	"golang.org/x/tools/go/packages"
+)
+
+func TestDiskCache(t *testing.T) {
+	const pkgpath = "github.com/podhmo/reflect-shape/metadata/testdata/multi"
+	dir := t.TempDir()
+
+	newLookup := func() (*Lookup, *int) {
+		l := NewLookup(token.NewFileSet())
+		l.CacheDir = dir
+		loaded := 0
+		l.Loader = func(cfg *packages.Config, patterns ...string) ([]*packages.Package, error) {
+			loaded++
+			return packages.Load(cfg, patterns...)
+		}
+		return l, &loaded
+	}
+
+	l0, loaded := newLookup()
+	want, err := l0.LookupFromType(multi.Value{})
+	if err != nil {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+	if want, got := 1, *loaded; want != got {
+		t.Errorf("loaded count (first): want:%d != got:%d", want, got)
+	}
+	if _, err := os.Stat(l0.diskCachePath(pkgpath)); err != nil {
+		t.Fatalf("cache file is not written: %+v", err)
+	}
+
+	t.Run("hit", func(t *testing.T) {
+		l, loaded := newLookup()
+		got, err := l.LookupFromType(multi.Value{})
+		if err != nil {
+			t.Fatalf("unexpected error: %+v", err)
+		}
+		if want, got := 0, *loaded; want != got {
+			t.Errorf("loaded count (cached): want:%d != got:%d", want, got)
+		}
+		if want, got := want.Doc(), got.Doc(); want != got {
+			t.Errorf("Doc(): want:%q != got:%q", want, got)
+		}
+		if want, got := l0.Fset.Position(want.Raw.Pos), l.Fset.Position(got.Raw.Pos); want != got {
+			t.Errorf("Pos: want:%v != got:%v", want, got)
+		}
+		if want, got := l0.Fset.Position(want.Raw.Fields["Name"].Pos), l.Fset.Position(got.Raw.Fields["Name"].Pos); want != got {
+			t.Errorf("Pos of field: want:%v != got:%v", want, got)
+		}
+	})
+
+	t.Run("read-again", func(t *testing.T) {
+		l, _ := newLookup()
+		if _, err := l.LookupFromType(multi.Value{}); err != nil {
+			t.Fatalf("unexpected error: %+v", err)
+		}
+		base := l.Fset.Base()
+		for i := 0; i < 2; i++ {
+			l.Forget(pkgpath)
+			if _, err := l.LookupFromType(multi.Value{}); err != nil {
+				t.Fatalf("unexpected error: %+v", err)
+			}
+		}
+		if want, got := base, l.Fset.Base(); want != got {
+			t.Errorf("the files are added again, Fset.Base(): want:%d != got:%d", want, got)
+		}
+	})
+
+	t.Run("invalidated", func(t *testing.T) {
+		filename := l0.diskCachePath(pkgpath)
+		b, err := os.ReadFile(filename)
+		if err != nil {
+			t.Fatalf("unexpected error: %+v", err)
+		}
+		var entry diskEntry
+		if err := json.Unmarshal(b, &entry); err != nil {
+			t.Fatalf("unexpected error: %+v", err)
+		}
+		entry.Files[0].Hash = "changed"
+		b, err = json.Marshal(entry)
+		if err != nil {
+			t.Fatalf("unexpected error: %+v", err)
+		}
+		if err := os.WriteFile(filename, b, 0o644); err != nil {
+			t.Fatalf("unexpected error: %+v", err)
+		}
+
+		l, loaded := newLookup()
+		if _, err := l.LookupFromType(multi.Value{}); err != nil {
+			t.Fatalf("unexpected error: %+v", err)
+		}
+		if want, got := 1, *loaded; want != got {
+			t.Errorf("loaded count (invalidated): want:%d != got:%d", want, got)
+		}
+	})
+
+	t.Run("gob", func(t *testing.T) {
+		for i, wantLoaded := range []int{1, 0} {
+			l, loaded := newLookup()
+			l.Encoding = EncodingGob
+			got, err := l.LookupFromType(multi.Value{})
+			if err != nil {
+				t.Fatalf("unexpected error: %+v", err)
+			}
+			if want, got := wantLoaded, *loaded; want != got {
+				t.Errorf("loaded count (%d): want:%d != got:%d", i, want, got)
+			}
+			if want, got := want.Doc(), got.Doc(); want != got {
+				t.Errorf("Doc(): want:%q != got:%q", want, got)
+			}
+			if want, got := ".gob", filepath.Ext(l.diskCachePath(pkgpath)); want != got {
+				t.Errorf("the extension of the cache file: want:%q != got:%q", want, got)
+			}
+		}
+	})
+
+	t.Run("build-context", func(t *testing.T) {
+		l, _ := newLookup()
+		l.GOOS = "windows"
+		if want, got := filepath.Base(l0.diskCachePath(pkgpath)), filepath.Base(l.diskCachePath(pkgpath)); want == got {
+			t.Errorf("the cache must be keyed by the build context: %v", got)
+		}
+	})
+}
@@ -0,0 +1,155 @@
+package metadata
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+	"fmt"
+
+	"github.com/podhmo/commentof/collect"
+)
+
+// Encoding is the encoding of the metadata stored in the disk cache and registered by the precompiled code.
+type Encoding string
+
+const (
+	// EncodingJSON is the default encoding, human-readable.
+	EncodingJSON Encoding = "json"
+	// EncodingGob is faster to decode than JSON, for the large packages loaded at startup. (e.g. the multi-megabyte precompiled metadata)
+	EncodingGob Encoding = "gob"
+)
+
+func (l *Lookup) encoding() Encoding {
+	if l.Encoding == "" {
+		return EncodingJSON
+	}
+	return l.Encoding
+}
+
+// encodeEntry encodes the entry with the encoding.
+func encodeEntry(enc Encoding, entry *diskEntry) ([]byte, error) {
+	switch enc {
+	case EncodingJSON, "":
+		return json.Marshal(entry)
+	case EncodingGob:
+		copied := *entry
+		copied.Package = gobPackage(entry.Package)
+		var buf bytes.Buffer
+		if err := gob.NewEncoder(&buf).Encode(&copied); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	default:
+		return nil, fmt.Errorf("unknown encoding %q", enc)
+	}
+}
+
+// decodeEntry decodes the entry encoded by encodeEntry.
+func decodeEntry(enc Encoding, data []byte, entry *diskEntry) error {
+	switch enc {
+	case EncodingJSON, "":
+		return json.Unmarshal(data, entry)
+	case EncodingGob:
+		if err := gob.NewDecoder(bytes.NewReader(data)).Decode(entry); err != nil {
+			return err
+		}
+		if entry.Package != nil {
+			walkFuncs(entry.Package, func(fn *collect.Func) {
+				// gob doesn't distinguish the empty slices from nil, but nil ParamNames means unknown (see Func.Args)
+				if fn.ParamNames == nil {
+					fn.ParamNames = []string{}
+				}
+				if fn.ReturnNames == nil {
+					fn.ReturnNames = []string{}
+				}
+			})
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown encoding %q", enc)
+	}
+}
+
+// gobPackage returns the copy of the package, without the fields not stored in JSON (json:"-"), as JSON does.
+// (gob encodes all exported fields, but Object.Parent is cyclic, Files is redundant, and the positions are restored by diskEntry.Pos)
+func gobPackage(p *collect.Package) *collect.Package {
+	if p == nil {
+		return nil
+	}
+	var copyObject func(ob *collect.Object) *collect.Object
+	copyFunc := func(fn *collect.Func) *collect.Func {
+		copied := *fn
+		copied.Pos = 0
+		copied.Params = copyFields(fn.Params, copyObject)
+		copied.Returns = copyFields(fn.Returns, copyObject)
+		return &copied
+	}
+	copyObject = func(ob *collect.Object) *collect.Object {
+		copied := *ob
+		copied.Pos, copied.Token, copied.Parent = 0, 0, nil
+		copied.Fields = copyFields(ob.Fields, copyObject)
+		if ob.Methods != nil {
+			copied.Methods = make(map[string]*collect.Func, len(ob.Methods))
+			for name, m := range ob.Methods {
+				copied.Methods[name] = copyFunc(m)
+			}
+		}
+		return &copied
+	}
+
+	copied := *p
+	copied.Files = nil
+	copied.Types = make(map[string]*collect.Object, len(p.Types))
+	for name, ob := range p.Types {
+		copied.Types[name] = copyObject(ob)
+	}
+	copied.Interfaces = make(map[string]*collect.Object, len(p.Interfaces))
+	for name, ob := range p.Interfaces {
+		copied.Interfaces[name] = copyObject(ob)
+	}
+	copied.Functions = make(map[string]*collect.Func, len(p.Functions))
+	for name, fn := range p.Functions {
+		copied.Functions[name] = copyFunc(fn)
+	}
+	return &copied
+}
+
+func copyFields(fields map[string]*collect.Field, copyObject func(*collect.Object) *collect.Object) map[string]*collect.Field {
+	if fields == nil {
+		return nil
+	}
+	r := make(map[string]*collect.Field, len(fields))
+	for name, f := range fields {
+		copied := *f
+		copied.Pos = 0
+		if f.Anonymous != nil {
+			copied.Anonymous = copyObject(f.Anonymous)
+		}
+		r[name] = &copied
+	}
+	return r
+}
+
+// walkFuncs calls fn with the functions and the methods in the package.
+func walkFuncs(p *collect.Package, fn func(*collect.Func)) {
+	var walkObject func(ob *collect.Object)
+	walkObject = func(ob *collect.Object) {
+		for _, m := range ob.Methods {
+			fn(m)
+		}
+		for _, f := range ob.Fields {
+			if f.Anonymous != nil {
+				walkObject(f.Anonymous)
+			}
+		}
+	}
+	for _, ob := range p.Types {
+		walkObject(ob)
+	}
+	for _, ob := range p.Interfaces {
+		walkObject(ob)
+	}
+	for _, f := range p.Functions {
+		fn(f)
+	}
+}
@@ -0,0 +1,84 @@
+package metadata
+
+import (
+	"context"
+	"go/token"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"golang.org/x/tools/go/packages"
+)
+
+// newTestEntry returns the precompiled entry of the package, and its collected package.
+func newTestEntry(tb testing.TB, pkgpath string) (*diskEntry, *packageRef) {
+	tb.Helper()
+	l := NewLookup(token.NewFileSet())
+	pkgs, err := l.load(l.packagesConfig(context.Background()), pkgpath)
+	if err != nil {
+		tb.Fatalf("unexpected error: %+v", err)
+	}
+	var pkg *packages.Package
+	for _, p := range l.selectPackages(pkgs) {
+		if p.PkgPath == pkgpath {
+			pkg = p
+		}
+	}
+	ref, err := l.addPackage(pkg)
+	if err != nil {
+		tb.Fatalf("unexpected error: %+v", err)
+	}
+	entry, err := l.newDiskEntry(pkg, ref, true)
+	if err != nil {
+		tb.Fatalf("unexpected error: %+v", err)
+	}
+	return entry, ref
+}
+
+func TestEncodeEntry(t *testing.T) {
+	entry, ref := newTestEntry(t, "net/http")
+
+	decode := func(enc Encoding) *diskEntry {
+		t.Helper()
+		b, err := encodeEntry(enc, entry)
+		if err != nil {
+			t.Fatalf("encodeEntry(%s): unexpected error: %+v", enc, err)
+		}
+		var decoded diskEntry
+		if err := decodeEntry(enc, b, &decoded); err != nil {
+			t.Fatalf("decodeEntry(%s): unexpected error: %+v", enc, err)
+		}
+		t.Logf("%s: %d bytes", enc, len(b))
+		return &decoded
+	}
+
+	want := decode(EncodingJSON)
+	got := decode(EncodingGob)
+	if diff := cmp.Diff(want, got, cmp.AllowUnexported(diskEntry{}), cmpopts.EquateEmpty()); diff != "" {
+		t.Errorf("the gob-encoded entry must be the same as the JSON one: mismatch (-json, +gob): \n%v", diff)
+	}
+	if fn := got.Package.Types["Request"].Methods["Context"]; fn.ParamNames == nil {
+		t.Errorf("ParamNames of the func without params must not be nil (nil means unknown)")
+	}
+	if ref.Package.Types["Request"].Methods["Context"].Pos == 0 {
+		t.Errorf("the original package must not be modified by the encoding")
+	}
+}
+
+func BenchmarkDecodeEntry(b *testing.B) {
+	entry, _ := newTestEntry(b, "net/http")
+	for _, enc := range []Encoding{EncodingJSON, EncodingGob} {
+		data, err := encodeEntry(enc, entry)
+		if err != nil {
+			b.Fatalf("unexpected error: %+v", err)
+		}
+		b.Run(string(enc), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				var decoded diskEntry
+				if err := decodeEntry(enc, data, &decoded); err != nil {
+					b.Fatalf("unexpected error: %+v", err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,205 @@
+package metadata
+
+import (
+	"errors"
+	"fmt"
+	"go/ast"
+	"sort"
+	"strings"
+
+	"golang.org/x/tools/go/packages"
+)
+
+var (
+	// ErrSourceUnavailable is the error that the source of the symbol cannot be read. (e.g. the binary is built on the other machine)
+	ErrSourceUnavailable = errors.New("source unavailable")
+	// ErrPackageLoad is the error that the package cannot be loaded. (e.g. go command is not found, or the package is broken)
+	ErrPackageLoad = errors.New("package load failed")
+	// ErrAmbiguousSymbol is the error that the symbol cannot be identified. (e.g. the local types having the same name)
+	ErrAmbiguousSymbol = errors.New("ambiguous symbol")
+)
+
+// SourceUnavailableError is the error that the source file of the symbol cannot be read or parsed.
+type SourceUnavailableError struct {
+	Symbol   string // e.g. "Foo", "S.Method"
+	PkgPath  string
+	Filename string
+	Err      error
+}
+
+func (e *SourceUnavailableError) Error() string {
+	return fmt.Sprintf("source of %s.%s is unavailable (file=%s): %v", e.PkgPath, e.Symbol, e.Filename, e.Err)
+}
+
+func (e *SourceUnavailableError) Unwrap() error {
+	return e.Err
+}
+
+func (e *SourceUnavailableError) Is(target error) bool {
+	return target == ErrSourceUnavailable
+}
+
+// PackageLoadError is the error that the package cannot be loaded by go/packages.
+type PackageLoadError struct {
+	PkgPath string
+	Offline bool     // loaded in offline mode (GOPROXY=off)
+	Errors  []string // the errors of the package (e.g. syntax errors, the package is not found)
+	Err     error    // the error of packages.Load(), if failed
+}
+
+func newPackageLoadError(pkgpath string, errs []packages.Error) *PackageLoadError {
+	msgs := make([]string, len(errs))
+	for i, err := range errs {
+		msgs[i] = err.Error()
+	}
+	return &PackageLoadError{PkgPath: pkgpath, Errors: msgs}
+}
+
+func (e *PackageLoadError) Error() string {
+	mode := ""
+	if e.Offline {
+		mode = " in offline mode (GOPROXY=off)"
+	}
+	if e.Err != nil {
+		return fmt.Sprintf("packages.Load()%s %s: %v", mode, e.PkgPath, e.Err)
+	}
+	return fmt.Sprintf("package %s is broken%s: %s", e.PkgPath, mode, strings.Join(e.Errors, "; "))
+}
+
+func (e *PackageLoadError) Unwrap() error {
+	return e.Err
+}
+
+// Is reports ErrPackageLoad, and ErrNotFound for the broken package (the metadata is not found, for compatibility).
+func (e *PackageLoadError) Is(target error) bool {
+	return target == ErrPackageLoad || (target == ErrNotFound && e.Err == nil)
+}
+
+// AmbiguousSymbolError is the error that the symbol matches more than one declaration.
+// (e.g. reflect cannot distinguish the types declared in the different functions with the same name)
+type AmbiguousSymbolError struct {
+	Symbol    string
+	PkgPath   string
+	Positions []string // the positions of the declarations
+}
+
+func (e *AmbiguousSymbolError) Error() string {
+	return fmt.Sprintf("%s.%s is ambiguous, declared at %s", e.PkgPath, e.Symbol, strings.Join(e.Positions, ", "))
+}
+
+func (e *AmbiguousSymbolError) Is(target error) bool {
+	return target == ErrAmbiguousSymbol
+}
+
+// NotFoundError is the error that the metadata is not found, with the diagnosis.
+type NotFoundError struct {
+	Symbol     string   // the looked-up name (e.g. "Foo", "S.Method")
+	PkgPath    string   // the package path of the symbol
+	Filename   string   // the parsed file ("" if the whole package is collected)
+	Candidates []string // the names available in the index
+	Closest    []string // the candidates similar to Symbol (naming mismatch?)
+	Unexported bool     // the symbol is unexported, and the lookup excludes unexported names
+}
+
+func (e *NotFoundError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "lookup metadata of %s.%s is failed", e.PkgPath, e.Symbol)
+	if e.Filename != "" {
+		fmt.Fprintf(&b, " (file=%s)", e.Filename)
+	}
+	if e.Unexported {
+		b.WriteString(", unexported names are excluded")
+	}
+	if len(e.Closest) > 0 {
+		fmt.Fprintf(&b, ", did you mean %s?", strings.Join(e.Closest, " or "))
+	}
+	fmt.Fprintf(&b, " %v", ErrNotFound)
+	return b.String()
+}
+
+func (e *NotFoundError) Unwrap() error {
+	return ErrNotFound
+}
+
+func (l *Lookup) notFound(symbol, pkgpath, filename string, candidates []string) error {
+	sort.Strings(candidates)
+	name := symbol
+	if i := strings.LastIndex(name, "."); i >= 0 {
+		name = name[i+1:] // method name
+	}
+	return &NotFoundError{
+		Symbol:     symbol,
+		PkgPath:    pkgpath,
+		Filename:   filename,
+		Candidates: candidates,
+		Closest:    closest(name, candidates),
+		Unexported: !l.IncludeUnexported && !ast.IsExported(name),
+	}
+}
+
+func keys[V any](m map[string]V) []string {
+	r := make([]string, 0, len(m))
+	for k := range m {
+		r = append(r, k)
+	}
+	sort.Strings(r)
+	return r
+}
+
+// closest returns the candidates similar to name (by edit distance, ignoring case), at most 3.
+func closest(name string, candidates []string) []string {
+	type scored struct {
+		name  string
+		score int
+	}
+	threshold := len(name)/3 + 1
+	var found []scored
+	for _, c := range candidates {
+		if c == name {
+			continue
+		}
+		d := editDistance(strings.ToLower(name), strings.ToLower(c))
+		if d <= threshold {
+			found = append(found, scored{name: c, score: d})
+		}
+	}
+	sort.SliceStable(found, func(i, j int) bool { return found[i].score < found[j].score })
+	if len(found) > 3 {
+		found = found[:3]
+	}
+	r := make([]string, len(found))
+	for i, x := range found {
+		r[i] = x.name
+	}
+	return r
+}
+
+func editDistance(x, y string) int {
+	prev := make([]int, len(y)+1)
+	cur := make([]int, len(y)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(x); i++ {
+		cur[0] = i
+		for j := 1; j <= len(y); j++ {
+			cost := 1
+			if x[i-1] == y[j-1] {
+				cost = 0
+			}
+			cur[j] = min3(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(y)]
+}
+
+func min3(x, y, z int) int {
+	if y < x {
+		x = y
+	}
+	if z < x {
+		x = z
+	}
+	return x
+}
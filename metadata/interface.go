@@ -0,0 +1,249 @@
+package metadata
+
+import (
+	"context"
+	"fmt"
+	"go/ast"
+	"go/token"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Interface is the metadata of the interface, with the methods of the embedded interfaces.
+type Interface struct {
+	*Type
+	Methods []*Method // in the order of the declaration, the methods of the embedded interfaces are expanded at their positions
+}
+
+// Method is the metadata of the method of the interface.
+type Method struct {
+	Name     string
+	Pos      token.Pos
+	Doc      string
+	Embedded string // the embedded interface declaring the method (e.g. "io.Reader"), or "" if declared directly
+}
+
+// MethodComments returns the docs of the methods, keyed by the names. (including the methods of the embedded interfaces)
+func (iface *Interface) MethodComments() map[string]string {
+	comments := make(map[string]string, len(iface.Methods))
+	for _, m := range iface.Methods {
+		comments[m.Name] = m.Doc
+	}
+	return comments
+}
+
+// LookupFromInterface returns the metadata of the interface. ob is the pointer to the interface. (e.g. (*io.ReadCloser)(nil))
+func (l *Lookup) LookupFromInterface(ob interface{}) (*Interface, error) {
+	rt := reflect.TypeOf(ob)
+	if rt != nil && rt.Kind() == reflect.Pointer {
+		rt = rt.Elem()
+	}
+	return l.LookupFromInterfaceForReflectType(rt)
+}
+
+// LookupFromInterfaceForReflectType returns the metadata of the interface.
+// The embedded interfaces are looked up recursively, and the methods not found (e.g. the package is broken) have no docs.
+func (l *Lookup) LookupFromInterfaceForReflectType(rt reflect.Type) (*Interface, error) {
+	if rt == nil || rt.Kind() != reflect.Interface {
+		return nil, fmt.Errorf("%v is not interface: %w", rt, ErrNotSupported)
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	defer l.evict()
+
+	ctx := context.Background()
+	m, err := l.lookupFromTypeForReflectType(ctx, rt)
+	if err != nil {
+		return nil, err
+	}
+	pkgpath, _ := l.resolvePkgpath(rt.PkgPath())
+	iface := &Interface{Type: m}
+	iface.Methods = l.expandMethods(ctx, pkgpath, m, "", map[string]bool{pkgpath + "." + m.Name(): true})
+	return iface, nil
+}
+
+// expandMethods returns the methods of the interface, with the methods of the embedded interfaces. (l.mu must be held)
+// The methods of the embedded interfaces come first, in the order of the embedding, and then the methods declared directly.
+func (l *Lookup) expandMethods(ctx context.Context, pkgpath string, m *Type, embedded string, seen map[string]bool) []*Method {
+	var r []*Method
+	var embeds []string
+	if p, ok := l.cache[pkgpath]; ok {
+		embeds = p.embeds[m.Name()]
+	}
+	filename := l.Fset.Position(m.Raw.Pos).Filename // the aliases of the imports are resolved in the file declaring the interface
+	for _, name := range embeds {
+		typepath, obname := l.resolveEmbedded(pkgpath, filename, name)
+		if obname == "" {
+			if l.Trace {
+				l.logf("trace: embedded %q in %s is not resolved", name, m.Name())
+			}
+			continue
+		}
+		if seen[typepath+"."+obname] {
+			continue
+		}
+		seen[typepath+"."+obname] = true
+		sub, err := l.lookupType(ctx, typepath, obname)
+		if err != nil {
+			if l.Trace {
+				l.logf("trace: embedded %q in %s is not found: %v", name, m.Name(), err)
+			}
+			continue
+		}
+		if _, ok := l.cache[typepath].Interfaces[obname]; !ok { // e.g. interface{ ~int | MyInt } (type constraint)
+			continue
+		}
+		label := embedded
+		if label == "" {
+			label = name
+		}
+		r = append(r, l.expandMethods(ctx, typepath, sub, label, seen)...)
+	}
+	for _, name := range m.Raw.FieldNames {
+		f := m.Raw.Fields[name]
+		if f.Embedded {
+			continue // see embeds (the qualified names like "io.Reader" are not collected)
+		}
+		doc := f.Doc
+		if doc == "" {
+			doc = f.Comment
+		}
+		r = append(r, &Method{Name: f.Name, Pos: f.Pos, Doc: strings.TrimSpace(stripSections(doc)), Embedded: embedded})
+	}
+
+	// the methods declared directly win, and the first one wins in the embedded interfaces (overlapping method sets)
+	uniq := make([]*Method, 0, len(r))
+	found := make(map[string]int, len(r))
+	for _, x := range r {
+		if i, ok := found[x.Name]; ok {
+			if uniq[i].Embedded != "" && x.Embedded == "" {
+				uniq[i] = x
+			}
+			continue
+		}
+		found[x.Name] = len(uniq)
+		uniq = append(uniq, x)
+	}
+	return uniq
+}
+
+// resolveEmbedded returns the package path and the name of the embedded interface, declared in the file. (e.g. "io.Reader" -> "io", "Reader")
+// obname is "" if not resolved (e.g. the union of the type constraints, or the builtin interfaces like "comparable").
+// If the file is unknown (e.g. the position is not restored), the alias is resolved only if all the files agree.
+func (l *Lookup) resolveEmbedded(pkgpath, filename, name string) (typepath string, obname string) {
+	if name == "comparable" || name == "any" || name == "error" {
+		return "", ""
+	}
+	alias, obname, qualified := strings.Cut(name, ".")
+	if !qualified {
+		return pkgpath, alias
+	}
+	p, ok := l.cache[pkgpath]
+	if !ok {
+		return "", ""
+	}
+	if imports, ok := p.imports[filename]; ok {
+		typepath = imports[alias]
+	} else {
+		for _, imports := range p.imports {
+			if path, ok := imports[alias]; ok {
+				if typepath != "" && typepath != path {
+					return "", "" // ambiguous
+				}
+				typepath = path
+			}
+		}
+	}
+	if typepath == "" {
+		return "", ""
+	}
+	return typepath, obname
+}
+
+var importVersionSuffixRx = regexp.MustCompile(`^v[0-9]+$`)
+
+// collectImports collects the import paths of the files, keyed by the filenames and then the names used in the files.
+// If the import is not named, the name is guessed from the path. (e.g. "gopkg.in/yaml.v3" -> "yaml", "github.com/foo/go-bar/v2" -> "bar")
+func collectImports(fset *token.FileSet, files []*ast.File) map[string]map[string]string {
+	var r map[string]map[string]string
+	for _, f := range files {
+		tf := fset.File(f.Pos())
+		if tf == nil {
+			continue
+		}
+		for _, spec := range f.Imports {
+			path, err := strconv.Unquote(spec.Path.Value)
+			if err != nil {
+				continue
+			}
+			var name string
+			if spec.Name != nil {
+				name = spec.Name.Name
+				if name == "_" || name == "." {
+					continue
+				}
+			} else {
+				parts := strings.Split(path, "/")
+				name = parts[len(parts)-1]
+				if importVersionSuffixRx.MatchString(name) && len(parts) > 1 {
+					name = parts[len(parts)-2]
+				}
+				name, _, _ = strings.Cut(name, ".")
+				name = strings.TrimPrefix(name, "go-")
+				name = strings.ReplaceAll(name, "-", "")
+			}
+			if r == nil {
+				r = map[string]map[string]string{}
+			}
+			if r[tf.Name()] == nil {
+				r[tf.Name()] = map[string]string{}
+			}
+			r[tf.Name()][name] = path
+		}
+	}
+	return r
+}
+
+// collectEmbeds collects the embedded types of the interfaces, keyed by the names of the interfaces. (e.g. "ReadCloser" -> ["Reader", "Closer"])
+func collectEmbeds(files []*ast.File) map[string][]string {
+	var r map[string][]string
+	for _, f := range files {
+		for _, decl := range f.Decls {
+			decl, ok := decl.(*ast.GenDecl)
+			if !ok || decl.Tok != token.TYPE {
+				continue
+			}
+			for _, spec := range decl.Specs {
+				spec := spec.(*ast.TypeSpec)
+				typ, ok := spec.Type.(*ast.InterfaceType)
+				if !ok || typ.Methods == nil {
+					continue
+				}
+				for _, field := range typ.Methods.List {
+					if len(field.Names) > 0 {
+						continue
+					}
+					var name string
+					switch x := field.Type.(type) {
+					case *ast.Ident:
+						name = x.Name
+					case *ast.SelectorExpr:
+						if pkg, ok := x.X.(*ast.Ident); ok {
+							name = pkg.Name + "." + x.Sel.Name
+						}
+					}
+					if name == "" {
+						continue // e.g. ~int | ~string (type constraint)
+					}
+					if r == nil {
+						r = map[string][]string{}
+					}
+					r[spec.Name.Name] = append(r[spec.Name.Name], name)
+				}
+			}
+		}
+	}
+	return r
+}
@@ -0,0 +1,113 @@
+package metadata
+
+import (
+	"context"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// J is J
+type J interface {
+	I
+	context.Context
+
+	// Err is overridden
+	Err() error
+}
+
+func TestLookupFromInterface(t *testing.T) {
+	l := NewLookup(token.NewFileSet())
+	l.IncludeGoTestFiles = true
+
+	iface, err := l.LookupFromInterface((*J)(nil))
+	if err != nil {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+	if want, got := "J is J", iface.Doc(); want != got {
+		t.Errorf("Doc(): want:%q != got:%q", want, got)
+	}
+
+	type method struct {
+		Name     string
+		Embedded string
+	}
+	var got []method
+	for _, m := range iface.Methods {
+		got = append(got, method{Name: m.Name, Embedded: m.Embedded})
+	}
+	want := []method{{"Foo", "I"}, {"Deadline", "context.Context"}, {"Done", "context.Context"}, {"Err", ""}, {"Value", "context.Context"}} // Err is overridden in place
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Methods mismatch (-want, +got): \n%v", diff)
+	}
+
+	comments := iface.MethodComments()
+	if want, got := "Foo is Foo", comments["Foo"]; want != got {
+		t.Errorf("MethodComments()[Foo]: want:%q != got:%q", want, got)
+	}
+	if want, got := "Err is overridden", comments["Err"]; want != got {
+		t.Errorf("MethodComments()[Err]: want:%q != got:%q", want, got)
+	}
+	if want, got := "Done returns a channel", comments["Done"]; !strings.HasPrefix(got, want) {
+		t.Errorf("MethodComments()[Done]: want prefix:%q != got:%q", want, got)
+	}
+
+	if _, err := l.LookupFromInterface(Person{}); err == nil {
+		t.Errorf("want error (not interface), but nil")
+	}
+}
+
+func TestCollectImports(t *testing.T) {
+	cases := []struct {
+		path string
+		want string
+	}{
+		{path: `"io"`, want: "io"},
+		{path: `"gopkg.in/yaml.v3"`, want: "yaml"},
+		{path: `"github.com/foo/go-bar/v2"`, want: "bar"},
+	}
+	for _, c := range cases {
+		c := c
+		t.Run(c.want, func(t *testing.T) {
+			fset := token.NewFileSet()
+			f, err := parser.ParseFile(fset, "x.go", "package x\nimport "+c.path, parser.ImportsOnly)
+			if err != nil {
+				t.Fatalf("unexpected error: %+v", err)
+			}
+			got := collectImports(fset, []*ast.File{f})["x.go"]
+			if _, ok := got[c.want]; !ok {
+				t.Errorf("collectImports(): want:%q, but got:%v", c.want, got)
+			}
+		})
+	}
+}
+
+func TestResolveEmbedded(t *testing.T) {
+	l := NewLookup(token.NewFileSet())
+	l.cache["example.com/x"] = &packageRef{imports: map[string]map[string]string{
+		"a.go": {"yaml": "gopkg.in/yaml.v2", "io": "io"},
+		"b.go": {"yaml": "gopkg.in/yaml.v3", "io": "io"},
+	}}
+
+	cases := []struct {
+		filename string
+		name     string
+		want     string
+	}{
+		{filename: "a.go", name: "yaml.Marshaler", want: "gopkg.in/yaml.v2.Marshaler"},
+		{filename: "b.go", name: "yaml.Marshaler", want: "gopkg.in/yaml.v3.Marshaler"},
+		{filename: "", name: "yaml.Marshaler", want: "."}, // ambiguous
+		{filename: "", name: "io.Reader", want: "io.Reader"},
+		{filename: "a.go", name: "Reader", want: "example.com/x.Reader"},
+	}
+	for _, c := range cases {
+		typepath, obname := l.resolveEmbedded("example.com/x", c.filename, c.name)
+		if got := typepath + "." + obname; c.want != got {
+			t.Errorf("resolveEmbedded(%q, %q): want:%q != got:%q", c.filename, c.name, c.want, got)
+		}
+	}
+}
@@ -2,6 +2,7 @@ package unsaferuntime
 
 import (
 	"fmt"
+	"reflect"
 	"runtime"
 	"strings"
 	"unsafe"
@@ -57,6 +58,17 @@ func (a *Accessor) FuncForPC(pc uintptr) *runtime.Func {
 	return nil
 }
 
+// ModuleOf returns the plugin path of the module that includes pc ("" if the module is not plugin).
+// If pc is not included in any modules, ok is false.
+func (a *Accessor) ModuleOf(pc uintptr) (pluginpath string, ok bool) {
+	for datap := &runtime_firstmoduledata; datap != nil; datap = datap.next {
+		if datap.minpc <= pc && pc < datap.maxpc {
+			return datap.pluginpath, true
+		}
+	}
+	return "", false
+}
+
 func Print(pc uintptr, pkg string) error {
 	prefix := strings.TrimSuffix(pkg, ".") + "."
 
@@ -78,3 +90,78 @@ func Print(pc uintptr, pkg string) error {
 
 	return nil
 }
+
+// Module is the loaded module. (the main executable, or the plugin)
+type Module struct {
+	PluginPath   string // "" if the module is not plugin
+	MinPC, MaxPC uintptr
+	HasMain      bool
+}
+
+// Modules returns the loaded modules, the main executable first.
+func (a *Accessor) Modules() []Module {
+	var r []Module
+	for datap := &runtime_firstmoduledata; datap != nil; datap = datap.next {
+		r = append(r, Module{PluginPath: datap.pluginpath, MinPC: datap.minpc, MaxPC: datap.maxpc, HasMain: datap.hasmain == 1})
+	}
+	return r
+}
+
+// pcHeaderMagic is the magic of the pclntab, that the copied layout of moduledata is for. (go1.18, go1.19)
+const pcHeaderMagic = 0xFFFFFFF0
+
+// Supported reports whether the layout of the runtime internals is the same as the copied one.
+// If false, the other functions of this package must not be used.
+func Supported() bool {
+	datap := &runtime_firstmoduledata
+	if datap.pcHeader == nil || datap.pcHeader.magic != pcHeaderMagic {
+		return false
+	}
+	pc := reflect.ValueOf(Supported).Pointer()
+	if !(datap.minpc <= pc && pc < datap.maxpc) || len(datap.ftab) == 0 {
+		return false
+	}
+	want := runtime.FuncForPC(pc)
+	for _, functab := range datap.ftab {
+		if int(functab.funcoff) >= len(datap.pclntable) {
+			return false
+		}
+		rfunc := (*runtime.Func)(unsafe.Pointer(&datap.pclntable[functab.funcoff]))
+		if rfunc == want {
+			return true
+		}
+	}
+	return false
+}
+
+// Funcs returns the functions linked into the modules, in the order of the pcs for each module.
+func (a *Accessor) Funcs() []*runtime.Func {
+	var r []*runtime.Func
+	for datap := &runtime_firstmoduledata; datap != nil; datap = datap.next {
+		nftab := len(datap.ftab) - 1 // the last one is the sentinel (the end of the text)
+		for _, functab := range datap.ftab[:nftab] {
+			r = append(r, (*runtime.Func)(unsafe.Pointer(&datap.pclntable[functab.funcoff])))
+		}
+	}
+	return r
+}
+
+// Types returns the types linked into the modules, that reflect can look up. (the unnamed composite types, e.g. *T, []T, map[K]V)
+// The named types are reachable from them. (e.g. *T -> T)
+func (a *Accessor) Types() []reflect.Type {
+	var r []reflect.Type
+	for datap := &runtime_firstmoduledata; datap != nil; datap = datap.next {
+		base := *(*unsafe.Pointer)(unsafe.Pointer(&datap.types))
+		for _, off := range datap.typelinks {
+			r = append(r, toType(unsafe.Add(base, off)))
+		}
+	}
+	return r
+}
+
+// toType converts the pointer of the runtime type (*_type) to reflect.Type. (reflect.Type is always *reflect.rtype, the same layout as *_type)
+func toType(p unsafe.Pointer) reflect.Type {
+	t := reflect.TypeOf(0)
+	(*[2]unsafe.Pointer)(unsafe.Pointer(&t))[1] = p
+	return t
+}
@@ -0,0 +1,203 @@
+package metadata
+
+import (
+	"fmt"
+	"go/types"
+	"reflect"
+	"strconv"
+
+	"golang.org/x/tools/go/packages"
+)
+
+// Layout is the memory layout of the struct.
+type Layout struct {
+	Name   string
+	Size   int64
+	Align  int64
+	Fields []FieldLayout
+}
+
+// FieldLayout is the memory layout of the struct field.
+type FieldLayout struct {
+	Name   string
+	Type   string // e.g. "int32", "layout.Header"
+	Offset int64
+	Size   int64
+	Align  int64
+}
+
+// LayoutMismatch is the difference between the memory layouts. (see Layout.Compare)
+type LayoutMismatch struct {
+	Index int    // the index of the field, -1 for the struct itself
+	Field string // the name of the field, "" for the struct itself
+	What  string // "size", "align", "offset", "type", "name", "missing" or "extra"
+	Want  string
+	Got   string
+}
+
+func (m LayoutMismatch) String() string {
+	if m.Index < 0 {
+		return fmt.Sprintf("%s: want:%s != got:%s", m.What, m.Want, m.Got)
+	}
+	return fmt.Sprintf("fields[%d] %s %s: want:%s != got:%s", m.Index, m.Field, m.What, m.Want, m.Got)
+}
+
+// Compare compares the memory layouts, and returns the mismatches. (nil if compatible)
+//
+// The fields are compared by position, because the names may be different (e.g. the go struct and the cgo struct for the same C struct).
+// The differences of the names and the types are reported only if checkNames is true.
+func (l *Layout) Compare(other *Layout, checkNames bool) []LayoutMismatch {
+	var r []LayoutMismatch
+	itoa := func(n int64) string { return strconv.FormatInt(n, 10) }
+	if l.Size != other.Size {
+		r = append(r, LayoutMismatch{Index: -1, What: "size", Want: itoa(l.Size), Got: itoa(other.Size)})
+	}
+	if l.Align != other.Align {
+		r = append(r, LayoutMismatch{Index: -1, What: "align", Want: itoa(l.Align), Got: itoa(other.Align)})
+	}
+
+	for i, f := range l.Fields {
+		if i >= len(other.Fields) {
+			r = append(r, LayoutMismatch{Index: i, Field: f.Name, What: "missing", Want: f.Name})
+			continue
+		}
+		g := other.Fields[i]
+		if f.Offset != g.Offset {
+			r = append(r, LayoutMismatch{Index: i, Field: f.Name, What: "offset", Want: itoa(f.Offset), Got: itoa(g.Offset)})
+		}
+		if f.Size != g.Size {
+			r = append(r, LayoutMismatch{Index: i, Field: f.Name, What: "size", Want: itoa(f.Size), Got: itoa(g.Size)})
+		}
+		if f.Align != g.Align {
+			r = append(r, LayoutMismatch{Index: i, Field: f.Name, What: "align", Want: itoa(f.Align), Got: itoa(g.Align)})
+		}
+		if checkNames && f.Name != g.Name {
+			r = append(r, LayoutMismatch{Index: i, Field: f.Name, What: "name", Want: f.Name, Got: g.Name})
+		}
+		if checkNames && f.Type != g.Type {
+			r = append(r, LayoutMismatch{Index: i, Field: f.Name, What: "type", Want: f.Type, Got: g.Type})
+		}
+	}
+	for i := len(l.Fields); i < len(other.Fields); i++ {
+		r = append(r, LayoutMismatch{Index: i, Field: other.Fields[i].Name, What: "extra", Got: other.Fields[i].Name})
+	}
+	return r
+}
+
+// ReflectLayout returns the memory layout of the struct, on the running platform.
+func ReflectLayout(rt reflect.Type) (*Layout, error) {
+	if rt.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("%v is not struct, %w", rt, ErrNotSupported)
+	}
+	fields := make([]FieldLayout, rt.NumField())
+	for i := 0; i < rt.NumField(); i++ {
+		f := rt.Field(i)
+		fields[i] = FieldLayout{Name: f.Name, Type: f.Type.String(), Offset: int64(f.Offset), Size: int64(f.Type.Size()), Align: int64(f.Type.Align())}
+	}
+	return &Layout{Name: rt.Name(), Size: int64(rt.Size()), Align: int64(rt.Align()), Fields: fields}, nil
+}
+
+// LookupLayout returns the memory layout of the struct, computed statically from the go source, with the build context of l. (GOOS, GOARCH, BuildTags)
+//
+// Unlike ReflectLayout, the declarations in the build-constrained files and the sizes are the ones of the target platform. (e.g. GOARCH=386 for the 32bit agents)
+func (l *Lookup) LookupLayout(rt reflect.Type) (*Layout, error) {
+	for rt.Kind() == reflect.Pointer {
+		rt = rt.Elem()
+	}
+	pkgpath := rt.PkgPath()
+	if pkgpath == "" || rt.Name() == "" {
+		return nil, fmt.Errorf("%v is not named type, %w", rt, ErrNotSupported)
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	cfg := &packages.Config{
+		Mode:  packages.NeedName | packages.NeedTypes | packages.NeedTypesSizes,
+		Tests: l.IncludeGoTestFiles,
+	}
+	pkgs, err := l.load(cfg, pkgpath)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, pkg := range l.selectPackages(pkgs) {
+		if pkg.PkgPath != pkgpath || pkg.Types == nil {
+			continue
+		}
+		ob := pkg.Types.Scope().Lookup(rt.Name())
+		if ob == nil {
+			return nil, l.notFound(rt.Name(), pkgpath, "", pkg.Types.Scope().Names())
+		}
+		return typesLayout(pkg.TypesSizes, ob.Type())
+	}
+	return nil, l.notFound(rt.Name(), pkgpath, "", nil)
+}
+
+func typesLayout(sizes types.Sizes, typ types.Type) (*Layout, error) {
+	st, ok := typ.Underlying().(*types.Struct)
+	if !ok {
+		return nil, fmt.Errorf("%v is not struct, %w", typ, ErrNotSupported)
+	}
+	if sizes == nil {
+		return nil, fmt.Errorf("the sizes of %v are unknown, %w", typ, ErrNotSupported)
+	}
+	sizes = paddedSizes{Sizes: sizes}
+
+	qualifier := func(pkg *types.Package) string { return pkg.Name() }
+	vars := make([]*types.Var, st.NumFields())
+	for i := 0; i < st.NumFields(); i++ {
+		vars[i] = st.Field(i)
+	}
+	offsets := sizes.Offsetsof(vars)
+
+	fields := make([]FieldLayout, len(vars))
+	for i, v := range vars {
+		fields[i] = FieldLayout{Name: v.Name(), Type: types.TypeString(v.Type(), qualifier), Offset: offsets[i], Size: sizes.Sizeof(v.Type()), Align: sizes.Alignof(v.Type())}
+	}
+	name := ""
+	if named, ok := typ.(*types.Named); ok {
+		name = named.Obj().Name()
+	}
+	return &Layout{Name: name, Size: sizes.Sizeof(typ), Align: sizes.Alignof(typ), Fields: fields}, nil
+}
+
+// paddedSizes rounds up the sizes of the structs to their alignments, like the gc compiler. (types.StdSizes of go1.19 doesn't pad the trailing fields)
+type paddedSizes struct {
+	types.Sizes
+}
+
+func (s paddedSizes) Sizeof(typ types.Type) int64 {
+	switch t := typ.Underlying().(type) {
+	case *types.Struct:
+		if t.NumFields() == 0 {
+			return 0
+		}
+		vars := make([]*types.Var, t.NumFields())
+		for i := 0; i < t.NumFields(); i++ {
+			vars[i] = t.Field(i)
+		}
+		offsets := s.Offsetsof(vars)
+		last := len(vars) - 1
+		return roundUp(offsets[last]+s.Sizeof(vars[last].Type()), s.Alignof(typ))
+	case *types.Array:
+		return t.Len() * s.Sizeof(t.Elem())
+	default:
+		return s.Sizes.Sizeof(typ)
+	}
+}
+
+func (s paddedSizes) Offsetsof(fields []*types.Var) []int64 {
+	offsets := make([]int64, len(fields))
+	var offset int64
+	for i, f := range fields {
+		offset = roundUp(offset, s.Alignof(f.Type()))
+		offsets[i] = offset
+		offset += s.Sizeof(f.Type())
+	}
+	return offsets
+}
+
+func roundUp(x, align int64) int64 {
+	return (x + align - 1) / align * align
+}
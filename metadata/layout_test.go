@@ -0,0 +1,142 @@
+package metadata
+
+import (
+	"go/token"
+	"reflect"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/podhmo/reflect-shape/metadata/testdata/layout"
+)
+
+func TestLookupLayout(t *testing.T) {
+	cases := []struct {
+		msg    string
+		goos   string
+		goarch string
+		input  interface{}
+		want   *Layout
+	}{
+		{msg: "amd64", goos: "linux", goarch: "amd64", input: layout.Header{},
+			want: &Layout{Name: "Header", Size: 24, Align: 8, Fields: []FieldLayout{
+				{Name: "Flag", Type: "uint8", Offset: 0, Size: 1, Align: 1},
+				{Name: "Size", Type: "uintptr", Offset: 8, Size: 8, Align: 8},
+				{Name: "Count", Type: "int32", Offset: 16, Size: 4, Align: 4},
+			}}},
+		{msg: "386", goos: "linux", goarch: "386", input: layout.Header{},
+			want: &Layout{Name: "Header", Size: 12, Align: 4, Fields: []FieldLayout{
+				{Name: "Flag", Type: "uint8", Offset: 0, Size: 1, Align: 1},
+				{Name: "Size", Type: "uintptr", Offset: 4, Size: 4, Align: 4},
+				{Name: "Count", Type: "int32", Offset: 8, Size: 4, Align: 4},
+			}}},
+		{msg: "build-constrained-linux", goos: "linux", goarch: "386", input: layout.Message{},
+			want: &Layout{Name: "Message", Size: 16, Align: 4, Fields: []FieldLayout{
+				{Name: "Header", Type: "layout.Header", Offset: 0, Size: 12, Align: 4},
+				{Name: "FD", Type: "int32", Offset: 12, Size: 4, Align: 4},
+			}}},
+		{msg: "build-constrained-windows", goos: "windows", goarch: "amd64", input: layout.Message{},
+			want: &Layout{Name: "Message", Size: 32, Align: 8, Fields: []FieldLayout{
+				{Name: "Header", Type: "layout.Header", Offset: 0, Size: 24, Align: 8},
+				{Name: "Handle", Type: "uintptr", Offset: 24, Size: 8, Align: 8},
+			}}},
+	}
+	for _, c := range cases {
+		c := c
+		t.Run(c.msg, func(t *testing.T) {
+			l := NewLookup(token.NewFileSet())
+			l.GOOS = c.goos
+			l.GOARCH = c.goarch
+
+			got, err := l.LookupLayout(reflect.TypeOf(c.input))
+			if err != nil {
+				t.Fatalf("unexpected error: %+v", err)
+			}
+			if diff := cmp.Diff(c.want, got); diff != "" {
+				t.Errorf("LookupLayout(): -want, +got: \n%v", diff)
+			}
+		})
+	}
+
+	t.Run("same-as-reflect", func(t *testing.T) {
+		l := NewLookup(token.NewFileSet())
+		rt := reflect.TypeOf(layout.Message{})
+
+		want, err := ReflectLayout(rt)
+		if err != nil {
+			t.Fatalf("unexpected error: %+v", err)
+		}
+		got, err := l.LookupLayout(rt)
+		if err != nil {
+			t.Fatalf("unexpected error: %+v", err)
+		}
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Errorf("LookupLayout(): -want, +got: \n%v", diff)
+		}
+	})
+}
+
+func TestLayoutCompare(t *testing.T) {
+	type point struct {
+		X int32
+		Y int32
+	}
+	type cpoint struct {
+		x int32
+		y int32
+	}
+	type wide struct {
+		X int64
+		Y int32
+	}
+	type point3 struct {
+		X int32
+		Y int32
+		Z int32
+	}
+
+	cases := []struct {
+		msg        string
+		x, y       interface{}
+		checkNames bool
+		want       []string
+	}{
+		{msg: "same", x: point{}, y: point{}, want: nil},
+		{msg: "renamed", x: point{}, y: cpoint{}, want: nil},
+		{msg: "renamed-check-names", x: point{}, y: cpoint{}, checkNames: true, want: []string{
+			"fields[0] X name: want:X != got:x",
+			"fields[1] Y name: want:Y != got:y",
+		}},
+		{msg: "wide", x: point{}, y: wide{}, want: []string{
+			"size: want:8 != got:16",
+			"align: want:4 != got:8",
+			"fields[0] X size: want:4 != got:8",
+			"fields[0] X align: want:4 != got:8",
+			"fields[1] Y offset: want:4 != got:8",
+		}},
+		{msg: "extra", x: point{}, y: point3{}, want: []string{
+			"size: want:8 != got:12",
+			"fields[2] Z extra: want: != got:Z",
+		}},
+	}
+	for _, c := range cases {
+		c := c
+		t.Run(c.msg, func(t *testing.T) {
+			x, err := ReflectLayout(reflect.TypeOf(c.x))
+			if err != nil {
+				t.Fatalf("unexpected error: %+v", err)
+			}
+			y, err := ReflectLayout(reflect.TypeOf(c.y))
+			if err != nil {
+				t.Fatalf("unexpected error: %+v", err)
+			}
+
+			var got []string
+			for _, m := range x.Compare(y, c.checkNames) {
+				got = append(got, m.String())
+			}
+			if diff := cmp.Diff(c.want, got); diff != "" {
+				t.Errorf("Layout.Compare(): -want, +got: \n%v", diff)
+			}
+		})
+	}
+}
@@ -1,6 +1,8 @@
 package metadata
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"go/ast"
 	"go/parser"
@@ -10,12 +12,14 @@ import (
 	"reflect"
 	"runtime"
 	"runtime/debug"
+	"sort"
 	"strconv"
 	"strings"
+	"sync"
 
 	"github.com/podhmo/commentof"
 	"github.com/podhmo/commentof/collect"
-	"github.com/podhmo/reflect-shape/metadata/internal/unsaferuntime"
+	"github.com/podhmo/reflect-shape/metadata/runtimeinfo"
 	"golang.org/x/tools/go/packages"
 )
 
@@ -25,28 +29,135 @@ var ErrNotFound = fmt.Errorf("not found")
 // ErrNotSupported is the error metadata is not supported, yet
 var ErrNotSupported = fmt.Errorf("not supported")
 
+// NoSourceError is the error that the function has no Go source. (e.g. implemented in assembly, or autogenerated wrapper)
+type NoSourceError struct {
+	Name     string // the name of runtime function
+	Filename string // e.g. "asm_amd64.s", "<autogenerated>"
+}
+
+func (e *NoSourceError) Error() string {
+	return fmt.Sprintf("no go source of %s (file=%s)", e.Name, e.Filename)
+}
+
+func (e *NoSourceError) Unwrap() error {
+	return ErrNotSupported
+}
+
+func (e *NoSourceError) Is(target error) bool {
+	return target == ErrSourceUnavailable
+}
+
+// ExternalPCError is the error that the pc cannot be handled. (e.g. not included in any modules, or in the main package of the plugin)
+type ExternalPCError struct {
+	PC         uintptr
+	Name       string // the name of runtime function, if found
+	PluginPath string // the plugin path of the module, if the pc is included in the plugin
+	InModule   bool   // false if the pc is not included in any modules
+}
+
+func (e *ExternalPCError) Error() string {
+	switch {
+	case !e.InModule:
+		return fmt.Sprintf("pc=%x is not included in any modules", e.PC)
+	case e.PluginPath != "":
+		return fmt.Sprintf("lookup metadata of %s in plugin %s", e.Name, e.PluginPath)
+	default:
+		return fmt.Sprintf("cannot find runtime.Func of pc=%x", e.PC)
+	}
+}
+
+func (e *ExternalPCError) Unwrap() error {
+	return ErrNotSupported
+}
+
 var DEBUG = false
 
+// Logger is the destination of the logs. (*log.Logger satisfies it, and the structured loggers can be adapted)
+type Logger interface {
+	Printf(format string, args ...interface{})
+}
+
+// LoggerFunc is the adapter to use the ordinary function as Logger. (e.g. func(format string, args ...any) { slog.Debug(fmt.Sprintf(format, args...)) })
+type LoggerFunc func(format string, args ...interface{})
+
+func (f LoggerFunc) Printf(format string, args ...interface{}) {
+	f(format, args...)
+}
+
 func init() {
 	if ok, _ := strconv.ParseBool(os.Getenv("DEBUG")); ok {
 		DEBUG = true
 	}
 }
 
+// Lookup looks up the metadata (comments, names of arguments) of the types and functions, from the go source.
+//
+// Lookup is safe for concurrent use. The lookups are serialized, because parsing mutates both the Fset and the cache,
+// except loading the packages: the different packages are loaded in parallel, and the same package is loaded only once. (singleflight)
+// (Fset is owned by Lookup, so don't use it for parsing elsewhere while looking up. The positions stored in metadata are relative to Fset)
 type Lookup struct {
 	Fset     *token.FileSet
-	accessor *unsaferuntime.Accessor
+	accessor runtimeinfo.Accessor
 
 	IncludeGoTestFiles bool
 	IncludeUnexported  bool
 
-	cache map[string]*packageRef // TODO: lock
+	// Offline forbids the network access (module downloads) during loading packages. (GOPROXY=off, GOTOOLCHAIN=local, and SourceModuleCache is skipped)
+	Offline bool
+
+	// GOOS, GOARCH, and BuildTags override the build context, to select the declarations in build-constrained files (e.g. x_linux.go, x_windows.go).
+	// (only for the types. the functions are looked up from the file of the running binary)
+	GOOS      string
+	GOARCH    string
+	BuildTags []string
+
+	// Trace logs how the names are parsed and which names are considered as candidates, for debugging the lookup failures.
+	Trace bool
+
+	// CacheDir enables the disk cache of the collected metadata of the types, if not empty. (opt-in, e.g. DefaultCacheDir())
+	// The entries are keyed by the package and the build context, and invalidated when the files of the package are changed.
+	CacheDir string
+
+	// Encoding is the encoding of the disk cache and the metadata generated by GeneratePrecompiled. If empty, EncodingJSON is used.
+	Encoding Encoding
+
+	// Loader is used instead of packages.Load, if not nil. (e.g. sharing the results loaded by the caller, see also AddPackage)
+	// It can be called concurrently for the different packages.
+	Loader func(cfg *packages.Config, patterns ...string) ([]*packages.Package, error)
+
+	// Logger receives the logs of the lookup (the load errors, the cache hits and misses, and the traces), if not nil.
+	// If nil, the standard logger (log.Printf) is used. (to silence, use log.New(io.Discard, "", 0))
+	Logger Logger
+
+	// MaxPackages limits the number of the cached packages, if positive. The least recently used packages are evicted.
+	MaxPackages int
+
+	// Sources is the ordered chain of the metadata sources, tried after the in-process cache. If nil, DefaultSources is used.
+	// The sources not in the chain are never used, e.g. []Source{SourcePrecompiled} never reads the source. (no sources means no docs)
+	Sources []Source
+
+	// CollectConcurrency is the number of the goroutines collecting the comments of the files in a package.
+	// If 0, runtime.GOMAXPROCS(0) is used. 1 means sequential. (the result doesn't depend on it)
+	CollectConcurrency int
+
+	// Redeclared chooses the declaration of the name declared in the multiple files of a package, if not nil.
+	// It returns the index of Redeclaration.Candidates, or -1 to use the default rule. (see Redeclaration.Default)
+	Redeclared func(r *Redeclaration) int
+
+	mu       sync.Mutex
+	cache    map[string]*packageRef
+	closures map[string]map[uintptr]*Func // pkgpath -> pc -> the metadata of the closure
+	inflight map[string]chan struct{}     // pkgpath -> closed when the loading is finished
+	used     map[string]uint64            // pkgpath -> the tick of the last use (for LRU eviction)
+	files    map[string]*token.File       // name + digest -> the file added to Fset by the cached metadata (see addFile)
+	tick     uint64
+	evicted  int
 }
 
 func NewLookup(fset *token.FileSet) *Lookup {
 	return &Lookup{
 		Fset:               fset,
-		accessor:           unsaferuntime.New(),
+		accessor:           runtimeinfo.New(),
 		IncludeGoTestFiles: false,
 		IncludeUnexported:  false,
 		cache:              map[string]*packageRef{},
@@ -57,6 +168,11 @@ type Func struct {
 	pc   uintptr
 	Raw  *collect.Func
 	Recv string
+
+	// IsClosure is true if the function is the func literal (e.g. "pkg.F.func1"), its name is "F.func1". (see lookupClosure)
+	IsClosure bool
+	// Enclosing is the function declaration having the closure, nil if not a closure or the closure of the package-level variable.
+	Enclosing *Func
 }
 
 func (m *Func) Fullname() string {
@@ -76,7 +192,11 @@ type Var struct {
 	Doc  string
 }
 
+// Args returns the arguments, nil if unknown. (e.g. the func literal of the closure is not found)
 func (m *Func) Args() []Var {
+	if m.Raw.ParamNames == nil {
+		return nil
+	}
 	vars := make([]Var, len(m.Raw.ParamNames))
 	for i, id := range m.Raw.ParamNames {
 		p := m.Raw.Params[id]
@@ -89,7 +209,11 @@ func (m *Func) Args() []Var {
 	return vars
 }
 
+// Returns returns the return values, nil if unknown.
 func (m *Func) Returns() []Var {
+	if m.Raw.ReturnNames == nil {
+		return nil
+	}
 	vars := make([]Var, len(m.Raw.ReturnNames))
 	for i, id := range m.Raw.ReturnNames {
 		p := m.Raw.Returns[id]
@@ -108,35 +232,68 @@ func (l *Lookup) LookupFromFunc(fn interface{}) (*Func, error) {
 }
 
 func (l *Lookup) LookupFromFuncForPC(pc uintptr) (*Func, error) {
+	return l.LookupFromFuncForPCContext(context.Background(), pc)
+}
+
+// LookupFromFuncContext is LookupFromFunc with the context. (the lookup is failed if ctx is done)
+func (l *Lookup) LookupFromFuncContext(ctx context.Context, fn interface{}) (*Func, error) {
+	pc := reflect.ValueOf(fn).Pointer()
+	return l.LookupFromFuncForPCContext(ctx, pc)
+}
+
+// LookupFromFuncForPCContext is LookupFromFuncForPC with the context. (the lookup is failed if ctx is done)
+func (l *Lookup) LookupFromFuncForPCContext(ctx context.Context, pc uintptr) (*Func, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	defer l.evict()
+	m, err := l.lookupFromFuncForPC(pc)
+	if err != nil && l.Trace && errors.Is(err, ErrNotFound) {
+		if rfunc := l.accessor.FuncForPC(pc); rfunc != nil {
+			l.traceCandidates(rfuncPkgpath(rfunc))
+		}
+	}
+	return m, err
+}
+
+func (l *Lookup) lookupFromFuncForPC(pc uintptr) (*Func, error) {
 	rfunc := l.accessor.FuncForPC(pc)
 	if rfunc == nil {
-		return nil, fmt.Errorf("cannot find runtime.Func")
+		_, inModule := l.accessor.ModuleOf(pc)
+		return nil, &ExternalPCError{PC: pc, InModule: inModule}
+	}
+	if m, _ := l.accessor.ModuleOf(pc); m.PluginPath != "" && strings.HasPrefix(rfunc.Name(), "plugin/unnamed-") {
+		// the main package of the plugin, its source cannot be located
+		return nil, &ExternalPCError{PC: pc, Name: rfunc.Name(), PluginPath: m.PluginPath, InModule: true}
 	}
 
 	filename, _ := rfunc.FileLine(rfunc.Entry())
-
-	// /<pkg name>.<function name>
-	// /<pkg name>.<recv>.<method name>
-	// /<pkg name>.<recv>.<method name>-fm
-
-	parts := strings.Split(rfunc.Name(), "/")
-	last := parts[len(parts)-1]
-	pkgname, name, isFunc := strings.Cut(last, ".")
-	_ = pkgname
-	if !isFunc {
-		return nil, fmt.Errorf("unexpected func: %v", rfunc.Name())
+	if !strings.HasSuffix(filename, ".go") {
+		return nil, &NoSourceError{Name: rfunc.Name(), Filename: filename}
 	}
 
-	recv, name, isMethod := strings.Cut(name, ".")
-	if isMethod {
-		recv = strings.Trim(recv, "(*)")
-	} else {
-		name = recv
-		recv = ""
+	// the generics are mapped to the generic declaration (see runtimeinfo.ParseFuncName)
+	fn, err := runtimeinfo.ParseFuncName(rfunc.Name())
+	if err != nil {
+		return nil, fmt.Errorf("unexpected func: %w", err)
+	}
+	if fn.IsClosure() {
+		return l.lookupClosure(pc, rfunc, fn)
+	}
+	pkgpath, recv, name, isMethod := fn.PkgPath, fn.Recv, fn.Name, fn.IsMethod()
+	if l.Trace {
+		l.logf("trace: func %q -> pkgpath=%q, recv=%q, name=%q, isMethod=%v, file=%s", rfunc.Name(), pkgpath, recv, name, isMethod, filename)
+	}
+	l.touch(pkgpath)
+	if _, ok := l.cache[pkgpath]; !ok {
+		if p, loads := l.fromSources(pkgpath); p != nil {
+			l.cache[pkgpath] = p
+		} else if len(loads) == 0 {
+			return nil, l.unavailable(strings.TrimPrefix(recv+"."+name, "."), pkgpath)
+		}
 	}
-	// log.Printf("pkgname:%-15s\trecv:%-10s\tname:%s\tisMethod:%v\n", pkgname, recv, name, isMethod)
-
-	pkgpath := rfuncPkgpath(rfunc)
 	p0, ok := l.cache[pkgpath]
 	if ok {
 		if p0.fullset {
@@ -147,33 +304,32 @@ func (l *Lookup) LookupFromFuncForPC(pc uintptr) (*Func, error) {
 			if isMethod {
 				ob, ok := p0.Types[recv]
 				if !ok {
-					// anonymous function? (TODO: correct check)
-					if _, ok := p0.Functions[name]; !ok {
-						return nil, fmt.Errorf("lookup metadata of anonymous function %s, %w", rfunc.Name(), ErrNotSupported)
-					}
-					return nil, fmt.Errorf("lookup metadata of method %s, %w", rfunc.Name(), ErrNotFound)
+					return nil, l.notFound(recv+"."+name, pkgpath, "", keys(p0.Types))
 				}
 				result, ok := ob.Methods[name]
 				if !ok {
-					return nil, fmt.Errorf("lookup metadata of method %s, %w", rfunc.Name(), ErrNotFound)
+					return nil, l.notFound(recv+"."+name, pkgpath, "", keys(ob.Methods))
 				}
 				if DEBUG {
-					log.Println("\tOK func cache (full)", rfunc.Name())
+					l.logf("\tOK func cache (full) %v", rfunc.Name())
 				}
 				return &Func{pc: pc, Raw: result, Recv: recv}, nil
 			} else {
 				result, ok := p0.Functions[name]
 				if !ok {
-					return nil, fmt.Errorf("lookup metadata of %s is failed %w", rfunc.Name(), ErrNotFound)
+					return nil, l.notFound(name, pkgpath, "", keys(p0.Functions))
 				}
 				if DEBUG {
-					log.Println("\tOK func cache (full)", rfunc.Name())
+					l.logf("\tOK func cache (full) %v", rfunc.Name())
 				}
 				return &Func{Raw: result}, nil
 			}
 		}
 
-		for _, visitedFile := range p0.FileNames {
+		if err, ok := p0.fileErrs[filename]; ok {
+			return nil, err // error cache
+		}
+		for _, visitedFile := range p0.visitedFiles() {
 			if visitedFile == filename {
 				f, ok := p0.Files[filename]
 				if !ok {
@@ -183,27 +339,23 @@ func (l *Lookup) LookupFromFuncForPC(pc uintptr) (*Func, error) {
 				if isMethod {
 					ob, ok := f.Types[recv]
 					if !ok {
-						// anonymous function? (TODO: correct check)
-						if _, ok := p0.Functions[name]; !ok {
-							return nil, fmt.Errorf("lookup metadata of anonymous function %s, %w", rfunc.Name(), ErrNotSupported)
-						}
-						return nil, fmt.Errorf("lookup metadata of method %s, %w", rfunc.Name(), ErrNotFound)
+						return nil, l.notFound(recv+"."+name, pkgpath, filename, keys(f.Types))
 					}
 					result, ok := ob.Methods[name]
 					if !ok {
-						return nil, fmt.Errorf("lookup metadata of method %s, %w", rfunc.Name(), ErrNotFound)
+						return nil, l.notFound(recv+"."+name, pkgpath, filename, keys(ob.Methods))
 					}
 					if DEBUG {
-						log.Println("\tOK func cache", rfunc.Name())
+						l.logf("\tOK func cache %v", rfunc.Name())
 					}
 					return &Func{pc: pc, Raw: result, Recv: recv}, nil
 				} else {
 					result, ok := f.Functions[name]
 					if !ok {
-						return nil, fmt.Errorf("lookup metadata of %s is failed.. %w", rfunc.Name(), ErrNotFound)
+						return nil, l.notFound(name, pkgpath, filename, keys(f.Functions))
 					}
 					if DEBUG {
-						log.Println("\tOK func cache", rfunc.Name())
+						l.logf("\tOK func cache %v", rfunc.Name())
 					}
 					return &Func{pc: pc, Raw: result}, nil
 				}
@@ -211,57 +363,111 @@ func (l *Lookup) LookupFromFuncForPC(pc uintptr) (*Func, error) {
 		}
 	}
 
+	if p0 == nil {
+		p0 = &packageRef{fullset: false}
+		l.cache[pkgpath] = p0
+	}
 	f, err := parser.ParseFile(l.Fset, filename, nil, parser.ParseComments)
 	if f == nil {
-		l.cache[pkgpath] = &packageRef{fullset: false, err: err} // error cache
+		err = &SourceUnavailableError{Symbol: strings.TrimPrefix(recv+"."+name, "."), PkgPath: pkgpath, Filename: filename, Err: err}
+		p0.setFileErr(filename, err) // error cache
 		return nil, err
 	}
 
-	p, err := commentof.File(l.Fset, f, commentof.WithIncludeUnexported(l.IncludeUnexported), func(b *collect.PackageBuilder) {
-		if p0 != nil {
+	p, err := commentof.File(l.Fset, normalizeGenericReceivers(f), commentof.WithIncludeUnexported(l.IncludeUnexported), func(b *collect.PackageBuilder) {
+		if p0.Package != nil {
 			b.Package = p0.Package // merge
 		}
 	})
-	if !ok && p != nil {
-		l.cache[pkgpath] = &packageRef{fullset: false, Package: p}
+	if p0.Package == nil && p != nil {
+		p0.Package = p
 	}
 	if err != nil {
+		p0.setFileErr(filename, err)
 		return nil, err
 	}
 
 	if DEBUG {
-		log.Println("\tNG func cache", rfunc.Name())
+		l.logf("\tNG func cache %v", rfunc.Name())
 	}
 	if isMethod {
 		ob, ok := p.Types[recv]
 		if !ok {
-			// anonymous function? (TODO: correct check)
-			if _, ok := p.Functions[name]; !ok {
-				return nil, fmt.Errorf("lookup metadata of anonymous function %s, %w", rfunc.Name(), ErrNotSupported)
-			}
-			return nil, fmt.Errorf("lookup metadata of method %s, %w", rfunc.Name(), ErrNotFound)
+			return nil, l.notFound(recv+"."+name, pkgpath, filename, keys(p.Types))
 		}
 		result, ok := ob.Methods[name]
 		if !ok {
-			return nil, fmt.Errorf("lookup metadata of method %s, %w", rfunc.Name(), ErrNotFound)
+			return nil, l.notFound(recv+"."+name, pkgpath, filename, keys(ob.Methods))
 		}
 		return &Func{pc: pc, Raw: result, Recv: recv}, nil
 	} else {
 		result, ok := p.Functions[name]
 		if !ok {
-			return nil, fmt.Errorf("lookup metadata of function %s, %w", rfunc.Name(), ErrNotFound)
+			return nil, l.notFound(name, pkgpath, filename, keys(p.Functions))
 		}
 		return &Func{pc: pc, Raw: result}, nil
 	}
 }
 
+// traceCandidates logs the names available in the package, considered as the candidates of the lookup.
+func (l *Lookup) traceCandidates(pkgpath string) {
+	ref, ok := l.cache[pkgpath]
+	if !ok || ref.Package == nil {
+		l.logf("trace: candidates of %q -> (package is not collected)", pkgpath)
+		return
+	}
+
+	var types, funcs []string
+	for name, ob := range ref.Types {
+		types = append(types, name)
+		for mname := range ob.Methods {
+			funcs = append(funcs, name+"."+mname)
+		}
+	}
+	for name := range ref.Interfaces {
+		types = append(types, name)
+	}
+	for name := range ref.Functions {
+		funcs = append(funcs, name)
+	}
+	sort.Strings(types)
+	sort.Strings(funcs)
+	l.logf("trace: candidates of %q (fullset=%v) -> types=%v, funcs=%v", pkgpath, ref.fullset, types, funcs)
+}
+
+// TrimTypeArgs removes the type arguments of the generic instantiations in the name of the runtime function.
+// (e.g. "pkg.Map[...]" -> "pkg.Map", "pkg.(*List[go.shape.int_0]).Push" -> "pkg.(*List).Push")
+func TrimTypeArgs(name string) string {
+	if !strings.Contains(name, "[") {
+		return name
+	}
+	var b strings.Builder
+	depth := 0
+	for _, c := range name {
+		switch {
+		case c == '[':
+			depth++
+		case c == ']' && depth > 0:
+			depth--
+		case depth == 0:
+			b.WriteRune(c)
+		}
+	}
+	return b.String()
+}
+
 func rfuncPkgpath(rfunc *runtime.Func) string {
-	parts := strings.Split(rfunc.Name(), ".")
-	return strings.Join(parts[:len(parts)-1], ".")
+	fn, err := runtimeinfo.ParseFuncName(rfunc.Name())
+	if err != nil {
+		return ""
+	}
+	return fn.PkgPath
 }
 
 type Type struct {
-	Raw *collect.Object
+	Raw        *collect.Object
+	Consts     []Const     // typed constants declared in the package (for enum)
+	TypeParams []TypeParam // the type parameters, if the type is generic (e.g. type List[T any] struct{...})
 }
 
 func (s *Type) Name() string {
@@ -283,7 +489,7 @@ func (s *Type) FieldComments() map[string]string {
 		if doc == "" {
 			doc = f.Comment
 		}
-		comments[f.Name] = strings.TrimSpace(doc)
+		comments[f.Name] = strings.TrimSpace(stripSections(doc))
 	}
 	return comments
 }
@@ -293,100 +499,434 @@ func (l *Lookup) LookupFromType(ob interface{}) (*Type, error) {
 	return l.LookupFromTypeForReflectType(rt)
 }
 func (l *Lookup) LookupFromTypeForReflectType(rt reflect.Type) (*Type, error) {
+	return l.LookupFromTypeForReflectTypeContext(context.Background(), rt)
+}
+
+// LookupFromTypeContext is LookupFromType with the context, passed to packages.Load(). (the loading is cancelled if ctx is done)
+func (l *Lookup) LookupFromTypeContext(ctx context.Context, ob interface{}) (*Type, error) {
+	rt := reflect.TypeOf(ob)
+	return l.LookupFromTypeForReflectTypeContext(ctx, rt)
+}
+
+// LookupFromTypeForReflectTypeContext is LookupFromTypeForReflectType with the context, passed to packages.Load().
+// The cancellation is not cached as the failure of the package, so the next lookup loads it again.
+func (l *Lookup) LookupFromTypeForReflectTypeContext(ctx context.Context, rt reflect.Type) (*Type, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	defer l.evict()
+	m, err := l.lookupFromTypeForReflectType(ctx, rt)
+	if err != nil && l.Trace && errors.Is(err, ErrNotFound) {
+		l.traceCandidates(rt.PkgPath())
+	}
+	return m, err
+}
+
+func (l *Lookup) lookupFromTypeForReflectType(ctx context.Context, rt reflect.Type) (*Type, error) {
 	obname, _, _ := strings.Cut(rt.Name(), "[") // for generics
 	pkgpath := rt.PkgPath()
+	if l.Trace {
+		l.logf("trace: type %q -> pkgpath=%q, name=%q", rt, pkgpath, obname)
+	}
 
-	if pkgpath == "main" {
-		binfo, ok := debug.ReadBuildInfo()
-		if !ok {
-			log.Println("debug.ReadBuildInfo() is failed")
-			return nil, ErrNotFound
+	pkgpath, ok := l.resolvePkgpath(pkgpath)
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return l.lookupType(ctx, pkgpath, obname)
+}
+
+// resolvePkgpath returns the real package path of "main" (the path of the main module), and others as is.
+func (l *Lookup) resolvePkgpath(pkgpath string) (string, bool) {
+	if pkgpath != "main" {
+		return pkgpath, true
+	}
+	binfo, ok := debug.ReadBuildInfo()
+	if !ok {
+		l.logf("debug.ReadBuildInfo() is failed")
+		return "", false
+	}
+	if binfo.Path == "" { // e.g. the test binary, the main is the synthesized one (the tested main package has the import path, see IsTestBinary)
+		l.logf("the path of the main package is unknown")
+		return "", false
+	}
+	return binfo.Path, true
+}
+
+// lookupType returns the metadata of the type in the package, loading the package if needed. (l.mu must be held)
+func (l *Lookup) lookupType(ctx context.Context, pkgpath, obname string) (*Type, error) {
+	l.touch(pkgpath)
+
+	// wait for the loading of the same package by the other goroutine, and use its result
+	for ch, ok := l.inflight[pkgpath]; ok; ch, ok = l.inflight[pkgpath] {
+		l.mu.Unlock()
+		select {
+		case <-ch:
+		case <-ctx.Done():
+		}
+		l.mu.Lock()
+		if err := ctx.Err(); err != nil {
+			return nil, err
 		}
-		pkgpath = binfo.Path
 	}
 
+	if p, ok := l.cache[pkgpath]; ok && p.loadErr != nil {
+		return nil, p.loadErr // error cache, not to load the broken package again
+	}
 	if p, ok := l.cache[pkgpath]; ok && p.fullset {
 		if p.err != nil {
 			return nil, p.err
 		}
 
-		result, ok := p.Types[obname]
-		if !ok {
-			result, ok = p.Interfaces[obname]
-			if !ok {
-				return nil, fmt.Errorf("lookup metadata of %v is failed %w", rt, ErrNotFound)
+		if DEBUG {
+			l.logf("OK package cache %v", pkgpath)
+		}
+		return l.typeOf(p, obname, pkgpath)
+	}
+
+	p, loads := l.fromSources(pkgpath)
+	if p != nil {
+		l.cache[pkgpath] = p
+		return l.typeOf(p, obname, pkgpath)
+	} else if len(loads) == 0 {
+		return nil, l.unavailable(obname, pkgpath)
+	}
+
+	pkgs, err := l.loadFromSources(ctx, pkgpath, loads)
+	if ctx.Err() != nil {
+		return nil, ctx.Err() // not cached, the (partial) result is dropped
+	}
+	if err != nil {
+		l.setLoadErr(pkgpath, err)
+		return nil, err
+	}
+
+	var found *packageRef
+	for _, pkg := range l.selectPackages(pkgs) {
+		ref, err := l.addPackage(pkg)
+		if pkg.PkgPath != pkgpath {
+			if err != nil {
+				l.logf("collect package error (%s) %+v", pkg, err)
+			}
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("collect: dir=%s, name=%s, %w", pkg.PkgPath, obname, err)
+		}
+		if l.CacheDir != "" {
+			l.writeDiskCache(pkg, ref)
+		}
+		found = ref
+	}
+
+	if found == nil {
+		var err error = l.notFound(obname, pkgpath, "", nil)
+		for _, pkg := range pkgs {
+			if pkg.PkgPath == pkgpath && len(pkg.Errors) > 0 {
+				err = newPackageLoadError(pkgpath, pkg.Errors)
+				break
+			}
+		}
+		l.setLoadErr(pkgpath, err)
+		return nil, err
+	}
+	if DEBUG {
+		l.logf("NG package cache %v", pkgpath)
+	}
+	return l.typeOf(found, obname, pkgpath)
+}
+
+// typeOf returns the metadata of the type in the collected package.
+func (l *Lookup) typeOf(p *packageRef, obname, pkgpath string) (*Type, error) {
+	result, ok := p.Types[obname]
+	if !ok {
+		result, ok = p.Interfaces[obname]
+	}
+	if !ok {
+		// the local types (declared in the functions) with the same name, reflect cannot distinguish them
+		// (the package-level type is preferred over the local ones)
+		if locals := p.locals[obname]; len(locals) > 1 {
+			return nil, &AmbiguousSymbolError{Symbol: obname, PkgPath: pkgpath, Positions: locals}
+		}
+		return nil, l.notFound(obname, pkgpath, "", append(keys(p.Types), keys(p.Interfaces)...))
+	}
+	return &Type{Raw: result, Consts: p.consts[obname], TypeParams: p.typeParams[obname]}, nil
+}
+
+func (l *Lookup) logf(format string, args ...interface{}) {
+	if l.Logger != nil {
+		l.Logger.Printf(format, args...)
+		return
+	}
+	log.Printf(format, args...)
+}
+
+// setLoadErr caches the error of loading the package. (the partial results of the func lookups are kept)
+func (l *Lookup) setLoadErr(pkgpath string, err error) {
+	ref, ok := l.cache[pkgpath]
+	if !ok {
+		ref = &packageRef{}
+		l.cache[pkgpath] = ref
+	}
+	ref.loadErr = err
+}
+
+// Forget drops the cached metadata of the package, so that the next lookup reads the source again. (e.g. after the code is changed)
+func (l *Lookup) Forget(pkgpath string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	delete(l.cache, pkgpath)
+	delete(l.closures, pkgpath)
+	delete(l.used, pkgpath)
+}
+
+// CacheStats is the statistics of the cache of Lookup.
+type CacheStats struct {
+	Packages int // the number of the cached packages (including the failures)
+	Evicted  int // the number of the evicted packages, by MaxPackages
+}
+
+// CacheStats returns the current statistics of the cache.
+func (l *Lookup) CacheStats() CacheStats {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return CacheStats{Packages: len(l.cache), Evicted: l.evicted}
+}
+
+// touch marks the package as used, for LRU eviction.
+func (l *Lookup) touch(pkgpath string) {
+	if l.MaxPackages <= 0 {
+		return
+	}
+	if l.used == nil {
+		l.used = map[string]uint64{}
+	}
+	l.tick++
+	l.used[pkgpath] = l.tick
+}
+
+// evict drops the least recently used packages, until the number of the cached packages is within MaxPackages.
+func (l *Lookup) evict() {
+	for l.MaxPackages > 0 && len(l.cache) > l.MaxPackages {
+		oldest, found := "", false
+		for pkgpath := range l.cache {
+			if !found || l.used[pkgpath] < l.used[oldest] || (l.used[pkgpath] == l.used[oldest] && pkgpath < oldest) {
+				oldest, found = pkgpath, true
 			}
 		}
 		if DEBUG {
-			log.Println("OK package cache", pkgpath)
+			l.logf("evict package cache %v", oldest)
 		}
-		return &Type{Raw: result}, nil
+		delete(l.cache, oldest)
+		delete(l.closures, oldest)
+		delete(l.used, oldest)
+		l.evicted++
 	}
+}
 
-	cfg := &packages.Config{
-		Fset:  l.Fset,
-		Mode:  packages.NeedName | packages.NeedFiles | packages.NeedSyntax,
-		Tests: l.IncludeGoTestFiles, // TODO: support <name>_test package
+// AddPackage registers the already loaded package, so that the lookup doesn't load it again.
+// The package must be loaded with (at least) packages.NeedName|packages.NeedFiles|packages.NeedSyntax, and parsed with parser.ParseComments.
+// (pkg.Fset is set only with packages.NeedTypes. If it is nil, the package must be loaded with l.Fset)
+func (l *Lookup) AddPackage(pkg *packages.Package) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	defer l.evict()
+	if _, err := l.addPackage(pkg); err != nil {
+		return fmt.Errorf("collect: dir=%s, %w", pkg.PkgPath, err)
+	}
+	return nil
+}
+
+// packagesConfig returns the config of packages.Load(), for collecting the comments.
+func (l *Lookup) packagesConfig(ctx context.Context) *packages.Config {
+	return &packages.Config{
+		Context: ctx,
+		Fset:    l.Fset,
+		Mode:    packages.NeedName | packages.NeedFiles | packages.NeedSyntax,
+		Tests:   l.IncludeGoTestFiles,
 		ParseFile: func(fset *token.FileSet, filename string, src []byte) (*ast.File, error) {
 			// TODO: debug print
 			const mode = parser.ParseComments //| parser.AllErrors
 			return parser.ParseFile(fset, filename, src, mode)
 		},
 	}
+}
+
+// loadUnlocked loads the package without holding l.mu, so that the other lookups are not blocked. (l.mu must be held)
+func (l *Lookup) loadUnlocked(cfg *packages.Config, pkgpath string) ([]*packages.Package, error) {
+	if l.inflight == nil {
+		l.inflight = map[string]chan struct{}{}
+	}
+	ch := make(chan struct{})
+	l.inflight[pkgpath] = ch
+	defer func() {
+		delete(l.inflight, pkgpath)
+		close(ch)
+	}()
+
+	l.mu.Unlock()
+	defer l.mu.Lock()
+	return l.load(cfg, pkgpath)
+}
+
+// load loads the package of pkgpath (and its tests, if cfg.Tests is true), with the build context of l. (Offline, GOOS, GOARCH, BuildTags)
+func (l *Lookup) load(cfg *packages.Config, pkgpath string) ([]*packages.Package, error) {
+	if l.Offline {
+		cfg.Env = append(cfg.Env, "GOPROXY=off", "GOTOOLCHAIN=local")
+	}
+	if l.GOOS != "" {
+		cfg.Env = append(cfg.Env, "GOOS="+l.GOOS)
+	}
+	if l.GOARCH != "" {
+		cfg.Env = append(cfg.Env, "GOARCH="+l.GOARCH)
+	}
+	if cfg.Env != nil {
+		cfg.Env = append(os.Environ(), cfg.Env...)
+	}
+	if len(l.BuildTags) > 0 {
+		cfg.BuildFlags = []string{"-tags=" + strings.Join(l.BuildTags, ",")}
+	}
 
 	patterns := []string{pkgpath}
 	if strings.HasSuffix(pkgpath, "_test") {
 		patterns = []string{strings.TrimSuffix(pkgpath, "_test")} // for go test
 	}
-	pkgs, err := packages.Load(cfg, patterns...)
+	load := l.Loader
+	if load == nil {
+		load = packages.Load
+	}
+	pkgs, err := load(cfg, patterns...)
 	if err != nil {
-		return nil, fmt.Errorf("packages.Load() %w", err)
+		return nil, &PackageLoadError{PkgPath: pkgpath, Offline: isOffline(cfg.Env), Err: err}
 	}
+	return pkgs, nil
+}
 
+// isOffline reports whether the network access is forbidden by the environment variables. (GOPROXY=off)
+func isOffline(env []string) bool {
+	offline := false
+	for _, kv := range env {
+		if strings.HasPrefix(kv, "GOPROXY=") {
+			offline = kv == "GOPROXY=off" // the last one wins
+		}
+	}
+	return offline
+}
+
+// selectPackages picks one package per import path from the result of packages.Load().
+//
+// A directory can yield several packages: x, x [x.test] (x with its internal _test.go files),
+// x_test [x.test] (the external test package) and the synthesized x.test main package.
+// For the same import path, the variant having the most files is used (it is a superset of the others),
+// and the synthesized test main package is skipped. Files excluded by build constraints
+// (e.g. tools with "//go:build ignore" and "package main") are never included.
+func (l *Lookup) selectPackages(pkgs []*packages.Package) []*packages.Package {
+	selected := make([]*packages.Package, 0, len(pkgs))
+	indices := make(map[string]int, len(pkgs))
 	for _, pkg := range pkgs {
 		if len(pkg.Errors) > 0 {
 			for _, err := range pkg.Errors {
-				log.Printf("lookup package error (%s) %+v", pkg, err)
+				l.logf("lookup package error (%s) %+v", pkg, err)
 			}
 			continue
 		}
+		if pkg.Name == "main" && strings.HasSuffix(pkg.PkgPath, ".test") {
+			continue // synthesized test main
+		}
 
-		if pkg.PkgPath != pkgpath {
+		if i, ok := indices[pkg.PkgPath]; ok {
+			if len(pkg.Syntax) > len(selected[i].Syntax) {
+				selected[i] = pkg
+			}
 			continue
 		}
-		tree := &ast.Package{Name: pkg.Name, Files: map[string]*ast.File{}}
-		for _, f := range pkg.Syntax {
-			filename := l.Fset.File(f.Pos()).Name()
-			tree.Files[filename] = f
-		}
+		indices[pkg.PkgPath] = len(selected)
+		selected = append(selected, pkg)
+	}
+	return selected
+}
 
-		ref := &packageRef{fullset: true}
-		l.cache[pkg.PkgPath] = ref
-		p, err := commentof.Package(l.Fset, tree, commentof.WithIncludeUnexported(l.IncludeUnexported))
-		if err != nil {
-			ref.err = err
-			return nil, fmt.Errorf("collect: dir=%s, name=%s, %w", pkg.PkgPath, obname, err)
-		}
-		ref.Package = p
+func (l *Lookup) addPackage(pkg *packages.Package) (*packageRef, error) {
+	fset := l.Fset
+	if pkg.Fset != nil {
+		fset = pkg.Fset
+	}
 
-		result, ok := p.Types[obname]
-		if !ok {
-			result, ok = p.Interfaces[obname]
-			if !ok {
-				continue
-			}
-		}
-		if DEBUG {
-			log.Println("NG package cache", pkgpath)
+	tree := &ast.Package{Name: pkg.Name, Files: map[string]*ast.File{}}
+	for _, f := range pkg.Syntax {
+		tf := fset.File(f.Pos())
+		if tf == nil {
+			return nil, fmt.Errorf("the file of %s is not found in the fset", pkg.PkgPath)
 		}
-		return &Type{Raw: result}, nil
+		tree.Files[tf.Name()] = f
 	}
-	return nil, fmt.Errorf("lookup metadata of %v is failed %w", rt, ErrNotFound)
+
+	ref := &packageRef{fullset: true}
+	l.cache[pkg.PkgPath] = ref
+	l.touch(pkg.PkgPath)
+	p, err := l.collectPackage(fset, pkg.PkgPath, tree)
+	if err != nil {
+		ref.err = err
+		return nil, err
+	}
+	ref.Package = p
+	ref.consts = collectConsts(pkg.Syntax)
+	ref.locals = collectLocalTypes(fset, pkg.Syntax)
+	ref.imports = collectImports(fset, pkg.Syntax)
+	ref.embeds = collectEmbeds(pkg.Syntax)
+	ref.typeParams = collectTypeParams(pkg.Syntax)
+	return ref, nil
 }
 
 type packageRef struct {
 	*collect.Package
 
-	fullset bool
-	err     error
+	fullset    bool
+	err        error
+	consts     map[string][]Const
+	locals     map[string][]string          // the positions of the local types (declared in the functions), keyed by name
+	imports    map[string]map[string]string // the import paths keyed by the names, per file (for resolving the embedded interfaces)
+	embeds     map[string][]string          // the embedded types of the interfaces, keyed by the names of the interfaces
+	typeParams map[string][]TypeParam       // the type parameters of the generic types, keyed by the names of the types
+	loadErr    error                        // the error of packages.Load() (or the package is not found)
+	fileErrs   map[string]error             // the errors of parsing the files, keyed by filename
+}
+
+func (r *packageRef) visitedFiles() []string {
+	if r.Package == nil {
+		return nil
+	}
+	return r.FileNames
+}
+
+func (r *packageRef) setFileErr(filename string, err error) {
+	if r.fileErrs == nil {
+		r.fileErrs = map[string]error{}
+	}
+	r.fileErrs[filename] = err
+}
+
+// collectLocalTypes collects the positions of the types declared in the functions, keyed by name.
+func collectLocalTypes(fset *token.FileSet, files []*ast.File) map[string][]string {
+	var r map[string][]string
+	for _, f := range files {
+		for _, decl := range f.Decls {
+			decl, ok := decl.(*ast.FuncDecl)
+			if !ok || decl.Body == nil {
+				continue
+			}
+			ast.Inspect(decl.Body, func(node ast.Node) bool {
+				if spec, ok := node.(*ast.TypeSpec); ok {
+					if r == nil {
+						r = map[string][]string{}
+					}
+					r[spec.Name.Name] = append(r[spec.Name.Name], fset.Position(spec.Pos()).String())
+				}
+				return true
+			})
+		}
+	}
+	return r
 }
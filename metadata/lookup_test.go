@@ -1,12 +1,25 @@
 package metadata
 
 import (
+	"bytes"
 	"context"
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/parser"
 	"go/token"
+	"log"
+	"os"
 	"reflect"
+	"runtime"
+	"strings"
+	"sync"
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
+	"github.com/podhmo/reflect-shape/metadata/testdata/multi"
+	"github.com/podhmo/reflect-shape/metadata/testdata/platform"
+	"golang.org/x/tools/go/packages"
 )
 
 // Person is person
@@ -34,6 +47,7 @@ func TestType(t *testing.T) {
 	fset := token.NewFileSet()
 	l := NewLookup(fset)
 	l.IncludeGoTestFiles = true // for test
+	l.Offline = true
 
 	metadata, err := l.LookupFromType(Person{})
 	if err != nil {
@@ -50,6 +64,238 @@ func TestType(t *testing.T) {
 	}
 }
 
+func TestAddPackage(t *testing.T) {
+	l := NewLookup(token.NewFileSet())
+	fset := l.Fset // shared
+	pkgs, err := packages.Load(&packages.Config{
+		Fset:  fset,
+		Mode:  packages.NeedName | packages.NeedFiles | packages.NeedSyntax,
+		Tests: true,
+		ParseFile: func(fset *token.FileSet, filename string, src []byte) (*ast.File, error) {
+			return parser.ParseFile(fset, filename, src, parser.ParseComments)
+		},
+	}, "github.com/podhmo/reflect-shape/metadata")
+	if err != nil {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+
+	l.Loader = func(*packages.Config, ...string) ([]*packages.Package, error) {
+		t.Fatalf("Loader must not be called")
+		return nil, nil
+	}
+	for _, pkg := range pkgs {
+		if pkg.PkgPath != "github.com/podhmo/reflect-shape/metadata" || len(pkg.Syntax) == 0 {
+			continue
+		}
+		if err := l.AddPackage(pkg); err != nil {
+			t.Fatalf("unexpected error: %+v", err)
+		}
+	}
+
+	metadata, err := l.LookupFromType(Person{})
+	if err != nil {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+	if want, got := "Person is person", metadata.Doc(); want != got {
+		t.Errorf("LookupFromType().Doc(): want:%q != got:%q", want, got)
+	}
+}
+
+func TestTypeNotFound(t *testing.T) {
+	fset := token.NewFileSet()
+	l := NewLookup(fset)
+	l.IncludeGoTestFiles = true
+
+	type Persom struct{} // not collected (defined in function)
+	_, err := l.LookupFromType(Persom{})
+
+	var notFound *NotFoundError
+	if !errors.As(err, &notFound) {
+		t.Fatalf("must be *NotFoundError, but got %+v", err)
+	}
+	if !errors.Is(err, ErrNotFound) {
+		t.Errorf("must be ErrNotFound, but got %+v", err)
+	}
+	if want, got := "Persom", notFound.Symbol; want != got {
+		t.Errorf("NotFoundError.Symbol: want:%q != got:%q", want, got)
+	}
+	if diff := cmp.Diff([]string{"Person"}, notFound.Closest); diff != "" {
+		t.Errorf("NotFoundError.Closest: -want, +got: \n%v", diff)
+	}
+	t.Logf("%v", err)
+}
+
+func TestForget(t *testing.T) {
+	fset := token.NewFileSet()
+	l := NewLookup(fset)
+	l.IncludeGoTestFiles = true
+
+	loaded := 0
+	l.Loader = func(cfg *packages.Config, patterns ...string) ([]*packages.Package, error) {
+		loaded++
+		return packages.Load(cfg, patterns...)
+	}
+
+	for i := 0; i < 2; i++ {
+		if _, err := l.LookupFromType(Person{}); err != nil {
+			t.Fatalf("unexpected error: %+v", err)
+		}
+	}
+	if want, got := 1, loaded; want != got {
+		t.Errorf("loaded count (cached): want:%d != got:%d", want, got)
+	}
+
+	l.Forget("github.com/podhmo/reflect-shape/metadata")
+	if _, err := l.LookupFromType(Person{}); err != nil {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+	if want, got := 2, loaded; want != got {
+		t.Errorf("loaded count (forgot): want:%d != got:%d", want, got)
+	}
+}
+
+// Bye is function returns farewell message
+func Bye(name string) string {
+	return "Bye " + name
+}
+
+func TestLookupCache(t *testing.T) {
+	t.Run("func-same-file", func(t *testing.T) {
+		fset := token.NewFileSet()
+		l := NewLookup(fset)
+		l.IncludeGoTestFiles = true
+
+		if _, err := l.LookupFromFunc(Hello); err != nil {
+			t.Fatalf("unexpected error: %+v", err)
+		}
+		base := fset.Base()
+		for _, fn := range []interface{}{Bye, Hello} {
+			if _, err := l.LookupFromFunc(fn); err != nil {
+				t.Fatalf("unexpected error: %+v", err)
+			}
+		}
+		if want, got := base, fset.Base(); want != got {
+			t.Errorf("the file is parsed again, fset.Base(): want:%d != got:%d", want, got)
+		}
+	})
+
+	t.Run("broken-package", func(t *testing.T) {
+		l := NewLookup(token.NewFileSet())
+		loaded := 0
+		l.Loader = func(cfg *packages.Config, patterns ...string) ([]*packages.Package, error) {
+			loaded++
+			return []*packages.Package{{PkgPath: patterns[0], Errors: []packages.Error{{Msg: "broken"}}}}, nil
+		}
+
+		want := 2 // tried once per loading source (SourceLoad and SourceModuleCache)
+		for i := 0; i < 2; i++ {
+			if _, err := l.LookupFromType(Person{}); !errors.Is(err, ErrNotFound) {
+				t.Fatalf("LookupFromType(): want:%v != got:%+v", ErrNotFound, err)
+			}
+		}
+		if got := loaded; want != got {
+			t.Errorf("loaded count (error cached): want:%d != got:%d", want, got)
+		}
+	})
+
+	t.Run("load-error", func(t *testing.T) {
+		l := NewLookup(token.NewFileSet())
+		loaded := 0
+		l.Loader = func(cfg *packages.Config, patterns ...string) ([]*packages.Package, error) {
+			loaded++
+			return nil, fmt.Errorf("go command is not found")
+		}
+
+		want := 2 // tried once per loading source (SourceLoad and SourceModuleCache)
+		for i := 0; i < 2; i++ {
+			if _, err := l.LookupFromType(Person{}); err == nil {
+				t.Fatalf("LookupFromType(): error is expected")
+			}
+		}
+		if got := loaded; want != got {
+			t.Errorf("loaded count (error cached): want:%d != got:%d", want, got)
+		}
+	})
+}
+
+func TestTypeBuildConstraint(t *testing.T) {
+	cases := []struct {
+		goos string
+		doc  string
+	}{
+		{goos: "linux", doc: "Platform is the declaration for linux."},
+		{goos: "windows", doc: "Platform is the declaration for windows."},
+		{goos: "darwin", doc: "Platform is the declaration for the other platforms."},
+	}
+	for _, c := range cases {
+		c := c
+		t.Run(c.goos, func(t *testing.T) {
+			l := NewLookup(token.NewFileSet())
+			l.GOOS = c.goos
+
+			metadata, err := l.LookupFromType(platform.Platform{})
+			if err != nil {
+				t.Fatalf("unexpected error: %+v", err)
+			}
+			if want, got := c.doc, metadata.Doc(); want != got {
+				t.Errorf("LookupFromType().Doc(): want:%q != got:%q", want, got)
+			}
+		})
+	}
+}
+
+func TestTypeMultiplePackages(t *testing.T) {
+	const pkgpath = "github.com/podhmo/reflect-shape/metadata/testdata/multi"
+
+	cases := []struct {
+		msg                string
+		includeGoTestFiles bool
+		pkgpath            string
+		name               string
+		doc                string // empty means not found
+	}{
+		{msg: "package", pkgpath: pkgpath, name: "Value", doc: "Value is the declaration in the package itself."},
+		{msg: "package-with-tests", includeGoTestFiles: true, pkgpath: pkgpath, name: "Value", doc: "Value is the declaration in the package itself."},
+		{msg: "internal-test", includeGoTestFiles: true, pkgpath: pkgpath, name: "InternalFixture", doc: "InternalFixture is the declaration in the internal test file."},
+		{msg: "internal-test-without-tests", pkgpath: pkgpath, name: "InternalFixture"},
+		{msg: "external-test", includeGoTestFiles: true, pkgpath: pkgpath + "_test", name: "ExternalFixture", doc: "ExternalFixture is the declaration in the external test package."},
+		{msg: "ignored-tool", includeGoTestFiles: true, pkgpath: pkgpath, name: "Tool"},
+	}
+	for _, c := range cases {
+		c := c
+		t.Run(c.msg, func(t *testing.T) {
+			l := NewLookup(token.NewFileSet())
+			l.IncludeGoTestFiles = c.includeGoTestFiles
+
+			// load the package first, then lookup from the cache
+			if _, err := l.LookupFromType(multi.Value{}); err != nil {
+				t.Fatalf("unexpected error: %+v", err)
+			}
+
+			ref, ok := l.cache[c.pkgpath]
+			if !ok {
+				if c.doc == "" {
+					return
+				}
+				t.Fatalf("package %q is not cached", c.pkgpath)
+			}
+			ob, ok := ref.Types[c.name]
+			if c.doc == "" {
+				if ok {
+					t.Errorf("%s is found, unexpectedly", c.name)
+				}
+				return
+			}
+			if !ok {
+				t.Fatalf("%s is not found in %q", c.name, c.pkgpath)
+			}
+			if want, got := c.doc, (&Type{Raw: ob}).Doc(); want != got {
+				t.Errorf("Doc: want:%q != got:%q", want, got)
+			}
+		})
+	}
+}
+
 // Hello is function returns greeting message
 func Hello(name string) string {
 	return "Hello " + name
@@ -91,6 +337,86 @@ func TestFunc(t *testing.T) {
 	}
 }
 
+// NewGreeter returns the greeter
+//
+//go:noinline
+func NewGreeter(prefix string) func(string) string {
+	// greet returns the greeting message
+	return func(name string) string {
+		return prefix + name
+	}
+}
+
+// OnEvent is the handler of the package-level variable
+var OnEvent = func(ctx context.Context, event string) error { return nil }
+
+func TestFuncClosure(t *testing.T) {
+	type result struct {
+		Name      string
+		Doc       string
+		Args      []string
+		IsClosure bool
+		Enclosing string
+	}
+
+	cases := []struct {
+		msg  string
+		fn   interface{}
+		want result
+	}{
+		{msg: "closure", fn: NewGreeter("hello "), want: result{Name: "NewGreeter.func1", Doc: "greet returns the greeting message", Args: []string{"name"}, IsClosure: true, Enclosing: "NewGreeter"}},
+		{msg: "package-level", fn: OnEvent, want: result{Name: "init.func1", Doc: "OnEvent is the handler of the package-level variable", Args: []string{"ctx", "event"}, IsClosure: true}},
+		{msg: "not-closure", fn: NewGreeter, want: result{Name: "NewGreeter", Doc: "NewGreeter returns the greeter", Args: []string{"prefix"}}},
+	}
+
+	fset := token.NewFileSet()
+	l := NewLookup(fset)
+	l.IncludeGoTestFiles = true
+
+	for _, c := range cases {
+		c := c
+		t.Run(c.msg, func(t *testing.T) {
+			metadata, err := l.LookupFromFunc(c.fn)
+			if err != nil {
+				t.Fatalf("unexpected error: %+v", err)
+			}
+			var args []string
+			for _, p := range metadata.Args() {
+				args = append(args, p.Name)
+			}
+			got := result{
+				Name:      metadata.Name(),
+				Doc:       metadata.Doc(),
+				Args:      args,
+				IsClosure: metadata.IsClosure,
+			}
+			if metadata.Enclosing != nil {
+				got.Enclosing = metadata.Enclosing.Name()
+			}
+			if diff := cmp.Diff(c.want, got); diff != "" {
+				t.Errorf("LookupFromFunc() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestFuncExternalPC(t *testing.T) {
+	fset := token.NewFileSet()
+	l := NewLookup(fset)
+
+	_, err := l.LookupFromFuncForPC(1)
+	var extErr *ExternalPCError
+	if !errors.As(err, &extErr) {
+		t.Fatalf("LookupFromFuncForPC(): must be *ExternalPCError, but got %+v", err)
+	}
+	if extErr.InModule {
+		t.Errorf("ExternalPCError.InModule: must be false")
+	}
+	if !errors.Is(err, ErrNotSupported) {
+		t.Errorf("LookupFromFuncForPC(): must be ErrNotSupported, but got %+v", err)
+	}
+}
+
 type S struct{}
 
 // Method1 is one of S
@@ -170,6 +496,88 @@ func TestMethod(t *testing.T) {
 	}
 }
 
+func callerPC() uintptr {
+	pc, _, _, _ := runtime.Caller(1)
+	return pc
+}
+
+// GenericMap maps the values.
+func GenericMap[T, U any](xs []T, fn func(T) U) (ys []U, pc uintptr) {
+	return nil, callerPC()
+}
+
+type GenericList[T any] struct{}
+
+// Push pushes the value.
+func (l *GenericList[T]) Push(value T) (pc uintptr) {
+	return callerPC()
+}
+
+// Pair returns the pair.
+func (l GenericList[T]) Pair(key string, value T) (pc uintptr) {
+	return callerPC()
+}
+
+func TestFuncGenerics(t *testing.T) {
+	type result struct {
+		Name string
+		Doc  string
+		Recv string
+		Args []string
+	}
+
+	_, mapPC := GenericMap[int, string](nil, nil)
+	cases := []struct {
+		msg  string
+		want result
+		pc   uintptr
+	}{
+		{msg: "func", want: result{Name: "GenericMap", Doc: "GenericMap maps the values.", Args: []string{"xs", "fn"}}, pc: mapPC},
+		{msg: "pointer-method", want: result{Name: "Push", Doc: "Push pushes the value.", Recv: "GenericList", Args: []string{"value"}}, pc: (&GenericList[int]{}).Push(0)},
+		{msg: "value-method", want: result{Name: "Pair", Doc: "Pair returns the pair.", Recv: "GenericList", Args: []string{"key", "value"}}, pc: GenericList[*S]{}.Pair("", nil)},
+	}
+
+	l := NewLookup(token.NewFileSet())
+	l.IncludeGoTestFiles = true
+	for _, c := range cases {
+		c := c
+		t.Run(c.msg, func(t *testing.T) {
+			metadata, err := l.LookupFromFuncForPC(c.pc)
+			if err != nil {
+				t.Fatalf("unexpected error: %+v", err)
+			}
+			var args []string
+			for _, p := range metadata.Args() {
+				args = append(args, p.Name)
+			}
+			got := result{Name: metadata.Name(), Doc: metadata.Doc(), Recv: metadata.Recv, Args: args}
+			if diff := cmp.Diff(c.want, got); diff != "" {
+				t.Errorf("LookupFromFuncForPC() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestTrimTypeArgs(t *testing.T) {
+	cases := []struct {
+		name string
+		want string
+	}{
+		{name: "github.com/foo/bar.Hello", want: "github.com/foo/bar.Hello"},
+		{name: "github.com/foo/bar.Map[...]", want: "github.com/foo/bar.Map"},
+		{name: "github.com/foo/bar.(*List[...]).Push-fm", want: "github.com/foo/bar.(*List).Push-fm"},
+		{name: "github.com/foo/bar.Map[go.shape.*github.com/foo/baz.T,go.shape.[]int]", want: "github.com/foo/bar.Map"},
+	}
+	for _, c := range cases {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			if want, got := c.want, TrimTypeArgs(c.name); want != got {
+				t.Errorf("TrimTypeArgs(): want:%q != got:%q", want, got)
+			}
+		})
+	}
+}
+
 // I is I
 type I interface {
 	// Foo is Foo
@@ -220,3 +628,295 @@ func TestInterface(t *testing.T) {
 		})
 	}
 }
+
+func TestConcurrentLookup(t *testing.T) {
+	fset := token.NewFileSet()
+	l := NewLookup(fset)
+	l.IncludeGoTestFiles = true
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			if _, err := l.LookupFromType(Person{}); err != nil {
+				t.Errorf("unexpected error: %+v", err)
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			if _, err := l.LookupFromFunc(Hello); err != nil {
+				t.Errorf("unexpected error: %+v", err)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestConcurrentLookupSingleflight(t *testing.T) {
+	l := NewLookup(token.NewFileSet())
+	l.IncludeGoTestFiles = true
+
+	var mu sync.Mutex
+	loaded := map[string]int{}
+	l.Loader = func(cfg *packages.Config, patterns ...string) ([]*packages.Package, error) {
+		mu.Lock()
+		loaded[patterns[0]]++
+		mu.Unlock()
+		return packages.Load(cfg, patterns...)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		for _, ob := range []interface{}{Person{}, multi.Value{}} {
+			ob := ob
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if _, err := l.LookupFromType(ob); err != nil {
+					t.Errorf("unexpected error: %+v", err)
+				}
+			}()
+		}
+	}
+	wg.Wait()
+
+	want := map[string]int{
+		"github.com/podhmo/reflect-shape/metadata":                1,
+		"github.com/podhmo/reflect-shape/metadata/testdata/multi": 1,
+	}
+	if diff := cmp.Diff(want, loaded); diff != "" {
+		t.Errorf("loaded count mismatch (-want, +got): \n%v", diff)
+	}
+}
+
+func TestTrace(t *testing.T) {
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	defer log.SetOutput(os.Stderr)
+
+	fset := token.NewFileSet()
+	l := NewLookup(fset)
+	l.IncludeGoTestFiles = true
+	l.Trace = true
+
+	if _, err := l.LookupFromFunc((&S{}).Method1); err != nil {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+	type unknown struct{} // not collected (defined in function)
+	if _, err := l.LookupFromType(unknown{}); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("must be ErrNotFound, but got %+v", err)
+	}
+
+	got := buf.String()
+	for _, want := range []string{
+		`recv="S", name="Method1", isMethod=true`,
+		`trace: type "metadata.unknown"`,
+		`types=[`,
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("trace output must contain %q, but got:\n%s", want, got)
+		}
+	}
+}
+
+func TestLookupMaxPackages(t *testing.T) {
+	l := NewLookup(token.NewFileSet())
+	l.IncludeGoTestFiles = true
+	l.MaxPackages = 1
+
+	loaded := 0
+	l.Loader = func(cfg *packages.Config, patterns ...string) ([]*packages.Package, error) {
+		loaded++
+		return packages.Load(cfg, patterns...)
+	}
+
+	for _, ob := range []interface{}{Person{}, multi.Value{}, Person{}} {
+		if _, err := l.LookupFromType(ob); err != nil {
+			t.Fatalf("unexpected error: %+v", err)
+		}
+		if want, got := 1, l.CacheStats().Packages; want != got {
+			t.Errorf("CacheStats().Packages: want:%d != got:%d", want, got)
+		}
+	}
+	if want, got := 3, loaded; want != got {
+		t.Errorf("loaded count (evicted): want:%d != got:%d", want, got)
+	}
+	if got := l.CacheStats().Evicted; got < 2 {
+		t.Errorf("CacheStats().Evicted: want:>=2 != got:%d", got)
+	}
+}
+
+func TestLookupContext(t *testing.T) {
+	l := NewLookup(token.NewFileSet())
+	l.IncludeGoTestFiles = true
+
+	loaded := 0
+	l.Loader = func(cfg *packages.Config, patterns ...string) ([]*packages.Package, error) {
+		loaded++
+		if loaded == 1 {
+			cancel := cfg.Context.Value(cancelKey{}).(context.CancelFunc)
+			cancel() // cancelled while loading
+			return nil, cfg.Context.Err()
+		}
+		return packages.Load(cfg, patterns...)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	ctx = context.WithValue(ctx, cancelKey{}, cancel)
+	if _, err := l.LookupFromTypeContext(ctx, Person{}); !errors.Is(err, context.Canceled) {
+		t.Fatalf("LookupFromTypeContext(): want:%v != got:%+v", context.Canceled, err)
+	}
+	if _, err := l.LookupFromTypeContext(ctx, Person{}); !errors.Is(err, context.Canceled) {
+		t.Fatalf("LookupFromTypeContext() (done): want:%v != got:%+v", context.Canceled, err)
+	}
+	if want, got := 1, loaded; want != got {
+		t.Errorf("loaded count (done): want:%d != got:%d", want, got)
+	}
+
+	// the cancellation is not cached
+	if _, err := l.LookupFromTypeContext(context.Background(), Person{}); err != nil {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+	if want, got := 2, loaded; want != got {
+		t.Errorf("loaded count (not cached): want:%d != got:%d", want, got)
+	}
+}
+
+type cancelKey struct{}
+
+func TestLookupLogger(t *testing.T) {
+	var logs []string
+	l := NewLookup(token.NewFileSet())
+	l.Logger = LoggerFunc(func(format string, args ...interface{}) {
+		logs = append(logs, fmt.Sprintf(format, args...))
+	})
+	l.Loader = func(cfg *packages.Config, patterns ...string) ([]*packages.Package, error) {
+		return []*packages.Package{{ID: patterns[0], PkgPath: patterns[0], Errors: []packages.Error{{Msg: "broken"}}}}, nil
+	}
+
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	defer log.SetOutput(os.Stderr)
+
+	if _, err := l.LookupFromType(Person{}); err == nil {
+		t.Fatalf("LookupFromType(): error is expected")
+	}
+	if want, got := 1, len(logs); want != got {
+		t.Fatalf("the number of logs: want:%d != got:%d (%q)", want, got, logs)
+	}
+	if want, got := "lookup package error", logs[0]; !strings.HasPrefix(got, want) {
+		t.Errorf("log: want prefix:%q != got:%q", want, got)
+	}
+	if got := buf.String(); got != "" {
+		t.Errorf("the standard logger must not be used, but %q", got)
+	}
+}
+
+func ambiguousA() interface{} {
+	type Ambiguous struct{}
+	return Ambiguous{}
+}
+
+func ambiguousB() interface{} {
+	type Ambiguous struct{ Name string }
+	return Ambiguous{}
+}
+
+// Shadowed is the package-level type, shadowed by the local type in shadowedLocal.
+type Shadowed struct{}
+
+func shadowedLocal() interface{} {
+	type Shadowed struct{ Name string }
+	return Shadowed{}
+}
+
+func TestTypedErrors(t *testing.T) {
+	t.Run("package-load", func(t *testing.T) {
+		l := NewLookup(token.NewFileSet())
+		l.Loader = func(cfg *packages.Config, patterns ...string) ([]*packages.Package, error) {
+			return nil, fmt.Errorf("go command is not found")
+		}
+
+		_, err := l.LookupFromType(Person{})
+		var loadErr *PackageLoadError
+		if !errors.As(err, &loadErr) {
+			t.Fatalf("must be *PackageLoadError, but got %+v", err)
+		}
+		if want, got := "github.com/podhmo/reflect-shape/metadata", loadErr.PkgPath; want != got {
+			t.Errorf("PackageLoadError.PkgPath: want:%q != got:%q", want, got)
+		}
+		if !errors.Is(err, ErrPackageLoad) {
+			t.Errorf("must be ErrPackageLoad, but got %+v", err)
+		}
+		if errors.Is(err, ErrNotFound) {
+			t.Errorf("must not be ErrNotFound, but got %+v", err)
+		}
+	})
+
+	t.Run("broken-package", func(t *testing.T) {
+		l := NewLookup(token.NewFileSet())
+		l.Loader = func(cfg *packages.Config, patterns ...string) ([]*packages.Package, error) {
+			return []*packages.Package{{PkgPath: patterns[0], Errors: []packages.Error{{Msg: "broken"}}}}, nil
+		}
+
+		_, err := l.LookupFromType(Person{})
+		var loadErr *PackageLoadError
+		if !errors.As(err, &loadErr) {
+			t.Fatalf("must be *PackageLoadError, but got %+v", err)
+		}
+		if diff := cmp.Diff([]string{"-: broken"}, loadErr.Errors); diff != "" {
+			t.Errorf("PackageLoadError.Errors: -want, +got: \n%v", diff)
+		}
+		if !errors.Is(err, ErrPackageLoad) || !errors.Is(err, ErrNotFound) {
+			t.Errorf("must be ErrPackageLoad and ErrNotFound, but got %+v", err)
+		}
+	})
+
+	t.Run("ambiguous-symbol", func(t *testing.T) {
+		l := NewLookup(token.NewFileSet())
+		l.IncludeGoTestFiles = true
+
+		for _, ob := range []interface{}{ambiguousA(), ambiguousB()} {
+			_, err := l.LookupFromType(ob)
+			var ambiguous *AmbiguousSymbolError
+			if !errors.As(err, &ambiguous) {
+				t.Fatalf("must be *AmbiguousSymbolError, but got %+v", err)
+			}
+			if want, got := "Ambiguous", ambiguous.Symbol; want != got {
+				t.Errorf("AmbiguousSymbolError.Symbol: want:%q != got:%q", want, got)
+			}
+			if want, got := 2, len(ambiguous.Positions); want != got {
+				t.Errorf("len(AmbiguousSymbolError.Positions): want:%d != got:%d", want, got)
+			}
+			if !errors.Is(err, ErrAmbiguousSymbol) {
+				t.Errorf("must be ErrAmbiguousSymbol, but got %+v", err)
+			}
+		}
+	})
+
+	t.Run("ambiguous-symbol-package-level", func(t *testing.T) {
+		l := NewLookup(token.NewFileSet())
+		l.IncludeGoTestFiles = true
+
+		_ = shadowedLocal()
+		got, err := l.LookupFromType(Shadowed{})
+		if err != nil {
+			t.Fatalf("the package-level type must be preferred, but got %+v", err)
+		}
+		if want, got := "Shadowed is the package-level type, shadowed by the local type in shadowedLocal.", got.Doc(); want != got {
+			t.Errorf("Type.Doc(): want:%q != got:%q", want, got)
+		}
+	})
+
+	t.Run("source-unavailable", func(t *testing.T) {
+		var err error = &NoSourceError{Name: "runtime.goexit", Filename: "asm_amd64.s"}
+		if !errors.Is(err, ErrSourceUnavailable) || !errors.Is(err, ErrNotSupported) {
+			t.Errorf("must be ErrSourceUnavailable and ErrNotSupported, but got %+v", err)
+		}
+		err = &SourceUnavailableError{Symbol: "Hello", PkgPath: "main", Filename: "main.go", Err: os.ErrNotExist}
+		if !errors.Is(err, ErrSourceUnavailable) || !errors.Is(err, os.ErrNotExist) {
+			t.Errorf("must be ErrSourceUnavailable and os.ErrNotExist, but got %+v", err)
+		}
+	})
+}
@@ -0,0 +1,161 @@
+package metadata
+
+import (
+	"fmt"
+	"go/build"
+	"go/token"
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/debug"
+	"strings"
+	"sync"
+
+	"golang.org/x/mod/modfile"
+	"golang.org/x/mod/module"
+)
+
+// PositionFormatter formats the positions with the portable paths, instead of the absolute paths of the build machine.
+// So the serialized dumps don't differ across the machines and the CI agents.
+//
+//   - the files of the standard library: the paths relative to GOROOT/src (e.g. "net/http/server.go")
+//   - the files in the module cache: the module path with the version (e.g. "golang.org/x/tools@v0.6.0/go/packages/packages.go")
+//   - the files in the vendor directory: the same, with the version found in the build info
+//   - the files under Root: the paths relative to Root (e.g. "pkg/foo/foo.go")
+//   - the other files: the module path of the nearest go.mod (e.g. "github.com/foo/bar/pkg/foo/foo.go")
+//
+// The paths not absolute (e.g. built with -trimpath) are kept as is.
+type PositionFormatter struct {
+	Fset *token.FileSet
+	Root string // the directory the paths are relative to (e.g. the repository root), optional
+
+	goroot   string
+	modcache string
+	versions map[string]string // module path -> version, from the build info
+
+	mu      sync.Mutex
+	modules map[string]string // dir -> module path ("" if no go.mod)
+}
+
+// NewPositionFormatter returns the formatter of the positions in fset.
+func NewPositionFormatter(fset *token.FileSet, root string) *PositionFormatter {
+	f := &PositionFormatter{Fset: fset, Root: root, goroot: runtime.GOROOT(), modules: map[string]string{}}
+	if f.goroot == "" {
+		f.goroot = build.Default.GOROOT
+	}
+	f.modcache = os.Getenv("GOMODCACHE")
+	if f.modcache == "" {
+		f.modcache = filepath.Join(build.Default.GOPATH, "pkg", "mod")
+	}
+	if info, ok := debug.ReadBuildInfo(); ok {
+		f.versions = make(map[string]string, len(info.Deps))
+		for _, dep := range info.Deps {
+			f.versions[dep.Path] = dep.Version
+		}
+	}
+	return f
+}
+
+// Position returns the position of pos, with the portable filename.
+func (f *PositionFormatter) Position(pos token.Pos) token.Position {
+	position := f.Fset.Position(pos)
+	if position.Filename != "" {
+		position.Filename = f.Filename(position.Filename)
+	}
+	return position
+}
+
+// Format returns the position as "<filename>:<line>:<column>", with the portable filename.
+func (f *PositionFormatter) Format(pos token.Pos) string {
+	return f.Position(pos).String()
+}
+
+// Filename returns the portable path of the filename.
+func (f *PositionFormatter) Filename(filename string) string {
+	if !filepath.IsAbs(filename) {
+		return filepath.ToSlash(filename)
+	}
+	if f.goroot != "" {
+		if rel, ok := under(filename, filepath.Join(f.goroot, "src")); ok {
+			return rel
+		}
+	}
+	if rel, ok := under(filename, f.modcache); ok {
+		// e.g. golang.org/x/tools@v0.6.0/go/packages/packages.go (the upper cases are escaped, e.g. github.com/!burnt!sushi/toml@v1.2.1)
+		if escaped, version, ok := strings.Cut(rel, "@"); ok {
+			if modpath, err := module.UnescapePath(escaped); err == nil {
+				return modpath + "@" + version
+			}
+		}
+		return rel
+	}
+	if i := strings.LastIndex(filepath.ToSlash(filename), "/vendor/"); i >= 0 {
+		rel := filepath.ToSlash(filename)[i+len("/vendor/"):]
+		return f.versioned(rel)
+	}
+	if f.Root != "" {
+		if rel, ok := under(filename, f.Root); ok {
+			return rel
+		}
+	}
+	dir := filepath.Dir(filename)
+	if moddir, modpath := f.module(dir); modpath != "" {
+		rel, _ := under(filename, moddir)
+		return modpath + "/" + rel
+	}
+	return filepath.ToSlash(filename)
+}
+
+// versioned returns the path with the version of the module found in the build info. (e.g. "golang.org/x/mod/module/module.go" -> "golang.org/x/mod@v0.8.0/module/module.go")
+func (f *PositionFormatter) versioned(path string) string {
+	best := ""
+	for modpath := range f.versions {
+		if (path == modpath || strings.HasPrefix(path, modpath+"/")) && len(modpath) > len(best) {
+			best = modpath
+		}
+	}
+	if best == "" || f.versions[best] == "" {
+		return path
+	}
+	return fmt.Sprintf("%s@%s%s", best, f.versions[best], strings.TrimPrefix(path, best))
+}
+
+// module returns the directory and the path of the module, that the dir belongs to. (by the nearest go.mod)
+func (f *PositionFormatter) module(dir string) (moddir string, modpath string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var visited []string
+	defer func() {
+		for _, d := range visited {
+			f.modules[d] = moddir + "\x00" + modpath
+		}
+	}()
+	for {
+		if cached, ok := f.modules[dir]; ok {
+			moddir, modpath, _ = strings.Cut(cached, "\x00")
+			return moddir, modpath
+		}
+		visited = append(visited, dir)
+		if b, err := os.ReadFile(filepath.Join(dir, "go.mod")); err == nil {
+			return dir, modfile.ModulePath(b)
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", ""
+		}
+		dir = parent
+	}
+}
+
+// under returns the slash-separated path of filename relative to dir, if filename is under dir.
+func under(filename, dir string) (string, bool) {
+	if dir == "" {
+		return "", false
+	}
+	rel, err := filepath.Rel(dir, filename)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", false
+	}
+	return filepath.ToSlash(rel), true
+}
@@ -0,0 +1,53 @@
+package metadata
+
+import (
+	"go/token"
+	"path/filepath"
+	"testing"
+)
+
+func TestPositionFormatter(t *testing.T) {
+	abs := func(path string) string {
+		r, err := filepath.Abs(path)
+		if err != nil {
+			t.Fatalf("unexpected error: %+v", err)
+		}
+		return r
+	}
+
+	f := NewPositionFormatter(token.NewFileSet(), abs("testdata"))
+	f.versions = map[string]string{"golang.org/x/mod": "v0.8.0", "golang.org/x": "v0.0.0"}
+
+	cases := []struct {
+		msg      string
+		filename string
+		want     string
+	}{
+		{msg: "root", filename: abs("testdata/multi/multi.go"), want: "multi/multi.go"},
+		{msg: "goroot", filename: filepath.Join(f.goroot, "src", "net", "http", "server.go"), want: "net/http/server.go"},
+		{msg: "modcache", filename: filepath.Join(f.modcache, "github.com", "!burnt!sushi", "toml@v1.2.1", "decode.go"), want: "github.com/BurntSushi/toml@v1.2.1/decode.go"},
+		{msg: "vendor", filename: filepath.Join(abs("."), "vendor", "golang.org", "x", "mod", "module", "module.go"), want: "golang.org/x/mod@v0.8.0/module/module.go"},
+		{msg: "vendor-unknown", filename: filepath.Join(abs("."), "vendor", "example.com", "foo", "foo.go"), want: "example.com/foo/foo.go"},
+		{msg: "module", filename: abs("position.go"), want: "github.com/podhmo/reflect-shape/metadata/position.go"},
+		{msg: "trimpath", filename: "github.com/foo/bar/bar.go", want: "github.com/foo/bar/bar.go"},
+	}
+	for _, c := range cases {
+		c := c
+		t.Run(c.msg, func(t *testing.T) {
+			if want, got := c.want, f.Filename(c.filename); want != got {
+				t.Errorf("PositionFormatter.Filename(), want:%v != got:%v", want, got)
+			}
+		})
+	}
+
+	t.Run("format", func(t *testing.T) {
+		file := f.Fset.AddFile(abs("position.go"), -1, 100)
+		file.SetLines([]int{0, 10, 20})
+		if want, got := "github.com/podhmo/reflect-shape/metadata/position.go:2:3", f.Format(file.Pos(12)); want != got {
+			t.Errorf("PositionFormatter.Format(), want:%v != got:%v", want, got)
+		}
+		if want, got := "-", f.Format(token.NoPos); want != got {
+			t.Errorf("PositionFormatter.Format(), want:%v != got:%v", want, got)
+		}
+	})
+}
@@ -0,0 +1,143 @@
+package metadata
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"go/format"
+	"go/token"
+	"io"
+	"sort"
+	"strconv"
+	"sync"
+)
+
+var (
+	precompiledMu sync.RWMutex
+	precompiled   = map[string]precompiledData{} // pkgpath -> the encoded diskEntry
+)
+
+type precompiledData struct {
+	encoding Encoding
+	data     string
+}
+
+// RegisterPrecompiled registers the precompiled metadata of the package. It is called in init() of the file generated by GeneratePrecompiled.
+//
+// The lookups consult the precompiled metadata first, so the package is neither loaded nor parsed,
+// and the source is not needed at runtime. (e.g. the binary deployed without the source)
+func RegisterPrecompiled(pkgpath string, data string) {
+	RegisterPrecompiledEncoded(pkgpath, EncodingJSON, data)
+}
+
+// RegisterPrecompiledEncoded is RegisterPrecompiled with the encoding of data, generated by GeneratePrecompiled with Lookup.Encoding.
+func RegisterPrecompiledEncoded(pkgpath string, encoding Encoding, data string) {
+	precompiledMu.Lock()
+	defer precompiledMu.Unlock()
+	precompiled[pkgpath] = precompiledData{encoding: encoding, data: data}
+}
+
+// readPrecompiled returns the package of the precompiled metadata, if registered.
+// The files of the package are added to l.Fset with the precompiled lines, so the positions are available without the source.
+func (l *Lookup) readPrecompiled(pkgpath string) (*packageRef, bool) {
+	precompiledMu.RLock()
+	data, ok := precompiled[pkgpath]
+	precompiledMu.RUnlock()
+	if !ok {
+		return nil, false
+	}
+
+	var entry diskEntry
+	if err := decodeEntry(data.encoding, []byte(data.data), &entry); err != nil || entry.Version != diskCacheVersion || entry.Package == nil {
+		l.logf("the precompiled metadata of %s is broken or outdated (version=%d), ignored: %v", pkgpath, entry.Version, err)
+		return nil, false
+	}
+	tfiles := make([]*token.File, len(entry.Files))
+	for i, f := range entry.Files {
+		f := f
+		tfiles[i] = l.addFile(f.Name, contentHash([]byte(fmt.Sprint(f.Lines))), f.Size, func(tf *token.File) bool {
+			if !tf.SetLines(f.Lines) {
+				l.logf("the precompiled lines of %s are invalid", f.Name)
+				return false
+			}
+			return true
+		})
+	}
+	if DEBUG {
+		l.logf("OK precompiled %v", pkgpath)
+	}
+	return restoreEntry(&entry, tfiles), true
+}
+
+// GeneratePrecompiled writes the Go file of the package pkgname, that registers the metadata of the packages by RegisterPrecompiled.
+// The metadata is collected with the settings of l (the build context, IncludeGoTestFiles, and IncludeUnexported).
+//
+//	//go:generate go run ./gen-metadata   (calling l.GeneratePrecompiled(w, "main", "github.com/foo/bar/model"))
+//
+// Import the generated file into the binary (e.g. by putting it into the main package), to use the metadata at runtime.
+func (l *Lookup) GeneratePrecompiled(w io.Writer, pkgname string, pkgpaths ...string) error {
+	var buf bytes.Buffer
+	fmt.Fprintln(&buf, "// Code generated by reflect-shape (metadata.GeneratePrecompiled); DO NOT EDIT.")
+	fmt.Fprintln(&buf)
+	fmt.Fprintf(&buf, "package %s\n\n", pkgname)
+	fmt.Fprintln(&buf, `import "github.com/podhmo/reflect-shape/metadata"`)
+	fmt.Fprintln(&buf)
+	fmt.Fprintln(&buf, "func init() {")
+
+	sorted := append([]string(nil), pkgpaths...)
+	sort.Strings(sorted)
+	for _, pkgpath := range sorted {
+		data, err := l.precompile(pkgpath)
+		if err != nil {
+			return fmt.Errorf("precompile %s: %w", pkgpath, err)
+		}
+		switch enc := l.encoding(); enc {
+		case EncodingJSON:
+			fmt.Fprintf(&buf, "\tmetadata.RegisterPrecompiled(%q, %s)\n", pkgpath, strconv.Quote(string(data)))
+		case EncodingGob:
+			fmt.Fprintf(&buf, "\tmetadata.RegisterPrecompiledEncoded(%q, metadata.EncodingGob, %s)\n", pkgpath, strconv.Quote(string(data)))
+		default:
+			return fmt.Errorf("precompile %s: unknown encoding %q", pkgpath, enc)
+		}
+	}
+	fmt.Fprintln(&buf, "}")
+
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		return fmt.Errorf("format generated code: %w", err)
+	}
+	_, err = w.Write(src)
+	return err
+}
+
+// precompile returns the metadata of the package, encoded with l.Encoding.
+func (l *Lookup) precompile(pkgpath string) ([]byte, error) {
+	pkgs, err := l.load(l.packagesConfig(context.Background()), pkgpath)
+	if err != nil {
+		return nil, err
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	defer l.evict()
+	for _, pkg := range l.selectPackages(pkgs) {
+		if pkg.PkgPath != pkgpath {
+			continue
+		}
+		ref, err := l.addPackage(pkg)
+		if err != nil {
+			return nil, err
+		}
+		entry, err := l.newDiskEntry(pkg, ref, true)
+		if err != nil {
+			return nil, err
+		}
+		return encodeEntry(l.encoding(), entry)
+	}
+	for _, pkg := range pkgs {
+		if pkg.PkgPath == pkgpath && len(pkg.Errors) > 0 {
+			return nil, newPackageLoadError(pkgpath, pkg.Errors)
+		}
+	}
+	return nil, fmt.Errorf("package %s: %w", pkgpath, ErrNotFound)
+}
@@ -0,0 +1,83 @@
+package metadata
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"strconv"
+	"testing"
+
+	"github.com/podhmo/reflect-shape/metadata/testdata/multi"
+	"golang.org/x/tools/go/packages"
+)
+
+func TestPrecompiled(t *testing.T) {
+	const pkgpath = "github.com/podhmo/reflect-shape/metadata/testdata/multi"
+
+	for _, enc := range []Encoding{EncodingJSON, EncodingGob} {
+		enc := enc
+		t.Run(string(enc), func(t *testing.T) {
+			l0 := NewLookup(token.NewFileSet())
+			l0.Encoding = enc
+			var buf bytes.Buffer
+			if err := l0.GeneratePrecompiled(&buf, "main", pkgpath); err != nil {
+				t.Fatalf("unexpected error: %+v", err)
+			}
+			want, err := l0.LookupFromType(multi.Value{})
+			if err != nil {
+				t.Fatalf("unexpected error: %+v", err)
+			}
+
+			// extract the data (the last argument) of RegisterPrecompiled() from the generated code
+			f, err := parser.ParseFile(token.NewFileSet(), "generated.go", buf.Bytes(), 0)
+			if err != nil {
+				t.Fatalf("the generated code is invalid: %+v\n%s", err, buf.String())
+			}
+			var data string
+			ast.Inspect(f, func(node ast.Node) bool {
+				if call, ok := node.(*ast.CallExpr); ok && len(call.Args) >= 2 {
+					if lit, ok := call.Args[len(call.Args)-1].(*ast.BasicLit); ok {
+						data, _ = strconv.Unquote(lit.Value)
+					}
+				}
+				return true
+			})
+			if data == "" {
+				t.Fatalf("RegisterPrecompiled() is not found in the generated code:\n%s", buf.String())
+			}
+
+			RegisterPrecompiledEncoded(pkgpath, enc, data)
+			defer func() {
+				precompiledMu.Lock()
+				delete(precompiled, pkgpath)
+				precompiledMu.Unlock()
+			}()
+
+			l := NewLookup(token.NewFileSet())
+			l.Loader = func(cfg *packages.Config, patterns ...string) ([]*packages.Package, error) {
+				return nil, fmt.Errorf("must not be loaded: %v", patterns)
+			}
+			got, err := l.LookupFromType(multi.Value{})
+			if err != nil {
+				t.Fatalf("unexpected error: %+v", err)
+			}
+			if want, got := want.Doc(), got.Doc(); want != got {
+				t.Errorf("Doc(): want:%q != got:%q", want, got)
+			}
+			if want, got := l0.Fset.Position(want.Raw.Fields["Name"].Pos), l.Fset.Position(got.Raw.Fields["Name"].Pos); want != got {
+				t.Errorf("Pos of field: want:%v != got:%v", want, got)
+			}
+
+			base := l.Fset.Base()
+			l.Forget(pkgpath)
+			if _, err := l.LookupFromType(multi.Value{}); err != nil {
+				t.Fatalf("unexpected error: %+v", err)
+			}
+			if want, got := base, l.Fset.Base(); want != got {
+				t.Errorf("the files are added again, Fset.Base(): want:%d != got:%d", want, got)
+			}
+		})
+	}
+}
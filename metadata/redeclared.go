@@ -0,0 +1,164 @@
+package metadata
+
+import (
+	"go/ast"
+	"go/build"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/podhmo/commentof/collect"
+)
+
+// Redeclaration is the declarations of the same name in the different files of a package.
+// e.g. the files for the other platforms (x_linux.go and x_windows.go, given by the custom Loader), or the generated overrides.
+type Redeclaration struct {
+	PkgPath    string
+	Name       string        // e.g. "Foo", "S#Method" for the methods
+	Candidates []Declaration // in the order of the filenames
+}
+
+// Declaration is one of the candidates of Redeclaration.
+type Declaration struct {
+	Filename  string
+	Doc       string
+	Matched   bool // the file matches the build context (GOOS, GOARCH, and BuildTags of Lookup)
+	Generated bool // the file has the "// Code generated ... DO NOT EDIT." comment
+}
+
+// Default returns the index of the candidate chosen by the default rule. The candidates are preferred in this order:
+//
+//  1. matching the build context
+//  2. not generated (the hand-written one is the source of the docs)
+//  3. having the doc
+//  4. the first one in the order of the filenames
+func (r *Redeclaration) Default() int {
+	best := 0
+	score := func(d Declaration) int {
+		n := 0
+		if d.Matched {
+			n += 4
+		}
+		if !d.Generated {
+			n += 2
+		}
+		if strings.TrimSpace(d.Doc) != "" {
+			n++
+		}
+		return n
+	}
+	for i, d := range r.Candidates {
+		if score(d) > score(r.Candidates[best]) {
+			best = i
+		}
+	}
+	return best
+}
+
+// resolveRedeclarations keeps only one declaration of each redeclared name, chosen by l.Redeclared (or Redeclaration.Default).
+// The files are in the order of the filenames, and the declarations of the others are removed from them.
+func (l *Lookup) resolveRedeclarations(pkgpath string, filenames []string, trees []*ast.File, files []*collect.File) {
+	seen := map[string][]int{} // name -> the indices of the files
+	for i, f := range files {
+		for _, name := range f.Names {
+			if indices := seen[name]; len(indices) == 0 || indices[len(indices)-1] != i {
+				seen[name] = append(indices, i)
+			}
+		}
+	}
+	names := make([]string, 0, len(seen))
+	for name, indices := range seen {
+		if len(indices) > 1 {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		return
+	}
+	sort.Strings(names)
+
+	ctxt := build.Default
+	if l.GOOS != "" {
+		ctxt.GOOS = l.GOOS
+	}
+	if l.GOARCH != "" {
+		ctxt.GOARCH = l.GOARCH
+	}
+	ctxt.BuildTags = l.BuildTags
+	matched := map[int]bool{}
+	for _, indices := range seen {
+		for _, i := range indices {
+			if _, ok := matched[i]; !ok {
+				ok, err := ctxt.MatchFile(filepath.Dir(filenames[i]), filepath.Base(filenames[i]))
+				matched[i] = ok || err != nil // unreadable files are not excluded
+			}
+		}
+	}
+
+	for _, name := range names {
+		r := &Redeclaration{PkgPath: pkgpath, Name: name}
+		for _, i := range seen[name] {
+			r.Candidates = append(r.Candidates, Declaration{Filename: filenames[i], Doc: docOf(files[i], name), Matched: matched[i], Generated: isGenerated(trees[i])})
+		}
+		chosen := -1
+		if l.Redeclared != nil {
+			chosen = l.Redeclared(r)
+		}
+		if chosen < 0 || chosen >= len(r.Candidates) {
+			chosen = r.Default()
+		}
+		if l.Trace {
+			l.logf("trace: redeclared %s.%s in %d files -> %s", pkgpath, name, len(r.Candidates), r.Candidates[chosen].Filename)
+		}
+		for j, i := range seen[name] {
+			if j != chosen {
+				removeDecl(files[i], name)
+			}
+		}
+	}
+}
+
+func docOf(f *collect.File, name string) string {
+	if ob, ok := f.Types[name]; ok {
+		return ob.Doc
+	}
+	if ob, ok := f.Interfaces[name]; ok {
+		return ob.Doc
+	}
+	if fn, ok := f.Functions[name]; ok {
+		return fn.Doc
+	}
+	return ""
+}
+
+func removeDecl(f *collect.File, name string) {
+	delete(f.Types, name)
+	delete(f.Interfaces, name)
+	delete(f.Functions, name)
+	names := f.Names[:0]
+	for _, x := range f.Names {
+		if x != name {
+			names = append(names, x)
+		}
+	}
+	f.Names = names
+}
+
+// generatedRegex matches the comment of the generated files. (see https://go.dev/s/generatedcode)
+var generatedRegex = regexp.MustCompile(`^// Code generated .* DO NOT EDIT\.$`)
+
+// isGenerated reports whether the file is generated, by the comment before the package clause.
+func isGenerated(f *ast.File) bool {
+	for _, cg := range f.Comments {
+		if cg.Pos() > f.Package {
+			break
+		}
+		for _, c := range cg.List {
+			if generatedRegex.MatchString(c.Text) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,109 @@
+package metadata
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+)
+
+// ReturnedTypeNames returns the names of the types constructed in the return statements of the function.
+// (e.g. "Foo" for `return &Foo{}`, `return Foo{...}` and `return new(Foo)`)
+//
+// Only the types declared in the same package are reported (the qualified names are skipped), in order of appearance without duplicates.
+// The return statements of the function literals in the body are not included.
+func (l *Lookup) ReturnedTypeNames(m *Func) ([]string, error) {
+	if m == nil || m.Raw == nil || !m.Raw.Pos.IsValid() {
+		return nil, fmt.Errorf("the position of the function is unknown, %w", ErrNotFound)
+	}
+
+	fset, f, pos, err := l.reparse(m.Raw.Pos)
+	if err != nil {
+		return nil, err
+	}
+
+	var decl *ast.FuncDecl
+	for _, d := range f.Decls {
+		if d, ok := d.(*ast.FuncDecl); ok && fset.Position(d.Pos()).Offset == pos.Offset {
+			decl = d
+			break
+		}
+	}
+	if decl == nil || decl.Body == nil {
+		return nil, l.notFound(m.Raw.Name, "", pos.Filename, nil)
+	}
+
+	var names []string
+	seen := map[string]bool{}
+	ast.Inspect(decl.Body, func(node ast.Node) bool {
+		switch node := node.(type) {
+		case *ast.FuncLit:
+			return false
+		case *ast.ReturnStmt:
+			for _, x := range node.Results {
+				if name := constructedTypeName(x); name != "" && !seen[name] {
+					seen[name] = true
+					names = append(names, name)
+				}
+			}
+		}
+		return true
+	})
+	return names, nil
+}
+
+// reparse parses the file including pos again, with the other fset. (to avoid adding the same file to l.Fset twice)
+// The returned position is the position of pos in l.Fset, and its Offset is also valid for the returned fset.
+func (l *Lookup) reparse(pos token.Pos) (*token.FileSet, *ast.File, token.Position, error) {
+	l.mu.Lock()
+	position := l.Fset.Position(pos)
+	l.mu.Unlock()
+	if position.Filename == "" {
+		return nil, nil, position, fmt.Errorf("the file of pos=%d is not found in the fset, %w", pos, ErrNotFound)
+	}
+
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, position.Filename, nil, parser.ParseComments)
+	if err != nil {
+		return nil, nil, position, fmt.Errorf("parse %s: %w", position.Filename, err)
+	}
+	return fset, f, position, nil
+}
+
+func constructedTypeName(x ast.Expr) string {
+	for {
+		switch v := x.(type) {
+		case *ast.ParenExpr:
+			x = v.X
+			continue
+		case *ast.UnaryExpr:
+			if v.Op != token.AND {
+				return ""
+			}
+			x = v.X
+			continue
+		case *ast.CallExpr:
+			if id, ok := v.Fun.(*ast.Ident); !ok || id.Name != "new" || len(v.Args) != 1 {
+				return ""
+			}
+			return typeName(v.Args[0])
+		case *ast.CompositeLit:
+			return typeName(v.Type)
+		}
+		return ""
+	}
+}
+
+func typeName(x ast.Expr) string {
+	switch v := x.(type) {
+	case *ast.Ident:
+		return v.Name
+	case *ast.IndexExpr: // generics
+		return typeName(v.X)
+	case *ast.IndexListExpr:
+		return typeName(v.X)
+	case *ast.ParenExpr:
+		return typeName(v.X)
+	}
+	return ""
+}
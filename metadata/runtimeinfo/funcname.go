@@ -0,0 +1,246 @@
+package runtimeinfo
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// FuncName is the parsed name of the runtime function (runtime.Func.Name()). (see ParseFuncName)
+type FuncName struct {
+	PkgPath  string // the import path, unescaped (e.g. "gopkg.in/yaml.v3"), "main" for the main package
+	Recv     string // the receiver type of the method, without the pointer and the type arguments (e.g. "List" for "(*List[...])"), "" if not a method
+	Name     string // the function or the method, without the type arguments. For the closures, the enclosing one ("init" for the package-level variables)
+	TypeArgs string // the type arguments as is (e.g. "[...]"), of the receiver for the methods, "" if not generic
+	Closure  string // the closure suffix (e.g. "func1", "func1.2"), "" if not a closure
+
+	IsPointerRecv bool // the receiver is the pointer (e.g. "(*T).M")
+	IsMethodValue bool // the wrapper of the method value, having the "-fm" suffix (e.g. "T.M-fm")
+}
+
+// IsMethod reports whether the name is of the method (or the closure in the method).
+func (fn FuncName) IsMethod() bool {
+	return fn.Recv != ""
+}
+
+// IsClosure reports whether the name is of the closure (the anonymous function).
+func (fn FuncName) IsClosure() bool {
+	return fn.Closure != ""
+}
+
+// String returns the name in the form of the runtime. (the package-level closures are in the form of go1.21, e.g. "pkg.init.func1")
+func (fn FuncName) String() string {
+	var b strings.Builder
+	b.WriteString(escapePkgpath(fn.PkgPath))
+	b.WriteByte('.')
+	switch {
+	case fn.Recv != "" && fn.IsPointerRecv:
+		b.WriteString("(*" + fn.Recv + fn.TypeArgs + ").")
+		b.WriteString(fn.Name)
+	case fn.Recv != "":
+		b.WriteString(fn.Recv + fn.TypeArgs + ".")
+		b.WriteString(fn.Name)
+	default:
+		b.WriteString(fn.Name + fn.TypeArgs)
+	}
+	if fn.Closure != "" {
+		b.WriteString("." + fn.Closure)
+	}
+	if fn.IsMethodValue {
+		b.WriteString("-fm")
+	}
+	return b.String()
+}
+
+// closurePartRegex matches the parts of the closure names. (e.g. "func1" and "2" of "F.func1.2", "gowrap1" and "deferwrap1" of go1.21)
+var closurePartRegex = regexp.MustCompile(`^(func\d+|\d+|gowrap\d+|deferwrap\d+)$`)
+
+// ParseFuncName parses the name of the runtime function. The grammar is:
+//
+//	name     = pkgpath "." body [ "-fm" ]
+//	body     = [ recv "." ] ident [ typeargs ] { "." closure }
+//	recv     = "(*" ident [ typeargs ] ")" | ident [ typeargs ]
+//	typeargs = "[" ... "]"                  (e.g. "[...]", "[go.shape.int_0]", may be nested)
+//	closure  = "func" digits | digits | "gowrap" digits | "deferwrap" digits
+//	pkgpath  = the import path, the last element is escaped as the linker does (e.g. "gopkg.in/yaml%2ev3" for "gopkg.in/yaml.v3")
+//
+// The closures of the package-level variables are "pkg.glob..func1" (until go1.20) or "pkg.init.func1", and both are parsed as Name="init".
+// The multiple init functions are "pkg.init.0", "pkg.init.1", ..., parsed as Closure="0", "1", ... too.
+// The value receiver and the enclosing function of the closure are distinguished by the closure parts. (e.g. "T.M" is the method, "F.func1" is the closure)
+func ParseFuncName(name string) (FuncName, error) {
+	var fn FuncName
+	if name == "" {
+		return fn, fmt.Errorf("invalid func name %q: empty", name)
+	}
+	rest := strings.TrimSuffix(name, "-fm")
+	fn.IsMethodValue = len(rest) < len(name)
+
+	// the package path ends at the first "." after the last "/" (the "." in the last element is escaped), the brackets are skipped
+	slash := -1
+	depth := 0
+	for i := 0; i < len(rest); i++ {
+		switch rest[i] {
+		case '[':
+			depth++
+		case ']':
+			depth--
+			if depth < 0 {
+				return fn, fmt.Errorf("invalid func name %q: unbalanced brackets", name)
+			}
+		case '/':
+			if depth == 0 {
+				slash = i
+			}
+		}
+	}
+	if depth != 0 {
+		return fn, fmt.Errorf("invalid func name %q: unbalanced brackets", name)
+	}
+	dot := strings.IndexByte(rest[slash+1:], '.')
+	if dot < 0 {
+		return fn, fmt.Errorf("invalid func name %q: no package path", name)
+	}
+	escaped := rest[:slash+1+dot]
+	if escaped == "" || strings.HasPrefix(escaped, "type:") || strings.HasPrefix(escaped, "go:") || escaped == "type" || escaped == "go" {
+		return fn, fmt.Errorf("invalid func name %q: not a function of the package", name) // e.g. type:.eq.T, go:buildid
+	}
+	pkgpath, err := unescapePkgpath(escaped)
+	if err != nil {
+		return fn, fmt.Errorf("invalid func name %q: %w", name, err)
+	}
+	if strings.ContainsAny(pkgpath, "[]") {
+		return fn, fmt.Errorf("invalid func name %q: invalid package path %q", name, pkgpath)
+	}
+	fn.PkgPath = pkgpath
+	rest = rest[slash+1+dot+1:]
+
+	// the package-level closures until go1.20
+	if strings.HasPrefix(rest, "glob..") {
+		rest = "init." + strings.TrimPrefix(rest, "glob..")
+	}
+
+	parts := splitTopLevel(rest, '.')
+	for _, p := range parts {
+		if p == "" {
+			return fn, fmt.Errorf("invalid func name %q: empty part", name)
+		}
+	}
+
+	hasRecv := true
+	switch {
+	case strings.HasPrefix(parts[0], "("):
+		if !strings.HasPrefix(parts[0], "(*") || !strings.HasSuffix(parts[0], ")") || len(parts) < 2 {
+			return fn, fmt.Errorf("invalid func name %q: invalid receiver %q", name, parts[0])
+		}
+		fn.IsPointerRecv = true
+		fn.Recv, fn.TypeArgs = cutTypeArgs(parts[0][2 : len(parts[0])-1])
+		fn.Name = parts[1]
+		parts = parts[2:]
+	case len(parts) >= 2 && !closurePartRegex.MatchString(parts[1]):
+		fn.Recv, fn.TypeArgs = cutTypeArgs(parts[0])
+		fn.Name = parts[1]
+		parts = parts[2:]
+	default:
+		hasRecv = false
+		fn.Name, fn.TypeArgs = cutTypeArgs(parts[0])
+		parts = parts[1:]
+	}
+	if !isIdent(fn.Name) || (hasRecv && !isIdent(fn.Recv)) {
+		return fn, fmt.Errorf("invalid func name %q: invalid identifier", name)
+	}
+	for _, p := range parts {
+		if !closurePartRegex.MatchString(p) {
+			return fn, fmt.Errorf("invalid func name %q: invalid closure part %q", name, p)
+		}
+	}
+	fn.Closure = strings.Join(parts, ".")
+	return fn, nil
+}
+
+// isIdent reports whether s is the Go identifier.
+func isIdent(s string) bool {
+	for i, r := range s {
+		if !(unicode.IsLetter(r) || r == '_' || (i > 0 && unicode.IsDigit(r))) {
+			return false
+		}
+	}
+	return s != ""
+}
+
+// splitTopLevel splits s by sep, except in the brackets.
+func splitTopLevel(s string, sep byte) []string {
+	var r []string
+	depth := 0
+	start := 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '[':
+			depth++
+		case ']':
+			depth--
+		case sep:
+			if depth == 0 {
+				r = append(r, s[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(r, s[start:])
+}
+
+// cutTypeArgs splits the identifier and the type arguments. (e.g. "List[...]" -> "List", "[...]")
+func cutTypeArgs(s string) (ident string, typeargs string) {
+	if i := strings.IndexByte(s, '['); i >= 0 && strings.HasSuffix(s, "]") {
+		return s[:i], s[i:]
+	}
+	return s, ""
+}
+
+// unescapePkgpath unescapes the package path escaped by the linker. (e.g. "gopkg.in/yaml%2ev3" -> "gopkg.in/yaml.v3")
+func unescapePkgpath(s string) (string, error) {
+	if !strings.Contains(s, "%") {
+		return s, nil
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '%' {
+			b.WriteByte(s[i])
+			continue
+		}
+		if i+2 >= len(s) || fromHex(s[i+1]) < 0 || fromHex(s[i+2]) < 0 {
+			return "", fmt.Errorf("invalid escape in %q", s)
+		}
+		b.WriteByte(byte(fromHex(s[i+1])<<4 | fromHex(s[i+2])))
+		i += 2
+	}
+	return b.String(), nil
+}
+
+// escapePkgpath escapes the package path as the linker does. (the control characters, '%', '"', and '.' in the last element)
+func escapePkgpath(s string) string {
+	slash := strings.LastIndexByte(s, '/')
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c <= ' ' || c == '%' || c == '"' || c >= 0x7f || (c == '.' && i > slash) {
+			fmt.Fprintf(&b, "%%%02x", c)
+			continue
+		}
+		b.WriteByte(c)
+	}
+	return b.String()
+}
+
+func fromHex(c byte) int {
+	switch {
+	case '0' <= c && c <= '9':
+		return int(c - '0')
+	case 'a' <= c && c <= 'f':
+		return int(c-'a') + 10
+	case 'A' <= c && c <= 'F':
+		return int(c-'A') + 10
+	default:
+		return -1
+	}
+}
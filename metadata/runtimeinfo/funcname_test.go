@@ -0,0 +1,147 @@
+package runtimeinfo_test
+
+import (
+	"reflect"
+	"runtime"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/podhmo/reflect-shape/metadata/runtimeinfo"
+)
+
+type List[E any] struct{ items []E }
+
+func (l *List[E]) Push(x E) { l.items = append(l.items, x) }
+
+func (s S) V() {}
+
+//go:noinline
+func Closure() func() { return func() {} }
+
+var parseFuncNameCases = []struct {
+	input string
+	want  runtimeinfo.FuncName
+}{
+	{input: "main.main", want: runtimeinfo.FuncName{PkgPath: "main", Name: "main"}},
+	{input: "github.com/foo/bar.F", want: runtimeinfo.FuncName{PkgPath: "github.com/foo/bar", Name: "F"}},
+	{input: "github.com/foo/bar.T.M", want: runtimeinfo.FuncName{PkgPath: "github.com/foo/bar", Recv: "T", Name: "M"}},
+	{input: "github.com/foo/bar.T.M-fm", want: runtimeinfo.FuncName{PkgPath: "github.com/foo/bar", Recv: "T", Name: "M", IsMethodValue: true}},
+	{input: "github.com/foo/bar.(*T).M", want: runtimeinfo.FuncName{PkgPath: "github.com/foo/bar", Recv: "T", Name: "M", IsPointerRecv: true}},
+	{input: "github.com/foo/bar.(*T).M-fm", want: runtimeinfo.FuncName{PkgPath: "github.com/foo/bar", Recv: "T", Name: "M", IsPointerRecv: true, IsMethodValue: true}},
+	// generics
+	{input: "github.com/foo/bar.Map[...]", want: runtimeinfo.FuncName{PkgPath: "github.com/foo/bar", Name: "Map", TypeArgs: "[...]"}},
+	{input: "github.com/foo/bar.(*List[...]).Push", want: runtimeinfo.FuncName{PkgPath: "github.com/foo/bar", Recv: "List", TypeArgs: "[...]", Name: "Push", IsPointerRecv: true}},
+	{input: "github.com/foo/bar.Pair[...].Swap", want: runtimeinfo.FuncName{PkgPath: "github.com/foo/bar", Recv: "Pair", TypeArgs: "[...]", Name: "Swap"}},
+	{input: "example.com/x.Map[example.com/y.T,map[string]go.shape.int]", want: runtimeinfo.FuncName{PkgPath: "example.com/x", Name: "Map", TypeArgs: "[example.com/y.T,map[string]go.shape.int]"}},
+	{input: "example.com/x.Map[go.shape.int_0,go.shape.*uint8_1]", want: runtimeinfo.FuncName{PkgPath: "example.com/x", Name: "Map", TypeArgs: "[go.shape.int_0,go.shape.*uint8_1]"}},
+	{input: "example.com/x.Apply[func(int) (string, error)]", want: runtimeinfo.FuncName{PkgPath: "example.com/x", Name: "Apply", TypeArgs: "[func(int) (string, error)]"}},
+	{input: "example.com/x.(*Tree[example.com/x.Node[go.shape.string]]).Walk", want: runtimeinfo.FuncName{PkgPath: "example.com/x", Recv: "Tree", TypeArgs: "[example.com/x.Node[go.shape.string]]", Name: "Walk", IsPointerRecv: true}},
+	// the method values of the generic types (go1.21~, until go1.20 they are the closures of the callers)
+	{input: "github.com/foo/bar.(*List[...]).Push-fm", want: runtimeinfo.FuncName{PkgPath: "github.com/foo/bar", Recv: "List", TypeArgs: "[...]", Name: "Push", IsPointerRecv: true, IsMethodValue: true}},
+	{input: "github.com/foo/bar.Pair[...].Swap-fm", want: runtimeinfo.FuncName{PkgPath: "github.com/foo/bar", Recv: "Pair", TypeArgs: "[...]", Name: "Swap", IsMethodValue: true}},
+	{input: "gopkg.in/yaml%2ev3.(*Decoder[...]).Decode-fm", want: runtimeinfo.FuncName{PkgPath: "gopkg.in/yaml.v3", Recv: "Decoder", TypeArgs: "[...]", Name: "Decode", IsPointerRecv: true, IsMethodValue: true}},
+	// closures
+	{input: "github.com/foo/bar.F.func1", want: runtimeinfo.FuncName{PkgPath: "github.com/foo/bar", Name: "F", Closure: "func1"}},
+	{input: "github.com/foo/bar.F.func1.2", want: runtimeinfo.FuncName{PkgPath: "github.com/foo/bar", Name: "F", Closure: "func1.2"}},
+	{input: "github.com/foo/bar.F.deferwrap1", want: runtimeinfo.FuncName{PkgPath: "github.com/foo/bar", Name: "F", Closure: "deferwrap1"}},
+	{input: "github.com/foo/bar.(*T).M.func1", want: runtimeinfo.FuncName{PkgPath: "github.com/foo/bar", Recv: "T", Name: "M", Closure: "func1", IsPointerRecv: true}},
+	{input: "github.com/foo/bar.Map[...].func1", want: runtimeinfo.FuncName{PkgPath: "github.com/foo/bar", Name: "Map", TypeArgs: "[...]", Closure: "func1"}},
+	{input: "github.com/foo/bar.glob..func1", want: runtimeinfo.FuncName{PkgPath: "github.com/foo/bar", Name: "init", Closure: "func1"}},
+	{input: "github.com/foo/bar.init.func1", want: runtimeinfo.FuncName{PkgPath: "github.com/foo/bar", Name: "init", Closure: "func1"}},
+	{input: "github.com/foo/bar.init.0", want: runtimeinfo.FuncName{PkgPath: "github.com/foo/bar", Name: "init", Closure: "0"}},
+	// dotted module paths
+	{input: "gopkg.in/yaml%2ev3.Marshal", want: runtimeinfo.FuncName{PkgPath: "gopkg.in/yaml.v3", Name: "Marshal"}},
+	{input: "gopkg.in/yaml%2ev3.(*Node).Decode", want: runtimeinfo.FuncName{PkgPath: "gopkg.in/yaml.v3", Recv: "Node", Name: "Decode", IsPointerRecv: true}},
+	{input: "go.uber.org/zap.(*Logger).Info", want: runtimeinfo.FuncName{PkgPath: "go.uber.org/zap", Recv: "Logger", Name: "Info", IsPointerRecv: true}},
+	{input: "example.com/a.b/c%2ed.Map[example.com/a.b/e%2ef.T]", want: runtimeinfo.FuncName{PkgPath: "example.com/a.b/c.d", Name: "Map", TypeArgs: "[example.com/a.b/e%2ef.T]"}},
+	{input: "k8s.io/client-go/tools/cache.(*sharedIndexInformer).Run.func1", want: runtimeinfo.FuncName{PkgPath: "k8s.io/client-go/tools/cache", Recv: "sharedIndexInformer", Name: "Run", Closure: "func1", IsPointerRecv: true}},
+	// the wrappers (the autogenerated methods of the embedded fields and the pointer receivers are named as the methods)
+	{input: "github.com/foo/bar.(*Outer).Inner", want: runtimeinfo.FuncName{PkgPath: "github.com/foo/bar", Recv: "Outer", Name: "Inner", IsPointerRecv: true}},
+	{input: "github.com/foo/bar.F.gowrap1", want: runtimeinfo.FuncName{PkgPath: "github.com/foo/bar", Name: "F", Closure: "gowrap1"}},
+}
+
+func TestParseFuncName(t *testing.T) {
+	for _, c := range parseFuncNameCases {
+		c := c
+		t.Run(c.input, func(t *testing.T) {
+			got, err := runtimeinfo.ParseFuncName(c.input)
+			if err != nil {
+				t.Fatalf("unexpected error: %+v", err)
+			}
+			if diff := cmp.Diff(c.want, got); diff != "" {
+				t.Errorf("ParseFuncName(): mismatch (-want, +got): \n%v", diff)
+			}
+			if got, err := runtimeinfo.ParseFuncName(got.String()); err != nil || got != c.want {
+				t.Errorf("ParseFuncName(FuncName.String()), must be the same, but got %+v (%v)", got, err)
+			}
+		})
+	}
+
+	t.Run("error", func(t *testing.T) {
+		for _, input := range []string{
+			"", "main", "github.com/foo/bar", "github.com/foo/bar.", "github.com/foo/bar.F..func1",
+			"github.com/foo/bar.Map[...", "github.com/foo/bar.(T).M", "github.com/foo/bar.(*T)", "github.com/foo/bar.(*T).M[int]",
+			"github.com/foo/bar.F.func1.x", "github.com/foo/bar%zz.F", "type:.eq.github.com/foo/bar.T", "go:buildid",
+			"github.com/foo/bar.F-fm-fm", "github.com/foo/bar.1F", "github.com/foo/bar.(*T-x).M", "github.com/foo/bar.type..eq.T",
+		} {
+			if got, err := runtimeinfo.ParseFuncName(input); err == nil {
+				t.Errorf("ParseFuncName(%q), must be error, but got %+v", input, got)
+			}
+		}
+	})
+
+	t.Run("runtime", func(t *testing.T) {
+		pkgpath := reflect.TypeOf(S{}).PkgPath()
+		var l List[int]
+		cases := []struct {
+			msg  string
+			fn   any
+			want runtimeinfo.FuncName
+		}{
+			{msg: "func", fn: Closure, want: runtimeinfo.FuncName{PkgPath: pkgpath, Name: "Closure"}},
+			{msg: "closure", fn: Closure(), want: runtimeinfo.FuncName{PkgPath: pkgpath, Name: "Closure", Closure: "func1"}},
+			{msg: "method-value", fn: S{}.V, want: runtimeinfo.FuncName{PkgPath: pkgpath, Recv: "S", Name: "V", IsMethodValue: true}},
+			{msg: "method-expression", fn: (*S).M, want: runtimeinfo.FuncName{PkgPath: pkgpath, Recv: "S", Name: "M", IsPointerRecv: true}},
+			// the generics are wrapped by the closures (e.g. "TestParseFuncName.func3.1"), only the package path is checked
+			{msg: "generic-method-expression", fn: (*List[int]).Push},
+			{msg: "generic-method-value", fn: l.Push},
+		}
+		for _, c := range cases {
+			name := runtime.FuncForPC(reflect.ValueOf(c.fn).Pointer()).Name()
+			got, err := runtimeinfo.ParseFuncName(name)
+			if err != nil {
+				t.Errorf("%s: unexpected error: %+v", c.msg, err)
+				continue
+			}
+			if c.want.Name == "" {
+				c.want = runtimeinfo.FuncName{PkgPath: pkgpath}
+				got = runtimeinfo.FuncName{PkgPath: got.PkgPath}
+			}
+			if diff := cmp.Diff(c.want, got); diff != "" {
+				t.Errorf("%s: ParseFuncName(%q): mismatch (-want, +got): \n%v", c.msg, name, diff)
+			}
+		}
+	})
+}
+
+func FuzzParseFuncName(f *testing.F) {
+	for _, c := range parseFuncNameCases {
+		f.Add(c.input)
+	}
+	f.Fuzz(func(t *testing.T, input string) {
+		got, err := runtimeinfo.ParseFuncName(input)
+		if err != nil {
+			return
+		}
+		if got.PkgPath == "" || got.Name == "" {
+			t.Errorf("ParseFuncName(%q), the package path and the name must not be empty, but got %+v", input, got)
+		}
+		again, err := runtimeinfo.ParseFuncName(got.String())
+		if err != nil {
+			t.Fatalf("ParseFuncName(%q).String() = %q, must be parsed, but %+v", input, got.String(), err)
+		}
+		if again != got {
+			t.Errorf("ParseFuncName(%q), the round trip must be the same, %+v != %+v", input, got, again)
+		}
+	})
+}
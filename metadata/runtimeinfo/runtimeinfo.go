@@ -0,0 +1,205 @@
+// Package runtimeinfo provides the information of the running program, that the runtime package doesn't expose.
+// (e.g. the method of the method value, the loaded modules)
+//
+// The implementation depends on the runtime internals, and it is enabled only if the layout is known (see Probe).
+// Otherwise, New returns the fallback accessor built on the runtime package only, so the importers don't break on the new go versions.
+package runtimeinfo
+
+import (
+	"reflect"
+	"runtime"
+	"strings"
+	"sync"
+
+	"github.com/podhmo/reflect-shape/metadata/internal/unsaferuntime"
+)
+
+// Accessor is the accessor of the runtime information.
+type Accessor interface {
+	// FuncForPC returns the function of pc, or nil if pc is not in the known functions.
+	// The method value (e.g. "pkg.(*T).M-fm") is resolved to the method (e.g. "pkg.(*T).M"), if Capabilities.MethodValues is true.
+	// If pc is in the inlined code, the innermost function is returned, as runtime.FuncForPC does. (see Frames)
+	FuncForPC(pc uintptr) *runtime.Func
+
+	// Frames returns the frames of pc, the innermost first. If pc is in the inlined code, the frames of the inlined functions are included.
+	// pc is the return address, as runtime.Callers returns.
+	Frames(pc uintptr) []runtime.Frame
+
+	// ModuleOf returns the module that includes pc. If pc is not included in any modules (or Capabilities.Modules is false), ok is false.
+	ModuleOf(pc uintptr) (m Module, ok bool)
+
+	// Modules returns the loaded modules, the main executable first. (nil if Capabilities.Modules is false)
+	Modules() []Module
+
+	// Funcs returns the functions linked into the binary, whose package paths have the prefix. (nil if Capabilities.Modules is false)
+	// If the prefix is "", all functions are returned (including the runtime's). The method value wrappers (-fm) are not included.
+	// The types are not included (see Types).
+	Funcs(pkgPrefix string) []FuncInfo
+
+	// Types returns the named types linked into the binary, reachable from the types that reflect can look up (e.g. *T -> T).
+	// The types only used internally by the functions may not be included. (nil if Capabilities.Modules is false)
+	Types() []reflect.Type
+}
+
+// FuncInfo is the function linked into the binary.
+type FuncInfo struct {
+	Name    string  // e.g. "github.com/foo/bar.(*T).M"
+	PkgPath string  // e.g. "github.com/foo/bar"
+	PC      uintptr // the entry pc, for metadata.Lookup.LookupFromFuncForPC
+}
+
+// Module is the loaded module. (the main executable, or the plugin)
+type Module struct {
+	PluginPath   string // "" if the module is not plugin
+	MinPC, MaxPC uintptr
+	HasMain      bool
+}
+
+// Capabilities is the result of Probe.
+type Capabilities struct {
+	MethodValues bool // FuncForPC resolves the method values
+	Modules      bool // ModuleOf and Modules enumerate the loaded modules
+}
+
+var (
+	probeOnce sync.Once
+	probed    Capabilities
+)
+
+// Probe returns the capabilities of the accessor returned by New, checking the layout of the runtime internals once.
+func Probe() Capabilities {
+	probeOnce.Do(func() {
+		if unsaferuntime.Supported() {
+			probed = Capabilities{MethodValues: true, Modules: true}
+		}
+	})
+	return probed
+}
+
+// New returns the accessor. If the runtime internals are not supported (see Probe), the fallback accessor is returned.
+func New() Accessor {
+	if c := Probe(); c.MethodValues && c.Modules {
+		return &unsafeAccessor{internal: unsaferuntime.New()}
+	}
+	return fallbackAccessor{}
+}
+
+type unsafeAccessor struct {
+	internal *unsaferuntime.Accessor
+}
+
+func (a *unsafeAccessor) FuncForPC(pc uintptr) *runtime.Func {
+	return a.internal.FuncForPC(pc)
+}
+
+func (a *unsafeAccessor) Frames(pc uintptr) []runtime.Frame {
+	return frames(pc)
+}
+
+func (a *unsafeAccessor) ModuleOf(pc uintptr) (Module, bool) {
+	for _, m := range a.Modules() {
+		if m.MinPC <= pc && pc < m.MaxPC {
+			return m, true
+		}
+	}
+	return Module{}, false
+}
+
+func (a *unsafeAccessor) Modules() []Module {
+	modules := a.internal.Modules()
+	r := make([]Module, len(modules))
+	for i, m := range modules {
+		r[i] = Module{PluginPath: m.PluginPath, MinPC: m.MinPC, MaxPC: m.MaxPC, HasMain: m.HasMain}
+	}
+	return r
+}
+
+func (a *unsafeAccessor) Funcs(pkgPrefix string) []FuncInfo {
+	var r []FuncInfo
+	for _, rfunc := range a.internal.Funcs() {
+		name := rfunc.Name()
+		fn, err := ParseFuncName(name)
+		if err != nil || fn.IsMethodValue {
+			continue
+		}
+		if !hasPkgPrefix(fn.PkgPath, pkgPrefix) {
+			continue
+		}
+		r = append(r, FuncInfo{Name: name, PkgPath: fn.PkgPath, PC: rfunc.Entry()})
+	}
+	return r
+}
+
+func (a *unsafeAccessor) Types() []reflect.Type {
+	var r []reflect.Type
+	seen := map[reflect.Type]bool{}
+	for _, rt := range a.internal.Types() {
+		for _, t := range []reflect.Type{rt, elemOf(rt)} {
+			if t == nil || t.Name() == "" || t.PkgPath() == "" || t.PkgPath() == "go.shape" || seen[t] {
+				continue
+			}
+			seen[t] = true
+			r = append(r, t)
+		}
+	}
+	return r
+}
+
+// elemOf returns the element type of the composite type, or nil.
+func elemOf(rt reflect.Type) reflect.Type {
+	switch rt.Kind() {
+	case reflect.Pointer, reflect.Slice, reflect.Array, reflect.Map, reflect.Chan:
+		return rt.Elem()
+	default:
+		return nil
+	}
+}
+
+// fallbackAccessor is the accessor built on the runtime package only.
+type fallbackAccessor struct{}
+
+func (fallbackAccessor) FuncForPC(pc uintptr) *runtime.Func {
+	return runtime.FuncForPC(pc) // the method value is not resolved
+}
+
+func (fallbackAccessor) Frames(pc uintptr) []runtime.Frame {
+	return frames(pc)
+}
+
+func (fallbackAccessor) ModuleOf(pc uintptr) (Module, bool) {
+	return Module{}, false
+}
+
+func (fallbackAccessor) Modules() []Module {
+	return nil
+}
+
+func (fallbackAccessor) Funcs(pkgPrefix string) []FuncInfo {
+	return nil
+}
+
+func (fallbackAccessor) Types() []reflect.Type {
+	return nil
+}
+
+// hasPkgPrefix reports whether pkgpath is the prefix itself or its sub package. (e.g. the prefix "github.com/foo" matches "github.com/foo/bar", but not "github.com/foobar")
+func hasPkgPrefix(pkgpath, prefix string) bool {
+	if prefix == "" || pkgpath == prefix {
+		return true
+	}
+	return strings.HasPrefix(pkgpath, strings.TrimSuffix(prefix, "/")+"/")
+}
+
+func frames(pc uintptr) []runtime.Frame {
+	var r []runtime.Frame
+	iter := runtime.CallersFrames([]uintptr{pc})
+	for {
+		frame, more := iter.Next()
+		if frame.Function != "" {
+			r = append(r, frame)
+		}
+		if !more {
+			return r
+		}
+	}
+}
@@ -0,0 +1,147 @@
+package runtimeinfo_test
+
+import (
+	"go/token"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/podhmo/reflect-shape/metadata"
+	"github.com/podhmo/reflect-shape/metadata/runtimeinfo"
+)
+
+type S struct{}
+
+// M is the method of S.
+func (s *S) M() {}
+
+func supported(t *testing.T) {
+	t.Helper()
+	if v := runtime.Version(); !strings.HasPrefix(v, "go1.18") && !strings.HasPrefix(v, "go1.19") {
+		t.Skipf("the runtime internals of %s are not supported", v)
+	}
+}
+
+func TestProbe(t *testing.T) {
+	supported(t)
+	if want, got := (runtimeinfo.Capabilities{MethodValues: true, Modules: true}), runtimeinfo.Probe(); want != got {
+		t.Errorf("Probe(), want:%+v != got:%+v", want, got)
+	}
+}
+
+func TestFuncForPC(t *testing.T) {
+	supported(t)
+	a := runtimeinfo.New()
+
+	cases := []struct {
+		msg  string
+		fn   interface{}
+		want string
+	}{
+		{msg: "func", fn: TestFuncForPC, want: "github.com/podhmo/reflect-shape/metadata/runtimeinfo_test.TestFuncForPC"},
+		{msg: "method-value", fn: new(S).M, want: "github.com/podhmo/reflect-shape/metadata/runtimeinfo_test.(*S).M"},
+	}
+	for _, c := range cases {
+		c := c
+		t.Run(c.msg, func(t *testing.T) {
+			rfunc := a.FuncForPC(reflect.ValueOf(c.fn).Pointer())
+			if rfunc == nil {
+				t.Fatalf("FuncForPC(), must not be nil")
+			}
+			if want, got := c.want, rfunc.Name(); want != got {
+				t.Errorf("FuncForPC().Name(), want:%v != got:%v", want, got)
+			}
+		})
+	}
+}
+
+func TestModules(t *testing.T) {
+	supported(t)
+	a := runtimeinfo.New()
+
+	modules := a.Modules()
+	if len(modules) == 0 {
+		t.Fatalf("Modules(), must not be empty")
+	}
+	if !modules[0].HasMain {
+		t.Errorf("Modules()[0].HasMain, the main executable must be first")
+	}
+
+	m, ok := a.ModuleOf(reflect.ValueOf(TestModules).Pointer())
+	if !ok {
+		t.Fatalf("ModuleOf(), must be found")
+	}
+	if want, got := modules[0], m; want != got {
+		t.Errorf("ModuleOf(), want:%+v != got:%+v", want, got)
+	}
+	if _, ok := a.ModuleOf(0); ok {
+		t.Errorf("ModuleOf(0), must not be found")
+	}
+}
+
+func TestFrames(t *testing.T) {
+	pcs := make([]uintptr, 1)
+	runtime.Callers(1, pcs)
+
+	frames := runtimeinfo.New().Frames(pcs[0])
+	if len(frames) == 0 {
+		t.Fatalf("Frames(), must not be empty")
+	}
+	if want, got := "github.com/podhmo/reflect-shape/metadata/runtimeinfo_test.TestFrames", frames[0].Function; want != got {
+		t.Errorf("Frames()[0].Function, want:%v != got:%v", want, got)
+	}
+}
+
+func TestFuncs(t *testing.T) {
+	supported(t)
+	a := runtimeinfo.New()
+
+	const pkgpath = "github.com/podhmo/reflect-shape/metadata/runtimeinfo_test"
+	found := map[string]runtimeinfo.FuncInfo{}
+	for _, fn := range a.Funcs(pkgpath) {
+		if fn.PkgPath != pkgpath {
+			t.Errorf("Funcs(), unexpected package %q (%s)", fn.PkgPath, fn.Name)
+		}
+		if strings.HasSuffix(fn.Name, "-fm") {
+			t.Errorf("Funcs(), the method value wrapper %s must not be included", fn.Name)
+		}
+		found[strings.TrimPrefix(fn.Name, pkgpath+".")] = fn
+	}
+	for _, name := range []string{"TestFuncs", "(*S).M"} {
+		if _, ok := found[name]; !ok {
+			t.Errorf("Funcs(), %s is not found", name)
+		}
+	}
+	if got := a.Funcs("github.com/podhmo/reflect-shape/metadata/runtimeinfo_tes"); len(got) != 0 {
+		t.Errorf("Funcs(), the prefix must be matched per path element, but got %d funcs", len(got))
+	}
+
+	t.Run("lookup", func(t *testing.T) {
+		l := metadata.NewLookup(token.NewFileSet())
+		l.IncludeGoTestFiles = true
+		m, err := l.LookupFromFuncForPC(found["(*S).M"].PC)
+		if err != nil {
+			t.Fatalf("unexpected error: %+v", err)
+		}
+		if want, got := "M is the method of S.", m.Doc(); want != got {
+			t.Errorf("LookupFromFuncForPC().Doc(), want:%q != got:%q", want, got)
+		}
+	})
+}
+
+func TestTypes(t *testing.T) {
+	supported(t)
+	var found bool
+	for _, rt := range runtimeinfo.New().Types() {
+		if rt.Name() == "" {
+			t.Errorf("Types(), the unnamed type %v must not be included", rt)
+		}
+		if rt == reflect.TypeOf(S{}) {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("Types(), S is not found")
+	}
+}
@@ -0,0 +1,116 @@
+package metadata
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"regexp"
+	"strings"
+)
+
+// sectionRx matches the separator comment of the fields. (e.g. "// --- Authentication ---")
+var sectionRx = regexp.MustCompile(`^-{3,}\s*(.*?)\s*-{3,}$`)
+
+// FieldSections returns the section names of the struct fields, declared by the separator comments. (e.g. "// --- Authentication ---")
+//
+// The fields after a separator belong to its section, until the next separator. The fields before the first separator are not included.
+func (l *Lookup) FieldSections(m *Type) (map[string]string, error) {
+	if m == nil || m.Raw == nil || !m.Raw.Pos.IsValid() {
+		return nil, fmt.Errorf("the position of the type is unknown, %w", ErrNotFound)
+	}
+
+	fset, f, pos, err := l.reparse(m.Raw.Pos)
+	if err != nil {
+		return nil, err
+	}
+
+	var typ *ast.StructType
+	for _, d := range f.Decls {
+		d, ok := d.(*ast.GenDecl)
+		if !ok || d.Tok != token.TYPE || fset.Position(d.Pos()).Offset != pos.Offset {
+			continue
+		}
+		for _, spec := range d.Specs {
+			if spec := spec.(*ast.TypeSpec); spec.Name.Name == m.Raw.Name {
+				typ, _ = spec.Type.(*ast.StructType)
+			}
+		}
+	}
+	if typ == nil {
+		return nil, l.notFound(m.Raw.Name, "", pos.Filename, nil)
+	}
+
+	type separator struct {
+		pos  token.Pos
+		name string
+	}
+	var separators []separator
+	for _, cg := range f.Comments {
+		if cg.Pos() < typ.Fields.Opening || typ.Fields.Closing < cg.End() {
+			continue
+		}
+		for _, c := range cg.List {
+			if name, ok := sectionName(c.Text); ok {
+				separators = append(separators, separator{pos: c.Pos(), name: name})
+			}
+		}
+	}
+
+	sections := map[string]string{}
+	if len(separators) == 0 {
+		return sections, nil
+	}
+	for _, field := range typ.Fields.List {
+		name := ""
+		for _, sep := range separators {
+			if sep.pos < field.Pos() {
+				name = sep.name
+			}
+		}
+		if name == "" {
+			continue
+		}
+		for _, id := range field.Names {
+			sections[id.Name] = name
+		}
+		if len(field.Names) == 0 { // embedded
+			sections[embeddedName(field.Type)] = name
+		}
+	}
+	return sections, nil
+}
+
+func sectionName(text string) (string, bool) {
+	text = strings.TrimSpace(strings.TrimPrefix(text, "//"))
+	m := sectionRx.FindStringSubmatch(text)
+	if m == nil {
+		return "", false
+	}
+	return m[1], true
+}
+
+// stripSections removes the separator lines from the doc of the field.
+func stripSections(doc string) string {
+	if !strings.Contains(doc, "---") {
+		return doc
+	}
+	lines := strings.Split(doc, "\n")
+	r := lines[:0]
+	for _, line := range lines {
+		if sectionRx.MatchString(strings.TrimSpace(line)) {
+			continue
+		}
+		r = append(r, line)
+	}
+	return strings.Join(r, "\n")
+}
+
+func embeddedName(x ast.Expr) string {
+	switch v := x.(type) {
+	case *ast.StarExpr:
+		return embeddedName(v.X)
+	case *ast.SelectorExpr:
+		return v.Sel.Name
+	}
+	return typeName(x)
+}
@@ -0,0 +1,116 @@
+package metadata
+
+import (
+	"context"
+	"fmt"
+
+	"golang.org/x/tools/go/packages"
+)
+
+// Source is the source of the metadata. The sources are tried in the order of Lookup.Sources, after the in-process cache.
+//
+// The DWARF of the binary is not a source, it has the positions of the declarations (as runtime.FuncForPC) but not the comments.
+type Source string
+
+const (
+	// SourcePrecompiled is the metadata embedded in the binary as Go code (see RegisterPrecompiled).
+	SourcePrecompiled Source = "precompiled"
+	// SourceDiskCache is the disk cache of the collected metadata (see Lookup.CacheDir, ignored if it is empty).
+	SourceDiskCache Source = "disk-cache"
+	// SourceLoad is parsing the source, loaded by packages.Load() without the network access. (the workspace, and the modules already in the module cache)
+	SourceLoad Source = "load"
+	// SourceModuleCache is SourceLoad downloading the missing modules into the module cache. (ignored if Lookup.Offline)
+	SourceModuleCache Source = "module-cache"
+	// SourceNone terminates the chain, the sources after it are not tried. (e.g. for disabling the defaults by prepending it)
+	SourceNone Source = "none"
+)
+
+// DefaultSources is the default value of Lookup.Sources.
+var DefaultSources = []Source{SourcePrecompiled, SourceDiskCache, SourceLoad, SourceModuleCache}
+
+func (l *Lookup) sources() []Source {
+	if l.Sources == nil {
+		return DefaultSources
+	}
+	return l.Sources
+}
+
+// fromSources returns the package found in the sources before the loading ones. (l.mu must be held)
+// If not found, loads are the loading sources of the chain. (if empty, the lookup gives up, see unavailable)
+func (l *Lookup) fromSources(pkgpath string) (p *packageRef, loads []Source) {
+	for _, src := range l.sources() {
+		switch src {
+		case SourcePrecompiled:
+			if p, ok := l.readPrecompiled(pkgpath); ok {
+				return p, nil
+			}
+		case SourceDiskCache:
+			if l.CacheDir == "" {
+				continue
+			}
+			if p, ok := l.readDiskCache(pkgpath); ok {
+				return p, nil
+			}
+		case SourceLoad, SourceModuleCache:
+			return nil, l.loadSources()
+		case SourceNone:
+			return nil, nil
+		default:
+			l.logf("unknown metadata source %q, ignored", src)
+		}
+	}
+	return nil, nil
+}
+
+// loadSources returns the loading sources of the chain in order, SourceLoad and SourceModuleCache.
+func (l *Lookup) loadSources() []Source {
+	var loads []Source
+	for _, src := range l.sources() {
+		switch src {
+		case SourceLoad:
+			loads = append(loads, src)
+		case SourceModuleCache:
+			if !l.Offline {
+				loads = append(loads, src)
+			}
+		case SourceNone:
+			return loads
+		}
+	}
+	return loads
+}
+
+// loadFromSources loads the package by the loading sources in order, until it is loaded. (l.mu must be held)
+func (l *Lookup) loadFromSources(ctx context.Context, pkgpath string, loads []Source) (pkgs []*packages.Package, err error) {
+	for i, src := range loads {
+		cfg := l.packagesConfig(ctx)
+		if src == SourceLoad {
+			cfg.Env = []string{"GOPROXY=off"}
+		}
+		pkgs, err = l.loadUnlocked(cfg, pkgpath)
+		if ctx.Err() != nil || i == len(loads)-1 || isLoaded(pkgs, err, pkgpath) {
+			break
+		}
+		if l.Trace {
+			l.logf("trace: load %q from %s failed, try %s", pkgpath, src, loads[i+1])
+		}
+	}
+	return pkgs, err
+}
+
+func isLoaded(pkgs []*packages.Package, err error, pkgpath string) bool {
+	if err != nil {
+		return false
+	}
+	for _, pkg := range pkgs {
+		if pkg.PkgPath == pkgpath && len(pkg.Errors) == 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// unavailable returns the error that the symbol is not found in any sources of the chain.
+func (l *Lookup) unavailable(symbol, pkgpath string) error {
+	return &SourceUnavailableError{Symbol: symbol, PkgPath: pkgpath, Err: fmt.Errorf("not found in the metadata sources %v", l.sources())}
+}
@@ -0,0 +1,117 @@
+package metadata
+
+import (
+	"errors"
+	"fmt"
+	"go/token"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"golang.org/x/tools/go/packages"
+)
+
+func TestSources(t *testing.T) {
+	cases := []struct {
+		msg        string
+		sources    []Source
+		wantLoaded int
+		wantErr    error
+	}{
+		{msg: "default", sources: nil, wantLoaded: 1},
+		{msg: "load-only", sources: []Source{SourceLoad}, wantLoaded: 1},
+		{msg: "disk-cache-without-dir", sources: []Source{SourceDiskCache, SourceLoad}, wantLoaded: 1},
+		{msg: "no-sources", sources: []Source{}, wantLoaded: 0, wantErr: ErrSourceUnavailable},
+		{msg: "precompiled-only", sources: []Source{SourcePrecompiled}, wantLoaded: 0, wantErr: ErrSourceUnavailable},
+		{msg: "module-cache-only", sources: []Source{SourceModuleCache}, wantLoaded: 1},
+		{msg: "none", sources: []Source{SourceNone, SourceLoad}, wantLoaded: 0, wantErr: ErrSourceUnavailable},
+	}
+	for _, c := range cases {
+		c := c
+		t.Run(c.msg, func(t *testing.T) {
+			l := NewLookup(token.NewFileSet())
+			l.IncludeGoTestFiles = true
+			l.Sources = c.sources
+			loaded := 0
+			l.Loader = func(cfg *packages.Config, patterns ...string) ([]*packages.Package, error) {
+				loaded++
+				return packages.Load(cfg, patterns...)
+			}
+
+			_, err := l.LookupFromType(Person{})
+			if c.wantErr == nil && err != nil {
+				t.Fatalf("LookupFromType(): unexpected error: %+v", err)
+			}
+			if c.wantErr != nil && !errors.Is(err, c.wantErr) {
+				t.Fatalf("LookupFromType(): want:%v != got:%+v", c.wantErr, err)
+			}
+			if _, err := l.LookupFromFunc(Hello); (c.wantErr == nil) != (err == nil) {
+				t.Errorf("LookupFromFunc(): want error:%v, but got %+v", c.wantErr, err)
+			}
+			if want, got := c.wantLoaded, loaded; want != got {
+				t.Errorf("loaded count: want:%d != got:%d", want, got)
+			}
+		})
+	}
+
+	t.Run("module-cache-fallback", func(t *testing.T) {
+		for _, offline := range []bool{false, true} {
+			l := NewLookup(token.NewFileSet())
+			l.IncludeGoTestFiles = true
+			l.Offline = offline
+			var proxies []bool
+			l.Loader = func(cfg *packages.Config, patterns ...string) ([]*packages.Package, error) {
+				proxies = append(proxies, !isOffline(cfg.Env))
+				if len(proxies) == 1 {
+					return nil, fmt.Errorf("module not found in the module cache")
+				}
+				return packages.Load(cfg, patterns...)
+			}
+
+			_, err := l.LookupFromType(Person{})
+			if offline {
+				if !errors.Is(err, ErrPackageLoad) {
+					t.Errorf("Offline: must be ErrPackageLoad, but got %+v", err)
+				}
+				if diff := cmp.Diff([]bool{false}, proxies); diff != "" {
+					t.Errorf("Offline: the network access of the loads: -want, +got: \n%v", diff)
+				}
+				continue
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %+v", err)
+			}
+			if diff := cmp.Diff([]bool{false, true}, proxies); diff != "" {
+				t.Errorf("the network access of the loads: -want, +got: \n%v", diff)
+			}
+		}
+	})
+
+	t.Run("precompiled-first", func(t *testing.T) {
+		const pkgpath = "github.com/podhmo/reflect-shape/metadata"
+		l0 := NewLookup(token.NewFileSet())
+		l0.IncludeGoTestFiles = true
+		data, err := l0.precompile(pkgpath)
+		if err != nil {
+			t.Fatalf("unexpected error: %+v", err)
+		}
+		RegisterPrecompiled(pkgpath, string(data))
+		defer func() {
+			precompiledMu.Lock()
+			delete(precompiled, pkgpath)
+			precompiledMu.Unlock()
+		}()
+
+		l := NewLookup(token.NewFileSet())
+		l.Sources = []Source{SourcePrecompiled}
+		l.Loader = func(cfg *packages.Config, patterns ...string) ([]*packages.Package, error) {
+			return nil, fmt.Errorf("must not be loaded: %v", patterns)
+		}
+		m, err := l.LookupFromFunc(Hello)
+		if err != nil {
+			t.Fatalf("unexpected error: %+v", err)
+		}
+		if want, got := "Hello is function returns greeting message", m.Doc(); want != got {
+			t.Errorf("Doc(): want:%q != got:%q", want, got)
+		}
+	})
+}
@@ -0,0 +1,20 @@
+package metadata
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// IsTestBinary reports whether the running binary is built by go test. (including the examples, the benchmarks and the fuzz targets)
+//
+// The test binaries are different from the normal ones: the functions and the types of the tested main package have the import path instead of "main",
+// the build info has no main module, and the declarations may be in the test files. (see Lookup.IncludeGoTestFiles)
+func IsTestBinary() bool {
+	if flag.Lookup("test.v") != nil { // registered by testing.Init(), called by the generated main of go test
+		return true
+	}
+	name := strings.TrimSuffix(filepath.Base(os.Args[0]), ".exe")
+	return strings.HasSuffix(name, ".test") // e.g. foo.test (go test -c)
+}
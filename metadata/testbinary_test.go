@@ -0,0 +1,9 @@
+package metadata
+
+import "testing"
+
+func TestIsTestBinary(t *testing.T) {
+	if want, got := true, IsTestBinary(); want != got {
+		t.Errorf("IsTestBinary(), want:%v != got:%v", want, got)
+	}
+}
@@ -0,0 +1,8 @@
+package layout
+
+// Header is the header of the wire format.
+type Header struct {
+	Flag  uint8
+	Size  uintptr
+	Count int32
+}
@@ -0,0 +1,9 @@
+//go:build !windows
+
+package layout
+
+// Message is the message for the other platforms.
+type Message struct {
+	Header Header
+	FD     int32
+}
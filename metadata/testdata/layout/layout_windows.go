@@ -0,0 +1,7 @@
+package layout
+
+// Message is the message for windows.
+type Message struct {
+	Header Header
+	Handle uintptr
+}
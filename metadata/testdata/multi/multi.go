@@ -0,0 +1,6 @@
+package multi
+
+// Value is the declaration in the package itself.
+type Value struct {
+	Name string
+}
@@ -0,0 +1,6 @@
+package multi_test
+
+// ExternalFixture is the declaration in the external test package.
+type ExternalFixture struct {
+	Name string
+}
@@ -0,0 +1,6 @@
+package multi
+
+// InternalFixture is the declaration in the internal test file.
+type InternalFixture struct {
+	Value Value
+}
@@ -0,0 +1,8 @@
+//go:build ignore
+
+package main
+
+// Tool is the declaration in the ignored tool file.
+type Tool struct{}
+
+func main() {}
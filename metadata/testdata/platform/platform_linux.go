@@ -0,0 +1,6 @@
+package platform
+
+// Platform is the declaration for linux.
+type Platform struct {
+	Name string // name of linux
+}
@@ -0,0 +1,8 @@
+//go:build !linux && !windows
+
+package platform
+
+// Platform is the declaration for the other platforms.
+type Platform struct {
+	Name string
+}
@@ -0,0 +1,6 @@
+package platform
+
+// Platform is the declaration for windows.
+type Platform struct {
+	Name string // name of windows
+}
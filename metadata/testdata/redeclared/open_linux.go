@@ -0,0 +1,4 @@
+package redeclared
+
+// Open is the declaration for linux.
+func Open(name string) error { return nil }
@@ -0,0 +1,4 @@
+package redeclared
+
+// Open is the declaration for windows.
+func Open(path string) error { return nil }
@@ -0,0 +1,4 @@
+package redeclared
+
+// Value is the hand-written declaration.
+type Value struct{}
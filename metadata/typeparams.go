@@ -0,0 +1,44 @@
+package metadata
+
+import (
+	"go/ast"
+	"go/token"
+	"go/types"
+)
+
+// TypeParam is the type parameter of the generic type declaration.
+type TypeParam struct {
+	Name       string // e.g. "T"
+	Constraint string // the expression of the constraint, as written in the source (e.g. "any", "~int | ~string", "fmt.Stringer")
+}
+
+// collectTypeParams collects the type parameters of the generic type declarations, keyed by the names of the types.
+func collectTypeParams(files []*ast.File) map[string][]TypeParam {
+	var r map[string][]TypeParam
+	for _, f := range files {
+		for _, decl := range f.Decls {
+			decl, ok := decl.(*ast.GenDecl)
+			if !ok || decl.Tok != token.TYPE {
+				continue
+			}
+			for _, spec := range decl.Specs {
+				spec := spec.(*ast.TypeSpec)
+				if spec.TypeParams == nil {
+					continue
+				}
+				var params []TypeParam
+				for _, field := range spec.TypeParams.List {
+					constraint := types.ExprString(field.Type)
+					for _, name := range field.Names { // [K, V any] is the two params
+						params = append(params, TypeParam{Name: name.Name, Constraint: constraint})
+					}
+				}
+				if r == nil {
+					r = map[string][]TypeParam{}
+				}
+				r[spec.Name.Name] = params
+			}
+		}
+	}
+	return r
+}
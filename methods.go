@@ -0,0 +1,123 @@
+package reflectshape
+
+import (
+	"reflect"
+	"runtime"
+	"strings"
+
+	"github.com/podhmo/reflect-shape/metadata/runtimeinfo"
+)
+
+// Method is the method of the type, as the func shape. The receiver is not included in the args (see Func.Receiver).
+type Method struct {
+	*Func
+	IsPointerReceiver bool // if true, only *T has the method
+}
+
+// Methods returns the methods of T and *T, in the order of the names. (the same as reflect.Type.Method)
+// The methods of the interface are not included (use Interface().Methods() instead),
+// and the methods promoted from the embedded fields have no docs. The methods vetoed by Hooks.BeforeExtract are skipped.
+func (s *Shape) Methods() (methods []*Method) {
+	defer s.e.recoverPanicAndLog(s.Type, "Methods()")
+	rt := s.Type
+	if rt.Kind() == reflect.Interface {
+		return nil
+	}
+
+	prt := reflect.PointerTo(rt)
+	r := make([]*Method, 0, prt.NumMethod())
+	for i := 0; i < prt.NumMethod(); i++ {
+		m := prt.Method(i)
+		isPointerReceiver := true
+		if vm, ok := rt.MethodByName(m.Name); ok {
+			m = vm // the method of *T is the autogenerated wrapper, if the receiver is T
+			isPointerReceiver = false
+		}
+		shape := s.e.extractMethod(methodSignature(m.Type), m.Func.Pointer())
+		if shape == nil { // vetoed by Hooks.BeforeExtract
+			continue
+		}
+		fn := shape.Func()
+		recv := *s
+		recv.Lv = 0
+		if isPointerReceiver {
+			recv.Lv = 1
+		}
+		fn.recv = &recv
+		r = append(r, &Method{Func: fn, IsPointerReceiver: isPointerReceiver})
+	}
+	return r
+}
+
+// Receiver returns the shape of the receiver, if the function is the method. (the method value, the method expression, or the one of Shape.Methods)
+// The shape of the pointer receiver has Lv=1. (e.g. *Queue for `func (q *Queue) Push(...)`)
+//
+// The receiver type is found from the signature of the method expression, the shapes extracted in the package,
+// or the types linked into the binary (see runtimeinfo.Types), in this order. It returns nil if not found, or not a method.
+// (the method values of the generic types are not methods, they are wrapped by the closures)
+func (f *Func) Receiver() (recv *Shape) {
+	defer f.Shape.e.recoverPanicAndLog(f.Shape.Type, "Receiver()") // e.g. runtimeinfo.Types() with the unsupported runtime
+	if f.recv != nil {
+		copied := *f.recv
+		return &copied
+	}
+	if f.Shape.ID.pc == 0 {
+		return nil
+	}
+	rfunc := runtime.FuncForPC(f.Shape.ID.pc)
+	if rfunc == nil {
+		return nil
+	}
+	name, err := runtimeinfo.ParseFuncName(rfunc.Name())
+	if err != nil || !name.IsMethod() || name.IsClosure() {
+		return nil
+	}
+	rt := f.receiverType(name)
+	if rt == nil {
+		return nil
+	}
+	if name.IsPointerRecv {
+		rt = reflect.PointerTo(rt)
+	}
+	return f.Shape.e.extract(rt, rzero(rt))
+}
+
+func (f *Func) receiverType(name runtimeinfo.FuncName) reflect.Type {
+	matches := func(rt reflect.Type) bool {
+		typename, _, _ := strings.Cut(rt.Name(), "[") // generics, e.g. List[int]
+		return rt.PkgPath() == name.PkgPath && typename == name.Recv
+	}
+
+	typ := f.Shape.Type
+	if !f.Shape.IsMethod && typ.NumIn() > 0 { // the method expression, e.g. (*T).M
+		rt := typ.In(0)
+		if rt.Kind() == reflect.Pointer {
+			rt = rt.Elem()
+		}
+		if matches(rt) {
+			return rt
+		}
+	}
+	if s, ok := f.Shape.Package.scope.lookup(name.Recv); ok && s.ID.pc == 0 {
+		return s.Type
+	}
+	for _, rt := range runtimeinfo.New().Types() {
+		if matches(rt) {
+			return rt
+		}
+	}
+	return nil
+}
+
+// methodSignature returns the signature of the method expression without the receiver. (e.g. func(T, int) error -> func(int) error)
+func methodSignature(rt reflect.Type) reflect.Type {
+	in := make([]reflect.Type, rt.NumIn()-1)
+	for i := range in {
+		in[i] = rt.In(i + 1)
+	}
+	out := make([]reflect.Type, rt.NumOut())
+	for i := range out {
+		out[i] = rt.Out(i)
+	}
+	return reflect.FuncOf(in, out, rt.IsVariadic())
+}
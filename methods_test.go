@@ -0,0 +1,243 @@
+package reflectshape_test
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	reflectshape "github.com/podhmo/reflect-shape"
+)
+
+type Queue struct{ items []string }
+
+// Len returns the number of the items.
+func (q Queue) Len() int { return len(q.items) }
+
+// Push pushes the item, and returns the new length.
+func (q *Queue) Push(ctx context.Context, item string) (n int, err error) {
+	q.items = append(q.items, item)
+	return len(q.items), nil
+}
+
+// Pop pops the item.
+func (q *Queue) Pop() (item string, ok bool) {
+	if len(q.items) == 0 {
+		return "", false
+	}
+	item, q.items = q.items[0], q.items[1:]
+	return item, true
+}
+
+func TestMethods(t *testing.T) {
+	type method struct {
+		Name              string
+		Doc               string
+		Recv              string
+		Args              []string
+		Returns           []string
+		IsPointerReceiver bool
+	}
+
+	want := []method{
+		{Name: "Queue.Len", Doc: "Len returns the number of the items.", Recv: "Queue", Returns: []string{""}},
+		{Name: "Queue.Pop", Doc: "Pop pops the item.", Recv: "Queue", Returns: []string{"item", "ok"}, IsPointerReceiver: true},
+		{Name: "Queue.Push", Doc: "Push pushes the item, and returns the new length.", Recv: "Queue", Args: []string{"ctx", "item"}, Returns: []string{"n", "err"}, IsPointerReceiver: true},
+	}
+
+	for _, input := range []any{Queue{}, &Queue{}} {
+		var got []method
+		for _, m := range cfg.Extract(input).Methods() {
+			if !m.IsMethod() {
+				t.Errorf("Method.IsMethod(), %s must be method", m.Name())
+			}
+			got = append(got, method{
+				Name:              m.Name(),
+				Doc:               m.Doc(),
+				Recv:              m.Recv(),
+				Args:              varNames(m.Args()),
+				Returns:           varNames(m.Returns()),
+				IsPointerReceiver: m.IsPointerReceiver,
+			})
+		}
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Errorf("Shape.Methods(): mismatch (-want, +got): \n%v", diff)
+		}
+	}
+
+	t.Run("interface", func(t *testing.T) {
+		if got := cfg.Extract(new(Storage)).Methods(); got != nil {
+			t.Errorf("Shape.Methods(), must be nil for the interface, but got %v", got)
+		}
+	})
+
+	t.Run("vetoed", func(t *testing.T) {
+		cfg := &reflectshape.Config{IncludeGoTestFiles: true}
+		cfg.Hooks.BeforeExtract = func(rt reflect.Type) bool { return rt.Kind() != reflect.Func }
+		if got := cfg.Extract(Queue{}).Methods(); len(got) != 0 {
+			t.Errorf("Shape.Methods(), the vetoed methods must be skipped, but got %v", got)
+		}
+	})
+}
+
+func varNames(vl reflectshape.VarList) []string {
+	var r []string
+	for _, v := range vl {
+		r = append(r, v.Name)
+	}
+	return r
+}
+
+// Enqueue pushes the items to the queue.
+func Enqueue(
+	ctx context.Context,
+	q *Queue, // the queue
+	items ...string, // the items to push
+) (n int, err error) {
+	return len(items), nil
+}
+
+func TestFuncArgsAndReturns(t *testing.T) {
+	type v struct {
+		Name string
+		Type string // FullName with the pointer level
+		Doc  string
+
+		Variadic bool
+	}
+	vars := func(vl reflectshape.VarList) []v {
+		r := make([]v, len(vl))
+		for i, x := range vl {
+			r[i] = v{Name: x.Name, Type: strings.Repeat("*", x.Shape.Lv) + x.Shape.FullName(), Doc: x.Doc, Variadic: x.Variadic}
+		}
+		return r
+	}
+
+	cases := []struct {
+		msg     string
+		cfg     *reflectshape.Config
+		input   any
+		args    []v
+		returns []v
+	}{
+		{
+			msg:   "func",
+			cfg:   &reflectshape.Config{IncludeGoTestFiles: true},
+			input: Enqueue,
+			args: []v{
+				{Name: "ctx", Type: "context.Context"},
+				{Name: "q", Type: "*github.com/podhmo/reflect-shape_test.Queue", Doc: "the queue"},
+				{Name: "items", Type: ".string", Doc: "the items to push", Variadic: true},
+			},
+			returns: []v{{Name: "n", Type: ".int"}, {Name: "err", Type: ".error"}},
+		},
+		{
+			msg:     "method-value",
+			cfg:     &reflectshape.Config{IncludeGoTestFiles: true},
+			input:   new(Queue).Push,
+			args:    []v{{Name: "ctx", Type: "context.Context"}, {Name: "item", Type: ".string"}},
+			returns: []v{{Name: "n", Type: ".int"}, {Name: "err", Type: ".error"}},
+		},
+		{
+			msg:   "method-expression", // the receiver is the first argument
+			cfg:   &reflectshape.Config{IncludeGoTestFiles: true},
+			input: (*Queue).Push,
+			args: []v{
+				{Name: "", Type: "*github.com/podhmo/reflect-shape_test.Queue"},
+				{Name: "ctx", Type: "context.Context"}, {Name: "item", Type: ".string"},
+			},
+			returns: []v{{Name: "n", Type: ".int"}, {Name: "err", Type: ".error"}},
+		},
+		{
+			msg:     "method-expression-fill-names",
+			cfg:     &reflectshape.Config{IncludeGoTestFiles: true, FillArgNames: true},
+			input:   Queue.Len,
+			args:    []v{{Name: "recv", Type: "github.com/podhmo/reflect-shape_test.Queue"}},
+			returns: []v{{Name: "", Type: ".int"}},
+		},
+	}
+
+	for _, c := range cases {
+		c := c
+		t.Run(c.msg, func(t *testing.T) {
+			fn := c.cfg.Extract(c.input).Func()
+			if diff := cmp.Diff(c.args, vars(fn.Args())); diff != "" {
+				t.Errorf("Func.Args(): mismatch (-want, +got): \n%v", diff)
+			}
+			if diff := cmp.Diff(c.returns, vars(fn.Returns())); diff != "" {
+				t.Errorf("Func.Returns(): mismatch (-want, +got): \n%v", diff)
+			}
+		})
+	}
+}
+
+func TestVariadic(t *testing.T) {
+	cfg := &reflectshape.Config{IncludeGoTestFiles: true}
+	fn := cfg.Extract(Enqueue).Func()
+	if !fn.IsVariadic() {
+		t.Errorf("Func.IsVariadic(), must be true")
+	}
+
+	imports := reflectshape.NewImports("github.com/podhmo/reflect-shape_test")
+	var got []string
+	for _, v := range fn.Args() {
+		got = append(got, imports.VarExpr(v))
+	}
+	if diff := cmp.Diff([]string{"context.Context", "*Queue", "...string"}, got); diff != "" {
+		t.Errorf("Imports.VarExpr(): mismatch (-want, +got): \n%v", diff)
+	}
+
+	if fn := cfg.Extract(IssueInvoice).Func(); fn.IsVariadic() || fn.Args()[0].Variadic {
+		t.Errorf("Func.IsVariadic(), must be false")
+	}
+}
+
+func TestFuncReceiver(t *testing.T) {
+	cases := []struct {
+		msg   string
+		input any
+		want  string // FullName with the pointer level
+	}{
+		{msg: "method-value-pointer", input: new(Queue).Push, want: "*github.com/podhmo/reflect-shape_test.Queue"},
+		{msg: "method-value", input: Queue{}.Len, want: "github.com/podhmo/reflect-shape_test.Queue"},
+		{msg: "method-value-via-pointer", input: new(Queue).Len, want: "github.com/podhmo/reflect-shape_test.Queue"}, // the declared receiver
+		{msg: "method-expression-pointer", input: (*Queue).Push, want: "*github.com/podhmo/reflect-shape_test.Queue"},
+		{msg: "method-expression", input: Queue.Len, want: "github.com/podhmo/reflect-shape_test.Queue"},
+		{msg: "func", input: Enqueue, want: ""},
+	}
+	for _, c := range cases {
+		c := c
+		t.Run(c.msg, func(t *testing.T) {
+			cfg := &reflectshape.Config{IncludeGoTestFiles: true} // Queue is not extracted yet
+			recv := cfg.Extract(c.input).Func().Receiver()
+			got := ""
+			if recv != nil {
+				got = strings.Repeat("*", recv.Lv) + recv.FullName()
+			}
+			if want := c.want; want != got {
+				t.Errorf("Func.Receiver(), want:%q != got:%q", want, got)
+			}
+			if recv != nil {
+				if want, got := "items", recv.Struct().Fields()[0].Name; want != got {
+					t.Errorf("Func.Receiver().Struct().Fields()[0].Name, want:%q != got:%q", want, got)
+				}
+			}
+		})
+	}
+
+	t.Run("methods", func(t *testing.T) {
+		cfg := &reflectshape.Config{IncludeGoTestFiles: true}
+		got := map[string]int{}
+		for _, m := range cfg.Extract(Queue{}).Methods() {
+			recv := m.Receiver()
+			if want, got := "github.com/podhmo/reflect-shape_test.Queue", recv.FullName(); want != got {
+				t.Errorf("Method.Receiver(), want:%q != got:%q", want, got)
+			}
+			got[m.Name()] = recv.Lv
+		}
+		if diff := cmp.Diff(map[string]int{"Queue.Len": 0, "Queue.Pop": 1, "Queue.Push": 1}, got); diff != "" {
+			t.Errorf("Method.Receiver().Lv: mismatch (-want, +got): \n%v", diff)
+		}
+	})
+}
@@ -0,0 +1,186 @@
+package reflectshape
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// Naming is the set of the name-normalization hooks. Each exporter can have its own naming. (e.g. camelCase for JSON Schema, snake_case for SQL)
+// If a hook is nil, the Go name is used as is. (the rename by Config.Patches is always respected)
+type Naming struct {
+	TypeName       func(s *Shape) string
+	FieldName      func(f *Field) string
+	EnumMemberName func(m *EnumMember) string
+}
+
+var (
+	// GoNaming uses the Go names as is.
+	GoNaming = Naming{}
+
+	// CamelCaseNaming is the naming for JSON Schema, OpenAPI, etc. (e.g. UserID -> userId)
+	CamelCaseNaming = Naming{
+		FieldName:      func(f *Field) string { return ToCamelCase(f.Name) },
+		EnumMemberName: func(m *EnumMember) string { return ToCamelCase(m.Name) },
+	}
+
+	// SnakeCaseNaming is the naming for SQL, etc. (e.g. UserID -> user_id)
+	SnakeCaseNaming = Naming{
+		TypeName:       func(s *Shape) string { return ToSnakeCase(s.Name) },
+		FieldName:      func(f *Field) string { return ToSnakeCase(f.Name) },
+		EnumMemberName: func(m *EnumMember) string { return strings.ToUpper(ToSnakeCase(m.Name)) },
+	}
+
+	// PascalCaseNaming is the naming for protocol buffers, etc. (e.g. user_id -> UserId)
+	PascalCaseNaming = Naming{
+		TypeName:  func(s *Shape) string { return ToPascalCase(s.Name) },
+		FieldName: func(f *Field) string { return ToPascalCase(f.Name) },
+	}
+)
+
+func (n Naming) Type(s *Shape) string {
+	if p := s.Patch(); p != nil && p.Rename != "" {
+		return p.Rename
+	}
+	if n.TypeName == nil {
+		return s.Name
+	}
+	return n.TypeName(s)
+}
+
+func (n Naming) Field(f *Field) string {
+	if f.Patch != nil && f.Patch.Rename != "" {
+		return f.Patch.Rename
+	}
+	if n.FieldName == nil {
+		return f.Name
+	}
+	return n.FieldName(f)
+}
+
+func (n Naming) EnumMember(m *EnumMember) string {
+	if n.EnumMemberName == nil {
+		return m.Name
+	}
+	return n.EnumMemberName(m)
+}
+
+// NameStyle is the style of the qualified names of the shapes. (see Shape.QualifiedName)
+type NameStyle int
+
+const (
+	NameStyleFull     NameStyle = iota // the full import path, e.g. "github.com/foo/bar/model.User"
+	NameStyleShort                     // the package name, e.g. "model.User"
+	NameStyleRelative                  // the package name, but not qualified in the current package, e.g. "User" in model, "model.User" otherwise
+)
+
+func (style NameStyle) String() string {
+	switch style {
+	case NameStyleFull:
+		return "full"
+	case NameStyleShort:
+		return "short"
+	case NameStyleRelative:
+		return "relative"
+	default:
+		return fmt.Sprintf("NameStyle(%d)", int(style))
+	}
+}
+
+// QualifiedName returns the name of the shape in the style. current is the package path of the current package, used by NameStyleRelative.
+// The pointer level is not included, and the unnamed types are rendered with the qualified names too. (e.g. "[]*model.User")
+// The builtin types are not qualified in any style. (e.g. "int")
+func (s *Shape) QualifiedName(style NameStyle, current string) string {
+	r := &typeRenderer{qualifier: func(pkgpath, pkgname string) string {
+		if pkgname == "" {
+			pkgname = guessPackageName(pkgpath)
+		}
+		switch style {
+		case NameStyleFull:
+			return pkgpath
+		case NameStyleRelative:
+			if pkgpath == current {
+				return ""
+			}
+			return pkgname
+		default:
+			return pkgname
+		}
+	}}
+	if s.Name == "" {
+		return r.typeExpr(s.Type)
+	}
+	pkgname := "" // guessed from the path for the functions and the methods (e.g. "model.User.Save")
+	if s.ID.pc == 0 {
+		pkgname, _, _ = strings.Cut(s.Type.String(), ".") // Package.Name is the last element of the path, not the package name
+	}
+	return r.name(s.Package.Path, pkgname, s.Name)
+}
+
+// ToCamelCase converts the name to camelCase. (e.g. UserID -> userId, HTTPServer -> httpServer)
+func ToCamelCase(name string) string {
+	words := splitWords(name)
+	for i, w := range words {
+		if i == 0 {
+			words[i] = strings.ToLower(w)
+		} else {
+			words[i] = capitalize(w)
+		}
+	}
+	return strings.Join(words, "")
+}
+
+// ToPascalCase converts the name to PascalCase. (e.g. user_id -> UserId)
+func ToPascalCase(name string) string {
+	words := splitWords(name)
+	for i, w := range words {
+		words[i] = capitalize(w)
+	}
+	return strings.Join(words, "")
+}
+
+// ToSnakeCase converts the name to snake_case. (e.g. UserID -> user_id)
+func ToSnakeCase(name string) string {
+	words := splitWords(name)
+	for i, w := range words {
+		words[i] = strings.ToLower(w)
+	}
+	return strings.Join(words, "_")
+}
+
+func capitalize(w string) string {
+	if w == "" {
+		return w
+	}
+	return strings.ToUpper(w[:1]) + strings.ToLower(w[1:])
+}
+
+// splitWords splits the name into the words. (e.g. HTTPServer -> [HTTP Server], user_id -> [user id])
+func splitWords(name string) []string {
+	var words []string
+	rs := []rune(name)
+	start := 0
+	for i := 0; i < len(rs); i++ {
+		r := rs[i]
+		if r == '_' || r == '-' || r == ' ' {
+			if start < i {
+				words = append(words, string(rs[start:i]))
+			}
+			start = i + 1
+			continue
+		}
+		if i == start || !unicode.IsUpper(r) {
+			continue
+		}
+		prev := rs[i-1]
+		// fooBar -> foo Bar, HTTPServer -> HTTP Server
+		if !unicode.IsUpper(prev) || (i+1 < len(rs) && unicode.IsLower(rs[i+1])) {
+			words = append(words, string(rs[start:i]))
+			start = i
+		}
+	}
+	if start < len(rs) {
+		words = append(words, string(rs[start:]))
+	}
+	return words
+}
@@ -0,0 +1,112 @@
+package reflectshape_test
+
+import (
+	"reflect"
+	"testing"
+
+	reflectshape "github.com/podhmo/reflect-shape"
+	"github.com/podhmo/reflect-shape/testdata/recursive"
+)
+
+func TestNameConversion(t *testing.T) {
+	cases := []struct {
+		input  string
+		camel  string
+		pascal string
+		snake  string
+	}{
+		{input: "UserID", camel: "userId", pascal: "UserId", snake: "user_id"},
+		{input: "HTTPServer", camel: "httpServer", pascal: "HttpServer", snake: "http_server"},
+		{input: "user_id", camel: "userId", pascal: "UserId", snake: "user_id"},
+		{input: "name", camel: "name", pascal: "Name", snake: "name"},
+	}
+	for _, c := range cases {
+		c := c
+		t.Run(c.input, func(t *testing.T) {
+			if want, got := c.camel, reflectshape.ToCamelCase(c.input); want != got {
+				t.Errorf("ToCamelCase(): want:%q != got:%q", want, got)
+			}
+			if want, got := c.pascal, reflectshape.ToPascalCase(c.input); want != got {
+				t.Errorf("ToPascalCase(): want:%q != got:%q", want, got)
+			}
+			if want, got := c.snake, reflectshape.ToSnakeCase(c.input); want != got {
+				t.Errorf("ToSnakeCase(): want:%q != got:%q", want, got)
+			}
+		})
+	}
+}
+
+func TestNaming(t *testing.T) {
+	cfg := &reflectshape.Config{SkipComments: true, Patches: map[string]*reflectshape.Patch{
+		"github.com/podhmo/reflect-shape_test.Account.Email": {Rename: "mail"},
+	}}
+	s := cfg.Extract(Account{})
+
+	cases := []struct {
+		msg    string
+		naming reflectshape.Naming
+		typ    string
+		fields []string
+	}{
+		{msg: "go", naming: reflectshape.GoNaming, typ: "Account", fields: []string{"Base", "Timestamp", "Meta", "mail"}},
+		{msg: "camel", naming: reflectshape.CamelCaseNaming, typ: "Account", fields: []string{"base", "timestamp", "meta", "mail"}},
+		{msg: "snake", naming: reflectshape.SnakeCaseNaming, typ: "account", fields: []string{"base", "timestamp", "meta", "mail"}},
+	}
+	for _, c := range cases {
+		c := c
+		t.Run(c.msg, func(t *testing.T) {
+			if want, got := c.typ, c.naming.Type(s); want != got {
+				t.Errorf("Naming.Type(): want:%q != got:%q", want, got)
+			}
+			var got []string
+			for _, f := range s.Struct().Fields() {
+				got = append(got, c.naming.Field(f))
+			}
+			if want := c.fields; !reflect.DeepEqual(want, got) {
+				t.Errorf("Naming.Field(): want:%q != got:%q", want, got)
+			}
+		})
+	}
+}
+
+func TestQualifiedName(t *testing.T) {
+	cfg := &reflectshape.Config{SkipComments: true}
+	const current = "github.com/podhmo/reflect-shape_test"
+
+	cases := []struct {
+		msg      string
+		input    any
+		full     string
+		short    string
+		relative string
+	}{
+		{msg: "builtin", input: 0, full: "int", short: "int", relative: "int"},
+		{msg: "other-package", input: recursive.Tree{},
+			full: "github.com/podhmo/reflect-shape/testdata/recursive.Tree", short: "recursive.Tree", relative: "recursive.Tree"},
+		{msg: "pointer", input: &Account{},
+			full: "github.com/podhmo/reflect-shape_test.Account", short: "reflectshape_test.Account", relative: "Account"},
+		{msg: "unnamed", input: []*recursive.Term{},
+			full: "[]*github.com/podhmo/reflect-shape/testdata/recursive.Term", short: "[]*recursive.Term", relative: "[]*recursive.Term"},
+		{msg: "generics", input: Pair[string, *recursive.Node]{},
+			full:     "github.com/podhmo/reflect-shape_test.Pair[string,*github.com/podhmo/reflect-shape/testdata/recursive.Node]",
+			short:    "reflectshape_test.Pair[string,*recursive.Node]",
+			relative: "Pair[string,*recursive.Node]"},
+		{msg: "method", input: new(Queue).Push,
+			full: "github.com/podhmo/reflect-shape_test.Queue.Push", short: "reflectshape_test.Queue.Push", relative: "Queue.Push"},
+	}
+	for _, c := range cases {
+		c := c
+		t.Run(c.msg, func(t *testing.T) {
+			s := cfg.Extract(c.input)
+			for style, want := range map[reflectshape.NameStyle]string{
+				reflectshape.NameStyleFull:     c.full,
+				reflectshape.NameStyleShort:    c.short,
+				reflectshape.NameStyleRelative: c.relative,
+			} {
+				if got := s.QualifiedName(style, current); want != got {
+					t.Errorf("Shape.QualifiedName(%v), want:%q != got:%q", style, want, got)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,48 @@
+package reflectshape
+
+import (
+	"fmt"
+	"reflect"
+	"runtime/debug"
+)
+
+// PanicError is the error converted from the panic, if Config.RecoverPanics is true.
+// The accessors without the error (Shape.Methods, Func.Args, Func.Returns, Func.Receiver, and Interface.Methods) log it and return nil instead.
+type PanicError struct {
+	Type  reflect.Type // the offending type (nil if unknown)
+	Value interface{}  // the recovered value
+	Stack []byte
+}
+
+func (e *PanicError) Error() string {
+	if e.Type == nil {
+		return fmt.Sprintf("panic: %v", e.Value)
+	}
+	return fmt.Sprintf("panic with %v: %v", e.Type, e.Value)
+}
+
+// Unwrap returns the recovered value, if it is an error.
+func (e *PanicError) Unwrap() error {
+	err, _ := e.Value.(error)
+	return err
+}
+
+// recoverPanic converts the panic into *PanicError, only if Config.RecoverPanics is true. (must be deferred directly)
+func (e *Extractor) recoverPanic(rt reflect.Type, err *error) {
+	if !e.Config.RecoverPanics {
+		return
+	}
+	if r := recover(); r != nil {
+		*err = &PanicError{Type: rt, Value: r, Stack: debug.Stack()}
+	}
+}
+
+// recoverPanicAndLog is recoverPanic for the accessors without the error (e.g. Func.Args), the *PanicError is logged. (must be deferred directly)
+func (e *Extractor) recoverPanicAndLog(rt reflect.Type, accessor string) {
+	if !e.Config.RecoverPanics {
+		return
+	}
+	if r := recover(); r != nil {
+		e.Config.logf("%s: %v\n%s", accessor, &PanicError{Type: rt, Value: r}, debug.Stack())
+	}
+}
@@ -0,0 +1,31 @@
+package reflectshape
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// Patch is the external refinement of the shape (or the field), that non-Go stakeholders can write without editing source.
+type Patch struct {
+	Doc        string `json:"doc,omitempty"`        // if not empty, replaces the doc comment
+	Example    string `json:"example,omitempty"`    // example value
+	Deprecated string `json:"deprecated,omitempty"` // deprecation message
+	Rename     string `json:"rename,omitempty"`     // the name used in exported output
+}
+
+// ReadPatches reads the patches from JSON, keyed by path (<pkgpath>.<Type> or <pkgpath>.<Type>.<Field>).
+//
+//	{
+//	  "github.com/foo/bar.User": {"doc": "User is the account of the service"},
+//	  "github.com/foo/bar.User.Name": {"example": "foo", "rename": "userName"}
+//	}
+//
+// YAML can be used, after converting to JSON.
+func ReadPatches(r io.Reader) (map[string]*Patch, error) {
+	var patches map[string]*Patch
+	if err := json.NewDecoder(r).Decode(&patches); err != nil {
+		return nil, fmt.Errorf("read patches: %w", err)
+	}
+	return patches, nil
+}
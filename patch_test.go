@@ -0,0 +1,43 @@
+package reflectshape_test
+
+import (
+	"strings"
+	"testing"
+
+	reflectshape "github.com/podhmo/reflect-shape"
+)
+
+func TestPatches(t *testing.T) {
+	patches, err := reflectshape.ReadPatches(strings.NewReader(`{
+  "github.com/podhmo/reflect-shape_test.Person": {"doc": "Person is the human"},
+  "github.com/podhmo/reflect-shape_test.Person.Father": {"doc": "father of person", "deprecated": "use Parents"},
+  "github.com/podhmo/reflect-shape_test.Person.Children": {"rename": "kids"}
+}`))
+	if err != nil {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+
+	cfg := &reflectshape.Config{IncludeGoTestFiles: true, Patches: patches}
+	s := cfg.Extract(Person{}).Struct()
+
+	if want, got := "Person is the human", s.Doc(); want != got {
+		t.Errorf("Shape.Struct().Doc(): want:%q != got:%q", want, got)
+	}
+
+	fields := s.Fields()
+	if want, got := "name of person", fields[0].Doc; want != got {
+		t.Errorf("Shape.Struct().Fields()[0].Doc: want:%q != got:%q", want, got)
+	}
+	if fields[0].Patch != nil {
+		t.Errorf("Shape.Struct().Fields()[0].Patch: must be nil, but got %+v", fields[0].Patch)
+	}
+	if want, got := "father of person", fields[1].Doc; want != got {
+		t.Errorf("Shape.Struct().Fields()[1].Doc: want:%q != got:%q", want, got)
+	}
+	if want, got := "use Parents", fields[1].Patch.Deprecated; want != got {
+		t.Errorf("Shape.Struct().Fields()[1].Patch.Deprecated: want:%q != got:%q", want, got)
+	}
+	if want, got := "kids", fields[2].Patch.Rename; want != got {
+		t.Errorf("Shape.Struct().Fields()[2].Patch.Rename: want:%q != got:%q", want, got)
+	}
+}
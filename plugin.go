@@ -0,0 +1,156 @@
+package reflectshape
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Exporter generates the artifact from the shapes. (e.g. JSON Schema, TypeScript definitions)
+type Exporter interface {
+	Export(w io.Writer, shapes []*Shape) error
+}
+
+// ExporterFunc is the adapter to use the ordinary function as Exporter.
+type ExporterFunc func(w io.Writer, shapes []*Shape) error
+
+func (f ExporterFunc) Export(w io.Writer, shapes []*Shape) error {
+	return f(w, shapes)
+}
+
+var (
+	exportersMu sync.RWMutex
+	exporters   = map[string]Exporter{}
+)
+
+// RegisterExporter makes the exporter available by name. (like database/sql.Register, it panics if the name is registered twice)
+func RegisterExporter(name string, exporter Exporter) {
+	exportersMu.Lock()
+	defer exportersMu.Unlock()
+	if exporter == nil {
+		panic("reflectshape: RegisterExporter exporter is nil")
+	}
+	if _, dup := exporters[name]; dup {
+		panic("reflectshape: RegisterExporter called twice for exporter " + name)
+	}
+	exporters[name] = exporter
+}
+
+// UnregisterExporter removes the exporter registered by name, so the name can be registered again. (e.g. in the tests)
+func UnregisterExporter(name string) {
+	exportersMu.Lock()
+	defer exportersMu.Unlock()
+	delete(exporters, name)
+}
+
+// LookupExporter returns the exporter registered by name.
+func LookupExporter(name string) (Exporter, bool) {
+	exportersMu.RLock()
+	defer exportersMu.RUnlock()
+	exporter, ok := exporters[name]
+	return exporter, ok
+}
+
+// Exporters returns the sorted names of the registered exporters.
+func Exporters() []string {
+	exportersMu.RLock()
+	defer exportersMu.RUnlock()
+	names := make([]string, 0, len(exporters))
+	for name := range exporters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ExecExporter is the exporter implemented by the external command. (the plugin)
+//
+// The protocol is simple: the shapes are written to stdin of the command as JSON (PluginInput),
+// and the artifact is read from stdout. If the command exits with non-zero status, it is failed (with stderr in the error message).
+type ExecExporter struct {
+	Command string
+	Args    []string
+	Env     []string // appended to os.Environ()
+	Dir     string
+}
+
+func (e *ExecExporter) Export(w io.Writer, shapes []*Shape) error {
+	input, err := json.Marshal(NewPluginInput(shapes))
+	if err != nil {
+		return fmt.Errorf("encode plugin input: %w", err)
+	}
+
+	var stderr bytes.Buffer
+	cmd := exec.Command(e.Command, e.Args...)
+	cmd.Stdin = bytes.NewReader(input)
+	cmd.Stdout = w
+	cmd.Stderr = &stderr
+	cmd.Dir = e.Dir
+	if len(e.Env) > 0 {
+		cmd.Env = append(os.Environ(), e.Env...)
+	}
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("plugin %s: %w (stderr: %s)", e.Command, err, strings.TrimSpace(stderr.String()))
+	}
+	return nil
+}
+
+// PluginInputVersion is the version of PluginInput. (incremented if the format is changed incompatibly)
+const PluginInputVersion = 1
+
+// PluginInput is the JSON passed to the plugin (ExecExporter) via stdin.
+type PluginInput struct {
+	Version int            `json:"version"`
+	Shapes  []*PluginShape `json:"shapes"`
+}
+
+// PluginShape is the shape in PluginInput.
+type PluginShape struct {
+	Number  int            `json:"number"`
+	Name    string         `json:"name"`
+	Kind    string         `json:"kind"`
+	Package string         `json:"package"` // mapped by Config.Namespaces, as Type
+	Type    string         `json:"type"`    // e.g. "github.com/foo/bar.Person", "[]string"
+	Doc     string         `json:"doc,omitempty"`
+	Fields  []*PluginField `json:"fields,omitempty"` // only for struct
+}
+
+// PluginField is the struct field in PluginShape.
+type PluginField struct {
+	Name     string `json:"name"`
+	Type     string `json:"type"`
+	Tag      string `json:"tag,omitempty"`
+	Doc      string `json:"doc,omitempty"`
+	Embedded bool   `json:"embedded,omitempty"`
+}
+
+// NewPluginInput builds the input of the plugin from the shapes.
+func NewPluginInput(shapes []*Shape) *PluginInput {
+	r := make([]*PluginShape, len(shapes))
+	for i, s := range shapes {
+		ps := &PluginShape{
+			Number:  s.Number,
+			Name:    s.Name,
+			Kind:    s.Kind.String(),
+			Package: s.Package.Namespace,
+			Type:    s.e.exportedTypeString(s.Type),
+		}
+		if s.Package.Path != "" {
+			ps.Doc = s.doc()
+		}
+		if s.Kind == reflect.Struct && s.ID.pc == 0 {
+			for _, f := range s.Struct().Fields() {
+				ps.Fields = append(ps.Fields, &PluginField{Name: f.Name, Type: s.e.exportedTypeString(f.Type), Tag: string(f.Tag), Doc: f.Doc, Embedded: f.Anonymous})
+			}
+		}
+		r[i] = ps
+	}
+	return &PluginInput{Version: PluginInputVersion, Shapes: r}
+}
@@ -0,0 +1,107 @@
+package reflectshape_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	reflectshape "github.com/podhmo/reflect-shape"
+)
+
+func TestRegisterExporter(t *testing.T) {
+	names := reflectshape.ExporterFunc(func(w io.Writer, shapes []*reflectshape.Shape) error {
+		for _, s := range shapes {
+			fmt.Fprintln(w, s.Name)
+		}
+		return nil
+	})
+	reflectshape.RegisterExporter("test-names", names)
+	t.Cleanup(func() { reflectshape.UnregisterExporter("test-names") })
+
+	exporter, ok := reflectshape.LookupExporter("test-names")
+	if !ok {
+		t.Fatalf("LookupExporter(): not found")
+	}
+	var buf strings.Builder
+	if err := exporter.Export(&buf, []*reflectshape.Shape{cfg.Extract(Person{})}); err != nil {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+	if want, got := "Person\n", buf.String(); want != got {
+		t.Errorf("Exporter.Export(): want:%q != got:%q", want, got)
+	}
+
+	t.Run("duplicated", func(t *testing.T) {
+		defer func() {
+			if r := recover(); r == nil {
+				t.Errorf("RegisterExporter(): must panic, if the name is registered twice")
+			}
+		}()
+		reflectshape.RegisterExporter("test-names", names)
+	})
+}
+
+func TestPluginInputNamespace(t *testing.T) {
+	cfg := &reflectshape.Config{IncludeGoTestFiles: true, Namespaces: map[string]string{"github.com/podhmo/reflect-shape_test": "shape"}}
+	input := reflectshape.NewPluginInput([]*reflectshape.Shape{cfg.Extract(Person{})})
+
+	s := input.Shapes[0]
+	if want, got := "shape", s.Package; want != got {
+		t.Errorf("PluginShape.Package: want:%q != got:%q", want, got)
+	}
+	if want, got := "shape.Person", s.Type; want != got {
+		t.Errorf("PluginShape.Type: want:%q != got:%q", want, got)
+	}
+	if want, got := "[]*shape.Person", s.Fields[2].Type; want != got {
+		t.Errorf("PluginField.Type: want:%q != got:%q", want, got)
+	}
+}
+
+// TestPluginHelperProcess is not the test, but the plugin command used by TestExecExporter.
+func TestPluginHelperProcess(t *testing.T) {
+	if os.Getenv("REFLECTSHAPE_PLUGIN_HELPER") != "1" {
+		return
+	}
+	defer os.Exit(0)
+
+	var input reflectshape.PluginInput
+	if err := json.NewDecoder(os.Stdin).Decode(&input); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	for _, s := range input.Shapes {
+		fields := make([]string, len(s.Fields))
+		for i, f := range s.Fields {
+			fields[i] = f.Name + " " + f.Type
+		}
+		fmt.Printf("%s %s (%s) {%s}\n", s.Kind, s.Name, s.Doc, strings.Join(fields, ", "))
+	}
+}
+
+func TestExecExporter(t *testing.T) {
+	exporter := &reflectshape.ExecExporter{
+		Command: os.Args[0],
+		Args:    []string{"-test.run=TestPluginHelperProcess"},
+		Env:     []string{"REFLECTSHAPE_PLUGIN_HELPER=1"},
+	}
+
+	var buf strings.Builder
+	if err := exporter.Export(&buf, []*reflectshape.Shape{cfg.Extract(Person{})}); err != nil {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+	want := "struct Person (Person object) {Name string, Father *github.com/podhmo/reflect-shape_test.Person, Children []*github.com/podhmo/reflect-shape_test.Person}\n"
+	if diff := cmp.Diff(want, buf.String()); diff != "" {
+		t.Errorf("ExecExporter.Export(): -want, +got: \n%v", diff)
+	}
+
+	t.Run("failed", func(t *testing.T) {
+		exporter := &reflectshape.ExecExporter{Command: os.Args[0], Args: []string{"-test.run=TestPluginHelperProcess"}, Env: []string{"REFLECTSHAPE_PLUGIN_HELPER=1"}}
+		exporter.Args = append(exporter.Args, "-test.unknown-flag")
+		if err := exporter.Export(io.Discard, nil); err == nil {
+			t.Errorf("ExecExporter.Export(): error is expected")
+		}
+	})
+}
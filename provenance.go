@@ -0,0 +1,14 @@
+package reflectshape
+
+// Provenance is how the piece of data was obtained.
+type Provenance string
+
+const (
+	ProvenanceNone      Provenance = ""          // not obtained (e.g. the doc is not found)
+	ProvenanceReflect   Provenance = "reflect"   // by reflection
+	ProvenanceSource    Provenance = "source"    // by parsing the go source (AST)
+	ProvenanceHook      Provenance = "hook"      // by Hooks.LookupType or Hooks.LookupFunc
+	ProvenancePatch     Provenance = "patch"     // by Config.Patches
+	ProvenanceOverride  Provenance = "override"  // by Config.Override
+	ProvenanceInherited Provenance = "inherited" // promoted from the embedded struct
+)
@@ -0,0 +1,219 @@
+package reflectshape
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"path"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Registry is the set of the shapes extracted by the config.
+type Registry struct {
+	e *Extractor
+}
+
+func (c *Config) Registry() *Registry {
+	c.init()
+	return &Registry{e: c.extractor}
+}
+
+// Shapes returns the extracted shapes, ordered by Shape.Number.
+func (r *Registry) Shapes() []*Shape {
+	var shapes []*Shape
+	for _, s := range r.e.snapshot() {
+		copied := *s
+		shapes = append(shapes, &copied)
+	}
+	sort.Slice(shapes, func(i, j int) bool { return shapes[i].Number < shapes[j].Number })
+	return shapes
+}
+
+// Refresh updates the shapes of the package after the code is changed, and returns them.
+// The structure of the types cannot be changed in the running process, so the cached metadata (comments) of the package is dropped,
+// and the next lookups read the source again. The other packages are kept as is, and the IDs and Numbers of all shapes are stable.
+func (r *Registry) Refresh(pkgpath string) []*Shape {
+	if r.e.Lookup != nil {
+		r.e.Lookup.Forget(pkgpath)
+	}
+	r.e.forgetViews(pkgpath)
+
+	var shapes []*Shape
+	for _, s := range r.e.snapshot() {
+		if s.Package.Path == pkgpath {
+			copied := *s
+			shapes = append(shapes, &copied)
+		}
+	}
+	sort.Slice(shapes, func(i, j int) bool { return shapes[i].Number < shapes[j].Number })
+	return shapes
+}
+
+// Query is the condition of Registry.Search. The zero value fields match everything.
+type Query struct {
+	Name       string       // the pattern of the name, in the syntax of path.Match (e.g. "*Request")
+	NameRegexp string       // the regular expression of the name, unanchored as regexp.MatchString (e.g. "^(Create|Delete)User")
+	Package    string       // the pattern of the package path (e.g. "github.com/foo/bar/...")
+	Kind       reflect.Kind // e.g. reflect.Struct
+	Doc        string       // the substring of the doc, case-insensitively (e.g. "deprecated")
+}
+
+// Search returns the shapes matched with the query, ordered by Shape.Number.
+func (r *Registry) Search(q Query) ([]*Shape, error) {
+	if q.Name != "" {
+		if _, err := path.Match(q.Name, ""); err != nil {
+			return nil, fmt.Errorf("invalid name pattern %q: %w", q.Name, err)
+		}
+	}
+	var nameRegexp *regexp.Regexp
+	if q.NameRegexp != "" {
+		rx, err := regexp.Compile(q.NameRegexp)
+		if err != nil {
+			return nil, fmt.Errorf("invalid name regexp %q: %w", q.NameRegexp, err)
+		}
+		nameRegexp = rx
+	}
+	doc := strings.ToLower(q.Doc)
+
+	var shapes []*Shape
+	for _, s := range r.Shapes() {
+		if q.Kind != reflect.Invalid && s.Kind != q.Kind {
+			continue
+		}
+		if q.Package != "" && !matchPackage(q.Package, s.Package.Path) {
+			continue
+		}
+		if q.Name != "" {
+			if ok, _ := path.Match(q.Name, s.Name); !ok {
+				continue
+			}
+		}
+		if nameRegexp != nil && !nameRegexp.MatchString(s.Name) {
+			continue
+		}
+		if doc != "" && (s.Package.Path == "" || !strings.Contains(strings.ToLower(s.doc()), doc)) {
+			continue
+		}
+		shapes = append(shapes, s)
+	}
+	return shapes, nil
+}
+
+// Hash returns the stable digest (sha256) of all contained shapes.
+// The digest doesn't depend on the order of extraction, so it can be used for asserting "the API surface did not change".
+// (only the structure is hashed: names, kinds, fields, tags, method sets, and signatures. docs are not included)
+func (r *Registry) Hash() string {
+	h := sha256.New()
+	r.WriteHash(h)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// WriteHash writes the canonical form of the shapes into h, ordered by the canonical forms.
+// (the forms start with the canonical type strings, and the rest orders the function-local types of the same name)
+func (r *Registry) WriteHash(h hash.Hash) {
+	shapes := r.e.snapshot()
+	forms := make([][]byte, 0, len(shapes))
+	for _, s := range shapes {
+		forms = append(forms, s.canonical())
+	}
+	sort.SliceStable(forms, func(i, j int) bool { return bytes.Compare(forms[i], forms[j]) < 0 })
+
+	for _, b := range forms {
+		h.Write(b)
+	}
+}
+
+func writeCanonicalShape(w io.Writer, s *Shape) {
+	rt := s.Type
+	fmt.Fprintf(w, "\tkind %s\n", rt.Kind())
+	if rt.Kind() == reflect.Struct {
+		for i := 0; i < rt.NumField(); i++ {
+			f := rt.Field(i)
+			fmt.Fprintf(w, "\tfield %s %s %q %v\n", f.Name, canonicalTypeString(f.Type), f.Tag, f.Anonymous)
+		}
+	}
+	if rt.Name() != "" {
+		ms := s.MethodSet()
+		fmt.Fprintf(w, "\tmethods %s\n", strings.Join(ms.Value, ","))
+		fmt.Fprintf(w, "\tpointer-methods %s\n", strings.Join(ms.Pointer, ","))
+	}
+}
+
+// canonicalTypeString returns the type expression, qualified by the full package path (e.g. map[string]*github.com/foo/bar.User).
+func canonicalTypeString(rt reflect.Type) string {
+	return qualifiedTypeString(rt, nil)
+}
+
+// exportedTypeString returns the type expression for the exporters, the package paths are mapped by Config.Namespaces.
+func (e *Extractor) exportedTypeString(rt reflect.Type) string {
+	return qualifiedTypeString(rt, e.Config.namespace)
+}
+
+// qualifiedTypeString is canonicalTypeString with the package paths mapped by qualify. (e.g. Config.namespace, if nil, as is)
+func qualifiedTypeString(rt reflect.Type, qualify func(pkgpath string) string) string {
+	if rt.Name() != "" {
+		if rt.PkgPath() == "" {
+			return rt.Name() // builtin
+		}
+		pkgpath := rt.PkgPath()
+		if qualify != nil {
+			pkgpath = qualify(pkgpath)
+		}
+		return pkgpath + "." + rt.Name()
+	}
+
+	switch rt.Kind() {
+	case reflect.Pointer:
+		return "*" + qualifiedTypeString(rt.Elem(), qualify)
+	case reflect.Slice:
+		return "[]" + qualifiedTypeString(rt.Elem(), qualify)
+	case reflect.Array:
+		return fmt.Sprintf("[%d]%s", rt.Len(), qualifiedTypeString(rt.Elem(), qualify))
+	case reflect.Map:
+		return "map[" + qualifiedTypeString(rt.Key(), qualify) + "]" + qualifiedTypeString(rt.Elem(), qualify)
+	case reflect.Chan:
+		switch rt.ChanDir() {
+		case reflect.RecvDir:
+			return "<-chan " + qualifiedTypeString(rt.Elem(), qualify)
+		case reflect.SendDir:
+			return "chan<- " + qualifiedTypeString(rt.Elem(), qualify)
+		default:
+			return "chan " + qualifiedTypeString(rt.Elem(), qualify)
+		}
+	case reflect.Func:
+		args := make([]string, rt.NumIn())
+		for i := range args {
+			args[i] = qualifiedTypeString(rt.In(i), qualify)
+		}
+		if rt.IsVariadic() {
+			args[len(args)-1] = "..." + qualifiedTypeString(rt.In(len(args)-1).Elem(), qualify)
+		}
+		rets := make([]string, rt.NumOut())
+		for i := range rets {
+			rets[i] = qualifiedTypeString(rt.Out(i), qualify)
+		}
+		return "func(" + strings.Join(args, ", ") + ") (" + strings.Join(rets, ", ") + ")"
+	case reflect.Struct:
+		fields := make([]string, rt.NumField())
+		for i := range fields {
+			f := rt.Field(i)
+			fields[i] = fmt.Sprintf("%s %s %q", f.Name, qualifiedTypeString(f.Type, qualify), f.Tag)
+		}
+		return "struct{" + strings.Join(fields, "; ") + "}"
+	case reflect.Interface:
+		methods := make([]string, rt.NumMethod())
+		for i := range methods {
+			m := rt.Method(i)
+			methods[i] = m.Name + strings.TrimPrefix(qualifiedTypeString(m.Type, qualify), "func")
+		}
+		return "interface{" + strings.Join(methods, "; ") + "}"
+	default:
+		return rt.String()
+	}
+}
@@ -0,0 +1,124 @@
+package reflectshape_test
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	reflectshape "github.com/podhmo/reflect-shape"
+)
+
+func TestRegistryHash(t *testing.T) {
+	extract := func(obs ...any) *reflectshape.Registry {
+		cfg := &reflectshape.Config{SkipComments: true}
+		for _, ob := range obs {
+			cfg.Extract(ob)
+		}
+		return cfg.Registry()
+	}
+
+	x := extract(Person{}, Node{}, Foo).Hash()
+	if want, got := x, extract(Foo, Node{}, &Person{}).Hash(); want != got {
+		t.Errorf("Registry.Hash(): must not depend on the order of extraction, want:%v != got:%v", want, got)
+	}
+	if x == extract(Person{}, Node{}).Hash() {
+		t.Errorf("Registry.Hash(): must be changed, if shapes are changed")
+	}
+
+	t.Run("same-name-local-types", func(t *testing.T) {
+		a, b := localTypeA(), localTypeB()
+		want := extract(a, b).Hash()
+		for i := 0; i < 20; i++ { // the order of the shapes in the registry is random
+			if got := extract(b, a).Hash(); want != got {
+				t.Fatalf("Registry.Hash(): must not depend on the order, want:%v != got:%v", want, got)
+			}
+		}
+	})
+}
+
+func localTypeA() any {
+	type hashed struct{ X int }
+	return hashed{}
+}
+
+func localTypeB() any {
+	type hashed struct{ Y string }
+	return hashed{}
+}
+
+func TestRegistryRefresh(t *testing.T) {
+	cfg := &reflectshape.Config{IncludeGoTestFiles: true}
+	person := cfg.Extract(Person{})
+	cfg.Extract(http.Client{})
+
+	shapes := cfg.Registry().Refresh("github.com/podhmo/reflect-shape_test")
+	if want, got := 1, len(shapes); want != got {
+		t.Fatalf("Registry.Refresh(): len, want:%d != got:%d", want, got)
+	}
+	if !shapes[0].Equal(person) || shapes[0].Number != person.Number {
+		t.Errorf("Registry.Refresh(): must be stable, want:%v != got:%v", person, shapes[0])
+	}
+	if want, got := "Person object", shapes[0].Struct().Doc(); want != got {
+		t.Errorf("Shape.Struct().Doc(): want:%q != got:%q", want, got)
+	}
+}
+
+// CreateUserRequest is the request for creating the user.
+type CreateUserRequest struct{}
+
+// DeleteUserRequest is the request for deleting the user.
+//
+// Deprecated: use ArchiveUserRequest.
+type DeleteUserRequest struct{}
+
+// UserResponse is the response of the user. (not deprecated)
+type UserResponse struct{}
+
+func TestRegistrySearch(t *testing.T) {
+	cfg := &reflectshape.Config{IncludeGoTestFiles: true}
+	cfg.Extract(CreateUserRequest{})
+	cfg.Extract(DeleteUserRequest{})
+	cfg.Extract(UserResponse{})
+	cfg.Extract(http.Request{})
+
+	cases := []struct {
+		msg   string
+		query reflectshape.Query
+		want  []string
+	}{
+		{msg: "name", query: reflectshape.Query{Name: "*Request"}, want: []string{"CreateUserRequest", "DeleteUserRequest", "Request"}},
+		{msg: "name-and-package", query: reflectshape.Query{Name: "*Request", Package: "github.com/podhmo/reflect-shape_test"}, want: []string{"CreateUserRequest", "DeleteUserRequest"}},
+		{msg: "package-pattern", query: reflectshape.Query{Name: "*Request", Package: "net/..."}, want: []string{"Request"}},
+		{msg: "doc", query: reflectshape.Query{Doc: "deprecated:"}, want: []string{"DeleteUserRequest"}},
+		{msg: "kind", query: reflectshape.Query{Name: "User*", Kind: reflect.Struct}, want: []string{"UserResponse"}},
+		{msg: "nothing", query: reflectshape.Query{Name: "*Reply"}, want: nil},
+		{msg: "name-regexp", query: reflectshape.Query{NameRegexp: "^(Create|Delete)User"}, want: []string{"CreateUserRequest", "DeleteUserRequest"}},
+		{msg: "name-and-name-regexp", query: reflectshape.Query{Name: "*Request", NameRegexp: "^Create"}, want: []string{"CreateUserRequest"}},
+	}
+	for _, c := range cases {
+		c := c
+		t.Run(c.msg, func(t *testing.T) {
+			shapes, err := cfg.Registry().Search(c.query)
+			if err != nil {
+				t.Fatalf("unexpected error: %+v", err)
+			}
+			var got []string
+			for _, s := range shapes {
+				got = append(got, s.Name)
+			}
+			if diff := cmp.Diff(c.want, got); diff != "" {
+				t.Errorf("Registry.Search(): -want, +got: \n%v", diff)
+			}
+		})
+	}
+
+	t.Run("invalid-pattern", func(t *testing.T) {
+		if _, err := cfg.Registry().Search(reflectshape.Query{Name: "[*Request"}); err == nil {
+			t.Errorf("Registry.Search(): error is expected")
+		}
+		if _, err := cfg.Registry().Search(reflectshape.Query{NameRegexp: "(Request"}); err == nil {
+			t.Errorf("Registry.Search(): error is expected, for the regexp")
+		}
+	})
+}
@@ -0,0 +1,175 @@
+package reflectshape
+
+import (
+	"reflect"
+	"strings"
+)
+
+// schemaBuilder builds the JSON Schema of the shapes. (the subset shared with OpenAPI v3 and AsyncAPI)
+type schemaBuilder struct {
+	refPrefix string                 // e.g. "#/components/schemas/"
+	defs      map[string]interface{} // the schemas of the named structs, referenced by $ref (see defName)
+	defNames  map[string]string      // the full name of the struct -> the key of defs
+
+	// inline makes the named structs inlined instead of $ref. (e.g. the structural schemas of CRDs)
+	inline   bool
+	visiting map[ID]bool
+
+	// onField customizes the schema of the field, and returns whether the field is required.
+	onField func(f *Field, prop map[string]interface{}, required bool) bool
+	// onObject customizes the schema of the struct.
+	onObject func(s *Struct, r map[string]interface{})
+}
+
+func newSchemaBuilder(refPrefix string) *schemaBuilder {
+	return &schemaBuilder{refPrefix: refPrefix, defs: map[string]interface{}{}, defNames: map[string]string{}}
+}
+
+// defName returns the key of the named struct in b.defs. It is the name of the struct (e.g. "User"),
+// or qualified by the namespace of the package if the name is taken by the struct of the other package. (e.g. "github.com.foo.bar.User")
+func (b *schemaBuilder) defName(s *Shape) string {
+	fullname := s.FullName()
+	if name, ok := b.defNames[fullname]; ok {
+		return name
+	}
+	name := s.Name
+	if _, taken := b.defs[name]; taken {
+		name = qualifiedDefName(s)
+	}
+	b.defNames[fullname] = name
+	return name
+}
+
+// qualifiedDefName returns the name of the struct qualified by the namespace of the package. (e.g. "github.com.foo.bar.User")
+func qualifiedDefName(s *Shape) string {
+	return strings.ReplaceAll(s.Package.Namespace, "/", ".") + "." + s.Name // "/" is escaped in $ref, so not used
+}
+
+// schema returns the schema of the shape. The named structs are stored in b.defs, and referenced by $ref.
+func (b *schemaBuilder) schema(s *Shape) map[string]interface{} {
+	if s == nil { // vetoed by Hooks.BeforeExtract, anything
+		return map[string]interface{}{}
+	}
+	if s.IsWellKnown {
+		return wellKnownSchema(s)
+	}
+
+	rt := s.Type
+	switch rt.Kind() {
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Slice, reflect.Array:
+		if rt.Elem().Kind() == reflect.Uint8 {
+			return map[string]interface{}{"type": "string", "format": "byte"} // like encoding/json
+		}
+		return map[string]interface{}{"type": "array", "items": b.schema(s.e.extract(rt.Elem(), rzero(rt.Elem())))}
+	case reflect.Map:
+		return map[string]interface{}{"type": "object", "additionalProperties": b.schema(s.e.extract(rt.Elem(), rzero(rt.Elem())))}
+	case reflect.Struct:
+		if s.Name == "" {
+			return b.object(s.Struct())
+		}
+		if b.inline {
+			if b.visiting[s.ID] { // recursive types cannot be inlined
+				return map[string]interface{}{"type": "object", "x-kubernetes-preserve-unknown-fields": true}
+			}
+			if b.visiting == nil {
+				b.visiting = map[ID]bool{}
+			}
+			b.visiting[s.ID] = true
+			defer delete(b.visiting, s.ID)
+			return b.object(s.Struct())
+		}
+		name := b.defName(s)
+		if _, ok := b.defs[name]; !ok {
+			b.defs[name] = nil // for recursive types
+			b.defs[name] = b.object(s.Struct())
+		}
+		return map[string]interface{}{"$ref": b.refPrefix + name}
+	default: // interface, func, chan, ...
+		return map[string]interface{}{}
+	}
+}
+
+// object returns the schema of the struct, with the properties named by the json tags. (or Patch.Rename of the fields)
+func (b *schemaBuilder) object(s *Struct) map[string]interface{} {
+	r := map[string]interface{}{"type": "object"}
+	if doc := s.Doc(); doc != "" {
+		r["description"] = doc
+	}
+
+	props := map[string]interface{}{}
+	var required []string
+	for _, f := range s.FlattenFields() {
+		if !f.IsExported() {
+			continue
+		}
+		tag, _ := f.LookupTag("json")
+		if tag.Name == "-" && len(tag.Options) == 0 {
+			continue
+		}
+		name := tag.Name
+		if f.Patch != nil && f.Patch.Rename != "" { // the rename by Config.Patches wins, as Naming.Field
+			name = f.Patch.Rename
+		} else if name == "" {
+			name = f.Name
+		}
+
+		prop := b.schema(f.Shape)
+		if f.Doc != "" || f.Patch != nil || b.onField != nil {
+			copied := make(map[string]interface{}, len(prop)+1)
+			for k, v := range prop {
+				copied[k] = v
+			}
+			if f.Doc != "" {
+				copied["description"] = f.Doc
+			}
+			if f.Patch != nil && f.Patch.Deprecated != "" {
+				copied["deprecated"] = true
+			}
+			if f.Patch != nil && f.Patch.Example != "" {
+				copied["example"] = f.Patch.Example
+			}
+			prop = copied
+		}
+
+		isRequired := f.Shape.Lv == 0 && !tag.HasOption("omitempty")
+		if b.onField != nil {
+			isRequired = b.onField(f, prop, isRequired)
+		}
+		props[name] = prop
+		if isRequired {
+			required = append(required, name)
+		}
+	}
+	if len(props) > 0 {
+		r["properties"] = props
+	}
+	if len(required) > 0 {
+		r["required"] = required
+	}
+	if b.onObject != nil {
+		b.onObject(s, r)
+	}
+	return r
+}
+
+func wellKnownSchema(s *Shape) map[string]interface{} {
+	switch s.Package.Path + "." + s.Name {
+	case "time.Time":
+		return map[string]interface{}{"type": "string", "format": "date-time"}
+	case "net/url.URL":
+		return map[string]interface{}{"type": "string", "format": "uri"}
+	case "github.com/google/uuid.UUID":
+		return map[string]interface{}{"type": "string", "format": "uuid"}
+	default:
+		return map[string]interface{}{"type": "string"}
+	}
+}
@@ -2,13 +2,20 @@ package reflectshape
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"go/constant"
 	"go/token"
-	"log"
 	"reflect"
 	"regexp"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
 
+	"github.com/podhmo/commentof/collect"
 	"github.com/podhmo/reflect-shape/metadata"
+	"github.com/podhmo/reflect-shape/metadata/runtimeinfo"
 )
 
 type ID struct {
@@ -17,25 +24,223 @@ type ID struct {
 }
 
 type Shape struct {
-	Name     string
-	Kind     reflect.Kind
-	IsMethod bool
+	Name        string
+	Kind        reflect.Kind
+	IsMethod    bool
+	IsWellKnown bool // if true, the shape should be treated as scalar, not descended into (e.g. time.Time)
+	IsOpaque    bool // if true, the shape is originated from cgo (e.g. C.struct_x), having no go source
 
 	ID           ID
 	Type         reflect.Type
 	DefaultValue reflect.Value
 
-	Number  int // If all shapes are from the same extractor, this value can be used as ID
-	Lv      int // pointer level. v is 0, *v is 1.
-	Package *Package
-	e       *Extractor
+	Number     int        // If all shapes are from the same extractor, this value can be used as ID
+	Lv         int        // pointer level. v is 0, *v is 1.
+	Provenance Provenance // how the shape was obtained (ProvenanceReflect or ProvenanceOverride)
+	Package    *Package
+	e          *Extractor
 }
 
+// Equal reports whether the shapes are of the same type, ignoring the pointer level. (the shapes of T and *T are equal, see Identical)
 func (s *Shape) Equal(another *Shape) bool {
 	return s.ID == another.ID
 }
 
+// Identical reports whether the shapes are of the same type and the same pointer level. (the shapes of T and *T are not identical)
+func (s *Shape) Identical(another *Shape) bool {
+	return s.ID == another.ID && s.Lv == another.Lv
+}
+
+// IsPointer reports whether the shape is of the pointer. (Lv > 0)
+func (s *Shape) IsPointer() bool {
+	return s.Lv > 0
+}
+
+// ReflectType returns the reflect.Type of the shape, with the pointer level. (e.g. *User for the shape of *User, Type is User)
+// It is for going back to the reflection after navigating the shapes. (e.g. reflect.New(f.Shape.ReflectType()) for the field)
+// The overridden shape returns the replacement type, and the method shape returns the signature without the receiver.
+func (s *Shape) ReflectType() reflect.Type {
+	rt := s.Type
+	for i := 0; i < s.Lv; i++ {
+		rt = reflect.PointerTo(rt)
+	}
+	return rt
+}
+
+// Deref returns the shape of the underlying type, with all the pointers removed. (e.g. **User -> User, see Elem for removing one)
+// The docs are resolved from the underlying type regardless of the pointer level, so the result has the same docs.
+func (s *Shape) Deref() *Shape {
+	copied := *s
+	copied.Lv = 0
+	return &copied
+}
+
+// IsNilable returns true if the value of the shape can be nil. (pointer, map, slice, chan, func, interface)
+func (s *Shape) IsNilable() bool {
+	if s.Lv > 0 {
+		return true
+	}
+	switch s.Kind {
+	case reflect.Map, reflect.Slice, reflect.Chan, reflect.Func, reflect.Interface, reflect.UnsafePointer:
+		return true
+	default:
+		return false
+	}
+}
+
+// Elem returns the shape of the element, for the pointer (Lv > 0), map, slice, array, and chan. (e.g. []*User -> *User, *User -> User)
+// The element is extracted recursively, so it returns nil if vetoed by Hooks.BeforeExtract. It panics for the other kinds, as reflect.Type.Elem does.
+func (s *Shape) Elem() *Shape {
+	if s.Lv > 0 {
+		rt := s.Type
+		for i := 1; i < s.Lv; i++ {
+			rt = reflect.PointerTo(rt)
+		}
+		return s.e.extract(rt, rzero(rt))
+	}
+	switch s.Kind {
+	case reflect.Map, reflect.Slice, reflect.Array, reflect.Chan:
+		rt := s.Type.Elem()
+		return s.e.extract(rt, rzero(rt))
+	default:
+		panic(fmt.Sprintf("shape %v has no element, %s", s, s.Kind))
+	}
+}
+
+// Key returns the shape of the key of the map. (e.g. map[string]*User -> string)
+// The key is extracted recursively, so it returns nil if vetoed by Hooks.BeforeExtract. It panics for the other kinds, as reflect.Type.Key does.
+func (s *Shape) Key() *Shape {
+	if s.Lv > 0 || s.Kind != reflect.Map {
+		panic(fmt.Sprintf("shape %v is not Map kind, %s", s, s.Kind))
+	}
+	rt := s.Type.Key()
+	return s.e.extract(rt, rzero(rt))
+}
+
+// MethodSet is the method names of the value receiver (T) and the pointer receiver (*T).
+type MethodSet struct {
+	Value   []string // the method set of T
+	Pointer []string // the method set of *T (including the methods of T)
+}
+
+// PointerOnly returns the method names that only *T has.
+func (ms MethodSet) PointerOnly() []string {
+	seen := make(map[string]bool, len(ms.Value))
+	for _, name := range ms.Value {
+		seen[name] = true
+	}
+	r := make([]string, 0, len(ms.Pointer)-len(ms.Value))
+	for _, name := range ms.Pointer {
+		if !seen[name] {
+			r = append(r, name)
+		}
+	}
+	return r
+}
+
+func (s *Shape) MethodSet() MethodSet {
+	var ms MethodSet
+	rt := s.Type
+	for i := 0; i < rt.NumMethod(); i++ {
+		ms.Value = append(ms.Value, rt.Method(i).Name)
+	}
+	if rt.Kind() == reflect.Interface {
+		return ms // *I has no methods
+	}
+	prt := reflect.PointerTo(rt)
+	for i := 0; i < prt.NumMethod(); i++ {
+		ms.Pointer = append(ms.Pointer, prt.Method(i).Name)
+	}
+	return ms
+}
+
+// Convention is the common formatting convention, that the shape implements.
+type Convention struct {
+	Name              string       // method name ("String", "GoString", or "Error")
+	Interface         reflect.Type // fmt.Stringer, fmt.GoStringer, or error
+	IsPointerReceiver bool         // if true, only *T implements it
+	Doc               string       // the doc of the method
+}
+
+var conventions = []struct {
+	name  string
+	iface reflect.Type
+}{
+	{name: "String", iface: reflect.TypeOf(func(fmt.Stringer) {}).In(0)},
+	{name: "GoString", iface: reflect.TypeOf(func(fmt.GoStringer) {}).In(0)},
+	{name: "Error", iface: rerrType},
+}
+
+// Conventions returns the formatting conventions (fmt.Stringer, fmt.GoStringer, and error), that the shape implements.
+func (s *Shape) Conventions() []*Convention {
+	var r []*Convention
+	var methods map[string]*collect.Func
+	for _, c := range conventions {
+		isPointer := false
+		if !s.Type.Implements(c.iface) {
+			if s.Type.Kind() == reflect.Interface || !reflect.PointerTo(s.Type).Implements(c.iface) {
+				continue
+			}
+			isPointer = true
+		}
+
+		if methods == nil && s.Name != "" {
+			methods = map[string]*collect.Func{}
+			if metadata, _, err := s.e.lookupType(s.Type); err != nil {
+				s.e.Config.logf("Conventions(): %+v", err)
+			} else if metadata != nil {
+				methods = metadata.Raw.Methods
+			}
+		}
+
+		doc := ""
+		if m, ok := methods[c.name]; ok {
+			doc = strings.TrimSpace(m.Doc)
+		}
+		r = append(r, &Convention{Name: c.name, Interface: c.iface, IsPointerReceiver: isPointer, Doc: doc})
+	}
+	return r
+}
+
+// Clone returns the independent copy of the shape.
+// If deep is true, the referenced package (and the shapes in its scope) is also copied.
+func (s *Shape) Clone(deep bool) *Shape {
+	copied := *s
+	if !deep || s.Package == nil {
+		return &copied
+	}
+
+	p := *s.Package
+	pkg := &p
+	pkg.scope = nil // not shared
+	if s.Package.scope != nil {
+		defer s.Package.scope.lock()()
+		pkg.scope = &Scope{shapes: make(map[string]*Shape, len(s.Package.scope.shapes))}
+		for name, x := range s.Package.scope.shapes {
+			y := *x
+			y.Package = pkg
+			pkg.scope.shapes[name] = &y
+		}
+		pkg.scope.shapes[s.Name] = &copied
+	}
+	copied.Package = pkg
+	return &copied
+}
+
+// Patch returns the patch of the shape in Config.Patches, or nil.
+func (s *Shape) Patch() *Patch {
+	if s.Name == "" {
+		return nil
+	}
+	return s.e.Config.Patches[s.FullName()]
+}
+
+// FullName returns the name qualified by the package path. (e.g. "github.com/foo/bar.Person")
+// For the unnamed types, it returns the type literal. (e.g. "struct { Name string }")
 func (s *Shape) FullName() string {
+	if s.Name == "" {
+		return s.Type.String()
+	}
 	return fmt.Sprintf("%s.%s", s.Package.Path, s.Name)
 }
 
@@ -47,34 +252,32 @@ func (s *Shape) Struct() *Struct {
 	if s.Kind != reflect.Struct {
 		panic(fmt.Sprintf("shape %v is not Struct kind, %s", s, s.Kind))
 	}
-	lookup := s.e.Lookup
-	if lookup == nil || s.Name == "" {
+	if s.Name == "" {
 		return &Struct{Shape: s}
 	}
 
-	metadata, err := lookup.LookupFromTypeForReflectType(s.Type)
+	metadata, provenance, err := s.e.lookupType(s.Type)
 	if err != nil {
-		log.Printf("MustStruct(): %+v", err)
-		return &Struct{Shape: s}
+		s.e.Config.logf("MustStruct(): %+v", err)
+		return &Struct{Shape: s, err: err}
 	}
-	return &Struct{Shape: s, metadata: metadata}
+	return &Struct{Shape: s, metadata: metadata, provenance: provenance}
 }
 
 func (s *Shape) Interface() *Interface {
 	if s.Kind != reflect.Interface {
 		panic(fmt.Sprintf("shape %v is not Interface kind, %s", s, s.Kind))
 	}
-	lookup := s.e.Lookup
-	if lookup == nil || s.Name == "" {
+	if s.Name == "" {
 		return &Interface{Shape: s}
 	}
 
-	metadata, err := lookup.LookupFromTypeForReflectType(s.Type)
+	metadata, provenance, err := s.e.lookupType(s.Type)
 	if err != nil {
-		log.Printf("MustInterface(): %+v", err)
+		s.e.Config.logf("MustInterface(): %+v", err)
 		return &Interface{Shape: s}
 	}
-	return &Interface{Shape: s, metadata: metadata}
+	return &Interface{Shape: s, metadata: metadata, provenance: provenance}
 }
 
 var anonymousFuncNameRegex = regexp.MustCompile(`func\d+$`)
@@ -83,37 +286,60 @@ func (s *Shape) Func() *Func {
 	if s.Kind != reflect.Func && s.ID.pc == 0 {
 		panic(fmt.Sprintf("shape %v is not func kind, %s", s, s.Kind))
 	}
-	lookup := s.e.Lookup
-	if lookup == nil || s.Name == "" || (s.Package.Path == "" && anonymousFuncNameRegex.MatchString(s.Name)) {
+	if s.Name == "" || (s.Package.Path == "" && anonymousFuncNameRegex.MatchString(s.Name)) {
 		return &Func{Shape: s}
 	}
 
-	metadata, err := lookup.LookupFromFuncForPC(s.ID.pc)
+	m, provenance, err := s.e.lookupFunc(s.ID.pc, s.Package.Path)
 	if err != nil {
-		log.Printf("MustFunc(): %+v", err)
-		return &Func{Shape: s}
+		var noSource *metadata.NoSourceError
+		if !errors.As(err, &noSource) {
+			s.e.Config.logf("MustFunc(): %+v", err)
+		}
+		return &Func{Shape: s, err: err}
 	}
-	return &Func{Shape: s, metadata: metadata}
+	return &Func{Shape: s, metadata: m, provenance: provenance}
 }
 
 func (s *Shape) Named() *Named {
 	// TODO: check
-	lookup := s.e.Lookup
-	if lookup == nil || s.Name == "" {
+	if s.Name == "" {
 		return &Named{Shape: s}
 	}
 
-	metadata, err := lookup.LookupFromTypeForReflectType(s.Type)
+	metadata, provenance, err := s.e.lookupType(s.Type)
 	if err != nil {
-		log.Printf("MustType(): %+v", err)
+		s.e.Config.logf("MustType(): %+v", err)
 		return &Named{Shape: s}
 	}
-	return &Named{Shape: s, metadata: metadata}
+	return &Named{Shape: s, metadata: metadata, provenance: provenance}
+}
+
+func (s *Shape) Enum() *Enum {
+	switch s.Kind {
+	case reflect.Bool, reflect.String,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+	default:
+		panic(fmt.Sprintf("shape %v is not scalar kind, %s", s, s.Kind))
+	}
+	if s.Name == "" {
+		return &Enum{Shape: s}
+	}
+
+	metadata, provenance, err := s.e.lookupType(s.Type)
+	if err != nil {
+		s.e.Config.logf("MustEnum(): %+v", err)
+		return &Enum{Shape: s}
+	}
+	return &Enum{Shape: s, metadata: metadata, provenance: provenance}
 }
 
 type Named struct {
-	Shape    *Shape
-	metadata *metadata.Type
+	Shape      *Shape
+	metadata   *metadata.Type
+	provenance Provenance
 }
 
 func (t *Named) Name() string {
@@ -125,12 +351,26 @@ func (t *Named) Pos() token.Pos {
 }
 
 func (t *Named) Doc() string {
+	if p := t.Shape.Patch(); p != nil && p.Doc != "" {
+		return p.Doc
+	}
 	if t.metadata == nil {
 		return ""
 	}
 	return t.metadata.Doc()
 }
 
+// DocProvenance returns how the doc was obtained.
+func (t *Named) DocProvenance() Provenance {
+	if p := t.Shape.Patch(); p != nil && p.Doc != "" {
+		return ProvenancePatch
+	}
+	if t.metadata == nil {
+		return ProvenanceNone
+	}
+	return t.provenance
+}
+
 func (t *Named) String() string {
 	doc := t.Doc()
 	tsize := t.Shape.e.Config.DocTruncationSize
@@ -140,27 +380,176 @@ func (t *Named) String() string {
 	return fmt.Sprintf("&Type{Name: %q, kind: %s, type: %v, Doc: %q}", t.Name(), t.Shape.Kind, t.Shape.Type, doc)
 }
 
+// Enum is the named scalar type with the constants declared in the same package.
+type Enum struct {
+	Shape      *Shape
+	metadata   *metadata.Type
+	provenance Provenance
+}
+
+func (e *Enum) Name() string {
+	return e.Shape.Name
+}
+
+func (e *Enum) Doc() string {
+	if p := e.Shape.Patch(); p != nil && p.Doc != "" {
+		return p.Doc
+	}
+	if e.metadata == nil {
+		return ""
+	}
+	return e.metadata.Doc()
+}
+
+// DocProvenance returns how the doc was obtained.
+func (e *Enum) DocProvenance() Provenance {
+	if p := e.Shape.Patch(); p != nil && p.Doc != "" {
+		return ProvenancePatch
+	}
+	if e.metadata == nil {
+		return ProvenanceNone
+	}
+	return e.provenance
+}
+
+func (e *Enum) Members() []*EnumMember {
+	if e.metadata == nil {
+		return nil
+	}
+	r := make([]*EnumMember, len(e.metadata.Consts))
+	for i, c := range e.metadata.Consts {
+		r[i] = &EnumMember{Name: c.Name, Value: constantValue(e.Shape.Type, c.Value), Doc: c.Doc}
+	}
+	return r
+}
+
+func (e *Enum) String() string {
+	doc := e.Doc()
+	tsize := e.Shape.e.Config.DocTruncationSize
+	if len(doc) > tsize {
+		doc = doc[:tsize] + "..."
+	}
+
+	members := e.Members()
+	memberNames := make([]string, len(members))
+	for i, m := range members {
+		memberNames[i] = m.Name
+	}
+	return fmt.Sprintf("&Enum{Name: %q, Members: %v, Doc: %q}", e.Name(), memberNames, doc)
+}
+
+type EnumMember struct {
+	Name  string
+	Value reflect.Value // invalid if the value cannot be evaluated
+	Doc   string
+}
+
+func constantValue(rt reflect.Type, v constant.Value) reflect.Value {
+	if v == nil {
+		return reflect.Value{}
+	}
+	switch rt.Kind() {
+	case reflect.Bool:
+		if v.Kind() == constant.Bool {
+			return reflect.ValueOf(constant.BoolVal(v)).Convert(rt)
+		}
+	case reflect.String:
+		if v.Kind() == constant.String {
+			return reflect.ValueOf(constant.StringVal(v)).Convert(rt)
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if x, ok := constant.Int64Val(constant.ToInt(v)); ok {
+			return reflect.ValueOf(x).Convert(rt)
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if x, ok := constant.Uint64Val(constant.ToInt(v)); ok {
+			return reflect.ValueOf(x).Convert(rt)
+		}
+	case reflect.Float32, reflect.Float64:
+		if x, ok := constant.Float64Val(constant.ToFloat(v)); ok {
+			return reflect.ValueOf(x).Convert(rt)
+		}
+	}
+	return reflect.Value{}
+}
+
 type Struct struct {
-	Shape    *Shape
-	metadata *metadata.Type
+	Shape      *Shape
+	metadata   *metadata.Type
+	provenance Provenance
+	err        error // the error of the metadata lookup
+
+	// the synthesized identity of the anonymous struct in the field (see Field.Struct)
+	path    string // e.g. "Config.Server"
+	pkgpath string
 }
 
+// Name returns the name of the struct. If the struct is the anonymous struct in the field, it returns the path from the named struct (e.g. "Config.Server").
 func (s *Struct) Name() string {
+	if s.path != "" {
+		return s.path
+	}
 	return s.Shape.Name
 }
 
+// IsAnonymous reports whether the struct is the anonymous struct. (e.g. struct{ Name string })
+func (s *Struct) IsAnonymous() bool {
+	return s.Shape.Name == ""
+}
+
+// fullName returns the name qualified by the package path, for the keys of Config.Patches.
+func (s *Struct) fullName() string {
+	if s.path != "" {
+		return s.pkgpath + "." + s.path
+	}
+	return s.Shape.FullName()
+}
+
+func (s *Struct) patch() *Patch {
+	return s.Shape.e.Config.Patches[s.fullName()]
+}
+
 func (s *Struct) Pos() token.Pos {
 	return s.metadata.Raw.Pos
 }
 
 func (s *Struct) Doc() string {
+	if p := s.patch(); p != nil && p.Doc != "" {
+		return p.Doc
+	}
 	if s.metadata == nil {
 		return ""
 	}
 	return s.metadata.Doc()
 }
 
+// DocProvenance returns how the doc was obtained.
+func (s *Struct) DocProvenance() Provenance {
+	if p := s.patch(); p != nil && p.Doc != "" {
+		return ProvenancePatch
+	}
+	if s.metadata == nil {
+		return ProvenanceNone
+	}
+	return s.provenance
+}
+
+// Fields returns the fields of the struct. The result is cached, and copied on each call, so it can be modified freely.
+// If Config.FlattenEmbedded is true, the fields promoted from the embedded structs are included. (see PromotedFields)
 func (s *Struct) Fields() FieldList {
+	if s.Shape.e.Config.FlattenEmbedded {
+		return s.PromotedFields()
+	}
+	return s.directFields()
+}
+
+// directFields returns the fields declared in the struct, regardless of Config.FlattenEmbedded.
+// The fields of the anonymous struct in the field are not cached, because the same struct type can have the different comments per parent.
+func (s *Struct) directFields() FieldList {
+	return s.Shape.e.cachedFields(s.Shape.ID, s.err == nil && s.path == "", s.fields)
+}
+
+func (s *Struct) fields() FieldList {
 	typ := s.Shape.Type
 	var comments map[string]string
 	if s.metadata != nil {
@@ -169,17 +558,186 @@ func (s *Struct) Fields() FieldList {
 		comments = map[string]string{}
 	}
 
-	r := make([]*Field, typ.NumField())
+	r := make([]*Field, 0, typ.NumField())
 	for i := 0; i < typ.NumField(); i++ {
 		f := typ.Field(i)
 		rt := f.Type
 		rv := rzero(f.Type)
 		shape := s.Shape.e.extract(rt, rv)
-		r[i] = &Field{StructField: f, Shape: shape, Doc: comments[f.Name]}
+		if shape == nil { // vetoed by Hooks.BeforeExtract
+			continue
+		}
+		doc := comments[f.Name]
+		docProvenance := ProvenanceNone
+		if doc != "" {
+			docProvenance = s.provenance
+		}
+		patch := s.Shape.e.Config.Patches[s.fullName()+"."+f.Name]
+		if patch != nil && patch.Doc != "" {
+			doc = patch.Doc
+			docProvenance = ProvenancePatch
+		}
+		doc = s.Shape.e.intern(doc)
+		r = append(r, &Field{StructField: f, Shape: shape, Doc: doc, Patch: patch, Provenance: ProvenanceReflect, DocProvenance: docProvenance, parent: s})
+	}
+	return FieldList(r)
+}
+
+// FlattenFields returns the fields, with the fields of the embedded structs promoted (Field.Anonymous reports embedded or not).
+// Field.Index of the promoted field is the index sequence for reflect.Value.FieldByIndex().
+//
+// Like encoding/json, an embedded struct having json tag name is not flattened, and the names are the json names (the json tag name, or the field name).
+// If the json names are conflicted, the shallowest field wins (or the json tagged one, at the same depth). the others are dropped.
+// The fields excluded by json:"-" are not conflicted with the others, and the unexported fields are not included. (except the embedded ones)
+func (s *Struct) FlattenFields() FieldList {
+	return s.promote(promotion{
+		walk: func(f *Field) (candidate bool, descend bool) {
+			if !f.IsExported() && !f.Anonymous { // not serialized by encoding/json
+				return false, false
+			}
+			if f.Anonymous && f.Shape.Kind == reflect.Struct && tagName(f.Tag, "json") == "" {
+				return false, true
+			}
+			return true, false
+		},
+		name: func(f *Field) string {
+			if f.Tag.Get("json") == "-" {
+				return ""
+			}
+			if name := tagName(f.Tag, "json"); name != "" {
+				return name
+			}
+			return f.Name
+		},
+		resolve: func(fields []*Field) *Field {
+			var tagged []*Field
+			for _, f := range fields {
+				if tagName(f.Tag, "json") != "" {
+					tagged = append(tagged, f)
+				}
+			}
+			if len(tagged) == 1 {
+				return tagged[0]
+			}
+			return nil
+		},
+	})
+}
+
+// PromotedFields returns the fields, with the fields promoted from the embedded structs, by the promotion rules of Go.
+// Unlike FlattenFields, the embedded fields themselves are included, and the json tags are not considered.
+// Field.Index of the promoted field is the index sequence for reflect.Value.FieldByIndex(), and the docs are the ones of the embedded types.
+//
+// The shallowest field shadows the deeper ones. If names are conflicted at the same depth, the name is ambiguous (like the selector x.f), all of them are dropped.
+func (s *Struct) PromotedFields() FieldList {
+	return s.promote(promotion{
+		walk: func(f *Field) (candidate bool, descend bool) {
+			return true, f.Anonymous && f.Shape.Kind == reflect.Struct
+		},
+		name: func(f *Field) string { return f.Name },
+	})
+}
+
+// promotion is the rule of the promotion of the fields, of Go (PromotedFields) or encoding/json (FlattenFields).
+type promotion struct {
+	walk    func(f *Field) (candidate bool, descend bool) // candidate: the field can be in the result, descend: the fields of the embedded struct are promoted
+	name    func(f *Field) string                         // the name conflicted with the others, "" if never conflicted
+	resolve func(fields []*Field) *Field                  // the winner of the conflicted fields at the same depth, nil if all are dropped (optional)
+}
+
+// promote walks the fields of the embedded structs breadth-first, and returns the fields decided by the rule.
+func (s *Struct) promote(rule promotion) FieldList {
+	type node struct {
+		s     *Struct
+		index []int
 	}
+
+	var r []*Field
+	decided := map[string]bool{}
+	visited := map[ID]bool{}
+	current := []node{{s: s}}
+	for len(current) > 0 {
+		var next []node
+		var candidates []*Field
+		for _, n := range current {
+			if visited[n.s.Shape.ID] { // already walked at the shallower depth
+				continue
+			}
+			for _, f := range n.s.directFields() {
+				candidate, descend := rule.walk(f)
+				if !candidate && !descend {
+					continue
+				}
+				index := make([]int, len(n.index), len(n.index)+1)
+				copy(index, n.index)
+				index = append(index, f.Index...)
+
+				if descend {
+					next = append(next, node{s: f.Shape.Struct(), index: index})
+				}
+				if !candidate {
+					continue
+				}
+				copied := *f
+				copied.Index = index
+				if len(index) > 1 {
+					copied.Provenance = ProvenanceInherited
+				}
+				candidates = append(candidates, &copied)
+			}
+		}
+		// the same type embedded twice at the same depth is walked twice, so that its fields are conflicted
+		for _, n := range current {
+			visited[n.s.Shape.ID] = true
+		}
+
+		groups := map[string][]*Field{}
+		var names []string
+		for _, f := range candidates {
+			name := rule.name(f)
+			if name == "" {
+				r = append(r, f)
+				continue
+			}
+			if decided[name] {
+				continue
+			}
+			if _, ok := groups[name]; !ok {
+				names = append(names, name)
+			}
+			groups[name] = append(groups[name], f)
+		}
+		for _, name := range names {
+			decided[name] = true
+			fields := groups[name]
+			if len(fields) == 1 {
+				r = append(r, fields[0])
+				continue
+			}
+			if rule.resolve != nil {
+				if f := rule.resolve(fields); f != nil {
+					r = append(r, f)
+				}
+			}
+		}
+		current = next
+	}
+	sortFieldsByIndex(r)
 	return FieldList(r)
 }
 
+func sortFieldsByIndex(r []*Field) {
+	sort.SliceStable(r, func(i, j int) bool {
+		x, y := r[i].Index, r[j].Index
+		for k := 0; k < len(x) && k < len(y); k++ {
+			if x[k] != y[k] {
+				return x[k] < y[k]
+			}
+		}
+		return len(x) < len(y)
+	})
+}
+
 func (s *Struct) String() string {
 	doc := s.Doc()
 	tsize := s.Shape.e.Config.DocTruncationSize
@@ -195,6 +753,67 @@ func (s *Struct) String() string {
 	return fmt.Sprintf("&Struct{Name: %q, Fields: %v, Doc: %q}", s.Name(), fieldNames, doc)
 }
 
+// FieldGroup is the named section of the struct fields.
+type FieldGroup struct {
+	Name   string // "" for the fields without section
+	Fields FieldList
+}
+
+// Groups returns the fields grouped into the sections, in order of appearance.
+//
+// The section is declared by the `group:"<name>"` tag, or by the separator comment (e.g. "// --- Authentication ---") applied to the following fields.
+// The tag is prior to the comment.
+func (s *Struct) Groups() []*FieldGroup {
+	var sections map[string]string
+	if s.metadata != nil && s.Shape.e.Lookup != nil {
+		m, err := s.Shape.e.Lookup.FieldSections(s.metadata)
+		if err != nil {
+			s.Shape.e.Config.logf("Groups(): %+v", err)
+		}
+		sections = m
+	}
+
+	var groups []*FieldGroup
+	indices := map[string]int{}
+	for _, f := range s.Fields() {
+		name, ok := f.Tag.Lookup("group")
+		if !ok {
+			name = sections[f.Name]
+		}
+		i, ok := indices[name]
+		if !ok {
+			i = len(groups)
+			indices[name] = i
+			groups = append(groups, &FieldGroup{Name: name})
+		}
+		groups[i].Fields = append(groups[i].Fields, f)
+	}
+	return groups
+}
+
+// Layout returns the memory layout of the struct, on the running platform.
+func (s *Struct) Layout() *metadata.Layout {
+	layout, err := metadata.ReflectLayout(s.Shape.Type)
+	if err != nil {
+		s.Shape.e.Config.logf("Layout(): %+v", err)
+	}
+	return layout
+}
+
+// StaticLayout returns the memory layout of the struct, computed from the go source as it would appear for Config.GOOS, GOARCH and BuildTags.
+// (the sizes and the build-constrained fields are the ones of the target platform, for validating the wire structs of the other platforms)
+func (s *Struct) StaticLayout() (*metadata.Layout, error) {
+	if s.Shape.e.Lookup == nil {
+		return nil, fmt.Errorf("StaticLayout() needs the go source (SkipComments is true), %w", metadata.ErrNotSupported)
+	}
+	return s.Shape.e.Lookup.LookupLayout(s.Shape.Type)
+}
+
+// CompareLayout compares the memory layouts of the structs on the running platform, by position. (see metadata.Layout.Compare)
+func (s *Struct) CompareLayout(other *Struct, checkNames bool) []metadata.LayoutMismatch {
+	return s.Layout().Compare(other.Layout(), checkNames)
+}
+
 type FieldList []*Field
 
 func (fl FieldList) Len() int {
@@ -213,6 +832,38 @@ type Field struct {
 	reflect.StructField
 	Shape *Shape
 	Doc   string
+	Patch *Patch // nil if not patched (see Config.Patches)
+
+	Provenance    Provenance // ProvenanceReflect, or ProvenanceInherited if promoted from the embedded struct
+	DocProvenance Provenance // how the doc was obtained
+
+	parent *Struct // the struct declaring the field
+}
+
+// Struct returns the struct of the field's type (the pointers are dereferenced).
+// If the type is the anonymous struct (e.g. Server struct{ Host string }), the struct is identified by the path from the parent (e.g. "Config.Server"),
+// and the comments are the ones written in the parent's declaration. (the comments of *struct{...} and []struct{...} are not collected)
+func (f *Field) Struct() *Struct {
+	if f.Shape.Name != "" || f.parent == nil || f.Shape.Kind != reflect.Struct {
+		return f.Shape.Struct()
+	}
+	parent := f.parent
+	path := parent.Name() + "." + f.Name
+	if parent.Name() == "" {
+		path = "" // the parent is the anonymous struct not in the field
+	}
+	pkgpath := parent.pkgpath
+	if pkgpath == "" {
+		pkgpath = parent.Shape.Package.Path
+	}
+	s := &Struct{Shape: f.Shape, err: parent.err, path: path, pkgpath: pkgpath}
+	if parent.metadata != nil {
+		if raw, ok := parent.metadata.Raw.Fields[f.Name]; ok && raw.Anonymous != nil {
+			s.metadata = &metadata.Type{Raw: raw.Anonymous}
+			s.provenance = parent.provenance
+		}
+	}
+	return s
 }
 
 func (f *Field) String() string {
@@ -225,8 +876,9 @@ func (f *Field) String() string {
 }
 
 type Interface struct {
-	Shape    *Shape
-	metadata *metadata.Type
+	Shape      *Shape
+	metadata   *metadata.Type
+	provenance Provenance
 }
 
 func (iface *Interface) Name() string {
@@ -238,28 +890,54 @@ func (iface *Interface) Pos() token.Pos {
 }
 
 func (iface *Interface) Doc() string {
+	if p := iface.Shape.Patch(); p != nil && p.Doc != "" {
+		return p.Doc
+	}
 	if iface.metadata == nil {
 		return ""
 	}
 	return iface.metadata.Doc()
 }
 
-func (iface *Interface) Methods() VarList {
+// DocProvenance returns how the doc was obtained.
+func (iface *Interface) DocProvenance() Provenance {
+	if p := iface.Shape.Patch(); p != nil && p.Doc != "" {
+		return ProvenancePatch
+	}
+	if iface.metadata == nil {
+		return ProvenanceNone
+	}
+	return iface.provenance
+}
+
+func (iface *Interface) Methods() (methods VarList) {
+	defer iface.Shape.e.recoverPanicAndLog(iface.Shape.Type, "Methods()")
 	typ := iface.Shape.Type
 	var comments map[string]string
 	if iface.metadata != nil {
 		comments = iface.metadata.FieldComments()
+		if e := iface.Shape.e; iface.provenance == ProvenanceSource && e.Lookup != nil {
+			// the docs of the methods of the embedded interfaces (e.g. io.ReadCloser embeds io.Reader and io.Closer)
+			if m, err := e.Lookup.LookupFromInterfaceForReflectType(typ); err != nil {
+				e.Config.logf("MustInterface().Methods(): %+v", err)
+			} else {
+				comments = m.MethodComments()
+			}
+		}
 	} else {
 		comments = map[string]string{}
 	}
 
-	r := make([]*Var, typ.NumMethod())
+	r := make([]*Var, 0, typ.NumMethod())
 	for i := 0; i < typ.NumMethod(); i++ {
 		f := typ.Method(i)
 		rt := f.Type
 		rv := rzero(f.Type)
 		shape := iface.Shape.e.extract(rt, rv)
-		r[i] = &Var{Name: f.Name, Shape: shape, Doc: comments[f.Name]}
+		if shape == nil { // vetoed by Hooks.BeforeExtract
+			continue
+		}
+		r = append(r, &Var{Name: f.Name, Shape: shape, Doc: comments[f.Name]})
 	}
 	return r
 }
@@ -280,8 +958,21 @@ func (iface *Interface) String() string {
 }
 
 type Func struct {
-	Shape    *Shape
-	metadata *metadata.Func
+	Shape      *Shape
+	metadata   *metadata.Func
+	provenance Provenance
+	err        error
+
+	recv *Shape // the receiver, set by Shape.Methods (see Receiver)
+
+	returnedOnce  sync.Once
+	returnedNames []string // the names of the types constructed in the return statements (see ConcreteReturns)
+	returnedErr   error
+}
+
+// Err returns the error of the metadata lookup. (e.g. *metadata.NoSourceError for the function implemented in assembly)
+func (f *Func) Err() error {
+	return f.err
 }
 
 func (f *Func) Name() string {
@@ -295,60 +986,113 @@ func (f *Func) Pos() token.Pos {
 func (f *Func) IsMethod() bool {
 	return f.Shape.IsMethod
 }
+
+// IsClosure returns true if the function is the func literal (e.g. "F.func1"), its doc is the comment immediately preceding the literal.
+func (f *Func) IsClosure() bool {
+	if f.metadata != nil {
+		return f.metadata.IsClosure
+	}
+	rfunc := runtime.FuncForPC(f.Shape.ID.pc)
+	if rfunc == nil {
+		return false
+	}
+	name, err := runtimeinfo.ParseFuncName(rfunc.Name())
+	return err == nil && name.IsClosure()
+}
+
+// IsVariadic returns true if the function is variadic. The last argument is marked as Var.Variadic, and its shape is of the element type.
 func (f *Func) IsVariadic() bool {
 	return f.Shape.Type.IsVariadic()
 }
 
-func (f *Func) Args() VarList {
+// Args returns the arguments of the function. The result is cached, and copied on each call, so it can be modified freely.
+func (f *Func) Args() (args VarList) {
+	defer f.Shape.e.recoverPanicAndLog(f.Shape.Type, "Args()") // not cached
+	return f.Shape.e.cachedVars(f.Shape.ID, f.err == nil, func(v *views) *VarList { return &v.args }, f.args)
+}
+
+func (f *Func) args() VarList {
 	typ := f.Shape.Type
 	var args []metadata.Var
 	if f.metadata != nil {
 		args = f.metadata.Args()
-	} else {
-		args = make([]metadata.Var, typ.NumIn())
 	}
+	args, hasRecv := alignVars(args, typ.NumIn())
 
-	r := make([]*Var, typ.NumIn())
+	r := make([]*Var, 0, typ.NumIn())
 	needFillNames := f.Shape.e.Config.FillArgNames
+	used := usedNames(args)
 	for i := 0; i < typ.NumIn(); i++ {
 		rt := typ.In(i)
+		variadic := i == typ.NumIn()-1 && typ.IsVariadic()
+		if variadic {
+			rt = rt.Elem() // ...string -> string
+		}
 		rv := rzero(rt)
 		shape := f.Shape.e.extract(rt, rv)
 		p := args[i]
 		name := p.Name
-		if name == "" && needFillNames {
+		if (name == "" || name == "_") && needFillNames {
+			name = ""
+			if h := f.Shape.e.Config.Hooks.ArgName; h != nil {
+				name = h(f, i, typ.In(i))
+			}
 			switch {
+			case name != "":
+			case i == 0 && hasRecv:
+				name = "recv"
 			case rcontextType == rt:
 				name = "ctx"
 			default:
 				name = fmt.Sprintf("arg%d", i)
 			}
+			name = uniqueName(name, used)
 		}
-		r[i] = &Var{Name: name, Shape: shape, Doc: p.Doc}
+		if shape == nil { // vetoed by Hooks.BeforeExtract
+			continue
+		}
+		r = append(r, &Var{Name: name, Shape: shape, Doc: p.Doc, Variadic: variadic})
 	}
 	return VarList(r)
 }
 
-func (f *Func) Returns() VarList {
+// Returns returns the return values of the function. The result is cached, and copied on each call, so it can be modified freely.
+func (f *Func) Returns() (returns VarList) {
+	defer f.Shape.e.recoverPanicAndLog(f.Shape.Type, "Returns()") // not cached
+	return f.Shape.e.cachedVars(f.Shape.ID, f.err == nil, func(v *views) *VarList { return &v.returns }, f.returns)
+}
+
+func (f *Func) returns() VarList {
 	typ := f.Shape.Type
 	var args []metadata.Var
 	if f.metadata != nil {
 		args = f.metadata.Returns()
-	} else {
-		args = make([]metadata.Var, typ.NumOut())
 	}
+	args, _ = alignVars(args, typ.NumOut())
 
 	needFillNames := f.Shape.e.Config.FillReturnNames
+	var used map[string]bool
+	if needFillNames {
+		used = usedNames(args)
+		for _, v := range f.Args() { // the names must be unique in the signature, e.g. func(err error) (ret0 int, err1 error)
+			used[v.Name] = true
+		}
+	}
 	errUsed := false
-	r := make([]*Var, typ.NumOut())
+	r := make([]*Var, 0, typ.NumOut())
 	for i := 0; i < typ.NumOut(); i++ {
 		rt := typ.Out(i)
 		rv := rzero(rt)
 		shape := f.Shape.e.extract(rt, rv)
 		p := args[i]
 		name := p.Name
-		if name == "" && needFillNames {
+		if (name == "" || name == "_") && needFillNames {
+			name = ""
+			if h := f.Shape.e.Config.Hooks.ReturnName; h != nil {
+				name = h(f, i, rt)
+			}
 			switch {
+			case name != "":
 			case rerrType == rt && errUsed:
 				name = fmt.Sprintf("err%d", i)
 			case rerrType == rt:
@@ -357,18 +1101,123 @@ func (f *Func) Returns() VarList {
 			default:
 				name = fmt.Sprintf("ret%d", i)
 			}
+			name = uniqueName(name, used)
+		}
+		if shape == nil { // vetoed by Hooks.BeforeExtract
+			continue
 		}
-		r[i] = &Var{Name: name, Shape: shape, Doc: p.Doc}
+		r = append(r, &Var{Name: name, Shape: shape, Doc: p.Doc})
 	}
 	return VarList(r)
 }
 
+// usedNames returns the names in the metadata, the filled names must not conflict with them.
+func usedNames(vars []metadata.Var) map[string]bool {
+	used := make(map[string]bool, len(vars))
+	for _, v := range vars {
+		if v.Name != "" && v.Name != "_" {
+			used[v.Name] = true
+		}
+	}
+	return used
+}
+
+// uniqueName returns the name not used yet (e.g. "err1" if "err" is used), and marks it as used.
+func uniqueName(name string, used map[string]bool) string {
+	candidate := name
+	for i := 1; used[candidate]; i++ {
+		candidate = fmt.Sprintf("%s%d", name, i)
+	}
+	used[candidate] = true
+	return candidate
+}
+
+// alignVars aligns the vars in the metadata with the n vars of the reflect.Type (In or Out).
+// For the method expression (e.g. (*T).M), the receiver is the first argument of the type, but not in the metadata, so the vars are aligned to the tail.
+// The vars not found in the metadata are zero values. (e.g. the metadata is not found)
+func alignVars(vars []metadata.Var, n int) (aligned []metadata.Var, hasRecv bool) {
+	if len(vars) == n {
+		return vars, false
+	}
+	aligned = make([]metadata.Var, n)
+	if vars == nil { // no metadata
+		return aligned, false
+	}
+	if len(vars) > n { // unexpected, the metadata of the other function?
+		copy(aligned, vars)
+		return aligned, false
+	}
+	copy(aligned[n-len(vars):], vars)
+	return aligned, n-len(vars) == 1
+}
+
+// ConcreteReturns returns the shapes of the concrete types returned by the function. (e.g. for the constructor `func NewFoo() Fooer { return &foo{} }`, the shape of foo)
+//
+// The declared return types are used as-is, unless they are interfaces (error is skipped).
+// For the interfaces, the types constructed in the return statements are found from the source,
+// and resolved with the shapes already extracted in the same package. (the types not extracted yet are not included)
+func (f *Func) ConcreteReturns() []*Shape {
+	typ := f.Shape.Type
+	var r []*Shape
+	hasInterface := false
+	for i := 0; i < typ.NumOut(); i++ {
+		rt := typ.Out(i)
+		if rt.Kind() == reflect.Interface {
+			if rt != rerrType {
+				hasInterface = true
+			}
+			continue
+		}
+		if s := f.Shape.e.extract(rt, rzero(rt)); s != nil { // nil if vetoed by Hooks.BeforeExtract
+			r = append(r, s)
+		}
+	}
+	if !hasInterface || f.metadata == nil || f.Shape.e.Lookup == nil {
+		return r
+	}
+
+	f.returnedOnce.Do(func() { // the source is parsed only once per Func
+		f.returnedNames, f.returnedErr = f.Shape.e.Lookup.ReturnedTypeNames(f.metadata)
+	})
+	names, err := f.returnedNames, f.returnedErr
+	if err != nil {
+		f.Shape.e.Config.logf("ConcreteReturns(): %+v", err)
+		return r
+	}
+	scope := f.Shape.Package.scope
+	for _, name := range names {
+		s, ok := scope.lookup(name)
+		if !ok || s.Kind == reflect.Func {
+			continue
+		}
+		copied := *s
+		r = append(r, &copied)
+	}
+	return r
+}
+
 func (f *Func) Doc() string {
+	if p := f.Shape.Patch(); p != nil && p.Doc != "" {
+		return p.Doc
+	}
 	if f.metadata == nil {
 		return ""
 	}
 	return f.metadata.Doc()
 }
+
+// DocProvenance returns how the doc was obtained.
+func (f *Func) DocProvenance() Provenance {
+	if p := f.Shape.Patch(); p != nil && p.Doc != "" {
+		return ProvenancePatch
+	}
+	if f.metadata == nil {
+		return ProvenanceNone
+	}
+	return f.provenance
+}
+
+// Recv returns the name of the receiver type in the source (e.g. "Queue"), "" if not a method. (see Receiver for the shape)
 func (f *Func) Recv() string {
 	if f.metadata == nil {
 		if f.Shape.IsMethod {
@@ -414,10 +1263,13 @@ func (vl VarList) String() string {
 	return fmt.Sprintf("%+v", parts)
 }
 
+// Var is the argument or the return value of the function, the name and the doc are from the metadata, and the shape is from the reflect.Type.
 type Var struct {
 	Name  string
 	Shape *Shape
 	Doc   string
+
+	Variadic bool // the last argument of the variadic function, the Shape is of the element type (e.g. string for ...string)
 }
 
 func (v *Var) String() string {
@@ -426,7 +1278,11 @@ func (v *Var) String() string {
 	if len(doc) > tsize {
 		doc = doc[:tsize] + "..."
 	}
-	return fmt.Sprintf("&Var{Name: %q, type: %v, Doc: %q}", v.Name, v.Shape.Type, doc)
+	typ := v.Shape.Type.String()
+	if v.Variadic {
+		typ = "..." + typ
+	}
+	return fmt.Sprintf("&Var{Name: %q, type: %v, Doc: %q}", v.Name, typ, doc)
 }
 
 func rzero(rt reflect.Type) reflect.Value {
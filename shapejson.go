@@ -0,0 +1,221 @@
+package reflectshape
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"runtime"
+
+	"github.com/podhmo/reflect-shape/metadata/runtimeinfo"
+)
+
+// GraphJSON is the JSON form of Graph, for the tools without the reflection. (e.g. the doc site builder, the diff tool)
+//
+// The shapes are referenced by the stable IDs, so the recursive types are dumped without the infinite recursion,
+// and the IDs are the same in the dumps of the other processes. (the canonical type strings, e.g. "github.com/foo/bar.User",
+// "[]*github.com/foo/bar.User", and "github.com/foo/bar.S.M func(int) (string)" for the funcs and the methods)
+// The package paths in the IDs and ShapeJSON.Package are mapped by Config.Namespaces.
+//
+//	b, _ := json.Marshal(cfg.Graph(User{}))  // in the process having the types
+//
+//	var g reflectshape.GraphJSON             // in the other tool
+//	err := json.Unmarshal(b, &g)             // the references are checked
+//	user := g.Node(g.Roots[0].ID)
+type GraphJSON struct {
+	Roots []RefJSON    `json:"roots"`
+	Nodes []*ShapeJSON `json:"nodes"` // in the order of Graph.Nodes
+
+	index map[string]*ShapeJSON
+}
+
+// RefJSON is the reference to the shape in GraphJSON, with the pointer level. (e.g. {"id": "github.com/foo/bar.User", "lv": 1} for *User)
+type RefJSON struct {
+	ID string `json:"id"`
+	Lv int    `json:"lv,omitempty"`
+}
+
+// ShapeJSON is the JSON form of Shape. The shapes referenced from it are RefJSON.
+type ShapeJSON struct {
+	ID          string `json:"id"`
+	Name        string `json:"name,omitempty"`
+	Package     string `json:"package,omitempty"`
+	Kind        string `json:"kind"`
+	Lv          int    `json:"lv,omitempty"` // only for Shape.MarshalJSON, the nodes of GraphJSON have no pointer levels
+	Doc         string `json:"doc,omitempty"`
+	IsMethod    bool   `json:"isMethod,omitempty"`
+	IsWellKnown bool   `json:"isWellKnown,omitempty"`
+	IsOpaque    bool   `json:"isOpaque,omitempty"`
+
+	Key     *RefJSON     `json:"key,omitempty"`     // for the map
+	Elem    *RefJSON     `json:"elem,omitempty"`    // for the map, slice, array, and chan
+	Len     int          `json:"len,omitempty"`     // for the array
+	Fields  []*FieldJSON `json:"fields,omitempty"`  // for the struct
+	Args    []*FieldJSON `json:"args,omitempty"`    // for the func
+	Returns []*FieldJSON `json:"returns,omitempty"` // for the func
+	Methods []*FieldJSON `json:"methods,omitempty"` // for the interface, the Type is the signature
+}
+
+// FieldJSON is the struct field, the argument, the return value, or the method of the interface in ShapeJSON.
+type FieldJSON struct {
+	Name     string   `json:"name,omitempty"`
+	Type     *RefJSON `json:"type"`
+	Tag      string   `json:"tag,omitempty"`
+	Doc      string   `json:"doc,omitempty"`
+	Embedded bool     `json:"embedded,omitempty"`
+	Variadic bool     `json:"variadic,omitempty"` // the Type is of the element (e.g. string for ...string)
+}
+
+// Node returns the shape of the id, or nil if not found.
+func (g *GraphJSON) Node(id string) *ShapeJSON {
+	if g.index == nil {
+		g.index = make(map[string]*ShapeJSON, len(g.Nodes))
+		for _, n := range g.Nodes {
+			g.index[n.ID] = n
+		}
+	}
+	return g.index[id]
+}
+
+// UnmarshalJSON decodes the graph, and checks that all the references are resolved.
+func (g *GraphJSON) UnmarshalJSON(b []byte) error {
+	type plain GraphJSON
+	var decoded plain
+	if err := json.Unmarshal(b, &decoded); err != nil {
+		return err
+	}
+	*g = GraphJSON(decoded)
+	g.index = nil
+
+	seen := make(map[string]bool, len(g.Nodes))
+	for _, n := range g.Nodes {
+		if seen[n.ID] {
+			return fmt.Errorf("duplicated node %q", n.ID)
+		}
+		seen[n.ID] = true
+	}
+	check := func(from string, ref *RefJSON) error {
+		if ref != nil && !seen[ref.ID] {
+			return fmt.Errorf("unresolved reference %q, in %q", ref.ID, from)
+		}
+		return nil
+	}
+	for i := range g.Roots {
+		if err := check("roots", &g.Roots[i]); err != nil {
+			return err
+		}
+	}
+	for _, n := range g.Nodes {
+		refs := []*RefJSON{n.Key, n.Elem}
+		for _, fields := range [][]*FieldJSON{n.Fields, n.Args, n.Returns, n.Methods} {
+			for _, f := range fields {
+				refs = append(refs, f.Type)
+			}
+		}
+		for _, ref := range refs {
+			if err := check(n.ID, ref); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// JSON returns the JSON form of the graph.
+func (g *Graph) JSON() *GraphJSON {
+	r := &GraphJSON{Roots: make([]RefJSON, len(g.Roots)), Nodes: make([]*ShapeJSON, len(g.Nodes))}
+	for i, s := range g.Roots {
+		r.Roots[i] = refJSON(s)
+	}
+	for i, s := range g.Nodes {
+		r.Nodes[i] = newShapeJSON(s)
+	}
+	return r
+}
+
+func (g *Graph) MarshalJSON() ([]byte, error) {
+	return json.Marshal(g.JSON())
+}
+
+// MarshalJSON encodes the shape as ShapeJSON. For dumping the shapes referenced from it too, use Config.Graph.
+func (s *Shape) MarshalJSON() ([]byte, error) {
+	n := newShapeJSON(s)
+	n.Lv = s.Lv
+	return json.Marshal(n)
+}
+
+// shapeJSONID returns the stable ID of the shape. (see GraphJSON)
+// The funcs are qualified by the signatures, for distinguishing the instantiations of the generic func (e.g. Ident[int] and Ident[string]),
+// and the method values have the "-fm" suffix as the runtime names. (e.g. S{}.M and the method expression S.M)
+func shapeJSONID(s *Shape) string {
+	if s.ID.pc == 0 {
+		return s.e.exportedTypeString(s.Type)
+	}
+	id := s.Package.Namespace + "." + s.Name
+	if rfunc := runtime.FuncForPC(s.ID.pc); rfunc != nil {
+		if fn, err := runtimeinfo.ParseFuncName(rfunc.Name()); err == nil && fn.IsMethodValue {
+			id += "-fm"
+		}
+	}
+	return id + " " + s.e.exportedTypeString(s.Type)
+}
+
+func refJSON(s *Shape) RefJSON {
+	return RefJSON{ID: shapeJSONID(s), Lv: s.Lv}
+}
+
+func newShapeJSON(s *Shape) *ShapeJSON {
+	n := &ShapeJSON{
+		ID:          shapeJSONID(s),
+		Name:        s.Name,
+		Package:     s.Package.Namespace,
+		Kind:        s.Kind.String(),
+		IsMethod:    s.IsMethod,
+		IsWellKnown: s.IsWellKnown,
+		IsOpaque:    s.IsOpaque,
+	}
+	if s.Package.Path != "" {
+		n.Doc = s.doc()
+	}
+	if s.IsWellKnown || s.IsOpaque {
+		return n
+	}
+
+	vars := func(vl VarList) []*FieldJSON {
+		r := make([]*FieldJSON, len(vl))
+		for i, v := range vl {
+			ref := refJSON(v.Shape)
+			r[i] = &FieldJSON{Name: v.Name, Type: &ref, Doc: v.Doc, Variadic: v.Variadic}
+		}
+		return r
+	}
+	elem := func(s *Shape) *RefJSON {
+		if s == nil { // vetoed
+			return nil
+		}
+		ref := refJSON(s)
+		return &ref
+	}
+
+	switch {
+	case s.Kind == reflect.Func || s.ID.pc != 0:
+		fn := s.Func()
+		n.Args = vars(fn.Args())
+		n.Returns = vars(fn.Returns())
+	case s.Kind == reflect.Struct:
+		for _, f := range s.Struct().Fields() {
+			ref := refJSON(f.Shape)
+			n.Fields = append(n.Fields, &FieldJSON{Name: f.Name, Type: &ref, Tag: string(f.Tag), Doc: f.Doc, Embedded: f.Anonymous})
+		}
+	case s.Kind == reflect.Interface:
+		n.Methods = vars(s.Interface().Methods())
+	case s.Kind == reflect.Map:
+		n.Key = elem(s.Deref().Key())
+		n.Elem = elem(s.Deref().Elem())
+	case s.Kind == reflect.Slice || s.Kind == reflect.Array || s.Kind == reflect.Chan:
+		n.Elem = elem(s.Deref().Elem())
+		if s.Kind == reflect.Array {
+			n.Len = s.Type.Len()
+		}
+	}
+	return n
+}
@@ -0,0 +1,134 @@
+package reflectshape_test
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	reflectshape "github.com/podhmo/reflect-shape"
+)
+
+// Ident returns v as is.
+func Ident[T any](v T) T { return v }
+
+func TestGraphJSON(t *testing.T) {
+	cfg := &reflectshape.Config{IncludeGoTestFiles: true}
+	b, err := json.Marshal(cfg.Graph(Person{}, IssueInvoice))
+	if err != nil {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+
+	var g reflectshape.GraphJSON
+	if err := json.Unmarshal(b, &g); err != nil {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+
+	const pkg = "github.com/podhmo/reflect-shape_test"
+	t.Run("roots", func(t *testing.T) {
+		want := []reflectshape.RefJSON{{ID: pkg + ".Person"}, {ID: pkg + ".IssueInvoice func(int) (*" + pkg + ".Invoice)"}}
+		if diff := cmp.Diff(want, g.Roots); diff != "" {
+			t.Errorf("GraphJSON.Roots: mismatch (-want, +got): \n%v", diff)
+		}
+	})
+
+	t.Run("recursive", func(t *testing.T) {
+		person := g.Node(pkg + ".Person")
+		if person == nil {
+			t.Fatalf("GraphJSON.Node(): not found, in %s", b)
+		}
+		want := []*reflectshape.FieldJSON{
+			{Name: "Name", Type: &reflectshape.RefJSON{ID: "string"}, Doc: "name of person"},
+			{Name: "Father", Type: &reflectshape.RefJSON{ID: pkg + ".Person", Lv: 1}},
+			{Name: "Children", Type: &reflectshape.RefJSON{ID: "[]*" + pkg + ".Person"}},
+		}
+		if diff := cmp.Diff(want, person.Fields); diff != "" {
+			t.Errorf("ShapeJSON.Fields: mismatch (-want, +got): \n%v", diff)
+		}
+		children := g.Node("[]*" + pkg + ".Person")
+		if want, got := (&reflectshape.RefJSON{ID: pkg + ".Person", Lv: 1}), children.Elem; !cmp.Equal(want, got) {
+			t.Errorf("ShapeJSON.Elem: want:%v != got:%v", want, got)
+		}
+	})
+
+	t.Run("func", func(t *testing.T) {
+		fn := g.Node(pkg + ".IssueInvoice func(int) (*" + pkg + ".Invoice)")
+		if want, got := "IssueInvoice issues the invoice.", fn.Doc; want != got {
+			t.Errorf("ShapeJSON.Doc: want:%q != got:%q", want, got)
+		}
+		want := []*reflectshape.FieldJSON{{Type: &reflectshape.RefJSON{ID: pkg + ".Invoice", Lv: 1}}}
+		if diff := cmp.Diff(want, fn.Returns); diff != "" {
+			t.Errorf("ShapeJSON.Returns: mismatch (-want, +got): \n%v", diff)
+		}
+		if g.Node(pkg+".Invoice") == nil {
+			t.Errorf("GraphJSON.Node(): the referenced shape must be dumped")
+		}
+	})
+
+	t.Run("shape", func(t *testing.T) {
+		b, err := json.Marshal(cfg.Extract(&Person{}))
+		if err != nil {
+			t.Fatalf("unexpected error: %+v", err)
+		}
+		var got reflectshape.ShapeJSON
+		if err := json.Unmarshal(b, &got); err != nil {
+			t.Fatalf("unexpected error: %+v", err)
+		}
+		if want, got := (reflectshape.RefJSON{ID: pkg + ".Person", Lv: 1}), (reflectshape.RefJSON{ID: got.ID, Lv: got.Lv}); want != got {
+			t.Errorf("Shape.MarshalJSON(): want:%v != got:%v", want, got)
+		}
+	})
+
+	t.Run("funcs", func(t *testing.T) {
+		// the instantiations of the generic func, and the method value and the method expression have the same names
+		b, err := json.Marshal(cfg.Graph(Ident[int], Ident[string], S0{}.M, S0.M))
+		if err != nil {
+			t.Fatalf("unexpected error: %+v", err)
+		}
+		var g reflectshape.GraphJSON
+		if err := json.Unmarshal(b, &g); err != nil {
+			t.Fatalf("round-trip: unexpected error: %+v", err)
+		}
+		if want, got := 4, len(g.Roots); want != got {
+			t.Fatalf("GraphJSON.Roots: len, want:%d != got:%d", want, got)
+		}
+		// the generic funcs are named "Ident" (or the closure wrapping it, before go1.21), qualified by the signatures
+		for i, suffix := range []string{" func(int) (int)", " func(string) (string)"} {
+			if got := g.Roots[i].ID; !strings.HasSuffix(got, suffix) {
+				t.Errorf("GraphJSON.Roots[%d]: must have the suffix %q, but got %q", i, suffix, got)
+			}
+		}
+		want := []string{pkg + ".S0.M-fm func() ()", pkg + ".S0.M func(" + pkg + ".S0) ()"}
+		if diff := cmp.Diff(want, []string{g.Roots[2].ID, g.Roots[3].ID}); diff != "" {
+			t.Errorf("GraphJSON.Roots: mismatch (-want, +got): \n%v", diff)
+		}
+	})
+
+	t.Run("unresolved", func(t *testing.T) {
+		var g reflectshape.GraphJSON
+		err := json.Unmarshal([]byte(`{"roots":[{"id":"x.A"}],"nodes":[{"id":"x.A","kind":"struct","fields":[{"name":"B","type":{"id":"x.B"}}]}]}`), &g)
+		if err == nil || !strings.Contains(err.Error(), `unresolved reference "x.B"`) {
+			t.Errorf("GraphJSON.UnmarshalJSON(): must be error, but got %v", err)
+		}
+	})
+}
+
+func TestGraphJSONNamespace(t *testing.T) {
+	cfg := &reflectshape.Config{IncludeGoTestFiles: true, Namespaces: map[string]string{"github.com/podhmo/reflect-shape_test": "shape"}}
+	g := cfg.Graph(Person{}, IssueInvoice).JSON()
+
+	want := []reflectshape.RefJSON{{ID: "shape.Person"}, {ID: "shape.IssueInvoice func(int) (*shape.Invoice)"}}
+	if diff := cmp.Diff(want, g.Roots); diff != "" {
+		t.Errorf("GraphJSON.Roots: mismatch (-want, +got): \n%v", diff)
+	}
+	person := g.Node("shape.Person")
+	if person == nil {
+		t.Fatalf("GraphJSON.Node(): not found")
+	}
+	if want, got := "shape", person.Package; want != got {
+		t.Errorf("ShapeJSON.Package: want:%q != got:%q", want, got)
+	}
+	if g.Node("[]*shape.Person") == nil {
+		t.Errorf("GraphJSON.Node(): the elements are not mapped")
+	}
+}
@@ -0,0 +1,104 @@
+package reflectshape
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// Tag is the parsed value of the struct tag. (e.g. `json:"name,omitempty"` -> Tag{Key: "json", Name: "name", Options: ["omitempty"]})
+type Tag struct {
+	Key     string
+	Name    string   // the first element of the comma-separated value
+	Options []string // the rest of the comma-separated value
+}
+
+// HasOption reports whether the tag has the option. (e.g. "omitempty")
+func (t Tag) HasOption(option string) bool {
+	for _, x := range t.Options {
+		if x == option {
+			return true
+		}
+	}
+	return false
+}
+
+// Tags returns the parsed struct tags of the field, in the order of appearance. The malformed part of the tag is ignored.
+func (f *Field) Tags() []Tag {
+	return parseTags(f.Tag)
+}
+
+// LookupTag returns the parsed struct tag of the key, as reflect.StructTag.Lookup does. If the key is not found, ok is false.
+func (f *Field) LookupTag(key string) (tag Tag, ok bool) {
+	value, ok := f.Tag.Lookup(key)
+	if !ok {
+		return Tag{}, false
+	}
+	return newTag(key, value), true
+}
+
+// tagName returns the name of the struct tag of the key, "" if not found or ignored. (e.g. `json:"-"`, but `json:"-,"` is named "-")
+func tagName(tag reflect.StructTag, key string) string {
+	t := newTag(key, tag.Get(key))
+	if t.Name == "-" && len(t.Options) == 0 {
+		return ""
+	}
+	return t.Name
+}
+
+func newTag(key, value string) Tag {
+	name, rest, _ := strings.Cut(value, ",")
+	tag := Tag{Key: key, Name: name}
+	if rest != "" {
+		tag.Options = strings.Split(rest, ",")
+	}
+	return tag
+}
+
+// parseTags parses the struct tag, in the same way as reflect.StructTag.Lookup.
+func parseTags(tag reflect.StructTag) []Tag {
+	var r []Tag
+	for tag != "" {
+		// skip leading space
+		i := 0
+		for i < len(tag) && tag[i] == ' ' {
+			i++
+		}
+		tag = tag[i:]
+		if tag == "" {
+			break
+		}
+
+		// scan to colon. a space, a quote or a control character is a syntax error.
+		i = 0
+		for i < len(tag) && tag[i] > ' ' && tag[i] != ':' && tag[i] != '"' && tag[i] != 0x7f {
+			i++
+		}
+		if i == 0 || i+1 >= len(tag) || tag[i] != ':' || tag[i+1] != '"' {
+			break
+		}
+		key := string(tag[:i])
+		tag = tag[i+1:]
+
+		// scan quoted string to find value
+		i = 1
+		for i < len(tag) && tag[i] != '"' {
+			if tag[i] == '\\' {
+				i++
+			}
+			i++
+		}
+		if i >= len(tag) {
+			break
+		}
+		quoted := string(tag[:i+1])
+		tag = tag[i+1:]
+
+		value, err := strconv.Unquote(quoted)
+		if err != nil {
+			break
+		}
+		r = append(r, newTag(key, value))
+	}
+	return r
+}
@@ -0,0 +1,57 @@
+package reflectshape_test
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	reflectshape "github.com/podhmo/reflect-shape"
+)
+
+type Row struct {
+	// ID is the primary key.
+	ID   int64  `json:"id" db:"id,primarykey"`
+	Name string `json:"name,omitempty" yaml:"name" db:"name"`
+	Memo string
+}
+
+func TestFieldTags(t *testing.T) {
+	fields := cfg.Extract(Row{}).Struct().Fields()
+
+	cases := []struct {
+		msg  string
+		i    int
+		want []reflectshape.Tag
+	}{
+		{msg: "multiple", i: 0, want: []reflectshape.Tag{{Key: "json", Name: "id"}, {Key: "db", Name: "id", Options: []string{"primarykey"}}}},
+		{msg: "options", i: 1, want: []reflectshape.Tag{{Key: "json", Name: "name", Options: []string{"omitempty"}}, {Key: "yaml", Name: "name"}, {Key: "db", Name: "name"}}},
+		{msg: "no-tags", i: 2, want: nil},
+	}
+	for _, c := range cases {
+		c := c
+		t.Run(c.msg, func(t *testing.T) {
+			if diff := cmp.Diff(c.want, fields[c.i].Tags()); diff != "" {
+				t.Errorf("Field.Tags(): mismatch (-want, +got): \n%v", diff)
+			}
+		})
+	}
+
+	t.Run("lookup", func(t *testing.T) {
+		f := fields[1]
+		tag, ok := f.LookupTag("json")
+		if !ok {
+			t.Fatalf("Field.LookupTag(), must be found")
+		}
+		if want, got := true, tag.HasOption("omitempty"); want != got {
+			t.Errorf("Tag.HasOption(), want:%v != got:%v", want, got)
+		}
+		if want, got := "name,omitempty", f.Tag.Get("json"); want != got {
+			t.Errorf("Field.Tag.Get(), want:%v != got:%v", want, got)
+		}
+		if want, got := "ID is the primary key.", fields[0].Doc; want != got {
+			t.Errorf("Field.Doc, want:%v != got:%v", want, got)
+		}
+		if _, ok := f.LookupTag("xml"); ok {
+			t.Errorf("Field.LookupTag(), must not be found")
+		}
+	})
+}
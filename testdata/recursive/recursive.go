@@ -0,0 +1,26 @@
+// Package recursive is the fixture of the recursive types.
+package recursive
+
+// Node is the node of the linked list.
+type Node struct {
+	Value int
+	Next  *Node
+}
+
+// Expr is the expression, mutually recursive with Term.
+type Expr struct {
+	Op    string
+	Terms []*Term
+}
+
+// Term is the term of the expression.
+type Term struct {
+	Lit  string
+	Expr *Expr // the parenthesized expression
+}
+
+// Tree is the tree, referencing itself through the map and the pointer.
+type Tree struct {
+	Children map[string]*Tree
+	Parent   *Tree
+}
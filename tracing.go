@@ -0,0 +1,62 @@
+package reflectshape
+
+import (
+	"runtime"
+	"strings"
+)
+
+// SpanAttribute is the attribute of the span, the key-value pair of OpenTelemetry.
+// The value is string, int, or []string. (e.g. convert by attribute.String, attribute.Int, and attribute.StringSlice)
+type SpanAttribute struct {
+	Key   string
+	Value interface{}
+}
+
+// SpanName returns the name of the span for the function, "<package name>.<name>". (e.g. "user.Create", "user.Service.Create" for the method)
+// The name is of low cardinality, the same for all calls, so it is for the instrumentation wrappers naming the spans automatically.
+func (f *Func) SpanName() string {
+	if f.Shape.Package.Name == "" {
+		return f.Shape.Name
+	}
+	return f.Shape.Package.Name + "." + f.Shape.Name
+}
+
+// SpanAttributes returns the attributes of the span for the function, in the semantic conventions of OpenTelemetry.
+//
+//	code.namespace   the package path, with the receiver for the method (e.g. "github.com/foo/user.Service")
+//	code.function    the name of the function, or the method (e.g. "Create")
+//	code.filepath    the file of the function (if found)
+//	code.lineno      the line of the declaration (if found, or the line of the first statement without the source)
+//	code.function.args  the names of the arguments (e.g. ["ctx", "name"], see Config.FillArgNames for the unnamed ones)
+//
+// code.function.args is not the key of the semantic conventions, but the extension of this package.
+func (f *Func) SpanAttributes() []SpanAttribute {
+	namespace, name := f.Shape.Package.Path, f.Shape.Name
+	if (f.IsMethod() || f.Recv() != "") && !f.IsClosure() { // the method value, or the method expression (e.g. (*T).M)
+		if i := strings.LastIndexByte(name, '.'); i >= 0 {
+			namespace, name = namespace+"."+name[:i], name[i+1:]
+		}
+	}
+	attrs := []SpanAttribute{
+		{Key: "code.namespace", Value: namespace},
+		{Key: "code.function", Value: name},
+	}
+	if f.metadata != nil && f.metadata.Raw.Pos.IsValid() && f.Shape.e.Lookup != nil { // the line of the declaration
+		position := f.Shape.e.Lookup.Fset.Position(f.metadata.Raw.Pos)
+		attrs = append(attrs, SpanAttribute{Key: "code.filepath", Value: position.Filename}, SpanAttribute{Key: "code.lineno", Value: position.Line})
+	} else if pc := f.Shape.ID.pc; pc != 0 {
+		// without the source, the line of the entry (the first statement, not the declaration)
+		if rfunc := runtime.FuncForPC(pc); rfunc != nil {
+			file, line := rfunc.FileLine(rfunc.Entry())
+			attrs = append(attrs, SpanAttribute{Key: "code.filepath", Value: file}, SpanAttribute{Key: "code.lineno", Value: line})
+		}
+	}
+
+	args := f.Args()
+	names := make([]string, len(args))
+	for i, v := range args {
+		names[i] = v.Name
+	}
+	attrs = append(attrs, SpanAttribute{Key: "code.function.args", Value: names})
+	return attrs
+}
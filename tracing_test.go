@@ -0,0 +1,54 @@
+package reflectshape_test
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	reflectshape "github.com/podhmo/reflect-shape"
+)
+
+func TestFuncSpan(t *testing.T) {
+	cfg := &reflectshape.Config{IncludeGoTestFiles: true, FillArgNames: true}
+	const pkg = "github.com/podhmo/reflect-shape_test"
+
+	cases := []struct {
+		msg       string
+		input     any
+		name      string
+		namespace string
+		function  string
+		args      []string
+		lineno    int // the line of the declaration, if checked
+	}{
+		{msg: "func", input: IssueInvoice, name: "reflect-shape_test.IssueInvoice", namespace: pkg, function: "IssueInvoice", args: []string{"amount"}, lineno: 21}, // findings_test.go
+		{msg: "method-expression", input: (*Queue).Push, name: "reflect-shape_test.Queue.Push", namespace: pkg + ".Queue", function: "Push", args: []string{"recv", "ctx", "item"}},
+		{msg: "method-value", input: (&Queue{}).Push, name: "reflect-shape_test.Queue.Push", namespace: pkg + ".Queue", function: "Push", args: []string{"ctx", "item"}},
+	}
+	for _, c := range cases {
+		c := c
+		t.Run(c.msg, func(t *testing.T) {
+			fn := cfg.Extract(c.input).Func()
+			if want, got := c.name, fn.SpanName(); want != got {
+				t.Errorf("Func.SpanName(): want:%q != got:%q", want, got)
+			}
+
+			attrs := map[string]interface{}{}
+			for _, a := range fn.SpanAttributes() {
+				attrs[a.Key] = a.Value
+			}
+			want := map[string]interface{}{"code.namespace": c.namespace, "code.function": c.function, "code.function.args": c.args}
+			got := map[string]interface{}{"code.namespace": attrs["code.namespace"], "code.function": attrs["code.function"], "code.function.args": attrs["code.function.args"]}
+			if diff := cmp.Diff(want, got); diff != "" {
+				t.Errorf("Func.SpanAttributes(): mismatch (-want, +got): \n%v", diff)
+			}
+			if file, _ := attrs["code.filepath"].(string); file == "" {
+				t.Errorf("Func.SpanAttributes(): code.filepath must be found, but %v", attrs)
+			}
+			if line, _ := attrs["code.lineno"].(int); line == 0 {
+				t.Errorf("Func.SpanAttributes(): code.lineno must be found, but %v", attrs)
+			} else if c.lineno != 0 && c.lineno != line {
+				t.Errorf("Func.SpanAttributes(): code.lineno want:%d != got:%d", c.lineno, line)
+			}
+		})
+	}
+}
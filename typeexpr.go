@@ -0,0 +1,249 @@
+package reflectshape
+
+import (
+	"fmt"
+	"path"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Imports is the import set of the generated code, it renders the shapes as the Go type expressions (e.g. "map[string]*foo.User"),
+// and collects the packages used by them. (path -> alias)
+//
+//	imports := reflectshape.NewImports("github.com/foo/bar/gen")
+//	expr := imports.TypeExpr(shape) // e.g. "[]*model.User"
+//	fmt.Fprintln(w, imports) // import (...)
+type Imports struct {
+	Here string // the package path of the generated code, the types in this package are not qualified
+
+	aliases map[string]string // path -> alias
+	paths   map[string]string // alias -> path
+}
+
+// typeRenderer renders the reflect.Type as the Go type expression, the package qualifiers are decided by the qualifier.
+type typeRenderer struct {
+	qualifier func(pkgpath string, pkgname string) string // returns "" if not qualified (pkgname is "" if unknown)
+}
+
+// NewImports returns the import set of the generated code in the package here.
+func NewImports(here string) *Imports {
+	return &Imports{Here: here, aliases: map[string]string{}, paths: map[string]string{}}
+}
+
+// TypeExpr returns the Go type expression of the shape, adding the required imports. The pointer level of the shape is included. (e.g. "*foo.User")
+func (im *Imports) TypeExpr(s *Shape) string {
+	return strings.Repeat("*", s.Lv) + im.renderer().typeExpr(s.Type)
+}
+
+func (im *Imports) renderer() *typeRenderer {
+	return &typeRenderer{qualifier: func(pkgpath, pkgname string) string {
+		if pkgpath == im.Here {
+			return ""
+		}
+		return im.Import(pkgpath, pkgname)
+	}}
+}
+
+// VarExpr returns the Go type expression of the argument or the return value. (e.g. "...string" for the variadic argument)
+func (im *Imports) VarExpr(v *Var) string {
+	if v.Variadic {
+		return "..." + im.TypeExpr(v.Shape)
+	}
+	return im.TypeExpr(v.Shape)
+}
+
+// Import adds the package, and returns the alias of it. If the name is already used by the other package, the alias is numbered. (e.g. "json2")
+// The name is the package name, if empty, it is guessed from the path. (e.g. "yaml" for "gopkg.in/yaml.v3")
+func (im *Imports) Import(pkgpath string, name string) string {
+	if alias, ok := im.aliases[pkgpath]; ok {
+		return alias
+	}
+	if name == "" {
+		name = guessPackageName(pkgpath)
+	}
+	alias := name
+	for i := 2; ; i++ {
+		if _, used := im.paths[alias]; !used {
+			break
+		}
+		alias = fmt.Sprintf("%s%d", name, i)
+	}
+	im.aliases[pkgpath] = alias
+	im.paths[alias] = pkgpath
+	return alias
+}
+
+// Map returns the imports, path -> alias.
+func (im *Imports) Map() map[string]string {
+	r := make(map[string]string, len(im.aliases))
+	for k, v := range im.aliases {
+		r[k] = v
+	}
+	return r
+}
+
+// String returns the import declaration. (the alias is omitted if it is the same as the guessed package name)
+func (im *Imports) String() string {
+	if len(im.aliases) == 0 {
+		return ""
+	}
+	pkgpaths := make([]string, 0, len(im.aliases))
+	for pkgpath := range im.aliases {
+		pkgpaths = append(pkgpaths, pkgpath)
+	}
+	sort.Strings(pkgpaths)
+
+	var b strings.Builder
+	b.WriteString("import (\n")
+	for _, pkgpath := range pkgpaths {
+		if alias := im.aliases[pkgpath]; alias != guessPackageName(pkgpath) {
+			fmt.Fprintf(&b, "\t%s %q\n", alias, pkgpath)
+		} else {
+			fmt.Fprintf(&b, "\t%q\n", pkgpath)
+		}
+	}
+	b.WriteString(")")
+	return b.String()
+}
+
+func (r *typeRenderer) typeExpr(rt reflect.Type) string {
+	if name := rt.Name(); name != "" {
+		if rt.PkgPath() == "" { // builtin (e.g. int, error)
+			return name
+		}
+		pkgname, _, _ := strings.Cut(rt.String(), ".")
+		return r.name(rt.PkgPath(), pkgname, name)
+	}
+
+	switch rt.Kind() {
+	case reflect.Pointer:
+		return "*" + r.typeExpr(rt.Elem())
+	case reflect.Slice:
+		return "[]" + r.typeExpr(rt.Elem())
+	case reflect.Array:
+		return fmt.Sprintf("[%d]%s", rt.Len(), r.typeExpr(rt.Elem()))
+	case reflect.Map:
+		return fmt.Sprintf("map[%s]%s", r.typeExpr(rt.Key()), r.typeExpr(rt.Elem()))
+	case reflect.Chan:
+		elem := r.typeExpr(rt.Elem())
+		switch rt.ChanDir() {
+		case reflect.RecvDir:
+			return "<-chan " + elem
+		case reflect.SendDir:
+			return "chan<- " + elem
+		default:
+			if rt.Elem().Kind() == reflect.Chan && rt.Elem().Name() == "" && rt.Elem().ChanDir() == reflect.RecvDir {
+				return "chan (" + elem + ")" // not chan<- (chan T)
+			}
+			return "chan " + elem
+		}
+	case reflect.Func:
+		return "func" + r.signature(rt)
+	case reflect.Struct:
+		if rt.NumField() == 0 {
+			return "struct{}"
+		}
+		fields := make([]string, rt.NumField())
+		for i := 0; i < rt.NumField(); i++ {
+			f := rt.Field(i)
+			typ := r.typeExpr(f.Type)
+			if !f.Anonymous {
+				typ = f.Name + " " + typ
+			}
+			if f.Tag != "" {
+				if strings.Contains(string(f.Tag), "`") {
+					typ += fmt.Sprintf(" %q", f.Tag)
+				} else {
+					typ += " `" + string(f.Tag) + "`"
+				}
+			}
+			fields[i] = typ
+		}
+		return "struct{ " + strings.Join(fields, "; ") + " }"
+	case reflect.Interface:
+		if rt.NumMethod() == 0 {
+			return "interface{}"
+		}
+		methods := make([]string, rt.NumMethod())
+		for i := 0; i < rt.NumMethod(); i++ {
+			m := rt.Method(i)
+			methods[i] = m.Name + r.signature(m.Type)
+		}
+		return "interface{ " + strings.Join(methods, "; ") + " }"
+	default:
+		return rt.String()
+	}
+}
+
+// signature returns the parameters and the results of the func type. (e.g. "(int, ...string) (bool, error)")
+func (r *typeRenderer) signature(rt reflect.Type) string {
+	params := make([]string, rt.NumIn())
+	for i := 0; i < rt.NumIn(); i++ {
+		if i == rt.NumIn()-1 && rt.IsVariadic() {
+			params[i] = "..." + r.typeExpr(rt.In(i).Elem())
+			continue
+		}
+		params[i] = r.typeExpr(rt.In(i))
+	}
+	results := make([]string, rt.NumOut())
+	for i := 0; i < rt.NumOut(); i++ {
+		results[i] = r.typeExpr(rt.Out(i))
+	}
+
+	sig := "(" + strings.Join(params, ", ") + ")"
+	switch len(results) {
+	case 0:
+		return sig
+	case 1:
+		if strings.HasPrefix(results[0], "func(") { // not func() func() (int), the result is ambiguous
+			return sig + " (" + results[0] + ")"
+		}
+		return sig + " " + results[0]
+	default:
+		return sig + " (" + strings.Join(results, ", ") + ")"
+	}
+}
+
+// qualifiedNameRegex matches the fully qualified names in the type arguments. (e.g. "github.com/foo/bar.User" in "[int,github.com/foo/bar.User]")
+var qualifiedNameRegex = regexp.MustCompile(`([A-Za-z0-9_\-~./]+)\.([A-Za-z_][A-Za-z0-9_]*)`)
+
+// name returns the qualified name of the named type. For the generics, the type arguments are qualified too. (reflect names them with the package paths, e.g. Pair[int,github.com/foo/bar.User])
+func (r *typeRenderer) name(pkgpath, pkgname, name string) string {
+	if i := strings.Index(name, "["); i >= 0 {
+		name = name[:i] + qualifiedNameRegex.ReplaceAllStringFunc(name[i:], func(s string) string {
+			m := qualifiedNameRegex.FindStringSubmatch(s)
+			return r.name(m[1], "", m[2])
+		})
+	}
+	if pkgpath == "" {
+		return name
+	}
+	if prefix := r.qualifier(pkgpath, pkgname); prefix != "" {
+		return prefix + "." + name
+	}
+	return name
+}
+
+// guessPackageName returns the package name guessed from the path. (e.g. "yaml" for "gopkg.in/yaml.v3", "chi" for "github.com/go-chi/chi/v5")
+func guessPackageName(pkgpath string) string {
+	name := path.Base(pkgpath)
+	if len(name) >= 2 && name[0] == 'v' && strings.Trim(name[1:], "0123456789") == "" && strings.Contains(pkgpath, "/") { // major version suffix
+		name = path.Base(path.Dir(pkgpath))
+	}
+	if i := strings.Index(name, "."); i >= 0 { // gopkg.in/yaml.v3
+		name = name[:i]
+	}
+	name = strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' {
+			return r
+		}
+		return -1
+	}, name)
+	if name == "" || unicode.IsDigit(rune(name[0])) {
+		name = "_" + name
+	}
+	return name
+}
@@ -0,0 +1,110 @@
+package reflectshape_test
+
+import (
+	"context"
+	htmltemplate "html/template"
+	"testing"
+	"text/template"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	reflectshape "github.com/podhmo/reflect-shape"
+	"github.com/podhmo/reflect-shape/testdata/recursive"
+)
+
+// Rendered has the fields of the various type expressions.
+type Rendered struct {
+	Int      int
+	Trees    map[string][]*recursive.Tree
+	Time     *time.Time
+	Pair     Pair[string, *recursive.Node]
+	Handler  func(context.Context, ...string) (int, error)
+	Factory  func() func() error
+	Events   <-chan chan<- int
+	Recv     chan (<-chan int)
+	Stringer interface{ String() string }
+	Any      any
+	Point    struct {
+		X int `json:"x"`
+		Y int
+	}
+	Matrix    [2][]*int
+	Templates []*template.Template
+	HTML      *htmltemplate.Template
+}
+
+func TestImportsTypeExpr(t *testing.T) {
+	cfg := &reflectshape.Config{SkipComments: true}
+	fields := cfg.Extract(Rendered{}).Struct().Fields()
+
+	imports := reflectshape.NewImports("github.com/podhmo/reflect-shape_test")
+	got := map[string]string{}
+	for _, f := range fields {
+		got[f.Name] = imports.TypeExpr(f.Shape)
+	}
+	want := map[string]string{
+		"Int":       "int",
+		"Trees":     "map[string][]*recursive.Tree",
+		"Time":      "*time.Time",
+		"Pair":      "Pair[string,*recursive.Node]",
+		"Handler":   "func(context.Context, ...string) (int, error)",
+		"Factory":   "func() (func() error)",
+		"Events":    "<-chan chan<- int",
+		"Recv":      "chan (<-chan int)",
+		"Stringer":  "interface{ String() string }",
+		"Any":       "interface{}",
+		"Point":     "struct{ X int `json:\"x\"`; Y int }",
+		"Matrix":    "[2][]*int",
+		"Templates": "[]*template.Template",
+		"HTML":      "*template2.Template",
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Imports.TypeExpr(): mismatch (-want, +got): \n%v", diff)
+	}
+
+	wantImports := map[string]string{
+		"context": "context",
+		"github.com/podhmo/reflect-shape/testdata/recursive": "recursive",
+		"html/template": "template2",
+		"text/template": "template",
+		"time":          "time",
+	}
+	if diff := cmp.Diff(wantImports, imports.Map()); diff != "" {
+		t.Errorf("Imports.Map(): mismatch (-want, +got): \n%v", diff)
+	}
+
+	wantDecl := `import (
+	"context"
+	"github.com/podhmo/reflect-shape/testdata/recursive"
+	template2 "html/template"
+	"text/template"
+	"time"
+)`
+	if want, got := wantDecl, imports.String(); want != got {
+		t.Errorf("Imports.String(), want:\n%s\n!= got:\n%s", want, got)
+	}
+
+	t.Run("other-package", func(t *testing.T) {
+		imports := reflectshape.NewImports("github.com/podhmo/reflect-shape/gen")
+		if want, got := "reflectshape_test.Pair[string,*recursive.Node]", imports.TypeExpr(fields[3].Shape); want != got {
+			t.Errorf("Imports.TypeExpr(), want:%q != got:%q", want, got)
+		}
+		if want, got := "*reflectshape_test.Rendered", imports.TypeExpr(cfg.Extract(&Rendered{})); want != got {
+			t.Errorf("Imports.TypeExpr(), want:%q != got:%q", want, got)
+		}
+	})
+
+	t.Run("import", func(t *testing.T) {
+		imports := reflectshape.NewImports("")
+		for _, c := range []struct{ path, want string }{
+			{path: "gopkg.in/yaml.v3", want: "yaml"},
+			{path: "github.com/go-chi/chi/v5", want: "chi"},
+			{path: "github.com/podhmo/reflect-shape", want: "reflectshape"},
+			{path: "example.com/yaml", want: "yaml2"},
+		} {
+			if want, got := c.want, imports.Import(c.path, ""); want != got {
+				t.Errorf("Imports.Import(%q), want:%q != got:%q", c.path, want, got)
+			}
+		}
+	})
+}
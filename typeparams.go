@@ -0,0 +1,76 @@
+package reflectshape
+
+import "strings"
+
+// TypeParam is the type parameter of the generic type, with the type argument of the instantiation.
+type TypeParam struct {
+	Name       string // e.g. "T" ("" if the source is not available)
+	Constraint string // e.g. "any", "~int | ~string" ("" if the source is not available)
+	Arg        string // the type argument of the instantiation, as reflect names it (e.g. "int", "github.com/foo/bar.User")
+}
+
+// TypeParams returns the type parameters of the generic type instantiation (e.g. List[int]), or nil if the shape is not generic.
+// The names and the constraints are read from the generic declaration in the source, the arguments are from the reflect name.
+func (s *Shape) TypeParams() []*TypeParam {
+	args := s.TypeArgs()
+	if args == nil {
+		return nil
+	}
+	r := make([]*TypeParam, len(args))
+	for i, arg := range args {
+		r[i] = &TypeParam{Arg: arg}
+	}
+
+	if s.e == nil || s.ID.pc != 0 {
+		return r
+	}
+	metadata, _, err := s.e.lookupType(s.Type)
+	if err != nil {
+		s.e.Config.logf("TypeParams(): %+v", err)
+		return r
+	}
+	if metadata == nil || len(metadata.TypeParams) != len(r) {
+		return r
+	}
+	for i, p := range metadata.TypeParams {
+		r[i].Name = p.Name
+		r[i].Constraint = p.Constraint
+	}
+	return r
+}
+
+// TypeArgs returns the type arguments of the generic type instantiation. (e.g. ["string", "[]int"] for Map[string,[]int])
+// It doesn't need the source, the arguments are parsed from the name.
+func (s *Shape) TypeArgs() []string {
+	if s.ID.pc != 0 {
+		return nil
+	}
+	return parseTypeArgs(s.Name)
+}
+
+// parseTypeArgs splits the type arguments in the name, at the top-level commas. (e.g. "Pair[map[string]int,func(int, int)]")
+func parseTypeArgs(name string) []string {
+	start := strings.Index(name, "[")
+	if start < 0 || !strings.HasSuffix(name, "]") {
+		return nil
+	}
+	inner := name[start+1 : len(name)-1]
+
+	var r []string
+	depth := 0
+	prev := 0
+	for i := 0; i < len(inner); i++ {
+		switch inner[i] {
+		case '[', '(', '{':
+			depth++
+		case ']', ')', '}':
+			depth--
+		case ',':
+			if depth == 0 {
+				r = append(r, strings.TrimSpace(inner[prev:i]))
+				prev = i + 1
+			}
+		}
+	}
+	return append(r, strings.TrimSpace(inner[prev:]))
+}
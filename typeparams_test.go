@@ -0,0 +1,63 @@
+package reflectshape_test
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	reflectshape "github.com/podhmo/reflect-shape"
+)
+
+// Pair is the pair of the values.
+type Pair[K comparable, V any] struct {
+	Key   K
+	Value V
+}
+
+// Numbers is the numbers.
+type Numbers[T ~int | ~float64] struct {
+	Values []T
+}
+
+func TestTypeParams(t *testing.T) {
+	cfg := &reflectshape.Config{IncludeGoTestFiles: true}
+
+	cases := []struct {
+		msg   string
+		input any
+		want  []*reflectshape.TypeParam
+	}{
+		{msg: "not-generic", input: Person{}, want: nil},
+		{msg: "struct", input: Pair[string, []int]{},
+			want: []*reflectshape.TypeParam{{Name: "K", Constraint: "comparable", Arg: "string"}, {Name: "V", Constraint: "any", Arg: "[]int"}}},
+		{msg: "nested", input: Pair[string, Pair[int, map[string]bool]]{},
+			want: []*reflectshape.TypeParam{{Name: "K", Constraint: "comparable", Arg: "string"}, {Name: "V", Constraint: "any", Arg: "github.com/podhmo/reflect-shape_test.Pair[int,map[string]bool]"}}},
+		{msg: "union", input: Numbers[float64]{},
+			want: []*reflectshape.TypeParam{{Name: "T", Constraint: "~int | ~float64", Arg: "float64"}}},
+	}
+	for _, c := range cases {
+		c := c
+		t.Run(c.msg, func(t *testing.T) {
+			s := cfg.Extract(c.input)
+			if diff := cmp.Diff(c.want, s.TypeParams()); diff != "" {
+				t.Errorf("Shape.TypeParams() mismatch (-want, +got): \n%v", diff)
+			}
+			if want, got := c.want != nil, s.TypeArgs() != nil; want != got {
+				t.Errorf("Shape.TypeArgs() is found: want:%v != got:%v", want, got)
+			}
+		})
+	}
+
+	t.Run("doc", func(t *testing.T) {
+		if want, got := "Pair is the pair of the values.", cfg.Extract(Pair[int, int]{}).Struct().Doc(); want != got {
+			t.Errorf("Shape.Struct().Doc(): want:%q != got:%q", want, got)
+		}
+	})
+
+	t.Run("without-source", func(t *testing.T) {
+		cfg := &reflectshape.Config{SkipComments: true}
+		want := []*reflectshape.TypeParam{{Arg: "int"}, {Arg: "func(int, string) error"}}
+		if diff := cmp.Diff(want, cfg.Extract(Pair[int, func(int, string) error]{}).TypeParams()); diff != "" {
+			t.Errorf("Shape.TypeParams() mismatch (-want, +got): \n%v", diff)
+		}
+	})
+}
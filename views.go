@@ -0,0 +1,116 @@
+package reflectshape
+
+// views are the materialized Fields(), Args(), Returns() and the canonical form of the shape, cached by the extractor.
+// (the exporters call them repeatedly, and building them needs the lookups of the metadata and the shapes of the elements)
+type views struct {
+	fields  FieldList
+	args    VarList
+	returns VarList
+
+	hasFields bool // fields can be empty (e.g. all fields are vetoed)
+
+	canonical []byte // the canonical form for the fingerprint (see fingerprint.go), never modified once built
+}
+
+// cachedFields returns the copy of the cached fields of the shape. If cacheable is false (e.g. the lookup is failed), the fields are not cached.
+func (e *Extractor) cachedFields(id ID, cacheable bool, build func() FieldList) FieldList {
+	if !cacheable {
+		return build()
+	}
+	e.mu.Lock()
+	v := e.views[id]
+	e.mu.Unlock()
+	if v != nil && v.hasFields {
+		return v.fields.clone()
+	}
+
+	fields := build()
+	e.mu.Lock()
+	if v := e.view(id); v != nil {
+		v.fields, v.hasFields = fields, true
+	}
+	e.mu.Unlock()
+	return fields.clone()
+}
+
+// cachedVars returns the copy of the cached vars (args or returns, selected by field) of the shape.
+func (e *Extractor) cachedVars(id ID, cacheable bool, field func(*views) *VarList, build func() VarList) VarList {
+	if !cacheable {
+		return build()
+	}
+	e.mu.Lock()
+	v := e.views[id]
+	e.mu.Unlock()
+	if v != nil && *field(v) != nil {
+		return field(v).clone()
+	}
+
+	vars := build()
+	e.mu.Lock()
+	if v := e.view(id); v != nil {
+		*field(v) = vars
+	}
+	e.mu.Unlock()
+	return vars.clone()
+}
+
+// view returns the views of the shape, creating if not found. (e.mu must be held)
+// It returns nil if the shape is evicted, so the views of the shapes held past the eviction are not cached. (bounded by Config.MaxShapes)
+func (e *Extractor) view(id ID) *views {
+	if e.views == nil {
+		e.views = map[ID]*views{}
+	}
+	v, ok := e.views[id]
+	if !ok {
+		if _, seen := e.seen[id]; !seen {
+			return nil
+		}
+		v = &views{}
+		e.views[id] = v
+	}
+	return v
+}
+
+// forgetViews drops the cached views of the shapes in the package. (e.g. after Registry.Refresh)
+func (e *Extractor) forgetViews(pkgpath string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	for id := range e.views {
+		if s, ok := e.seen[id]; !ok || s.Package.Path == pkgpath {
+			delete(e.views, id)
+		}
+	}
+}
+
+// clone returns the copy of the fields, with the copied shapes and indices, so that the consumers cannot poison the cache.
+func (fl FieldList) clone() FieldList {
+	r := make(FieldList, len(fl))
+	for i, f := range fl {
+		copied := *f
+		copied.Shape = cloneViewShape(f.Shape)
+		copied.Index = append([]int(nil), f.Index...)
+		r[i] = &copied
+	}
+	return r
+}
+
+// clone returns the copy of the vars, with the copied shapes.
+func (vl VarList) clone() VarList {
+	r := make(VarList, len(vl))
+	for i, v := range vl {
+		copied := *v
+		copied.Shape = cloneViewShape(v.Shape)
+		r[i] = &copied
+	}
+	return r
+}
+
+// cloneViewShape returns the copy of the shape in the views, with the copied package. (the scope is shared, as copyCached)
+func cloneViewShape(s *Shape) *Shape {
+	copied := *s
+	if s.Package != nil {
+		pkg := *s.Package
+		copied.Package = &pkg
+	}
+	return &copied
+}
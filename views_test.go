@@ -0,0 +1,62 @@
+package reflectshape_test
+
+import (
+	"reflect"
+	"testing"
+
+	reflectshape "github.com/podhmo/reflect-shape"
+)
+
+func TestFieldsCopyOnRead(t *testing.T) {
+	cfg := &reflectshape.Config{IncludeGoTestFiles: true}
+	s := cfg.Extract(Person{}).Struct()
+
+	fields := s.Fields()
+	want := fields[0].Name
+	fields[0].Name = "modified"
+	fields[0].Shape.Name = "modified"
+	fields[1].Shape.Package.Name = "modified"
+	fields[1].Index[0] = 9
+	fields[0] = nil
+
+	got := cfg.Extract(Person{}).Struct().Fields()
+	if got[0] == nil || got[0].Name != want || got[0].Shape.Name == "modified" {
+		t.Errorf("the cached fields are poisoned: %v", got)
+	}
+	if want, got := "reflect-shape_test", got[1].Shape.Package.Name; want != got {
+		t.Errorf("the package of the cached field is poisoned: want:%v != got:%v", want, got)
+	}
+	if want, got := []int{1}, got[1].Index; !reflect.DeepEqual(want, got) {
+		t.Errorf("the index of the cached field is poisoned: want:%v != got:%v", want, got)
+	}
+}
+
+func TestArgsCopyOnRead(t *testing.T) {
+	cfg := &reflectshape.Config{IncludeGoTestFiles: true}
+	fn := cfg.Extract(Hello).Func()
+
+	args := fn.Args()
+	want := args[0].Name
+	args[0].Name = "modified"
+	args[0].Shape.Package.Name = "modified"
+
+	if got := cfg.Extract(Hello).Func().Args()[0].Name; want != got {
+		t.Errorf("the cached args are poisoned: want:%v != got:%v", want, got)
+	}
+	if want, got := "reflect-shape_test", cfg.Extract(Hello).Func().Args()[0].Shape.Package.Name; want != got {
+		t.Errorf("the package of the cached arg is poisoned: want:%v != got:%v", want, got)
+	}
+	if want, got := len(fn.Returns()), cfg.Extract(Hello).Func().Returns().Len(); want != got {
+		t.Errorf("Returns(): want:%v != got:%v", want, got)
+	}
+}
+
+func BenchmarkFields(b *testing.B) {
+	cfg := &reflectshape.Config{IncludeGoTestFiles: true}
+	s := cfg.Extract(UserCreated{}).Struct()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		s.Fields()
+	}
+}